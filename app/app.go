@@ -1,11 +1,16 @@
 package app
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -26,6 +31,15 @@ type Application struct {
 	activityRepository repository.ActivityRepository
 	userRepository     repository.UserRepository
 	invoiceRepository  repository.InvoiceRepository
+	notification       service.NotificationService
+	accountVerifier    service.AccountVerifier
+	currencyConverter  service.CurrencyConverter
+	senderPolicy       domain.SenderPolicy
+	geoLocator         service.GeoLocator
+	// requireEmailVerification refuses logins until the user has verified their email
+	requireEmailVerification bool
+	// amendGracePeriod is how long after issuing an invoice it can still be amended
+	amendGracePeriod time.Duration
 }
 
 // NewApplication initializes a new application with the provided dependencies.
@@ -37,6 +51,9 @@ type Application struct {
 //   - activityRepository: repository.ActivityRepository, a repository for storing and retrieving user activities.
 //   - userRepository: repository.UserRepository, a repository for storing and retrieving user information.
 //   - invoiceRepository: repository.InvoiceRepository, a repository for storing and retrieving invoice information.
+//   - notification: service.NotificationService, a service for delivering notifications to customers.
+//   - accountVerifier: service.AccountVerifier, a service for resolving bank account details.
+//   - currencyConverter: service.CurrencyConverter, an optional converter to the user's default currency, nil when disabled.
 //
 // Returns:
 //   - *Application, a pointer to a new Application instance with the provided dependencies.
@@ -47,6 +64,9 @@ func NewApplication(
 	activityRepository repository.ActivityRepository,
 	userRepository repository.UserRepository,
 	invoiceRepository repository.InvoiceRepository,
+	notification service.NotificationService,
+	accountVerifier service.AccountVerifier,
+	currencyConverter service.CurrencyConverter,
 	// well we can add other dependencies as needed
 
 ) *Application {
@@ -58,9 +78,36 @@ func NewApplication(
 		activityRepository: activityRepository,
 		userRepository:     userRepository,
 		invoiceRepository:  invoiceRepository,
+		notification:       notification,
+		accountVerifier:    accountVerifier,
+		currencyConverter:  currencyConverter,
 	}
 }
 
+// SetSenderPolicy sets which sender emails users may put on their invoices. The policy is not enforced
+// until it is set.
+func (app *Application) SetSenderPolicy(policy domain.SenderPolicy) {
+	app.senderPolicy = policy
+}
+
+// SetGeoLocator sets the provider used to locate the IP address of logins. Without one, logins
+// record only the IP address.
+func (app *Application) SetGeoLocator(locator service.GeoLocator) {
+	app.geoLocator = locator
+}
+
+// SetRequireEmailVerification sets whether users must verify their email before they can log in.
+// Accounts created before email verification existed are unverified, so it is off by default.
+func (app *Application) SetRequireEmailVerification(require bool) {
+	app.requireEmailVerification = require
+}
+
+// SetAmendGracePeriod sets how long after issuing an invoice its notes, discount, items or due date can still
+// be amended. Issued invoices cannot be amended until it is set.
+func (app *Application) SetAmendGracePeriod(grace time.Duration) {
+	app.amendGracePeriod = grace
+}
+
 // SignUpHandler handles the user registration process.
 // It parses the request body, validates the input, hashes the password,
 // creates a new user, and attempts to add the user to the database.
@@ -70,31 +117,23 @@ func NewApplication(
 func (app *Application) SignUpHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		data := new(SignUpRequestModel)
-		print(data)
 
 		// bind the request body to the data struct
 		if err := c.BodyParser(data); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   err.Error(),
-				"message": fmt.Sprintf("%s: %q", ErrInvalidInputReceived.Error(), "from the client"),
-			})
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("%s: %q", ErrInvalidInputReceived.Error(), "from the client"))
 		}
 
 		// validate the data input
 		validateData := FieldValidator(data)
-		if validateData != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "invalid input",
-				"message": fmt.Sprintf("%q: %v", "from the client", validateData),
-			})
+		if len(validateData) > 0 {
+			f := validateData[0]
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
 		}
 
 		//hHash the user's password
 		hashedPassword, err := app.passwordHasher.CreateHash(data.Password)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to hash password: "+err.Error())
 		}
 
 		// server-side validation of the user input
@@ -106,20 +145,41 @@ func (app *Application) SignUpHandler() fiber.Handler {
 			data.PhoneNumber,
 		)
 		if err != nil {
-			slog.Info("No user is created", "user", data)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "cannot create user",
-				"message": ErrInvalidInputReceived.Error(),
-			})
+			requestLogger(c).Info("No user is created", "user", data)
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeValidationFailed), "cannot create user: "+err.Error())
+		}
+
+		if data.DefaultCurrency != "" {
+			currency, err := domain.NormalizeCurrency(data.DefaultCurrency)
+			if err != nil {
+				return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid default currency: "+err.Error())
+			}
+			user.DefaultCurrency = currency
 		}
 
+		// new accounts start unverified until the emailed link is opened
+		verificationToken, err := user.StartEmailVerification()
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "cannot create user, please try again later")
+		}
+		newUserID := user.ID
+
 		// attempt to add the user to the database
 		user, err = app.userRepository.AddUser(app.db, user, user.Email)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "cannot add existing user",
-				"message": fmt.Sprintf("%s: %q", ErrUserAlreadyExists.Error(), "go back to login"),
-			})
+			requestLogger(c).Error("Failed to add user", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "cannot create user, please try again later")
+		}
+
+		logger := requestLogger(c)
+		// AddUser returns the existing account when the email is taken, which keeps its own verification
+		if user.ID == newUserID {
+			go func() {
+				link := fmt.Sprintf("%s/api/verify-email?token=%s", os.Getenv("API_BASE_URL"), verificationToken)
+				if err := app.notification.SendEmailVerification(user.Email, link); err != nil {
+					logger.Error("Failed to send email verification", "error", err)
+				}
+			}()
 		}
 
 		go func() {
@@ -132,7 +192,7 @@ func (app *Application) SignUpHandler() fiber.Handler {
 				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
@@ -154,69 +214,58 @@ func (app *Application) LoginHandler() fiber.Handler {
 
 		// parse the request body into the LoginRequestModel struct
 		if err := c.BodyParser(data); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   err.Error(),
-				"message": "Invalid input received from the client",
-			})
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
 		}
 
 		// validate user input
 		validateData := FieldValidator(data)
 		if len(validateData) > 0 {
 			for _, f := range validateData {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"error":   "Invalid input",
-					"message": fmt.Sprintf("%s: %s", f.Message, f.NameSpace),
-				})
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
 			}
 		}
 
 		// check and verify the stored hashed password in the database
 		user, err := app.userRepository.VerifyLogin(app.db, data.Email, data.Password)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   ErrLoginFailed.Error(),
-				"message": err.Error(),
-			})
+			return respondError(c, fiber.StatusBadRequest, CodeLoginFailed, err.Error())
 		}
 
 		// lets compare login passowrd with the stored hashed password
-		ok, err := app.passwordHasher.VerifyPassword(data.Password, user.Password)
+		ok, err := app.passwordHasher.VerifyPassword(user.Password, data.Password)
 		if !ok || err != nil {
-			slog.Info("Password does not match", "user", user)
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrInvalidCredentials,
-				"message": err.Error(),
-			})
+			requestLogger(c).Info("Password does not match", "userID", user.ID)
+			return respondError(c, fiber.StatusUnauthorized, CodeInvalidCredentials, "email or password is incorrect")
+		}
+
+		if app.requireEmailVerification && !user.EmailVerified {
+			return respondError(c, fiber.StatusForbidden, CodeEmailNotVerified, "Verify your email address before logging in")
 		}
 
 		token, err := app.authorizeJWT.GenerateJWTToken(user.ID, user.Email)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   ErrGenerateToken.Error(),
-				"message": err.Error(),
-			})
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, err.Error())
 		}
 
 		// save the token in the database
 		if err := app.userRepository.SaveToken(app.db, user.ID, token); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   ErrInvalidUpdateToken.Error(),
-				"message": err.Error(),
-			})
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, err.Error())
 		}
 
+		logger := requestLogger(c)
+		ip := clientIP(c)
 		go func() {
 			activity := &domain.Activity{
 				UserID:    user.ID,
 				Action:    infra.UserLoginActivity,
 				Timestamp: time.Now(),
 				Metadata: map[string]interface{}{
-					"email": user.Email,
+					"email":   user.Email,
+					"ip_info": app.locateIP(logger, ip),
 				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
@@ -241,765 +290,4899 @@ func (app *Application) LoginHandler() fiber.Handler {
 	}
 }
 
-// CreateInvoiceHandler handles the creation of a new invoice for a user.
-// It checks for authentication, validates input, and stores the invoice in the database.
+// VerifyEmailHandler verifies the email of the account holding the `token` query value, sent to the user
+// when they signed up. A token can only be used once and expires after domain.EmailVerificationTTL.
 //
 // Returns:
-//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the creation process.
-func (app *Application) CreateInvoiceHandler() fiber.Handler {
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the verification.
+func (app *Application) VerifyEmailHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
-		}
-
-		data := new(InvoiceRequestModel)
-		if err := c.BodyParser(data); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   err.Error(),
-				"message": "Invalid input received from the client",
-			})
-		}
-
-		userID := c.Params("userID")
-		if userID == "" {
-			slog.Error("Invalid user", "Error", "the userID must be provided")
-			panic("user id is invalid")
-		}
-
-		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
-			slog.Error("Invalid user", "Error", "the userID must be a valid UUID")
-			panic(err)
-		}
-
-		validateData := FieldValidator(data)
-		if len(validateData) > 0 {
-			for _, f := range validateData {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"error":   "Invalid input",
-					"message": fmt.Sprintf("%s: %s", f.Message, f.NameSpace),
-				})
-			}
+		token := strings.TrimSpace(c.Query("token"))
+		if token == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "token must be provided")
 		}
 
-		// load and store the values in a list
-		items := make([]domain.Item, 0)
-		for _, val := range data.Items {
-			items = append(items,
-				domain.Item{
-					Description: val.Description,
-					Quantity:    val.Quantity,
-					UnitPrice:   val.UnitPrice,
-					TotalPrice:  val.TotalPrice,
-				})
-		}
-
-		// create a new invoice object from the input data and store it in memory
-		invoice, err := domain.NewInvoice(
-			userID,
-			data.InvoiceNumber,
-			data.BillingCurrency,
-			data.Discount,
-			data.IssueDate,
-			data.DueDate,
-			items,
-			domain.PaymentInformation{
-				AccountName:   data.PaymentInfo.AccountName,
-				AccountNumber: data.PaymentInfo.AccountNumber,
-				RoutingNumber: data.PaymentInfo.RoutingNumber,
-				BankName:      data.PaymentInfo.BankName,
-			},
-			domain.CustomerDetails{
-				Email:   data.Customer.Email,
-				Name:    data.Customer.Name,
-				Phone:   data.Customer.Phone,
-				Address: data.Customer.Address,
-			},
-			domain.SenderDetails{
-				Email:   data.Sender.Email,
-				Name:    data.Sender.Name,
-				Phone:   data.Sender.Phone,
-				Address: data.Sender.Address,
-			},
-
-			data.Status,
-		)
-
+		user, err := app.userRepository.VerifyEmail(app.db, token)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to create invoice",
-				"message": err.Error(),
-			})
-		}
-
-		// Add the invoice to the database
-		res := app.invoiceRepository.AddNewInvoice(app.db, userID, invoice)
-		if res != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to create invoice",
-				"message": err,
-			})
+			switch {
+			case errors.Is(err, infra.ErrVerificationTokenExpired):
+				return respondError(c, fiber.StatusGone, CodeVerificationTokenExpired, "The verification link has expired")
+			case errors.Is(err, infra.ErrInvalidVerificationToken):
+				return respondError(c, fiber.StatusBadRequest, CodeInvalidVerificationToken, "The verification link is invalid or was already used")
+			}
+			requestLogger(c).Error("Failed to verify email", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to verify email: "+err.Error())
 		}
 
-		// Record user activity
+		logger := requestLogger(c)
 		go func() {
 			activity := &domain.Activity{
-				UserID:    userID,
-				Action:    infra.CreateInvoiceActivity,
+				UserID:    user.ID,
+				Action:    infra.UserVerifiedEmailActivity,
 				Timestamp: time.Now(),
 				Metadata: map[string]interface{}{
-					"invoiceID":       invoice.InvoiceID,
-					"invoiceNumber":   invoice.InvoiceNumber,
-					"billingCurrency": invoice.BillingCurrency,
-					"totalAmount":     invoice.TotalAmountDue,
+					"email": user.Email,
 				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
-		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-			"message": fmt.Sprintf("Invoice: %s has been created successfully", invoice.InvoiceID),
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Email verified successfully",
+			"data":    user.ID,
 		})
 	}
 }
 
-// GetInvoiceHandler retrieves a specific invoice for a user.
-// It checks for authentication, validates request parameters, and fetches the invoice from the database.
+// LogoutHandler signs the authenticated user out by clearing their saved token, so the token presented
+// here and any copy of it are rejected from then on.
 //
 // Returns:
-//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
-func (app *Application) GetInvoiceHandler() fiber.Handler {
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the logout process.
+func (app *Application) LogoutHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
-		}
-		params := c.AllParams()
-		if params == nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
-		}
-		userID := params["userID"]
-		invoiceID := params["invoiceID"]
-		if userID == "" || invoiceID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
 		}
 
-		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
-		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-					"error":   "Invoice not found",
-					"message": fmt.Sprintf("No invoice found with ID %s for user %s", invoiceID, userID),
-				})
+		userID, _ := AuthUserID(c)
+		if err := app.userRepository.ClearToken(app.db, userID); err != nil {
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
 			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to retrieve invoice",
-				"message": err.Error(),
-			})
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, err.Error())
 		}
 
-		// recording user activity
+		logger := requestLogger(c)
 		go func() {
 			activity := &domain.Activity{
 				UserID:    userID,
-				Action:    infra.ViewInvoiceActivity,
+				Action:    infra.UserLogoutActivity,
 				Timestamp: time.Now(),
-				Metadata: map[string]interface{}{
-					"invoiceID":     invoiceID,
-					"invoiceNumber": invoice.InvoiceNumber,
-				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
+		// expire the cookie set on login
+		c.Cookie(&fiber.Cookie{
+			Name:     "bearerToken",
+			Value:    "",
+			MaxAge:   -1,
+			Expires:  time.Unix(0, 0),
+			Path:     "/login",
+			Domain:   "numeris.onrender.com",
+			Secure:   false,
+			HTTPOnly: true,
+		})
+
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Invoice retrieved successfully",
-			"data":    invoice,
+			"message": "Logout successful",
 		})
 	}
 }
 
-// ListAllInvoice retrieves all invoices for a specific user.
-// It checks for authentication, validates the user ID, and fetches the invoices from the database.
-//
-// Parameters:
-//   - c: fiber.Ctx, the context for the current request, which includes request and response objects.
+// ResetPasswordHandler replaces the password of the signed-in user, who is looked up by the id of the token
+// rather than the email in the request. The current password must be confirmed and the new password repeated.
+// The token of the user is revoked, so they have to log in again with the new password.
 //
 // Returns:
-//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
-func (app *Application) ListAllInvoiceHandler() fiber.Handler {
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the reset process.
+func (app *Application) ResetPasswordHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// checking authentication
 		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
 		}
 
-		// get userID from params
-		userID := c.Params("userID")
-		if userID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID must be provided",
-			})
+		data := new(ResetPasswordRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("%s: %q", ErrInvalidInputReceived.Error(), "from the client"))
 		}
 
-		// validate userID
-		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid user ID",
-				"message": "The provided userID is not a valid ObjectID",
-			})
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
 		}
 
-		// get all invoices for the user
-		invoices, err := app.invoiceRepository.FindAllInvoice(app.db, userID)
+		if data.Password != data.ConfirmPassword {
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, "password and confirm_password do not match")
+		}
+
+		authUserID, _ := AuthUserID(c)
+		user, err := app.userRepository.FindByID(app.db, authUserID)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to retrieve invoices",
-				"message": err.Error(),
-			})
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, "No account exists for this user")
 		}
 
-		// cecord user activity for this action
-		go func() {
-			activity := &domain.Activity{
-				UserID:    userID,
-				Action:    infra.ListInvoicesActivity,
-				Timestamp: time.Now(),
-				Metadata: map[string]interface{}{
-					"invoiceCount": len(invoices),
-				},
-			}
-			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+		// a user may only reset their own password
+		if data.Email != "" && !strings.EqualFold(user.Email, data.Email) {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only reset your own password")
+		}
+
+		ok, err := app.passwordHasher.VerifyPassword(user.Password, data.CurrentPassword)
+		if !ok || err != nil {
+			return respondError(c, fiber.StatusUnauthorized, CodeInvalidCredentials, "current password is incorrect")
+		}
+
+		hashedPassword, err := app.passwordHasher.CreateHash(data.Password)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, err.Error())
+		}
+
+		if err := app.userRepository.UpdatePassword(app.db, user.ID, hashedPassword); err != nil {
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, "No account exists for this user")
 			}
-		}()
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to reset password: "+err.Error())
+		}
 
-		// return all the invoices
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Invoices retrieved successfully",
-			"data":    invoices,
+			"message": "Password reset successfully, please log in again",
 		})
 	}
 }
 
-// UpdateUnIssuedInvoice handles the update of an unissued invoice for a specific user.
-// It checks for authentication, validates input, and updates the invoice in the database.
-//
-// Parameters:
-//   - c: fiber.Ctx, the context for the current request, which includes request and response objects.
+// refreshGracePeriod is how long after expiry a token can still be exchanged for a new one.
+const refreshGracePeriod = 24 * time.Hour
+
+// RefreshTokenHandler exchanges the current bearer token for a new one. Tokens that expired less than
+// refreshGracePeriod ago are still accepted, so clients can renew a session without logging in again.
 //
 // Returns:
-//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
-func (app *Application) UpdateUnIssuedInvoiceHandler() fiber.Handler {
-
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the refresh process.
+func (app *Application) RefreshTokenHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Check authentication
-		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
-		}
-
-		userID := c.Params("userID")
-		invoiceID := c.Params("invoiceID")
-		if userID == "" || invoiceID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
+		tokenSlices := strings.SplitN(c.Get("Authorization"), " ", 2)
+		if len(tokenSlices) != 2 || tokenSlices[0] != "Bearer" {
+			return respondError(c, fiber.StatusUnauthorized, CodeUnauthorized, "A bearer token must be provided")
 		}
 
-		updatedInvoice := new(InvoiceRequestModel)
-		if err := c.BodyParser(updatedInvoice); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   err.Error(),
-				"message": "Invalid input received from the client",
-			})
+		claims, err := app.authorizeJWT.ParseTokenWithGrace(tokenSlices[1], refreshGracePeriod)
+		if err != nil {
+			return respondError(c, fiber.StatusUnauthorized, CodeUnauthorized, err.Error())
 		}
 
-		validateData := FieldValidator(updatedInvoice)
-		if len(validateData) > 0 {
-			for _, f := range validateData {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"error":   "Invalid input",
-					"message": fmt.Sprintf("%s: %s", f.Message, f.NameSpace),
-				})
-			}
+		// the user may have been removed, logged out or logged in again since the token was issued
+		current, err := app.userRepository.IsCurrentToken(app.db, claims.UserUUID, tokenSlices[1])
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to check token: "+err.Error())
 		}
-
-		// convert the updated invoice data to domain.Invoice
-		items := make([]domain.Item, len(updatedInvoice.Items))
-		for i, item := range updatedInvoice.Items {
-			items[i] = domain.Item{
-				Description: item.Description,
-				Quantity:    item.Quantity,
-				UnitPrice:   item.UnitPrice,
-				TotalPrice:  item.TotalPrice,
-			}
+		if !current {
+			return respondError(c, fiber.StatusUnauthorized, CodeUnauthorized, "The token has been revoked or does not belong to an existing user")
 		}
 
-		domainInvoice, err := domain.NewInvoice(
-			userID,
-			updatedInvoice.InvoiceNumber,
-			updatedInvoice.BillingCurrency,
-			updatedInvoice.Discount,
-			updatedInvoice.IssueDate,
-			updatedInvoice.DueDate,
-			items,
-			domain.PaymentInformation(updatedInvoice.PaymentInfo),
-			domain.CustomerDetails(updatedInvoice.Customer),
-			domain.SenderDetails(updatedInvoice.Sender),
-			updatedInvoice.Status,
-		)
+		token, err := app.authorizeJWT.GenerateJWTToken(claims.UserUUID, claims.Email)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to create updated invoice",
-				"message": err.Error(),
-			})
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to generate token: "+err.Error())
 		}
 
-		// update the invoice
-		err = app.invoiceRepository.UpdateInvoiceBeforeDueDate(app.db, userID, invoiceID, domainInvoice)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to update invoice",
-				"message": err.Error(),
-			})
+		if err := app.userRepository.SaveToken(app.db, claims.UserUUID, token); err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, err.Error())
 		}
 
-		// record user activity
+		logger := requestLogger(c)
 		go func() {
 			activity := &domain.Activity{
-				UserID:    userID,
-				Action:    infra.UpdateInvoiceActivity,
+				UserID:    claims.UserUUID,
+				Action:    infra.TokenRefreshedActivity,
 				Timestamp: time.Now(),
 				Metadata: map[string]interface{}{
-					"invoiceID":     invoiceID,
-					"invoiceNumber": domainInvoice.InvoiceNumber,
+					"email": claims.Email,
 				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
+		c.Set("Authorization", "Bearer "+token)
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Invoice updated successfully",
-			"data":    domainInvoice,
+			"message": "Token refreshed successfully",
+			"data":    claims.UserUUID,
+			"token":   token,
 		})
 	}
 }
 
-func (app *Application) GetUserInvoiceStatHandler() fiber.Handler {
+// CreateInvoiceHandler handles the creation of a new invoice for a user.
+// It checks for authentication, validates input, and stores the invoice in the database.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the creation process.
+func (app *Application) CreateInvoiceHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// checking authentication
 		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(InvoiceRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
 		}
 
 		userID := c.Params("userID")
 		if userID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID must be provided",
-			})
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be provided")
 		}
 
 		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid user ID",
-				"message": "The provided userID is not a valid ObjectID",
-			})
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
 		}
 
-		// get the invoice statistic aggregated value
-		invoiceStatSummary, err := app.invoiceRepository.InvoiceStatSummary(app.db, userID)
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to get invoice statistic",
-				"message": err.Error(),
-			})
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		if err := app.senderPolicy.Check(user, data.Sender.Email); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeSenderEmailMismatch, err.Error())
+		}
+
+		invoice, err := app.buildInvoice(user, data)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), err.Error())
+		}
+
+		creditWarning, err := app.checkCreditLimit(user, invoice)
+		if err != nil {
+			if errors.Is(err, domain.ErrCreditLimitExceeded) {
+				return respondError(c, fiber.StatusConflict, CodeCreditLimitExceeded, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to check customer credit limit: "+err.Error())
+		}
+
+		// Add the invoice to the database
+		if err := app.invoiceRepository.AddNewInvoice(app.db, userID, invoice); err != nil {
+			requestLogger(c).Error("Failed to add invoice", "userID", userID, "error", err)
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			if errors.Is(err, infra.ErrDuplicateInvoiceNumber) {
+				return respondError(c, fiber.StatusConflict, CodeDuplicateInvoiceNumber, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to create invoice: "+err.Error())
+		}
+
+		// Record user activity
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.CreateInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":       invoice.InvoiceID,
+					"invoiceNumber":   invoice.InvoiceNumber,
+					"billingCurrency": invoice.BillingCurrency,
+					"totalAmount":     invoice.TotalAmountDue,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		response := fiber.Map{
+			"message": fmt.Sprintf("Invoice: %s has been created successfully", invoice.InvoiceID),
+		}
+		if creditWarning != "" {
+			response["warning"] = creditWarning
+		}
+		return c.Status(fiber.StatusCreated).JSON(response)
+	}
+}
+
+// maxBulkInvoices is the largest batch BulkCreateInvoicesHandler accepts, so one transaction stays short.
+const maxBulkInvoices = 100
+
+// BulkCreateInvoicesHandler creates a batch of invoices from a JSON array of invoice requests, for users migrating
+// from other systems. The batch is all-or-nothing: every invoice is validated first, and a batch with an invalid
+// invoice is rejected with a result per invoice, so nothing is created until the whole batch is valid.
+// A batch holds at most maxBulkInvoices invoices.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the creation process.
+func (app *Application) BulkCreateInvoicesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		var data []InvoiceRequestModel
+		if err := c.BodyParser(&data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The request body must be a JSON array of invoices")
+		}
+		if len(data) == 0 {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The batch has no invoices")
+		}
+		if len(data) > maxBulkInvoices {
+			return respondError(c, fiber.StatusRequestEntityTooLarge, CodeBatchTooLarge, fmt.Sprintf("A batch can hold at most %d invoices", maxBulkInvoices))
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		var outstanding map[string]float64
+		if len(user.CreditLimits) > 0 {
+			outstanding, err = app.invoiceRepository.OutstandingByCustomer(app.db, userID)
+			if err != nil {
+				return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to check customer credit limit: "+err.Error())
+			}
+		}
+
+		invoices := make([]*domain.Invoice, len(data))
+		results := make([]BulkInvoiceResult, len(data))
+		numbers := make(map[string]int)
+		invalid := 0
+		for idx := range data {
+			results[idx].Index = idx
+			invoice, warning, apiErr := app.prepareBulkInvoice(user, &data[idx], outstanding, numbers, idx)
+			if apiErr != nil {
+				results[idx].Code = apiErr.Code
+				results[idx].Error = apiErr.Message
+				invalid++
+				continue
+			}
+			invoices[idx] = invoice
+			results[idx].InvoiceNumber = invoice.InvoiceNumber
+			results[idx].Warning = warning
+		}
+
+		if invalid > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"code":    CodeValidationFailed,
+				"message": fmt.Sprintf("%d of %d invoice(s) are invalid, no invoice was created", invalid, len(data)),
+				"data":    results,
+			})
+		}
+
+		if err := app.invoiceRepository.AddNewInvoices(app.db, userID, invoices); err != nil {
+			requestLogger(c).Error("Failed to add invoices", "userID", userID, "error", err)
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			if errors.Is(err, infra.ErrDuplicateInvoiceNumber) {
+				return respondError(c, fiber.StatusConflict, CodeDuplicateInvoiceNumber, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to create invoices: "+err.Error())
+		}
+
+		for idx, invoice := range invoices {
+			results[idx].InvoiceID = invoice.InvoiceID
+			results[idx].InvoiceNumber = invoice.InvoiceNumber
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			for _, invoice := range invoices {
+				activity := &domain.Activity{
+					UserID:    userID,
+					Action:    infra.CreateInvoiceActivity,
+					Timestamp: time.Now(),
+					Metadata: map[string]interface{}{
+						"invoiceID":       invoice.InvoiceID,
+						"invoiceNumber":   invoice.InvoiceNumber,
+						"billingCurrency": invoice.BillingCurrency,
+						"totalAmount":     invoice.TotalAmountDue,
+						"bulk":            true,
+					},
+				}
+				if err := app.activityRepository.Save(app.db, activity); err != nil {
+					logger.Error("Failed to record user activity", "error", err)
+				}
+			}
+		}()
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": fmt.Sprintf("%d invoice(s) created", len(invoices)),
+			"data":    results,
+		})
+	}
+}
+
+// prepareBulkInvoice validates and builds the invoice at position idx of a bulk request, with any credit limit
+// warning. It returns an APIError describing the problem when the invoice is invalid.
+// numbers and outstanding carry the invoice numbers and customer balances of the invoices before it, so the batch
+// cannot reuse a number or exceed a credit limit across invoices.
+func (app *Application) prepareBulkInvoice(user *domain.User, data *InvoiceRequestModel, outstanding map[string]float64, numbers map[string]int, idx int) (*domain.Invoice, string, *APIError) {
+	if validateData := FieldValidator(data); len(validateData) > 0 {
+		f := validateData[0]
+		return nil, "", &APIError{Code: CodeValidationFailed, Message: fmt.Sprintf("%s: %s", f.Message, f.NameSpace)}
+	}
+
+	if err := app.senderPolicy.Check(user, data.Sender.Email); err != nil {
+		return nil, "", &APIError{Code: CodeSenderEmailMismatch, Message: err.Error()}
+	}
+
+	invoice, err := app.buildInvoice(user, data)
+	if err != nil {
+		return nil, "", &APIError{Code: errorCode(err, CodeInvalidRequest), Message: err.Error()}
+	}
+
+	if number := invoice.InvoiceNumber; number != "" {
+		if first, ok := numbers[number]; ok {
+			return nil, "", &APIError{
+				Code:    CodeDuplicateInvoiceNumber,
+				Message: fmt.Sprintf("invoice number %s is already used by invoice %d of the batch", number, first),
+			}
+		}
+		numbers[number] = idx
+	}
+
+	warning, err := checkCreditLimitWith(user, invoice, outstanding)
+	if err != nil {
+		return nil, "", &APIError{Code: errorCode(err, CodeInvalidRequest), Message: err.Error()}
+	}
+	if limit := user.CreditLimitFor(invoice.Customer.Email); limit != nil {
+		outstanding[limit.CustomerEmail] += invoice.TotalAmountDue
+	}
+
+	return invoice, warning, nil
+}
+
+// QuickCreateFromLastHandler bills a customer "same as last time": it clones the latest invoice of the customer
+// into a new draft with the same items, payment term and payment details, a fresh invoice number and new dates.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the creation process.
+func (app *Application) QuickCreateFromLastHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(QuickCreateRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		last, err := app.invoiceRepository.FindLatestCustomerInvoice(app.db, userID, data.CustomerEmail)
+		if err != nil {
+			if errors.Is(err, infra.ErrInvoiceNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, fmt.Sprintf("No previous invoice found for customer %s", data.CustomerEmail))
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve the latest customer invoice: "+err.Error())
+		}
+
+		if err := app.senderPolicy.Check(user, last.Sender.Email); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeSenderEmailMismatch, err.Error())
+		}
+
+		taken, err := app.invoiceRepository.UsedInvoiceNumbers(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoices: "+err.Error())
+		}
+
+		invoiceNumber := strings.TrimSpace(data.InvoiceNumber)
+		if invoiceNumber == "" {
+			invoiceNumber = domain.NextInvoiceNumber(last.InvoiceNumber)
+			for taken[invoiceNumber] {
+				invoiceNumber = domain.NextInvoiceNumber(invoiceNumber)
+			}
+		} else if taken[invoiceNumber] {
+			return respondError(c, fiber.StatusConflict, CodeDuplicateInvoiceNumber, fmt.Sprintf("Invoice number %s is already used by another invoice", invoiceNumber))
+		}
+
+		issueDate := time.Now()
+		if data.IssueDate != "" {
+			// the format was checked by the validator
+			issueDate, _ = time.Parse("2006-01-02", data.IssueDate)
+		}
+
+		invoice, err := last.CloneAsDraft(invoiceNumber, issueDate)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Failed to create invoice: "+err.Error())
+		}
+
+		app.applyBaseCurrency(invoice, user.DefaultCurrency)
+
+		creditWarning, err := app.checkCreditLimit(user, invoice)
+		if err != nil {
+			if errors.Is(err, domain.ErrCreditLimitExceeded) {
+				return respondError(c, fiber.StatusConflict, CodeCreditLimitExceeded, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to check customer credit limit: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.AddNewInvoice(app.db, userID, invoice); err != nil {
+			requestLogger(c).Error("Failed to add invoice", "userID", userID, "error", err)
+			if errors.Is(err, infra.ErrDuplicateInvoiceNumber) {
+				return respondError(c, fiber.StatusConflict, CodeDuplicateInvoiceNumber, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to create invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.CreateInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":       invoice.InvoiceID,
+					"invoiceNumber":   invoice.InvoiceNumber,
+					"billingCurrency": invoice.BillingCurrency,
+					"totalAmount":     invoice.TotalAmountDue,
+					"clonedFrom":      last.InvoiceID,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		response := fiber.Map{
+			"message": fmt.Sprintf("Invoice: %s has been created from invoice %s", invoice.InvoiceNumber, last.InvoiceNumber),
+			"data":    invoice,
+		}
+		if creditWarning != "" {
+			response["warning"] = creditWarning
+		}
+		return c.Status(fiber.StatusCreated).JSON(response)
+	}
+}
+
+// CloneInvoiceHandler copies an invoice of the user into a new draft with a fresh invoice ID, the next
+// sequential invoice number and today's issue date, keeping the payment term of the original. Payment, issue,
+// void and PDF details of the original are not copied, so any invoice can be cloned.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the creation process.
+func (app *Application) CloneInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		original, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		if err := app.senderPolicy.Check(user, original.Sender.Email); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeSenderEmailMismatch, err.Error())
+		}
+
+		// an empty number gets the next number of the user's invoice counter on save
+		invoice, err := original.CloneAsDraft("", time.Now())
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Failed to clone invoice: "+err.Error())
+		}
+
+		app.applyBaseCurrency(invoice, user.DefaultCurrency)
+
+		creditWarning, err := app.checkCreditLimit(user, invoice)
+		if err != nil {
+			if errors.Is(err, domain.ErrCreditLimitExceeded) {
+				return respondError(c, fiber.StatusConflict, CodeCreditLimitExceeded, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to check customer credit limit: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.AddNewInvoice(app.db, userID, invoice); err != nil {
+			requestLogger(c).Error("Failed to add invoice", "userID", userID, "error", err)
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			if errors.Is(err, infra.ErrDuplicateInvoiceNumber) {
+				return respondError(c, fiber.StatusConflict, CodeDuplicateInvoiceNumber, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to clone invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.CreateInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":       invoice.InvoiceID,
+					"invoiceNumber":   invoice.InvoiceNumber,
+					"billingCurrency": invoice.BillingCurrency,
+					"totalAmount":     invoice.TotalAmountDue,
+					"clonedFrom":      original.InvoiceID,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		response := fiber.Map{
+			"message": fmt.Sprintf("Invoice: %s has been cloned from invoice %s", invoice.InvoiceNumber, original.InvoiceNumber),
+			"data":    invoice,
+		}
+		if creditWarning != "" {
+			response["warning"] = creditWarning
+		}
+		return c.Status(fiber.StatusCreated).JSON(response)
+	}
+}
+
+// ImportInvoicesHandler imports draft invoices from the CSV file uploaded in the `file` form field, one invoice
+// per row. The import is tracked by a job under the required Idempotency-Key header: submitting the same file
+// with the same key resumes after the last processed row instead of inserting the rows again, and a completed
+// job returns its report. Rows whose invoice number already exists are skipped and invalid rows are kept for
+// download, see DownloadFailedImportRowsHandler.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the import.
+func (app *Application) ImportInvoicesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		idempotencyKey := strings.TrimSpace(c.Get("Idempotency-Key"))
+		if idempotencyKey == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The Idempotency-Key header must be provided")
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "A CSV file must be uploaded in the file field")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid import file: "+err.Error())
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid import file: "+err.Error())
+		}
+		fileHash := sha256.Sum256(content)
+
+		header, columns, records, err := parseImportFile(bytes.NewReader(content))
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid import file: "+err.Error())
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		job, err := app.invoiceRepository.FindImportJob(app.db, userID, idempotencyKey)
+		if errors.Is(err, infra.ErrNoDataFound) {
+			job, err = domain.NewImportJob(userID, idempotencyKey, fileHeader.Filename, hex.EncodeToString(fileHash[:]), header, len(records))
+			if err != nil {
+				return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid import: "+err.Error())
+			}
+			if err = app.invoiceRepository.CreateImportJob(app.db, job); errors.Is(err, infra.ErrImportJobConflict) {
+				// another request created the job first, continue with it
+				job, err = app.invoiceRepository.FindImportJob(app.db, userID, idempotencyKey)
+			}
+		}
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to start import: "+err.Error())
+		}
+
+		if job.FileHash != hex.EncodeToString(fileHash[:]) {
+			return respondError(c, fiber.StatusConflict, CodeImportJobConflict, "The idempotency key was already used to import a different file")
+		}
+
+		processedBefore := job.ProcessedRows
+		for idx := job.ProcessedRows; idx < len(records); idx++ {
+			result, err := app.importRow(user, columns, header, records[idx], idx+1)
+			if err != nil {
+				requestLogger(c).Error("Failed to import invoice row", "userID", userID, "jobID", job.ID, "row", idx+1, "error", err)
+				// the envelope also carries the job, so the client can see which rows were imported
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"code":    CodeInternal,
+					"message": fmt.Sprintf("Row %d could not be imported, submit the file again to resume: %v", idx+1, err),
+					"data":    job,
+				})
+			}
+
+			job.Record(result, records[idx])
+			if err := app.invoiceRepository.RecordImportRow(app.db, job); err != nil {
+				if errors.Is(err, infra.ErrImportJobConflict) {
+					return respondError(c, fiber.StatusConflict, CodeImportJobConflict, "The import is being processed by another request")
+				}
+				return respondError(c, fiber.StatusInternalServerError, CodeInternal, fmt.Sprintf("Row %d could not be recorded, submit the file again to resume: %v", idx+1, err))
+			}
+		}
+
+		if job.ProcessedRows > processedBefore {
+			logger := requestLogger(c)
+			go func() {
+				activity := &domain.Activity{
+					UserID:    userID,
+					Action:    infra.ImportInvoicesActivity,
+					Timestamp: time.Now(),
+					Metadata: map[string]interface{}{
+						"jobID":       job.ID,
+						"fileName":    job.FileName,
+						"resumedFrom": processedBefore,
+						"createdRows": job.CreatedRows,
+						"skippedRows": job.SkippedRows,
+						"failedRows":  job.FailedRows,
+					},
+				}
+				if err := app.activityRepository.Save(app.db, activity); err != nil {
+					logger.Error("Failed to record user activity", "error", err)
+				}
+			}()
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": fmt.Sprintf("Import %s: %d created, %d skipped, %d failed", job.Status, job.CreatedRows, job.SkippedRows, job.FailedRows),
+			"data":    job,
+		})
+	}
+}
+
+// GetImportJobHandler returns the progress and per-row report of an invoice import job.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetImportJobHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		jobID := c.Params("jobID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(jobID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "jobID must be a valid ObjectID")
+		}
+
+		job, err := app.invoiceRepository.FindImportJobByID(app.db, userID, jobID)
+		if err != nil {
+			if errors.Is(err, infra.ErrNoDataFound) {
+				return respondError(c, fiber.StatusNotFound, errorCode(err, CodeNotFound), "Import job not found: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve import job: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": fmt.Sprintf("Import job %s is %s", job.ID, job.Status),
+			"data":    job,
+		})
+	}
+}
+
+// DownloadFailedImportRowsHandler returns the failed rows of an invoice import job as a CSV file with an error
+// column, so they can be fixed and imported again.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the download.
+func (app *Application) DownloadFailedImportRowsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		jobID := c.Params("jobID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(jobID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "jobID must be a valid ObjectID")
+		}
+
+		job, err := app.invoiceRepository.FindImportJobByID(app.db, userID, jobID)
+		if err != nil {
+			if errors.Is(err, infra.ErrNoDataFound) {
+				return respondError(c, fiber.StatusNotFound, errorCode(err, CodeNotFound), "Import job not found: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve import job: "+err.Error())
+		}
+
+		var buf bytes.Buffer
+		if err := writeFailedImportRows(job, &buf); err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to export failed rows: "+err.Error())
+		}
+
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="import_%s_failed.csv"`, job.ID))
+		c.Set("Content-Type", "text/csv")
+		return c.Status(fiber.StatusOK).Send(buf.Bytes())
+	}
+}
+
+// GetInvoiceHandler retrieves a specific invoice for a user.
+// It checks for authentication, validates request parameters, and fetches the invoice from the database.
+// The optional `item_sort` query value orders the items, see domain.ValidateItemSort.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		params := c.AllParams()
+		if params == nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+		userID := params["userID"]
+		invoiceID := params["invoiceID"]
+		if userID == "" || invoiceID == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			if errors.Is(err, infra.ErrInvoiceNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, fmt.Sprintf("No invoice found with ID %s for user %s", invoiceID, userID))
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoice: "+err.Error())
+		}
+
+		if err := sortInvoiceItems(c, invoice); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid request parameters: "+err.Error())
+		}
+
+		// recording user activity
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.ViewInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": invoice.InvoiceNumber,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		if expand := parseExpand(c); len(expand) > 0 {
+			expanded, err := app.expandInvoices(userID, []*domain.Invoice{invoice}, expand)
+			if err != nil {
+				return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to expand invoice: "+err.Error())
+			}
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"message": "Invoice retrieved successfully",
+				"data":    expanded[0],
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice retrieved successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// ListAllInvoice retrieves a page of the invoices of a specific user, selected with the `limit` and `offset` query values.
+// The optional `status`, `tag`, `from` and `to` query values narrow the list to a status, a tag and an issue date window,
+// and `item_sort` orders the items of every invoice.
+// It checks for authentication, validates the user ID, and fetches the invoices from the database.
+//
+// Parameters:
+//   - c: fiber.Ctx, the context for the current request, which includes request and response objects.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) ListAllInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// checking authentication
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		// get userID from params
+		userID := c.Params("userID")
+		if userID == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be provided")
+		}
+
+		// validate userID
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		filter, err := parseInvoiceFilter(c)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid request parameters: "+err.Error())
+		}
+
+		// get limit and offset from query params, default to the first 20 invoices
+		limit, offset := parsePagination(c, 20, 100)
+
+		invoices, total, err := app.invoiceRepository.FindInvoicePage(app.db, userID, filter, limit, offset)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoices: "+err.Error())
+		}
+
+		if err := sortInvoiceItems(c, invoices...); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid request parameters: "+err.Error())
+		}
+
+		// cecord user activity for this action
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.ListInvoicesActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceCount": len(invoices),
+					"limit":        limit,
+					"offset":       offset,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		page := Pagination{Total: total, Limit: limit, Offset: offset}
+		setPaginationHeaders(c, page)
+
+		if expand := parseExpand(c); len(expand) > 0 {
+			expanded, err := app.expandInvoices(userID, invoices, expand)
+			if err != nil {
+				return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to expand invoices: "+err.Error())
+			}
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"message":    "Invoices retrieved successfully",
+				"data":       expanded,
+				"pagination": page,
+			})
+		}
+
+		// return the requested page of invoices
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":    "Invoices retrieved successfully",
+			"data":       invoices,
+			"pagination": page,
+		})
+	}
+}
+
+// ListInvoiceTagsHandler returns the distinct tags a user has put on their invoices, for autocomplete.
+// The optional `prefix` query value only keeps tags starting with it.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) ListInvoiceTagsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		tags, err := app.invoiceRepository.DistinctTags(app.db, userID, strings.ToLower(strings.TrimSpace(c.Query("prefix"))))
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoice tags: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice tags retrieved successfully",
+			"data":    tags,
+		})
+	}
+}
+
+// UpdateUnIssuedInvoice handles the update of an unissued invoice for a specific user.
+// It checks for authentication, validates input, and updates the invoice in the database. Only draft and pending
+// invoices can be updated, and an update without a status keeps the stored one.
+//
+// Parameters:
+//   - c: fiber.Ctx, the context for the current request, which includes request and response objects.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) UpdateUnIssuedInvoiceHandler() fiber.Handler {
+
+	return func(c *fiber.Ctx) error {
+		// Check authentication
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+		if userID == "" || invoiceID == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+
+		updatedInvoice := new(InvoiceRequestModel)
+		if err := c.BodyParser(updatedInvoice); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(updatedInvoice)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		// numbers are only generated for new invoices
+		if strings.TrimSpace(updatedInvoice.InvoiceNumber) == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, "invoice_number cannot be empty")
+		}
+
+		if err := app.senderPolicy.Check(user, updatedInvoice.Sender.Email); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeSenderEmailMismatch, err.Error())
+		}
+
+		billingCurrency, err := resolveBillingCurrency(updatedInvoice.BillingCurrency, user.DefaultCurrency)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid billing currency: "+err.Error())
+		}
+
+		// convert the updated invoice data to domain.Invoice
+		items := make([]domain.Item, len(updatedInvoice.Items))
+		for i, item := range updatedInvoice.Items {
+			items[i] = domain.Item{
+				Description: item.Description,
+				Quantity:    item.Quantity,
+				UnitPrice:   item.UnitPrice,
+				Billable:    item.Billable,
+				Order:       item.Order,
+			}
+		}
+
+		domainInvoice, err := domain.NewInvoice(
+			userID,
+			updatedInvoice.InvoiceNumber,
+			billingCurrency,
+			updatedInvoice.Discount,
+			updatedInvoice.IssueDate,
+			updatedInvoice.DueDate,
+			items,
+			updatedInvoice.domainPaymentInfo(),
+			domain.CustomerDetails(updatedInvoice.Customer),
+			domain.SenderDetails(updatedInvoice.Sender),
+			// an omitted status keeps the stored one
+			updatedInvoice.Status,
+		)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeValidationFailed), "Invalid invoice: "+err.Error())
+		}
+
+		if err := domainInvoice.SetExpenses(updatedInvoice.domainExpenses()); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid expenses: "+err.Error())
+		}
+
+		if err := domainInvoice.SetTax(updatedInvoice.TaxRate, updatedInvoice.TaxJurisdiction); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid tax: "+err.Error())
+		}
+
+		if err := domainInvoice.SetPaymentMethods(updatedInvoice.domainPaymentMethods()); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid payment methods: "+err.Error())
+		}
+
+		if err := domainInvoice.SetTags(updatedInvoice.Tags); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid tags: "+err.Error())
+		}
+
+		if err := domainInvoice.SetItemSort(updatedInvoice.ItemSort); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid item sort: "+err.Error())
+		}
+
+		app.applyBaseCurrency(domainInvoice, user.DefaultCurrency)
+
+		// update the invoice
+		err = app.invoiceRepository.UpdateInvoiceBeforeDueDate(app.db, userID, invoiceID, domainInvoice)
+		if err != nil {
+			if errors.Is(err, infra.ErrInvoiceNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+			}
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be updated: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to update invoice: "+err.Error())
+		}
+
+		// record user activity
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.UpdateInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": domainInvoice.InvoiceNumber,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice updated successfully",
+			"data":    domainInvoice,
+		})
+	}
+}
+
+// PatchInvoiceHandler changes only the notes, discount, items or due date of a draft or pending invoice, so
+// clients do not have to resubmit the whole invoice. The total is recalculated when the items or discount
+// change, and issued invoices are rejected.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) PatchInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(PatchInvoiceRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		patch := data.domainPatch()
+		if patch.IsEmpty() {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "At least one of notes, discount, items or due_date must be provided")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		if err := invoice.ApplyPatch(patch); err != nil {
+			if !invoice.IsDraft() {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be updated: "+err.Error())
+			}
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, "Invoice cannot be updated: "+err.Error())
+		}
+
+		if patch.ChangesTotal() {
+			app.applyBaseCurrency(invoice, user.DefaultCurrency)
+		}
+
+		if err := app.invoiceRepository.PatchInvoice(app.db, userID, invoice, patch); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, errorCode(err, CodeConflict), "Invoice cannot be updated: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to update invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.UpdateInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": invoice.InvoiceNumber,
+					"fields":        patch.Fields(),
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice updated successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// AmendInvoiceHandler changes the notes, discount, items or due date of an issued invoice within the grace
+// period after it was issued, so a typo noticed right after issuing can be fixed without voiding the invoice.
+// The stored PDF is rendered again from the amended invoice.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) AmendInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(PatchInvoiceRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		patch := data.domainPatch()
+		if patch.IsEmpty() {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "At least one of notes, discount, items or due_date must be provided")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		previousTotal := invoice.TotalAmountDue
+		now := time.Now()
+		if err := invoice.Amend(patch, now, app.amendGracePeriod); err != nil {
+			switch {
+			case errors.Is(err, domain.ErrAmendWindowClosed):
+				return respondError(c, fiber.StatusConflict, CodeAmendWindowClosed, "Invoice cannot be amended: "+err.Error())
+			case invoice.Status != "issued":
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be amended: "+err.Error())
+			}
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, "Invoice cannot be amended: "+err.Error())
+		}
+
+		if patch.ChangesTotal() {
+			app.applyBaseCurrency(invoice, user.DefaultCurrency)
+		}
+
+		if err := app.invoiceRepository.AmendInvoice(app.db, userID, invoice, patch, now.Add(-app.amendGracePeriod)); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, errorCode(err, CodeConflict), "Invoice cannot be amended: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to amend invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		if _, err := app.storeInvoicePDF(userID, invoice); err != nil {
+			logger.Error("Failed to store amended invoice PDF", "invoiceID", invoiceID, "error", err)
+		}
+
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.AmendInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": invoice.InvoiceNumber,
+					"fields":        patch.Fields(),
+					"previousTotal": previousTotal,
+					"total":         invoice.TotalAmountDue,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice amended successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+func (app *Application) GetUserInvoiceStatHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// checking authentication
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if userID == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be provided")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		// get the invoice statistic aggregated value
+		invoiceStatSummary, err := app.invoiceRepository.InvoiceStatSummary(app.db, userID)
+		if err != nil {
+			if errors.Is(err, infra.ErrNoDataFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, "User not found: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to get invoice statistic: "+err.Error())
+		}
+
+		// return the invoice statistic summary
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice statistic retrieved successfully",
+			"data":    invoiceStatSummary,
+		})
+	}
+}
+
+// RecalculateSummaryHandler recomputes the cached invoice summary of the user from the invoices and reports
+// the summary before and after, so totals that drifted can be corrected on demand.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) RecalculateSummaryHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		reconciliation, err := app.recalculateSummary(requestLogger(c), userID)
+		if err != nil {
+			if errors.Is(err, infra.ErrNoDataFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, "User not found: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to recalculate invoice summary: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice summary recalculated successfully",
+			"data":    reconciliation,
+		})
+	}
+}
+
+// ListCurrenciesHandler returns the ISO 4217 currency codes invoices can be billed in.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request.
+func (app *Application) ListCurrenciesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Currencies retrieved successfully",
+			"data":    domain.Currencies(),
+		})
+	}
+}
+
+// PreviewConversionHandler converts an amount to another currency at the current rate without storing anything.
+// The amount is either the total of the invoice given with the `invoice_id` query value, or the `amount` and
+// `from` query values. The `to` query value defaults to the user's default currency.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) PreviewConversionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		if app.currencyConverter == nil {
+			return respondError(c, fiber.StatusServiceUnavailable, CodeUnavailable, "No exchange rates are configured")
+		}
+
+		preview := ConversionPreview{}
+		if invoiceID := c.Query("invoice_id"); invoiceID != "" {
+			invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+			if err != nil {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+			}
+			preview.Amount = invoice.TotalAmountDue
+			preview.From = invoice.BillingCurrency
+		} else {
+			amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+			if err != nil || amount < 0 {
+				return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "amount must be a non-negative number when invoice_id is not given")
+			}
+			preview.Amount = amount
+			preview.From = c.Query("from")
+		}
+
+		to := c.Query("to")
+		if to == "" {
+			user, err := app.userRepository.FindByID(app.db, userID)
+			if err != nil {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			to = user.DefaultCurrency
+		}
+
+		var err error
+		if preview.From, err = domain.NormalizeCurrency(preview.From); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid source currency: "+err.Error())
+		}
+		if preview.To, err = domain.NormalizeCurrency(to); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid target currency: "+err.Error())
+		}
+
+		preview.Rate, preview.RateUpdatedAt, err = app.currencyConverter.Rate(preview.From, preview.To)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Exchange rate unavailable: "+err.Error())
+		}
+		if preview.ConvertedAmount, err = app.currencyConverter.Convert(preview.Amount, preview.From, preview.To); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Exchange rate unavailable: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Conversion preview computed successfully",
+			"data":    preview,
+		})
+	}
+}
+
+func (app *Application) SendIssuedInvoiceToCustomer() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		data := new(UpdateInvoiceStatusRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("%s: %q", ErrInvalidInputReceived.Error(), "from the client"))
+		}
+		// validate the data input
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			f := validateData[0]
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+		}
+
+		// checking authentication
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		// get all the parameters
+		params := c.AllParams()
+		if params == nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+		userID := params["userID"]
+		invoiceID := params["invoiceID"]
+		if userID == "" || invoiceID == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "invoiceID must be a valid ObjectID")
+		}
+
+		coverNote := sanitizeCoverNote(data.Message)
+
+		creditWarning, err := app.issueInvoice(requestLogger(c), userID, invoiceID)
+		if err != nil {
+			if errors.Is(err, infra.ErrInvoiceNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+			}
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, err.Error())
+			}
+			if errors.Is(err, domain.ErrCreditLimitExceeded) {
+				return respondError(c, fiber.StatusConflict, CodeCreditLimitExceeded, err.Error())
+			}
+			if errors.Is(err, domain.ErrSenderEmailMismatch) {
+				return respondError(c, fiber.StatusForbidden, CodeSenderEmailMismatch, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to update invoice status: "+err.Error())
+		}
+
+		// the invoice stays issued when the email fails, the user can share it another way
+		emailErr := app.sendInvoiceEmail(requestLogger(c), userID, invoiceID, coverNote)
+		if emailErr != nil {
+			requestLogger(c).Error("Failed to email issued invoice", "invoiceID", invoiceID, "error", emailErr)
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.IssueInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID": invoiceID,
+					"coverNote": coverNote,
+					"emailSent": emailErr == nil,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err, "userID", userID, "action", infra.IssueInvoiceActivity)
+			}
+		}()
+
+		response := fiber.Map{
+			"message":    "Invoice status updated successfully",
+			"email_sent": emailErr == nil,
+		}
+		if emailErr != nil {
+			response["email_error"] = emailErr.Error()
+		}
+		if creditWarning != "" {
+			response["warning"] = creditWarning
+		}
+		return c.Status(fiber.StatusOK).JSON(response)
+
+	}
+
+}
+
+// IssueAllReadyHandler issues every invoice of the user that is ready to be issued, as listed by
+// GetIssueInvoiceList, and reports the outcome for each invoice. Invoices issued in the meantime are
+// skipped, so running it again never issues an invoice twice.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) IssueAllReadyHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		invoices, err := app.invoiceRepository.GetIssueInvoiceList(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoices ready to be issued: "+err.Error())
+		}
+
+		results := make([]IssueResult, 0, len(invoices))
+		issued := 0
+		for _, invoice := range invoices {
+			result := IssueResult{
+				InvoiceID:     invoice.InvoiceID,
+				InvoiceNumber: invoice.InvoiceNumber,
+				CustomerEmail: invoice.Customer.Email,
+				Status:        "issued",
+			}
+			warning, err := app.issueInvoice(requestLogger(c), userID, invoice.InvoiceID)
+			if err != nil {
+				result.Status = "failed"
+				if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+					result.Status = "skipped"
+				} else {
+					requestLogger(c).Error("Failed to issue invoice", "invoiceID", invoice.InvoiceID, "error", err)
+				}
+				result.Error = err.Error()
+				results = append(results, result)
+				continue
+			}
+			result.Warning = warning
+			issued++
+			results = append(results, result)
+
+			logger := requestLogger(c)
+			go func() {
+				activity := &domain.Activity{
+					UserID:    userID,
+					Action:    infra.IssueInvoiceActivity,
+					Timestamp: time.Now(),
+					Metadata: map[string]interface{}{
+						"invoiceID": result.InvoiceID,
+						"batch":     true,
+					},
+				}
+				if err := app.activityRepository.Save(app.db, activity); err != nil {
+					logger.Error("Failed to record user activity", "error", err, "userID", userID, "action", infra.IssueInvoiceActivity)
+				}
+			}()
+		}
+
+		response := fiber.Map{
+			"message": fmt.Sprintf("%d of %d invoice(s) issued", issued, len(results)),
+			"data":    results,
+		}
+		if issued > 0 {
+			// a failed recalculation does not undo the issued invoices, it can be triggered again on its own
+			if reconciliation, err := app.recalculateSummary(requestLogger(c), userID); err != nil {
+				requestLogger(c).Error("Failed to recalculate invoice summary", "userID", userID, "error", err)
+			} else {
+				response["summary"] = reconciliation
+			}
+		}
+
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+// DeleteInvoiceHandler soft-deletes an invoice: it is kept but left out of lists and stats, and can be brought
+// back with RestoreInvoiceHandler. PurgeInvoiceHandler removes an invoice for good.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the deletion.
+func (app *Application) DeleteInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		params := c.AllParams()
+		if params == nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+
+		userID := params["userID"]
+		invoiceID := params["invoiceID"]
+
+		if userID == "" || invoiceID == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			requestLogger(c).Error("Invalid userID", "error", err)
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
+			requestLogger(c).Error("Invalid invoiceID", "error", err)
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "invoiceID must be a valid ObjectID")
+		}
+
+		err := app.invoiceRepository.SoftDeleteInvoice(app.db, userID, invoiceID, time.Now())
+		if err != nil {
+			requestLogger(c).Error("Failed to delete invoice", "userID", userID, "invoiceID", invoiceID, "error", err)
+			if errors.Is(err, infra.ErrInvoiceNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to delete invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.DeleteInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID": invoiceID,
+					"permanent": false,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		// Return success response
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice deleted successfully, it can be restored",
+		})
+	}
+}
+
+// RestoreInvoiceHandler brings back an invoice deleted with DeleteInvoiceHandler, so it shows up in lists and
+// stats again.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the restore.
+func (app *Application) RestoreInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "invoiceID must be a valid ObjectID")
+		}
+
+		if err := app.invoiceRepository.RestoreDeletedInvoice(app.db, userID, invoiceID); err != nil {
+			if errors.Is(err, infra.ErrInvoiceNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "No deleted invoice found: "+err.Error())
+			}
+			requestLogger(c).Error("Failed to restore invoice", "userID", userID, "invoiceID", invoiceID, "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to restore invoice: "+err.Error())
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to load restored invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.RestoreInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": invoice.InvoiceNumber,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice restored successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// PurgeInvoiceHandler permanently deletes an invoice, whether or not it was soft-deleted first. It cannot be
+// undone, so the user must re-confirm their password.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the deletion.
+func (app *Application) PurgeInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "invoiceID must be a valid ObjectID")
+		}
+
+		data := new(ConfirmPasswordRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, "User not found: "+err.Error())
+		}
+
+		ok, err := app.passwordHasher.VerifyPassword(user.Password, data.Password)
+		if !ok || err != nil {
+			return respondError(c, fiber.StatusUnauthorized, CodeInvalidCredentials, "password confirmation failed")
+		}
+
+		if err := app.invoiceRepository.DeleteInvoice(app.db, userID, invoiceID); err != nil {
+			requestLogger(c).Error("Failed to purge invoice", "userID", userID, "invoiceID", invoiceID, "error", err)
+			if errors.Is(err, infra.ErrInvoiceNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to delete invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.DeleteInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID": invoiceID,
+					"permanent": true,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice permanently deleted",
+		})
+	}
+}
+
+func (app *Application) DownloadInvoicePDFHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Check authentication
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		// Get parameters from request
+		params := c.AllParams()
+		if params == nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+		userID := params["userID"]
+		invoiceID := params["invoiceID"]
+
+		// Validate user ID and invoice ID
+		if userID == "" || invoiceID == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID and invoiceID must be provided")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			requestLogger(c).Error("Invalid userID", "error", err)
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
+			requestLogger(c).Error("Invalid invoiceID", "error", err)
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "invoiceID must be a valid ObjectID")
+		}
+
+		// Get the invoice data
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			if errors.Is(err, infra.ErrInvoiceNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "The specified invoice does not exist for the given user")
+			}
+			requestLogger(c).Error("Failed to retrieve invoice", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoice: "+err.Error())
+		}
+
+		// an item sort only changes drafts, issued invoices keep the order they were stored with
+		if err := sortInvoiceItems(c, invoice); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid request parameters: "+err.Error())
+		}
+
+		// drafts are rendered on every download straight into the response, issued invoices are served from
+		// their stored copy
+		if invoice.IsDraft() {
+			if err := WriteInvoicePDF(invoice, c.Status(fiber.StatusOK).Type("pdf")); err != nil {
+				requestLogger(c).Error("Failed to generate PDF", "error", err)
+				c.Response().ResetBody()
+				return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to generate invoice document: "+err.Error())
+			}
+		} else {
+			content, err := app.invoicePDF(requestLogger(c), userID, invoice)
+			if err != nil {
+				requestLogger(c).Error("Failed to generate PDF", "error", err)
+				return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to generate invoice document: "+err.Error())
+			}
+			if err := c.Status(fiber.StatusOK).Type("pdf").Send(content); err != nil {
+				return err
+			}
+		}
+
+		// set response headers for file download
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invoice_%s_%s.pdf"`, userID, invoiceID))
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.DownloadInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoice": invoice,
+					"stored":  !invoice.IsDraft(),
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return nil
+	}
+}
+
+// RegenerateInvoicePDFHandler replaces the stored PDF of an issued invoice with a freshly rendered one.
+// Downloads of issued invoices keep serving the copy stored at issue time until it is regenerated.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) RegenerateInvoicePDFHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "invoiceID must be a valid ObjectID")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		if invoice.IsDraft() {
+			return respondError(c, fiber.StatusConflict, CodeConflict, "Draft invoices are rendered on every download and have no stored document")
+		}
+
+		previousFileID := invoice.PDFFileID
+		if _, err := app.storeInvoicePDF(userID, invoice); err != nil {
+			requestLogger(c).Error("Failed to regenerate invoice PDF", "invoiceID", invoiceID, "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to regenerate invoice document: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.RegenerateInvoicePDFActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":      invoiceID,
+					"invoiceNumber":  invoice.InvoiceNumber,
+					"previousFileID": previousFileID,
+					"fileID":         invoice.PDFFileID,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice document regenerated successfully",
+			"data": fiber.Map{
+				"invoice_id":  invoiceID,
+				"pdf_file_id": invoice.PDFFileID,
+			},
+		})
+	}
+}
+
+// GetInvoiceActivitiesHandler returns a handler function that retrieves invoice activities for a specific user.
+// It checks for authentication, validates the user ID, and fetches the activities from the database.
+// The optional `from` and `to` query values are RFC3339 timestamps bounding the activities returned.
+//
+// Parameters:
+//   - c: fiber.Ctx, the context for the current request, which includes request and response objects.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetInvoiceActivitiesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Check authentication
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if userID == "" {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be provided")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		from, to, err := parseTimeWindow(c)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid time window: "+err.Error())
+		}
+
+		// get limit and offset from query params, default to the first 10 activities
+		limit, offset := parsePagination(c, 10, 100)
+
+		activities, total, err := app.invoiceRepository.GetInvoiceActivities(app.db, userID, from, to, limit, offset)
+		if err != nil {
+			requestLogger(c).Error("Failed to retrieve invoice activities", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoice activities: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.ViewInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"limit":           limit,
+					"activitiesCount": len(activities),
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		page := Pagination{Total: total, Limit: limit, Offset: offset}
+		setPaginationHeaders(c, page)
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":    "Invoice activities retrieved successfully",
+			"data":       activities,
+			"pagination": page,
+		})
+	}
+}
+
+// GetAllActivitiesHandler returns a page of every activity recorded for a user, newest first, such as logins,
+// account changes and invoice actions. The optional `actions` query value is a comma separated list of the
+// actions to return.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetAllActivitiesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		actions, err := parseActivityActions(c)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid actions: "+err.Error())
+		}
+
+		limit, offset := parsePagination(c, 20, 100)
+
+		activities, total, err := app.activityRepository.FindActivities(app.db, userID, actions, limit, offset)
+		if err != nil {
+			requestLogger(c).Error("Failed to retrieve user activities", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve activities: "+err.Error())
+		}
+
+		page := Pagination{Total: total, Limit: limit, Offset: offset}
+		setPaginationHeaders(c, page)
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":    "Activities retrieved successfully",
+			"data":       activities,
+			"pagination": page,
+		})
+	}
+}
+
+// GetDashboardHandler returns the user's dashboard in one response: the invoice summary, the number of invoices
+// of each status, the most recent activities and the invoices ready to be issued next. The optional `activities`
+// (default 10, at most 50) and `upcoming` (default 5, at most 20) query values set how many of each are listed.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetDashboardHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		activityLimit := parseLimit(c, "activities", 10, 50)
+		upcomingLimit := parseLimit(c, "upcoming", 5, 20)
+
+		dashboard, err := app.invoiceRepository.InvoiceDashboard(app.db, userID, upcomingLimit)
+		if err != nil {
+			if errors.Is(err, infra.ErrNoDataFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			requestLogger(c).Error("Failed to compute dashboard", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to compute dashboard: "+err.Error())
+		}
+
+		activities, _, err := app.activityRepository.FindActivities(app.db, userID, nil, activityLimit, 0)
+		if err != nil {
+			requestLogger(c).Error("Failed to retrieve user activities", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve activities: "+err.Error())
+		}
+		if activities != nil {
+			dashboard.RecentActivities = activities
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Dashboard retrieved successfully",
+			"data":    dashboard,
+		})
+	}
+}
+
+// GetActivityCountsHandler returns how many times each activity was recorded for a user between the
+// optional `from` and `to` dates. Every known action is included, with zero when it did not occur.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetActivityCountsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		from, to, err := parseDateRange(c)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid date range: "+err.Error())
+		}
+
+		counts, err := app.activityRepository.CountByAction(app.db, userID, from, to)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to count activities: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Activity counts retrieved successfully",
+			"data":    counts,
+		})
+	}
+}
+
+// portalLinkInterval is how often a portal link can be sent to the same customer of a user
+const portalLinkInterval = 5 * time.Minute
+
+// portalLinkAction names the rate limit of the portal links sent to a customer. The email is hashed, so it
+// can be used as a document field name.
+func portalLinkAction(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "portal_link_" + hex.EncodeToString(sum[:8])
+}
+
+// RequestPortalLinkHandler issues a customer portal magic link for the invoices a user has sent to a customer.
+// A customer is sent at most one link per portalLinkInterval. The response is the same whether or not the
+// customer exists or was rate limited, and the email is sent in the background, so the endpoint cannot be used
+// to enumerate customers.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) RequestPortalLinkHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		data := new(PortalLinkRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		accepted := func() error {
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+				"message": "If invoices exist for this email, a portal link has been sent to it",
+			})
+		}
+
+		logger := requestLogger(c)
+		invoices, err := app.invoiceRepository.FindCustomerInvoices(app.db, userID, data.Email)
+		if err != nil {
+			logger.Error("Failed to look up customer invoices", "userID", userID, "error", err)
+			return accepted()
+		}
+		if len(invoices) == 0 {
+			return accepted()
+		}
+
+		// the limit is only claimed for existing customers, so requests for unknown emails add nothing to the
+		// user, and a limited request gets the same response as any other
+		claimed, err := app.userRepository.ClaimRateLimit(app.db, userID, portalLinkAction(data.Email), portalLinkInterval, time.Now())
+		if err != nil {
+			logger.Error("Failed to check the portal link limit", "userID", userID, "error", err)
+			return accepted()
+		}
+		if !claimed {
+			return accepted()
+		}
+
+		token, err := app.authorizeJWT.GenerateCustomerToken(userID, strings.ToLower(data.Email))
+		if err != nil {
+			logger.Error("Failed to generate customer token", "userID", userID, "error", err)
+			return accepted()
+		}
+
+		email := data.Email
+		go func() {
+			var identity domain.EmailIdentity
+			if user, err := app.userRepository.FindByID(app.db, userID); err == nil {
+				identity = user.EmailIdentity
+			}
+
+			link := fmt.Sprintf("%s/portal?token=%s", os.Getenv("PORTAL_BASE_URL"), token)
+			if err := app.notification.SendPortalLink(email, link, identity); err != nil {
+				logger.Error("Failed to send customer portal link", "userID", userID, "error", err)
+				return
+			}
+
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.CustomerPortalLinkActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"customerEmail": email,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return accepted()
+	}
+}
+
+// PortalInvoicesHandler lists the invoices sent to the customer identified by the portal token.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) PortalInvoicesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := app.contextWithCustomerAuth(c)
+		if err != nil {
+			return respondError(c, fiber.StatusUnauthorized, CodeUnauthorized, err.Error())
+		}
+
+		invoices, err := app.invoiceRepository.FindCustomerInvoices(app.db, claims.UserUUID, claims.CustomerEmail)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoices: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoices retrieved successfully",
+			"data":    invoices,
+		})
+	}
+}
+
+// PortalInvoiceHandler returns a single invoice sent to the customer identified by the portal token.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) PortalInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := app.contextWithCustomerAuth(c)
+		if err != nil {
+			return respondError(c, fiber.StatusUnauthorized, CodeUnauthorized, err.Error())
+		}
+
+		invoiceID := c.Params("invoiceID")
+		invoices, err := app.invoiceRepository.FindCustomerInvoices(app.db, claims.UserUUID, claims.CustomerEmail)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoice: "+err.Error())
+		}
+
+		for _, invoice := range invoices {
+			if invoice.InvoiceID == invoiceID {
+				return c.Status(fiber.StatusOK).JSON(fiber.Map{
+					"message": "Invoice retrieved successfully",
+					"data":    invoice,
+				})
+			}
+		}
+
+		return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, fmt.Sprintf("No invoice found with ID %s", invoiceID))
+	}
+}
+
+// PortalStatementHandler returns the statement of account for the customer identified by the portal token.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) PortalStatementHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := app.contextWithCustomerAuth(c)
+		if err != nil {
+			return respondError(c, fiber.StatusUnauthorized, CodeUnauthorized, err.Error())
+		}
+
+		invoices, err := app.invoiceRepository.FindCustomerInvoices(app.db, claims.UserUUID, claims.CustomerEmail)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve statement: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Statement retrieved successfully",
+			"data":    domain.NewCustomerStatement(claims.CustomerEmail, invoices),
+		})
+	}
+}
+
+// GetDaysToPaymentHandler returns the average and median number of days between issuing and payment
+// for a user's paid invoices, optionally limited to payments made between `from` and `to`.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetDaysToPaymentHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		from, to, err := parseDateRange(c)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid date range: "+err.Error())
+		}
+
+		metric, err := app.invoiceRepository.AverageDaysToPayment(app.db, userID, from, to)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to compute days to payment: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Days to payment computed successfully",
+			"data":    metric,
+		})
+	}
+}
+
+// GetTaxByJurisdictionHandler reports the tax collected on the user's paid invoices, grouped by jurisdiction,
+// tax rate and currency. The optional `from` and `to` query values (YYYY-MM-DD) bound the payment date.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetTaxByJurisdictionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		from, to, err := parseDateRange(c)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid date range: "+err.Error())
+		}
+
+		taxes, err := app.invoiceRepository.TaxByJurisdiction(app.db, userID, from, to)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to compute tax by jurisdiction: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Tax by jurisdiction computed successfully",
+			"data":    taxes,
+		})
+	}
+}
+
+// CompareRevenueHandler compares the revenue and paid invoice count of the current period with the
+// equivalent prior period. `period` is either `mom` (this month against last month, the default) or
+// `yoy` (this month against the same month last year); `date` (YYYY-MM-DD) selects the current month.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) CompareRevenueHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		anchor := time.Now()
+		if value := c.Query("date"); value != "" {
+			parsed, err := time.Parse(inputDateFormat, value)
+			if err != nil {
+				return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("date must use the %s format", inputDateFormat))
+			}
+			anchor = parsed
+		}
+
+		period := c.Query("period", "mom")
+		currentFrom := time.Date(anchor.Year(), anchor.Month(), 1, 0, 0, 0, 0, time.UTC)
+		currentTo := currentFrom.AddDate(0, 1, 0)
+
+		var previousFrom time.Time
+		switch period {
+		case "mom":
+			previousFrom = currentFrom.AddDate(0, -1, 0)
+		case "yoy":
+			previousFrom = currentFrom.AddDate(-1, 0, 0)
+		default:
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "period must be one of mom or yoy")
+		}
+		previousTo := previousFrom.AddDate(0, 1, 0)
+
+		current, err := app.invoiceRepository.RevenueByPeriod(app.db, userID, currentFrom, currentTo)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to compute revenue: "+err.Error())
+		}
+
+		previous, err := app.invoiceRepository.RevenueByPeriod(app.db, userID, previousFrom, previousTo)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to compute revenue: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Revenue comparison computed successfully",
+			"data":    domain.NewRevenueComparison(period, *current, *previous),
+		})
+	}
+}
+
+// GetMonthlyRevenueHandler returns the revenue collected from paid invoices in each of the last `months` calendar
+// months (default 12, at most 60), ending with the current month. Months without paid invoices have zero revenue.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetMonthlyRevenueHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		months := 12
+		if value := c.Query("months"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > 60 {
+				return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "months must be between 1 and 60")
+			}
+			months = parsed
+		}
+
+		now := time.Now().UTC()
+		to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+		from := to.AddDate(0, -months, 0)
+
+		revenue, err := app.invoiceRepository.MonthlyRevenue(app.db, userID, from, to)
+		if err != nil {
+			requestLogger(c).Error("Failed to compute monthly revenue", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to compute revenue: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Monthly revenue computed successfully",
+			"data":    domain.NewRevenueSeries(from, months, revenue),
+		})
+	}
+}
+
+// GetTopCustomersHandler returns the customers who were billed the most, ranked by the total of their paid
+// invoices. Setting `all=true` counts every invoice sent to them instead. `limit` defaults to 5, at most 50.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetTopCustomersHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		customers, err := app.invoiceRepository.TopCustomers(app.db, userID, !c.QueryBool("all"), parseLimit(c, "limit", 5, 50))
+		if err != nil {
+			requestLogger(c).Error("Failed to rank top customers", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve top customers: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Top customers retrieved successfully",
+			"data":    customers,
+		})
+	}
+}
+
+// MarkInvoicePaidHandler marks an issued or overdue invoice as paid. The optional `paid_at` date backdates
+// the payment, otherwise it is recorded as paid now, and the optional `method` names how it was paid.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) MarkInvoicePaidHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(MarkPaidRequestModel)
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(data); err != nil {
+				return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+			}
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		paidAt := time.Now()
+		if data.PaidAt != "" {
+			paidAt, _ = time.Parse(inputDateFormat, data.PaidAt)
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		if err := invoice.MarkPaid(paidAt, data.Method); err != nil {
+			return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be marked as paid: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.UpdateInvoiceStatusToPaid(app.db, userID, invoiceID, invoice.Payments[len(invoice.Payments)-1]); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be marked as paid: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to mark invoice as paid: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.InvoicePaidActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": invoice.InvoiceNumber,
+					"amount":        invoice.TotalAmountDue,
+					"paidAt":        invoice.PaidAt,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice marked as paid successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// RefundInvoiceHandler refunds a paid invoice, fully or for the optional `amount`, with an optional reason.
+// The refunded amount no longer counts towards the total paid in the invoice statistics.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) RefundInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(RefundInvoiceRequestModel)
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(data); err != nil {
+				return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+			}
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		if err := invoice.Refund(data.Amount, strings.TrimSpace(data.Reason)); err != nil {
+			status := fiber.StatusBadRequest
+			if invoice.Status != "paid" {
+				status = fiber.StatusConflict
+			}
+			return respondError(c, status, statusCode(status), "Invoice cannot be refunded: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.RefundInvoice(app.db, userID, invoice); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be refunded: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to refund invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.InvoiceRefundedActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": invoice.InvoiceNumber,
+					"amount":        invoice.RefundAmount,
+					"reason":        invoice.RefundReason,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice refunded successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// VoidInvoiceHandler voids an issued invoice. The invoice keeps its number and stays on record for audit,
+// but it is excluded from reports and can no longer be edited or paid. Drafts should be deleted instead.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) VoidInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(VoidInvoiceRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		voidedBy, _ := c.Locals("email").(string)
+		if voidedBy == "" {
+			voidedBy = userID
+		}
+
+		if err := invoice.Void(strings.TrimSpace(data.Reason), voidedBy); err != nil {
+			return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be voided: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.VoidInvoice(app.db, userID, invoice); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be voided: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to void invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.InvoiceVoidedActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": invoice.InvoiceNumber,
+					"voidedBy":      invoice.VoidedBy,
+					"reason":        invoice.VoidReason,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice voided successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// CancelInvoiceHandler cancels an invoice that has not been paid. Drafts and issued invoices can both be
+// cancelled; the invoice is kept on record but is no longer issued, reminded or counted in totals.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) CancelInvoiceHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		previousStatus := invoice.Status
+		if err := invoice.Cancel(); err != nil {
+			return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be cancelled: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.CancelInvoice(app.db, userID, invoice); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be cancelled: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to cancel invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.InvoiceCancelledActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":      invoiceID,
+					"invoiceNumber":  invoice.InvoiceNumber,
+					"previousStatus": previousStatus,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice cancelled successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// ScheduleInvoiceSendHandler schedules an invoice that has not been issued yet to be issued and emailed to its
+// customer at `scheduled_send_at`, an RFC 3339 time in the future. Calling it again before the send time
+// reschedules the invoice.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) ScheduleInvoiceSendHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(ScheduleSendRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		// the format was checked by the validator
+		sendAt, _ := time.Parse(time.RFC3339, data.ScheduledSendAt)
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		// the send would fail on the same check, so it is reported now
+		if err := app.senderPolicy.Check(user, invoice.Sender.Email); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeSenderEmailMismatch, err.Error())
+		}
+
+		rescheduled := invoice.Status == "scheduled"
+		if err := invoice.ScheduleSend(sendAt, time.Now()); err != nil {
+			return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be scheduled: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.ScheduleInvoiceSend(app.db, userID, invoice); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be scheduled: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to schedule invoice: "+err.Error())
+		}
+
+		action := "scheduled"
+		if rescheduled {
+			action = "rescheduled"
+		}
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.InvoiceSendScheduledActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":       invoiceID,
+					"invoiceNumber":   invoice.InvoiceNumber,
+					"action":          action,
+					"scheduledSendAt": invoice.ScheduledSendAt,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": fmt.Sprintf("Invoice %s will be sent at %s", invoice.InvoiceNumber, sendAt.Format(time.RFC3339)),
+			"data":    invoice,
+		})
+	}
+}
+
+// UnscheduleInvoiceSendHandler cancels the scheduled send of an invoice before its send time and returns it
+// to draft.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) UnscheduleInvoiceSendHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		scheduledSendAt := invoice.ScheduledSendAt
+		if err := invoice.UnscheduleSend(); err != nil {
+			return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be unscheduled: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.UnscheduleInvoiceSend(app.db, userID, invoice); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict, "Invoice cannot be unscheduled: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to unschedule invoice: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.InvoiceSendScheduledActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":       invoiceID,
+					"invoiceNumber":   invoice.InvoiceNumber,
+					"action":          "cancelled",
+					"scheduledSendAt": scheduledSendAt,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Scheduled send cancelled, the invoice is back to draft",
+			"data":    invoice,
+		})
+	}
+}
+
+// ChangeInvoiceCustomerHandler moves a draft or pending invoice to another customer. The new details are
+// validated like those of a new invoice, and issued invoices are rejected because the customer already has them.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) ChangeInvoiceCustomerHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(CustomerDetails)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		previous := invoice.Customer
+		if err := invoice.ChangeCustomer(domain.CustomerDetails(*data)); err != nil {
+			status := fiber.StatusBadRequest
+			if !invoice.IsDraft() {
+				status = fiber.StatusConflict
+			}
+			return respondError(c, status, statusCode(status), "Invoice customer cannot be changed: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.ChangeInvoiceCustomer(app.db, userID, invoice); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, errorCode(err, CodeConflict), "Invoice customer cannot be changed: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to change invoice customer: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.InvoiceCustomerChangedActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":        invoiceID,
+					"invoiceNumber":    invoice.InvoiceNumber,
+					"previousCustomer": previous,
+					"customer":         invoice.Customer,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice customer changed successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// DownloadReceiptHandler renders and returns the payment receipt of a fully paid invoice.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) DownloadReceiptHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "invoiceID must be a valid ObjectID")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		if invoice.Status != "paid" {
+			return respondError(c, fiber.StatusConflict, CodeConflict, "A receipt is only available once the invoice has been fully paid")
+		}
+
+		var buf bytes.Buffer
+		if err := WriteReceiptPDF(invoice, &buf); err != nil {
+			requestLogger(c).Error("Failed to generate receipt", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to generate receipt: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.ReceiptGeneratedActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":     invoiceID,
+					"invoiceNumber": invoice.InvoiceNumber,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipt_%s.pdf"`, invoice.InvoiceNumber))
+		c.Set("Content-Type", "application/pdf")
+		return c.Status(fiber.StatusOK).Send(buf.Bytes())
+	}
+}
+
+// exportDataInterval is how often a user may export their data, since building the archive is expensive
+const exportDataInterval = time.Hour
+
+// ExportUserDataHandler assembles the user's profile, invoices, activities and customers into a ZIP
+// archive of JSON files. The user must re-confirm their password before the archive is produced, and may
+// export their data once per exportDataInterval; failed password confirmations do not count.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the export.
+func (app *Application) ExportUserDataHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		data := new(ConfirmPasswordRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, "User not found: "+err.Error())
+		}
+
+		ok, err := app.passwordHasher.VerifyPassword(user.Password, data.Password)
+		if !ok || err != nil {
+			return respondError(c, fiber.StatusUnauthorized, CodeInvalidCredentials, "password confirmation failed")
+		}
+
+		// the limit is kept on the authenticated user, so it holds across server processes
+		authUserID, _ := AuthUserID(c)
+		claimed, err := app.userRepository.ClaimRateLimit(app.db, authUserID, "export_data", exportDataInterval, time.Now())
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to check the export limit: "+err.Error())
+		}
+		if !claimed {
+			return respondError(c, fiber.StatusTooManyRequests, CodeTooManyRequests, "Your data was already exported within the last hour, please try again later")
+		}
+
+		invoices, err := app.invoiceRepository.FindAllInvoice(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to export invoices: "+err.Error())
+		}
+
+		activities, err := app.activityRepository.GetUserActivities(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to export activities: "+err.Error())
+		}
+
+		// the profile never carries credentials
+		archive, err := buildJSONArchive([]archiveFile{
+			{Name: "profile.json", Data: newUserProfile(user)},
+			{Name: "invoices.json", Data: invoices},
+			{Name: "activities.json", Data: activities},
+			{Name: "customers.json", Data: domain.DistinctCustomers(invoices)},
+		})
+		if err != nil {
+			requestLogger(c).Error("Failed to build data archive", "userID", userID, "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to build data archive: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.UserExportedDataActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceCount":  len(invoices),
+					"activityCount": len(activities),
+					"archiveSize":   len(archive),
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="numeris_export_%s.zip"`, userID))
+		c.Set("Content-Type", "application/zip")
+		return c.Status(fiber.StatusOK).Send(archive)
+	}
+}
+
+// ExportInvoicesJSONHandler returns all invoices of the user as an InvoiceBackup, which ImportInvoicesJSONHandler
+// accepts to recreate them, e.g. to restore a backup or move invoices to another account.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the export.
+func (app *Application) ExportInvoicesJSONHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		invoices, err := app.invoiceRepository.FindAllInvoice(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to export invoices: "+err.Error())
+		}
+		if invoices == nil {
+			invoices = []*domain.Invoice{}
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.UserExportedDataActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"format":       "json",
+					"invoiceCount": len(invoices),
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="numeris_invoices_%s.json"`, userID))
+		return c.Status(fiber.StatusOK).JSON(InvoiceBackup{
+			Version:    invoiceBackupVersion,
+			ExportedAt: time.Now().UTC(),
+			UserID:     userID,
+			Invoices:   invoices,
+		})
+	}
+}
+
+// ImportInvoicesJSONHandler recreates the invoices of an InvoiceBackup written by ExportInvoicesJSONHandler for the
+// user, keeping their invoice numbers and statuses. Invoices whose number the user already has are skipped, so a
+// backup can be imported again safely; invalid invoices are reported as failed and the others are still imported.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the import.
+func (app *Application) ImportInvoicesJSONHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		backup := new(InvoiceBackup)
+		if err := c.BodyParser(backup); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+		if backup.Version != invoiceBackupVersion {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("Unsupported backup version %d", backup.Version))
+		}
+		if len(backup.Invoices) > domain.MaxImportRows {
+			return respondError(c, fiber.StatusRequestEntityTooLarge, CodeBatchTooLarge, fmt.Sprintf("A backup can hold at most %d invoices", domain.MaxImportRows))
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		results := make([]domain.ImportRowResult, 0, len(backup.Invoices))
+		var created, skipped, failed int
+		for idx, exported := range backup.Invoices {
+			if exported == nil {
+				results = append(results, domain.ImportRowResult{Row: idx + 1, Status: domain.ImportRowFailed, Error: "invoice is empty"})
+				failed++
+				continue
+			}
+
+			result, err := app.restoreInvoice(user, exported, idx+1)
+			if err != nil {
+				requestLogger(c).Error("Failed to restore invoice", "userID", userID, "row", idx+1, "error", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"code":    CodeInternal,
+					"message": fmt.Sprintf("Invoice %d could not be restored, import the backup again to resume: %v", idx+1, err),
+					"data":    results,
+				})
+			}
+
+			switch result.Status {
+			case domain.ImportRowCreated:
+				created++
+			case domain.ImportRowSkipped:
+				skipped++
+			case domain.ImportRowFailed:
+				failed++
+			}
+			results = append(results, result)
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.InvoicesRestoredActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"createdRows": created,
+					"skippedRows": skipped,
+					"failedRows":  failed,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": fmt.Sprintf("Backup imported: %d created, %d skipped, %d failed", created, skipped, failed),
+			"data":    results,
+		})
+	}
+}
+
+// ActionNeededHandler returns the user's to-do view: drafts ready to issue, overdue invoices to chase
+// and invoices due within the next `days` days (default 7), each with its own list and count.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) ActionNeededHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		days := c.QueryInt("days", 7)
+		if days < 1 || days > 90 {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "days must be between 1 and 90")
+		}
+
+		actionNeeded, err := app.invoiceRepository.ActionNeededInvoices(app.db, userID, days)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoices needing action: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoices needing action retrieved successfully",
+			"data":    actionNeeded,
+		})
+	}
+}
+
+// MonthlyReportHandler returns a PDF summarizing the invoices issued in the `month` query value (YYYY-MM,
+// default the current month) with totals by status. Amounts are in the user's default currency where the
+// invoice was recorded in it. The optional `tz` query value (an IANA time zone, default UTC) sets the current
+// month and the generation time shown on the report.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) MonthlyReportHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		location, err := time.LoadLocation(c.Query("tz", "UTC"))
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("unknown time zone %q", c.Query("tz")))
+		}
+		now := time.Now().In(location)
+
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if value := c.Query("month"); value != "" {
+			month, err = time.Parse("2006-01", value)
+			if err != nil {
+				return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "month must be formatted as YYYY-MM")
+			}
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		invoices, err := app.invoiceRepository.FindIssuedInvoicesBetween(app.db, userID, month, month.AddDate(0, 1, -1))
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve invoices: "+err.Error())
+		}
+
+		report := domain.NewMonthlyReport(month, user.DefaultCurrency, invoices, now)
+
+		var buf bytes.Buffer
+		if err := WriteMonthlyReportPDF(report, &buf); err != nil {
+			requestLogger(c).Error("Failed to generate monthly report", "error", err)
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to generate monthly report: "+err.Error())
+		}
+
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invoice_report_%s.pdf"`, report.Month))
+		c.Set("Content-Type", "application/pdf")
+		return c.Status(fiber.StatusOK).Send(buf.Bytes())
+	}
+}
+
+// ListOutstandingInvoicesHandler returns the collections view: the issued and overdue invoices that still have a
+// balance due, which is the total minus the recorded payments, ordered by due date, and the total outstanding.
+// Drafts and fully paid invoices are left out.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) ListOutstandingInvoicesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		outstanding, err := app.invoiceRepository.OutstandingInvoices(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve outstanding invoices: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Outstanding invoices retrieved successfully",
+			"data":    outstanding,
+		})
+	}
+}
+
+// ListPaymentsHandler returns a page of the payments ledger: every payment recorded against the user's invoices,
+// latest first, with the invoice it was made against. The optional `method`, `from` and `to` query values narrow
+// the ledger to a payment method and payment date window, and `limit` and `offset` select the page.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) ListPaymentsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		filter, err := parsePaymentFilter(c)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid request parameters: "+err.Error())
+		}
+
+		limit, offset := parsePagination(c, 20, 100)
+
+		payments, total, err := app.invoiceRepository.FindPaymentPage(app.db, userID, filter, limit, offset)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve payments: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.ListPaymentsActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"paymentCount": len(payments),
+					"limit":        limit,
+					"offset":       offset,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		page := Pagination{Total: total, Limit: limit, Offset: offset}
+		setPaginationHeaders(c, page)
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":    "Payments retrieved successfully",
+			"data":       payments,
+			"pagination": page,
+		})
+	}
+}
+
+// ExportPaymentsHandler downloads the payments ledger as a CSV file. It takes the same `method`, `from` and `to`
+// query values as ListPaymentsHandler and exports every matching payment.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the export.
+func (app *Application) ExportPaymentsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		filter, err := parsePaymentFilter(c)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid request parameters: "+err.Error())
+		}
+
+		payments, _, err := app.invoiceRepository.FindPaymentPage(app.db, userID, filter, 0, 0)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve payments: "+err.Error())
+		}
+
+		var buf bytes.Buffer
+		if err := writePaymentsCSV(payments, &buf); err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to export payments: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.ExportPaymentsActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"paymentCount": len(payments),
+					"method":       filter.Method,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="payments_%s.csv"`, time.Now().Format("20060102")))
+		c.Set("Content-Type", "text/csv")
+		return c.Status(fiber.StatusOK).Send(buf.Bytes())
+	}
+}
+
+// PotentialDuplicatesHandler reports invoices that may bill a customer twice: same customer and amount, issued
+// within `days` (1 to 90, default 7) of each other. The sets are only flagged for review, nothing is changed.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) PotentialDuplicatesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		days := c.QueryInt("days", 7)
+		if days < 1 || days > 90 {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "days must be between 1 and 90")
+		}
+
+		duplicates, err := app.invoiceRepository.FindPotentialDuplicates(app.db, userID, days)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to check for duplicate invoices: "+err.Error())
 		}
 
-		// return the invoice statistic summary
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Invoice statistic retrieved successfully",
-			"data":    invoiceStatSummary,
+			"message": fmt.Sprintf("%d potential duplicate set(s) found", len(duplicates)),
+			"data":    duplicates,
 		})
 	}
 }
 
-func (app *Application) SendIssuedInvoiceToCustomer() fiber.Handler {
+// sendRemindersInterval is how often a user may send reminders by hand, so customers are not spammed
+const sendRemindersInterval = time.Hour
+
+// SendAllRemindersHandler immediately sends reminders for every overdue or nearly due unpaid invoice of the user,
+// instead of waiting for the scheduler, and reports the outcome for each invoice. Reminders can be sent this way
+// once per sendRemindersInterval.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) SendAllRemindersHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		data := new(UpdateInvoiceStatusRequestModel)
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		// the limit is kept on the authenticated user, so it holds across server processes
+		authUserID, _ := AuthUserID(c)
+		claimed, err := app.userRepository.ClaimRateLimit(app.db, authUserID, "send_reminders", sendRemindersInterval, time.Now())
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to check the reminder limit: "+err.Error())
+		}
+		if !claimed {
+			return respondError(c, fiber.StatusTooManyRequests, CodeTooManyRequests, "Reminders were already sent within the last hour, please try again later")
+		}
+
+		actionNeeded, err := app.invoiceRepository.ActionNeededInvoices(app.db, userID, 7)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve unpaid invoices: "+err.Error())
+		}
+
+		invoices := append(actionNeeded.Overdue.Invoices, actionNeeded.DueSoon.Invoices...)
+		results := make([]ReminderResult, 0, len(invoices))
+		for idx := range invoices {
+			invoice := &invoices[idx]
+			message := reminderMessage(invoice)
+
+			result := ReminderResult{
+				InvoiceID:     invoice.InvoiceID,
+				InvoiceNumber: invoice.InvoiceNumber,
+				CustomerEmail: invoice.Customer.Email,
+				Sent:          true,
+			}
+			if err := app.notification.SendReminder(invoice, message, user.EmailIdentity); err != nil {
+				requestLogger(c).Error("Failed to send invoice reminder", "invoiceID", invoice.InvoiceID, "error", err)
+				result.Sent = false
+				result.Error = err.Error()
+				results = append(results, result)
+				continue
+			}
+			results = append(results, result)
+
+			logger := requestLogger(c)
+			go func() {
+				activity := &domain.Activity{
+					UserID:    userID,
+					Action:    infra.InvoiceReminderActivity,
+					Timestamp: time.Now(),
+					Metadata: map[string]interface{}{
+						"invoiceID":     result.InvoiceID,
+						"customerEmail": result.CustomerEmail,
+						"manual":        true,
+					},
+				}
+				if err := app.activityRepository.Save(app.db, activity); err != nil {
+					logger.Error("Failed to record user activity", "error", err)
+				}
+			}()
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": fmt.Sprintf("%d reminder(s) processed", len(results)),
+			"data":    results,
+		})
+	}
+}
+
+// AddInvoiceReminderHandler schedules a reminder emailed to the customer a number of days before the invoice
+// is due. Reminders are sent by the reminder scheduler once the invoice is issued.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) AddInvoiceReminderHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(InvoiceReminder)
 		if err := c.BodyParser(data); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   err.Error(),
-				"message": fmt.Sprintf("%s: %q", ErrInvalidInputReceived.Error(), "from the client"),
-			})
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
 		}
-		// validate the data input
+
 		validateData := FieldValidator(data)
-		if validateData != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "invalid input",
-				"message": fmt.Sprintf("%q: %v", "from the client", validateData),
-			})
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
 		}
 
-		// checking authentication
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		if err := invoice.AddReminder(data.DaysBeforeDueDate, data.Message); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid reminder: "+err.Error())
+		}
+
+		if err := app.invoiceRepository.SetInvoiceReminders(app.db, userID, invoice); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, errorCode(err, CodeConflict), "Reminder cannot be added: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to add reminder: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.UpdateInvoiceActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"invoiceID":         invoiceID,
+					"field":             "reminders",
+					"daysBeforeDueDate": data.DaysBeforeDueDate,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Reminder added successfully",
+			"data":    invoice.Reminders,
+		})
+	}
+}
+
+// GetUserProfileHandler returns the profile of the logged-in user, without the password or token.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetUserProfileHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
 		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
 		}
-		// get all the parameters
-		params := c.AllParams()
-		if params == nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve user: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "User retrieved successfully",
+			"data":    newUserProfile(user),
+		})
+	}
+}
+
+// UpdateProfileHandler updates the first name, last name and phone number of the user. The email and
+// password cannot be changed here and requests carrying them are rejected.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) UpdateProfileHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(ProfileRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		if data.Email != "" || data.Password != "" {
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, "email and password cannot be changed through the profile")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		if err := user.UpdateProfile(data.FirstName, data.LastName, data.PhoneNumber); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid profile: "+err.Error())
+		}
+
+		if err := app.userRepository.UpdateProfile(app.db, user); err != nil {
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to update profile: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.UserUpdatedAccountActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"field": "profile",
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Profile updated successfully",
+			"data":    newUserProfile(user),
+		})
+	}
+}
+
+// ListCreditLimitsHandler returns the credit limits the user set for their customers, with how much of
+// each limit the customer's outstanding invoices are using.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) ListCreditLimitsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		outstanding, err := app.invoiceRepository.OutstandingByCustomer(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to retrieve outstanding balances: "+err.Error())
+		}
+
+		utilization := make([]domain.CreditUtilization, 0, len(user.CreditLimits))
+		for _, limit := range user.CreditLimits {
+			utilization = append(utilization, domain.NewCreditUtilization(limit, outstanding[limit.CustomerEmail]))
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Credit limits retrieved successfully",
+			"data":    utilization,
+		})
+	}
+}
+
+// SetCreditLimitHandler sets the credit limit of a customer. With "warn" enforcement, invoices taking the
+// customer over the limit are created and issued with a warning; with "block" they are rejected.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) SetCreditLimitHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(CreditLimitRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
+		}
+
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
+
+		limit, err := domain.NewCreditLimit(data.CustomerEmail, data.Limit, data.Enforcement)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid credit limit: "+err.Error())
+		}
+
+		if err := app.userRepository.SetCreditLimit(app.db, userID, limit); err != nil {
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to set credit limit: "+err.Error())
+		}
+
+		logger := requestLogger(c)
+		go func() {
+			activity := &domain.Activity{
+				UserID:    userID,
+				Action:    infra.UserUpdatedAccountActivity,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"field":          "credit_limits",
+					"customer_email": limit.CustomerEmail,
+					"limit":          limit.Limit,
+					"enforcement":    limit.Enforcement,
+				},
+			}
+			if err := app.activityRepository.Save(app.db, activity); err != nil {
+				logger.Error("Failed to record user activity", "error", err)
+			}
+		}()
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Credit limit set successfully",
+			"data":    limit,
+		})
+	}
+}
+
+// RemoveCreditLimitHandler removes the credit limit of a customer, given by the email path parameter.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the removal process.
+func (app *Application) RemoveCreditLimitHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
 		}
-		userID := params["userID"]
-		invoiceID := params["invoiceID"]
-		if userID == "" || invoiceID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
 		}
 
+		userID := c.Params("userID")
 		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
-			slog.Error("Invalid user", "Error", "the userID must be a valid UUID")
-			panic(err)
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
 		}
 
-		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
-			slog.Error("Invalid invoice", "Error", "the invoiceID must be a valid UUID")
-			panic(err)
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
 		}
 
-		err := app.invoiceRepository.UpdateInvoiceStatusToIssued(app.db, userID, invoiceID)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to update invoice status",
-				"message": err.Error(),
-			})
+		limit := user.CreditLimitFor(c.Params("email"))
+		if limit == nil {
+			return respondError(c, fiber.StatusNotFound, CodeNotFound, fmt.Sprintf("No credit limit is set for %s", c.Params("email")))
+		}
+		customerEmail := limit.CustomerEmail
+
+		if err := app.userRepository.RemoveCreditLimit(app.db, userID, customerEmail); err != nil {
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to remove credit limit: "+err.Error())
 		}
 
+		logger := requestLogger(c)
 		go func() {
 			activity := &domain.Activity{
 				UserID:    userID,
-				Action:    infra.IssueInvoiceActivity,
+				Action:    infra.UserUpdatedAccountActivity,
 				Timestamp: time.Now(),
 				Metadata: map[string]interface{}{
-					"invoiceID": invoiceID,
+					"field":          "credit_limits",
+					"customer_email": customerEmail,
+					"removed":        true,
 				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err, "userID", userID, "action", infra.IssueInvoiceActivity)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Invoice status updated successfully",
+			"message": fmt.Sprintf("Credit limit for %s removed successfully", customerEmail),
 		})
-
 	}
-
 }
 
-func (app *Application) DeleteInvoiceHandler() fiber.Handler {
+// UpdateEmailIdentityHandler sets the sender display name and reply-to address used on the user's
+// outgoing invoice, reminder and receipt emails. Empty values fall back to the system defaults.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) UpdateEmailIdentityHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
 		}
 
-		params := c.AllParams()
-		if params == nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
 		}
 
-		userID := params["userID"]
-		invoiceID := params["invoiceID"]
+		data := new(EmailIdentityRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
 
-		if userID == "" || invoiceID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
 		}
 
+		userID := c.Params("userID")
 		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
-			slog.Error("Invalid userID", "error", err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid userID",
-				"message": "userID must be a valid ObjectID",
-			})
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
 		}
 
-		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
-			slog.Error("Invalid invoiceID", "error", err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid invoiceID",
-				"message": "invoiceID must be a valid ObjectID",
-			})
+		identity, err := domain.NewEmailIdentity(data.FromName, data.ReplyTo)
+		if err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid email identity: "+err.Error())
 		}
 
-		err := app.invoiceRepository.DeleteInvoice(app.db, userID, invoiceID)
-		if err != nil {
-			slog.Error("Failed to delete invoice", "userID", userID, "invoiceID", invoiceID, "error", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to delete invoice",
-				"message": err.Error(),
-			})
+		if err := app.userRepository.UpdateEmailIdentity(app.db, userID, identity); err != nil {
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to update email identity: "+err.Error())
 		}
 
+		logger := requestLogger(c)
 		go func() {
 			activity := &domain.Activity{
 				UserID:    userID,
-				Action:    infra.DeleteInvoiceActivity,
+				Action:    infra.UserUpdatedAccountActivity,
 				Timestamp: time.Now(),
 				Metadata: map[string]interface{}{
-					"invoiceID": invoiceID,
+					"field": "email_identity",
 				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
-		// Return success response
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Invoice deleted successfully",
+			"message": "Email identity updated successfully",
+			"data":    identity,
 		})
 	}
 }
 
-func (app *Application) DownloadInvoicePDFHandler() fiber.Handler {
+// GetStatusConfigHandler returns the custom invoice statuses of the user with the core statuses and the
+// transitions allowed between them.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
+func (app *Application) GetStatusConfigHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Check authentication
 		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
-		}
-
-		// Get parameters from request
-		params := c.AllParams()
-		if params == nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
 		}
-		userID := params["userID"]
-		invoiceID := params["invoiceID"]
 
-		// Validate user ID and invoice ID
-		if userID == "" || invoiceID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID and invoiceID must be provided",
-			})
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
 		}
 
+		userID := c.Params("userID")
 		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
-			slog.Error("Invalid userID", "error", err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid userID",
-				"message": "userID must be a valid ObjectID",
-			})
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
 		}
 
-		if _, err := primitive.ObjectIDFromHex(invoiceID); err != nil {
-			slog.Error("Invalid invoiceID", "error", err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid invoiceID",
-				"message": "invoiceID must be a valid ObjectID",
-			})
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
 		}
 
-		// Get the invoice data
-		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
-		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-					"error":   "Invoice not found",
-					"message": "The specified invoice does not exist for the given user",
-				})
-			}
-			slog.Error("Failed to retrieve invoice", "error", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to retrieve invoice",
-				"message": err.Error(),
-			})
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Invoice statuses retrieved successfully",
+			"data": fiber.Map{
+				"core_statuses":    domain.InvoiceStatuses,
+				"core_transitions": domain.CoreStatusTransitions,
+				"custom":           user.StatusConfig,
+			},
+		})
+	}
+}
+
+// UpdateStatusConfigHandler replaces the custom invoice statuses of the user. Each custom status counts as one
+// of the core statuses, so reports and aggregations keep working, and the transitions list which statuses an
+// invoice may be labelled with from each status.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) UpdateStatusConfigHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
 		}
 
-		// Create temporary directory for PDF if it doesn't exist
-		tempDir := "./temp/invoices"
-		if err := os.MkdirAll(tempDir, 0755); err != nil {
-			slog.Error("Failed to create temp directory", "error", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to generate invoice document",
-				"message": err.Error(),
-			})
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
 		}
 
-		// Generate PDF filename
-		pdfFilename := fmt.Sprintf("invoice_%s_%s.pdf", userID, invoiceID)
-		pdfPath := filepath.Join(tempDir, pdfFilename)
+		data := new(StatusConfigRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
 
-		// Generate the PDF
-		if err := GenerateInvoicePDF(invoice, pdfPath); err != nil {
-			slog.Error("Failed to generate PDF", "error", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to generate invoice document",
-				"message": err.Error(),
-			})
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
+			}
 		}
 
-		// set response headers for file download
-		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, pdfFilename))
-		c.Set("Content-Type", "application/pdf")
+		userID := c.Params("userID")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "The provided userID is not a valid ObjectID")
+		}
 
-		if err := c.SendFile(pdfPath); err != nil {
-			slog.Error("Failed to send PDF file", "error", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to send invoice document",
-				"message": err.Error(),
-			})
+		config := data.domainStatusConfig()
+		if err := config.Validate(); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, "Invalid invoice statuses: "+err.Error())
 		}
 
-		go func() {
-			time.Sleep(5 * time.Minute)
-			if err := os.Remove(pdfPath); err != nil {
-				slog.Error("Failed to cleanup temporary PDF file", "error", err)
+		if err := app.userRepository.SetStatusConfig(app.db, userID, config); err != nil {
+			if errors.Is(err, infra.ErrUserNotFound) {
+				return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
 			}
-		}()
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to update invoice statuses: "+err.Error())
+		}
 
+		logger := requestLogger(c)
 		go func() {
 			activity := &domain.Activity{
 				UserID:    userID,
-				Action:    infra.DownloadInvoiceActivity,
+				Action:    infra.UserUpdatedAccountActivity,
 				Timestamp: time.Now(),
 				Metadata: map[string]interface{}{
-					"invoice":   invoice,
-					"file_path": pdfPath,
+					"field": "status_config",
 				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Invoice downloaded successfully",
+			"message": "Invoice statuses updated successfully",
+			"data":    config,
 		})
-
 	}
 }
 
-// GetInvoiceActivitiesHandler returns a handler function that retrieves invoice activities for a specific user.
-// It checks for authentication, validates the user ID, and fetches the activities from the database.
-//
-// Parameters:
-//   - c: fiber.Ctx, the context for the current request, which includes request and response objects.
+// SetInvoiceCustomStatusHandler labels an invoice with one of the user's custom statuses, or removes its label
+// when the requested status is the core status of the invoice. The move must be allowed by the user's status
+// configuration; the core status of the invoice never changes.
 //
 // Returns:
-//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the retrieval process.
-func (app *Application) GetInvoiceActivitiesHandler() fiber.Handler {
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the update process.
+func (app *Application) SetInvoiceCustomStatusHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Check authentication
 		if err := app.contextWithAuth(c, true); err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   ErrUnauthorized.Error(),
-				"message": "You are not authorized to perform this action",
-			})
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
 		}
 
-		userID := c.Params("userID")
-		if userID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request parameters",
-				"message": "userID must be provided",
-			})
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
 		}
 
-		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid user ID",
-				"message": "The provided userID is not a valid ObjectID",
-			})
+		data := new(InvoiceCustomStatusRequestModel)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
 		}
 
-		// get limit from query params, default to 10 if not provided
-		limit := int64(10)
-		if limitStr := c.Query("limit"); limitStr != "" {
-			parsedLimit, err := strconv.ParseInt(limitStr, 10, 64)
-			if err == nil && parsedLimit > 0 {
-				limit = parsedLimit
+		validateData := FieldValidator(data)
+		if len(validateData) > 0 {
+			for _, f := range validateData {
+				return respondError(c, fiber.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("%s: %s", f.Message, f.NameSpace))
 			}
 		}
 
-		activities, err := app.invoiceRepository.GetInvoiceActivities(app.db, userID, limit)
+		userID := c.Params("userID")
+		invoiceID := c.Params("invoiceID")
+
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "userID must be a valid ObjectID")
+		}
+
+		user, err := app.userRepository.FindByID(app.db, userID)
 		if err != nil {
-			slog.Error("Failed to retrieve invoice activities", "error", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to retrieve invoice activities",
-				"message": err.Error(),
-			})
+			return respondError(c, fiber.StatusNotFound, CodeUserNotFound, err.Error())
+		}
+
+		invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+		if err != nil {
+			return respondError(c, fiber.StatusNotFound, CodeInvoiceNotFound, "Invoice not found: "+err.Error())
+		}
+
+		config := user.StatusConfig
+		label := config.Custom(data.Status)
+		if label == nil && data.Status != invoice.Status {
+			return respondError(c, fiber.StatusBadRequest, CodeValidationFailed,
+				fmt.Sprintf("status %q is neither a custom status nor the current status of the invoice", data.Status))
 		}
 
+		previous := invoice.EffectiveStatus()
+		if !config.CanTransition(previous, data.Status) {
+			return respondError(c, fiber.StatusConflict, CodeInvoiceStatusConflict,
+				fmt.Sprintf("Invoice cannot move from %q to %q", previous, data.Status))
+		}
+
+		if err := app.invoiceRepository.SetInvoiceCustomStatus(app.db, userID, invoiceID, invoice.Status, label); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				return respondError(c, fiber.StatusConflict, errorCode(err, CodeConflict), "Invoice status changed, please retry: "+err.Error())
+			}
+			return respondError(c, fiber.StatusInternalServerError, CodeInternal, "Failed to update invoice status: "+err.Error())
+		}
+		invoice.CustomStatus = label
+
+		logger := requestLogger(c)
 		go func() {
 			activity := &domain.Activity{
 				UserID:    userID,
-				Action:    infra.ViewInvoiceActivity,
+				Action:    infra.InvoiceCustomStatusActivity,
 				Timestamp: time.Now(),
 				Metadata: map[string]interface{}{
-					"limit":           limit,
-					"activitiesCount": len(activities),
+					"invoiceID":      invoiceID,
+					"invoiceNumber":  invoice.InvoiceNumber,
+					"previousStatus": previous,
+					"status":         data.Status,
 				},
 			}
 			if err := app.activityRepository.Save(app.db, activity); err != nil {
-				slog.Error("Failed to record user activity", "error", err)
+				logger.Error("Failed to record user activity", "error", err)
 			}
 		}()
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Invoice activities retrieved successfully",
-			"data":    activities,
+			"message": "Invoice status updated successfully",
+			"data":    invoice,
+		})
+	}
+}
+
+// VerifyPaymentInfoHandler resolves the submitted bank details with the configured AccountVerifier and returns
+// the account holder name, so the user can confirm it before saving the payment information on an invoice.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request and returns an error if any occurs during the process.
+func (app *Application) VerifyPaymentInfoHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return respondError(c, fiber.StatusUnauthorized, errorCode(err, CodeUnauthorized), "You are not authorized to perform this action")
+		}
+
+		if err := app.authorizeOwner(c); err != nil {
+			return respondError(c, fiber.StatusForbidden, CodeForbidden, "You can only access your own account")
+		}
+
+		data := new(PaymentInformation)
+		if err := c.BodyParser(data); err != nil {
+			return respondError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid input received from the client")
+		}
+
+		paymentInfo := domain.PaymentInformation(*data)
+		if err := domain.ValidatePaymentInfo(paymentInfo); err != nil {
+			return respondError(c, fiber.StatusBadRequest, errorCode(err, CodeInvalidRequest), "Invalid payment information: "+err.Error())
+		}
+
+		account, err := app.accountVerifier.VerifyAccount(paymentInfo)
+		if err != nil {
+			return respondError(c, fiber.StatusUnprocessableEntity, errorCode(err, CodeUnprocessable), "Failed to verify account: "+err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Account details resolved successfully",
+			"data": fiber.Map{
+				"account":      account,
+				"name_matches": strings.EqualFold(strings.TrimSpace(account.AccountName), strings.TrimSpace(paymentInfo.AccountName)),
+			},
 		})
 	}
 }
+
+// recalculateSummary recomputes the cached invoice summary of the user and records the change. A failure to
+// record the change is logged to logger.
+func (app *Application) recalculateSummary(logger *slog.Logger, userID string) (*domain.SummaryReconciliation, error) {
+	reconciliation, err := app.invoiceRepository.RecalculateSummary(app.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		activity := &domain.Activity{
+			UserID:    userID,
+			Action:    infra.SummaryRecalculatedActivity,
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				"before":  reconciliation.Before,
+				"after":   reconciliation.After,
+				"changed": reconciliation.Changed,
+			},
+		}
+		if err := app.activityRepository.Save(app.db, activity); err != nil {
+			logger.Error("Failed to record user activity", "error", err)
+		}
+	}()
+
+	return reconciliation, nil
+}
+
+// issueInvoice checks the sender email and the customer credit limit, moves a ready invoice to "issued" and snapshots its PDF.
+// It returns the credit limit warning, if any. A failed snapshot is only logged to logger, it is retried on the
+// first download.
+func (app *Application) issueInvoice(logger *slog.Logger, userID, invoiceID string) (string, error) {
+	user, err := app.userRepository.FindByID(app.db, userID)
+	if err != nil {
+		return "", err
+	}
+	invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := app.senderPolicy.Check(user, invoice.Sender.Email); err != nil {
+		return "", err
+	}
+
+	creditWarning, err := app.checkCreditLimit(user, invoice)
+	if err != nil {
+		return "", err
+	}
+
+	if err := app.invoiceRepository.UpdateInvoiceStatusToIssued(app.db, userID, invoiceID); err != nil {
+		return "", err
+	}
+
+	if invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID); err != nil {
+		logger.Error("Failed to load issued invoice for its PDF snapshot", "invoiceID", invoiceID, "error", err)
+	} else if _, err := app.storeInvoicePDF(userID, invoice); err != nil {
+		logger.Error("Failed to store issued invoice PDF", "invoiceID", invoiceID, "error", err)
+	}
+	return creditWarning, nil
+}
+
+// sendInvoiceEmail emails an issued invoice to its customer from the user's email identity, opened by the
+// cover note when there is one. The invoice PDF is attached; it is the snapshot stored when the invoice was
+// issued, or rendered in memory when there is none.
+func (app *Application) sendInvoiceEmail(logger *slog.Logger, userID, invoiceID, coverNote string) error {
+	user, err := app.userRepository.FindByID(app.db, userID)
+	if err != nil {
+		return err
+	}
+	invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, userID, invoiceID)
+	if err != nil {
+		return err
+	}
+	pdf, err := app.invoicePDF(logger, userID, invoice)
+	if err != nil {
+		return fmt.Errorf("failed to generate invoice PDF: %w", err)
+	}
+	return app.notification.SendInvoiceEmail(invoice, invoiceMessage(invoice, coverNote), user.EmailIdentity, pdf)
+}
+
+// buildInvoice creates the invoice described by an InvoiceRequestModel for the user, in the user's default
+// currency when the request does not name one. The returned error describes which part of the request is invalid.
+func (app *Application) buildInvoice(user *domain.User, data *InvoiceRequestModel) (*domain.Invoice, error) {
+	// the invoice currency overrides the user's default currency
+	billingCurrency, err := resolveBillingCurrency(data.BillingCurrency, user.DefaultCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid billing currency: %w", err)
+	}
+
+	// load and store the values in a list
+	items := make([]domain.Item, 0)
+	for _, val := range data.Items {
+		items = append(items,
+			domain.Item{
+				Description: val.Description,
+				Quantity:    val.Quantity,
+				UnitPrice:   val.UnitPrice,
+				Billable:    val.Billable,
+				Order:       val.Order,
+			})
+	}
+
+	invoice, err := domain.NewInvoice(
+		user.ID,
+		strings.TrimSpace(data.InvoiceNumber),
+		billingCurrency,
+		data.Discount,
+		data.IssueDate,
+		data.DueDate,
+		items,
+		data.domainPaymentInfo(),
+		domain.CustomerDetails{
+			Email:   data.Customer.Email,
+			Name:    data.Customer.Name,
+			Phone:   data.Customer.Phone,
+			Address: data.Customer.Address,
+		},
+		domain.SenderDetails{
+			Email:   data.Sender.Email,
+			Name:    data.Sender.Name,
+			Phone:   data.Sender.Phone,
+			Address: data.Sender.Address,
+		},
+		data.invoiceStatus(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice: %w", err)
+	}
+
+	if err := invoice.SetExpenses(data.domainExpenses()); err != nil {
+		return nil, fmt.Errorf("invalid expenses: %w", err)
+	}
+	if err := invoice.SetTax(data.TaxRate, data.TaxJurisdiction); err != nil {
+		return nil, fmt.Errorf("invalid tax: %w", err)
+	}
+	if err := invoice.SetPaymentMethods(data.domainPaymentMethods()); err != nil {
+		return nil, fmt.Errorf("invalid payment methods: %w", err)
+	}
+	if err := invoice.SetTags(data.Tags); err != nil {
+		return nil, fmt.Errorf("invalid tags: %w", err)
+	}
+	if err := invoice.SetItemSort(data.ItemSort); err != nil {
+		return nil, fmt.Errorf("invalid item sort: %w", err)
+	}
+
+	app.applyBaseCurrency(invoice, user.DefaultCurrency)
+	return invoice, nil
+}
+
+// checkCreditLimit checks the invoice against the credit limit the user set for its customer. An exceeded
+// limit returns an error wrapping domain.ErrCreditLimitExceeded when it blocks, or a warning otherwise.
+func (app *Application) checkCreditLimit(user *domain.User, invoice *domain.Invoice) (string, error) {
+	limit := user.CreditLimitFor(invoice.Customer.Email)
+	if limit == nil {
+		return "", nil
+	}
+
+	outstanding, err := app.invoiceRepository.OutstandingByCustomer(app.db, user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return checkCreditLimitWith(user, invoice, outstanding)
+}
+
+// checkCreditLimitWith is checkCreditLimit against outstanding balances that are already loaded, so a batch
+// of invoices is checked with a single query.
+func checkCreditLimitWith(user *domain.User, invoice *domain.Invoice, outstanding map[string]float64) (string, error) {
+	limit := user.CreditLimitFor(invoice.Customer.Email)
+	if limit == nil {
+		return "", nil
+	}
+
+	err := limit.Check(outstanding[limit.CustomerEmail], invoice.TotalAmountDue)
+	if err == nil {
+		return "", nil
+	}
+	if limit.Enforcement == domain.CreditLimitBlock {
+		return "", err
+	}
+	return err.Error(), nil
+}
+
+// invoicePDF returns the PDF of an invoice. Drafts are rendered on every call. Issued invoices are served from
+// their stored copy, which is stored on first use when it is missing; the missing copy is logged to logger.
+func (app *Application) invoicePDF(logger *slog.Logger, userID string, invoice *domain.Invoice) ([]byte, error) {
+	if invoice.IsDraft() {
+		return renderInvoicePDF(invoice)
+	}
+
+	if invoice.PDFFileID != "" {
+		content, err := app.invoiceRepository.LoadInvoicePDF(app.db, invoice.PDFFileID)
+		if err == nil {
+			return content, nil
+		}
+		if !errors.Is(err, infra.ErrDocumentNotFound) {
+			return nil, err
+		}
+		logger.Warn("Stored invoice PDF is missing, storing a new copy", "invoiceID", invoice.InvoiceID)
+	}
+
+	return app.storeInvoicePDF(userID, invoice)
+}
+
+// storeInvoicePDF renders the invoice and stores the result as its PDF, returning the rendered content.
+func (app *Application) storeInvoicePDF(userID string, invoice *domain.Invoice) ([]byte, error) {
+	content, err := renderInvoicePDF(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	fileID, err := app.invoiceRepository.SaveInvoicePDF(app.db, userID, invoice, content)
+	if err != nil {
+		return nil, err
+	}
+	invoice.PDFFileID = fileID
+
+	return content, nil
+}
+
+// expandInvoices hydrates the customer and/or sender profiles requested in expand into each invoice.
+// The sender profile is the user's account; the customer profile is built from all of the user's invoices.
+func (app *Application) expandInvoices(userID string, invoices []*domain.Invoice, expand map[string]bool) ([]ExpandedInvoice, error) {
+	var sender *domain.SenderProfile
+	if expand["sender"] {
+		user, err := app.userRepository.FindByID(app.db, userID)
+		if err != nil {
+			return nil, err
+		}
+		sender = domain.NewSenderProfile(user)
+	}
+
+	var customers map[string]*domain.CustomerProfile
+	if expand["customer"] {
+		allInvoices, err := app.invoiceRepository.FindAllInvoice(app.db, userID)
+		if err != nil {
+			return nil, err
+		}
+		customers = domain.NewCustomerProfiles(allInvoices)
+	}
+
+	expanded := make([]ExpandedInvoice, len(invoices))
+	for idx, invoice := range invoices {
+		expanded[idx] = ExpandedInvoice{Invoice: invoice, SenderProfile: sender}
+		if customers != nil {
+			expanded[idx].CustomerProfile = customers[strings.ToLower(invoice.Customer.Email)]
+		}
+	}
+
+	return expanded, nil
+}
+
+// applyBaseCurrency records the invoice total in the user's default currency. Nothing is recorded when
+// the user has no default currency, or when the currencies differ and no converter is configured.
+func (app *Application) applyBaseCurrency(invoice *domain.Invoice, baseCurrency string) {
+	if baseCurrency == "" {
+		return
+	}
+
+	if invoice.BillingCurrency == baseCurrency {
+		invoice.BaseCurrency = baseCurrency
+		invoice.BaseAmountDue = invoice.TotalAmountDue
+		return
+	}
+
+	if app.currencyConverter == nil {
+		return
+	}
+
+	amount, err := app.currencyConverter.Convert(invoice.TotalAmountDue, invoice.BillingCurrency, baseCurrency)
+	if err != nil {
+		slog.Warn("Failed to convert invoice total to base currency", "invoiceID", invoice.InvoiceID, "error", err)
+		return
+	}
+	invoice.BaseCurrency = baseCurrency
+	invoice.BaseAmountDue = amount
+}
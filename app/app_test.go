@@ -0,0 +1,1437 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/db/repository"
+	"github.com/thebravebyte/numeris/db/service"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// testDatabaseURIEnv names the environment variable holding the URI of the MongoDB deployment the handler
+// tests run against. Invoices are saved in transactions, so it has to be a replica set.
+const testDatabaseURIEnv = "NUMERIS_TEST_DATABASE_URI"
+
+// newTestApplication returns an Application backed by the test database, skipping the test when none is
+// configured. Notifications are only logged.
+func newTestApplication(t *testing.T) *Application {
+	t.Helper()
+
+	uri := os.Getenv(testDatabaseURIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set, skipping database test", testDatabaseURIEnv)
+	}
+
+	client, err := infra.Connect(context.Background(), uri, infra.PoolConfig{MaxPoolSize: 5, ConnectTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { infra.ShutDown(client) })
+
+	authenticateJWT, err := service.NewAuthenticateJWT("numeris-test-token-key-0123456789", "HS256")
+	if err != nil {
+		t.Fatalf("NewAuthenticateJWT: %v", err)
+	}
+
+	return NewApplication(
+		client,
+		service.PasswordHasher{},
+		*authenticateJWT,
+		repository.ActivityRepository{},
+		repository.UserRepository{},
+		repository.InvoiceRepository{},
+		&service.EmailNotification{Sender: &service.LogEmailSender{}, From: "invoices@example.com"},
+		&service.NoopAccountVerifier{},
+		nil,
+	)
+}
+
+// testAccount is a user saved in the test database, with the password it can log in with.
+type testAccount struct {
+	ID       string
+	Email    string
+	Password string
+}
+
+// newTestAccount saves a user with a known password; the user and its invoices are removed when the test ends.
+func newTestAccount(t *testing.T, app *Application) testAccount {
+	t.Helper()
+
+	account := testAccount{ID: primitive.NewObjectID().Hex(), Password: "original-secret"}
+	account.Email = account.ID + "@example.com"
+
+	hash, err := app.passwordHasher.CreateHash(account.Password)
+	if err != nil {
+		t.Fatalf("CreateHash: %v", err)
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		ID:          account.ID,
+		FirstName:   "Ada",
+		LastName:    "Lovelace",
+		Email:       account.Email,
+		Password:    hash,
+		PhoneNumber: "+15550101",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := app.userRepository.AddUser(app.db, user, user.Email); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// soft-deleted invoices are removed too, so they are read from the user document
+		var user struct {
+			Invoices []struct {
+				InvoiceID string `bson:"invoice_id"`
+			} `bson:"invoices"`
+		}
+		if err := repository.UserData(app.db, "user").FindOne(ctx, bson.M{"_id": account.ID}).Decode(&user); err == nil {
+			for _, invoice := range user.Invoices {
+				_, _ = repository.InvoiceData(app.db, "invoice").DeleteOne(ctx, bson.M{"invoice_id": invoice.InvoiceID})
+			}
+		}
+		_, _ = repository.UserData(app.db, "user").DeleteOne(ctx, bson.M{"_id": account.ID})
+	})
+	return account
+}
+
+// newTestInvoice saves a draft invoice sent by account and returns it.
+func newTestInvoice(t *testing.T, app *Application, account testAccount) *domain.Invoice {
+	t.Helper()
+
+	user, err := app.userRepository.FindByID(app.db, account.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	invoice, err := app.buildInvoice(user, newTestInvoiceRequest(account))
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	if err := app.invoiceRepository.AddNewInvoice(app.db, account.ID, invoice); err != nil {
+		t.Fatalf("AddNewInvoice: %v", err)
+	}
+	return invoice
+}
+
+// newTestInvoiceRequest returns a valid request creating an invoice sent by account, issued today and due in
+// two weeks.
+func newTestInvoiceRequest(account testAccount) *InvoiceRequestModel {
+	today := time.Now().UTC()
+	request := validInvoiceRequest()
+	request.Sender.Email = account.Email
+	request.IssueDate = today.Format("2006-01-02")
+	request.DueDate = today.AddDate(0, 0, 14).Format("2006-01-02")
+	return request
+}
+
+// doJSON sends body as JSON to the handler registered on srv and returns the status and decoded response.
+func doJSON(t *testing.T, srv *fiber.App, method, path, token string, body any) (int, map[string]any) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encoding request: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := srv.Test(req, 10_000)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	result := map[string]any{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && err != io.EOF {
+		t.Fatalf("decoding response of %s %s: %v", method, path, err)
+	}
+	return resp.StatusCode, result
+}
+
+// login logs the account in with password and returns the token, failing the test unless it succeeds.
+func login(t *testing.T, srv *fiber.App, email, password string) string {
+	t.Helper()
+
+	status, body := doJSON(t, srv, fiber.MethodPost, "/api/login", "", LoginRequestModel{Email: email, Password: password})
+	if status != fiber.StatusOK {
+		t.Fatalf("login: status = %d, body = %v", status, body)
+	}
+	token, _ := body["token"].(string)
+	return token
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Post("/api/reset-password", app.ResetPasswordHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	rejected := []struct {
+		name       string
+		request    ResetPasswordRequestModel
+		wantStatus int
+	}{
+		{
+			name:       "mismatched confirmation",
+			request:    ResetPasswordRequestModel{CurrentPassword: account.Password, Password: "new-secret-1", ConfirmPassword: "new-secret-2"},
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "wrong current password",
+			request:    ResetPasswordRequestModel{CurrentPassword: "not-the-password", Password: "new-secret-1", ConfirmPassword: "new-secret-1"},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "unknown email",
+			request:    ResetPasswordRequestModel{Email: "someone-else@example.com", CurrentPassword: account.Password, Password: "new-secret-1", ConfirmPassword: "new-secret-1"},
+			wantStatus: fiber.StatusForbidden,
+		},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := doJSON(t, srv, fiber.MethodPost, "/api/reset-password", token, tt.request); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (%v)", status, tt.wantStatus, body)
+			}
+		})
+	}
+
+	t.Run("reset then login", func(t *testing.T) {
+		request := ResetPasswordRequestModel{Email: account.Email, CurrentPassword: account.Password, Password: "new-secret-1", ConfirmPassword: "new-secret-1"}
+		if status, body := doJSON(t, srv, fiber.MethodPost, "/api/reset-password", token, request); status != fiber.StatusOK {
+			t.Fatalf("status = %d, want %d (%v)", status, fiber.StatusOK, body)
+		}
+
+		// the token issued before the reset is revoked
+		if status, _ := doJSON(t, srv, fiber.MethodPost, "/api/reset-password", token, request); status != fiber.StatusUnauthorized {
+			t.Errorf("old token: status = %d, want %d", status, fiber.StatusUnauthorized)
+		}
+
+		status, _ := doJSON(t, srv, fiber.MethodPost, "/api/login", "", LoginRequestModel{Email: account.Email, Password: account.Password})
+		if status != fiber.StatusUnauthorized {
+			t.Errorf("login with the old password: status = %d, want %d", status, fiber.StatusUnauthorized)
+		}
+
+		if token := login(t, srv, account.Email, "new-secret-1"); token == "" {
+			t.Error("login with the new password returned no token")
+		}
+	})
+}
+
+// portalLinkRecorder is a NotificationService recording the customer emails portal links are sent to.
+type portalLinkRecorder struct {
+	service.NotificationService
+	sent chan string
+}
+
+func (r *portalLinkRecorder) SendPortalLink(customerEmail, link string, identity domain.EmailIdentity) error {
+	r.sent <- customerEmail
+	return nil
+}
+
+func TestPortalLinkAction(t *testing.T) {
+	action := portalLinkAction("Grace@Example.com ")
+	if action != portalLinkAction("grace@example.com") {
+		t.Error("portalLinkAction() differs by case or surrounding spaces of the email")
+	}
+	if action == portalLinkAction("ada@example.com") {
+		t.Error("portalLinkAction() is the same for different emails")
+	}
+	if strings.ContainsAny(action, ".$") {
+		t.Errorf("portalLinkAction() = %q, cannot be used as a field name", action)
+	}
+}
+
+func TestRequestPortalLinkHandler(t *testing.T) {
+	app := newTestApplication(t)
+	recorder := &portalLinkRecorder{sent: make(chan string, 4)}
+	app.notification = recorder
+
+	srv := fiber.New()
+	srv.Post("/api/portal/:userID/link", app.RequestPortalLinkHandler())
+
+	account := newTestAccount(t, app)
+	customer := "grace-" + account.ID + "@example.com"
+	invoice := &domain.Invoice{
+		InvoiceID:       primitive.NewObjectID().Hex(),
+		BillingCurrency: "USD",
+		TotalAmountDue:  100,
+		Status:          "issued",
+		Customer:        domain.CustomerDetails{Name: "Grace Hopper", Email: customer},
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if err := app.invoiceRepository.AddNewInvoice(app.db, account.ID, invoice); err != nil {
+		t.Fatalf("AddNewInvoice: %v", err)
+	}
+
+	path := "/api/portal/" + account.ID + "/link"
+	request := func(email string) (int, map[string]any) {
+		return doJSON(t, srv, fiber.MethodPost, path, "", PortalLinkRequestModel{Email: email})
+	}
+	// sent waits for the link sent in the background, if any
+	sent := func() string {
+		select {
+		case email := <-recorder.sent:
+			return email
+		case <-time.After(500 * time.Millisecond):
+			return ""
+		}
+	}
+
+	knownStatus, knownBody := request(customer)
+	if got := sent(); got != customer {
+		t.Errorf("portal link sent to %q, want %q", got, customer)
+	}
+
+	unknownStatus, unknownBody := request("someone-else@example.com")
+	if got := sent(); got != "" {
+		t.Errorf("portal link sent to unknown customer %q", got)
+	}
+	if knownStatus != fiber.StatusAccepted || unknownStatus != knownStatus || unknownBody["message"] != knownBody["message"] {
+		t.Errorf("unknown customer got %d %v, known customer got %d %v, want the same 202 response",
+			unknownStatus, unknownBody, knownStatus, knownBody)
+	}
+
+	limitedStatus, limitedBody := request(strings.ToUpper(customer))
+	if got := sent(); got != "" {
+		t.Errorf("second portal link within the interval sent to %q", got)
+	}
+	if limitedStatus != knownStatus || limitedBody["message"] != knownBody["message"] {
+		t.Errorf("rate limited request got %d %v, want the same response as the first", limitedStatus, limitedBody)
+	}
+}
+
+func TestInvalidIDsAreRejected(t *testing.T) {
+	// no recover middleware is installed, so a panicking handler fails the request instead of answering it
+	app := newTokenOnlyApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/portal/:userID/link", app.RequestPortalLinkHandler())
+
+	status, body := doJSON(t, srv, fiber.MethodPost, "/api/portal/not-an-object-id/link", "", PortalLinkRequestModel{Email: "grace@example.com"})
+	if status != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d (%v)", status, fiber.StatusBadRequest, body)
+	}
+	if body["code"] != CodeInvalidRequest {
+		t.Errorf("code = %v, want %q", body["code"], CodeInvalidRequest)
+	}
+}
+
+func TestSendIssuedInvoiceRejectsInvalidInvoiceID(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Post("/api/invoice/:userID/send/:invoiceID", app.SendIssuedInvoiceToCustomer())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	request := UpdateInvoiceStatusRequestModel{Status: "issued"}
+	status, body := doJSON(t, srv, fiber.MethodPost, "/api/invoice/"+account.ID+"/send/not-an-object-id", token, request)
+	if status != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d (%v)", status, fiber.StatusBadRequest, body)
+	}
+}
+
+func TestUpdateProfileHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Put("/api/user/:userID/profile", app.UpdateProfileHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+	path := "/api/user/" + account.ID + "/profile"
+
+	t.Run("updated", func(t *testing.T) {
+		request := ProfileRequestModel{FirstName: "Grace", LastName: "Hopper", PhoneNumber: "+15550199"}
+		status, body := doJSON(t, srv, fiber.MethodPut, path, token, request)
+		if status != fiber.StatusOK {
+			t.Fatalf("status = %d, want %d (%v)", status, fiber.StatusOK, body)
+		}
+
+		user, err := app.userRepository.FindByID(app.db, account.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if user.FirstName != "Grace" || user.LastName != "Hopper" || user.PhoneNumber != "+15550199" {
+			t.Errorf("stored profile = %q %q %q, want Grace Hopper +15550199", user.FirstName, user.LastName, user.PhoneNumber)
+		}
+		if user.Email != account.Email {
+			t.Errorf("email = %q, want it unchanged as %q", user.Email, account.Email)
+		}
+	})
+
+	t.Run("email change rejected", func(t *testing.T) {
+		request := ProfileRequestModel{FirstName: "Grace", LastName: "Hopper", PhoneNumber: "+15550199", Email: "grace@example.com"}
+		status, body := doJSON(t, srv, fiber.MethodPut, path, token, request)
+		if status != fiber.StatusBadRequest {
+			t.Errorf("status = %d, want %d (%v)", status, fiber.StatusBadRequest, body)
+		}
+
+		user, err := app.userRepository.FindByID(app.db, account.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if user.Email != account.Email {
+			t.Errorf("email = %q, want it unchanged as %q", user.Email, account.Email)
+		}
+	})
+}
+
+func TestGetUserProfileHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/user/:userID", app.GetUserProfileHandler())
+
+	owner := newTestAccount(t, app)
+	other := newTestAccount(t, app)
+	token := login(t, srv, owner.Email, owner.Password)
+
+	t.Run("found", func(t *testing.T) {
+		status, body := doJSON(t, srv, fiber.MethodGet, "/api/user/"+owner.ID, token, nil)
+		if status != fiber.StatusOK {
+			t.Fatalf("status = %d, want %d (%v)", status, fiber.StatusOK, body)
+		}
+		data, _ := body["data"].(map[string]any)
+		if data["id"] != owner.ID || data["email"] != owner.Email {
+			t.Errorf("data = %v, want the profile of %s", data, owner.ID)
+		}
+		for _, key := range []string{"password", "token"} {
+			if _, ok := data[key]; ok {
+				t.Errorf("profile contains %q", key)
+			}
+		}
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		status, body := doJSON(t, srv, fiber.MethodGet, "/api/user/"+other.ID, token, nil)
+		if status != fiber.StatusForbidden {
+			t.Errorf("status = %d, want %d (%v)", status, fiber.StatusForbidden, body)
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		// a token only authenticates an existing user, so the unknown id is rejected as another user's
+		status, body := doJSON(t, srv, fiber.MethodGet, "/api/user/"+primitive.NewObjectID().Hex(), token, nil)
+		if status != fiber.StatusForbidden {
+			t.Errorf("status = %d, want %d (%v)", status, fiber.StatusForbidden, body)
+		}
+	})
+}
+
+func TestLoginResponseOmitsPassword(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+
+	account := newTestAccount(t, app)
+
+	payload, err := json.Marshal(LoginRequestModel{Email: account.Email, Password: account.Password})
+	if err != nil {
+		t.Fatalf("encoding request: %v", err)
+	}
+	req := httptest.NewRequest(fiber.MethodPost, "/api/login", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := srv.Test(req, 10_000)
+	if err != nil {
+		t.Fatalf("POST /api/login: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d (%s)", resp.StatusCode, fiber.StatusOK, body)
+	}
+	if bytes.Contains(body, []byte(`"password"`)) {
+		t.Errorf("login response contains a password key: %s", body)
+	}
+}
+
+// emailRecorder is an EmailSender keeping the messages it is given instead of delivering them.
+type emailRecorder struct {
+	sent []*infra.EmailTemplate
+}
+
+func (r *emailRecorder) Send(message *infra.EmailTemplate) error {
+	r.sent = append(r.sent, message)
+	return nil
+}
+
+func TestSendInvoiceEmailAttachesPDF(t *testing.T) {
+	app := newTestApplication(t)
+	recorder := &emailRecorder{}
+	app.notification = service.NewEmailNotification(recorder, "invoices@example.com")
+
+	account := newTestAccount(t, app)
+	invoice := newTestInvoice(t, app, account)
+
+	if err := app.sendInvoiceEmail(slog.Default(), account.ID, invoice.InvoiceID, ""); err != nil {
+		t.Fatalf("sendInvoiceEmail: %v", err)
+	}
+	if len(recorder.sent) != 1 || len(recorder.sent[0].Attachments) != 1 {
+		t.Fatalf("sent %+v, want one email with one attachment", recorder.sent)
+	}
+
+	attachment := recorder.sent[0].Attachments[0]
+	if want := "invoice_" + invoice.InvoiceNumber + ".pdf"; attachment.FileName != want {
+		t.Errorf("attachment name = %q, want %q", attachment.FileName, want)
+	}
+	if attachment.ContentType != "application/pdf" || !bytes.HasPrefix(attachment.Content, []byte("%PDF-")) {
+		t.Errorf("attachment of type %q starts with %q, want a PDF", attachment.ContentType, attachment.Content[:min(len(attachment.Content), 8)])
+	}
+}
+
+func TestBuildInvoiceIgnoresClientTotalPrice(t *testing.T) {
+	app := &Application{}
+	request := validInvoiceRequest()
+	request.DueDate = "2030-01-15"
+	request.Items[0].TotalPrice = 1
+
+	invoice, err := app.buildInvoice(&domain.User{ID: primitive.NewObjectID().Hex()}, request)
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	// 2 x 150
+	if got := invoice.Items[0].TotalPrice; got != 300 {
+		t.Errorf("TotalPrice = %v, want 300", got)
+	}
+	if invoice.TotalAmountDue != 300 {
+		t.Errorf("TotalAmountDue = %v, want 300", invoice.TotalAmountDue)
+	}
+}
+
+func TestGetInvoiceActivitiesRejectsMalformedDates(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/invoice/:userID/activities", app.GetInvoiceActivitiesHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+	path := "/api/invoice/" + account.ID + "/activities"
+
+	for _, query := range []string{"?from=2024-05-01", "?to=not-a-time", "?from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z"} {
+		if status, body := doJSON(t, srv, fiber.MethodGet, path+query, token, nil); status != fiber.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d (%v)", query, status, fiber.StatusBadRequest, body)
+		}
+	}
+
+	if status, body := doJSON(t, srv, fiber.MethodGet, path+"?from=2024-05-01T00:00:00Z", token, nil); status != fiber.StatusOK {
+		t.Errorf("valid window: status = %d, want %d (%v)", status, fiber.StatusOK, body)
+	}
+}
+
+// loginActivityIPInfo waits for the login activity of userID and returns its IP info.
+func loginActivityIPInfo(t *testing.T, app *Application, userID string) infra.IPInfo {
+	t.Helper()
+
+	activity := waitForActivity(t, app, userID, infra.UserLoginActivity)
+	raw, err := bson.Marshal(bson.M{"ip_info": activity.Metadata["ip_info"]})
+	if err != nil {
+		t.Fatalf("encoding ip_info: %v", err)
+	}
+	var doc struct {
+		IPInfo infra.IPInfo `bson:"ip_info"`
+	}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("decoding ip_info: %v", err)
+	}
+	return doc.IPInfo
+}
+
+func TestLoginRecordsIPInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		locator service.GeoLocator
+		want    infra.IPInfo
+	}{
+		{name: "located", locator: &fixedGeoLocator{}, want: infra.IPInfo{IP: "203.0.113.7", City: "Lagos", Region: "Lagos", Country: "Nigeria"}},
+		{name: "lookup failed", locator: &fixedGeoLocator{err: errors.New("provider unavailable")}, want: infra.IPInfo{IP: "203.0.113.7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.SetGeoLocator(tt.locator)
+			srv := fiber.New()
+			srv.Post("/api/login", app.LoginHandler())
+
+			account := newTestAccount(t, app)
+
+			payload, err := json.Marshal(LoginRequestModel{Email: account.Email, Password: account.Password})
+			if err != nil {
+				t.Fatalf("encoding request: %v", err)
+			}
+			req := httptest.NewRequest(fiber.MethodPost, "/api/login", bytes.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(fiber.HeaderXForwardedFor, "203.0.113.7, 10.0.0.1")
+
+			resp, err := srv.Test(req, 10_000)
+			if err != nil {
+				t.Fatalf("POST /api/login: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+			}
+
+			if got := loginActivityIPInfo(t, app, account.ID); got != tt.want {
+				t.Errorf("ip_info = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// startTestVerification gives the account a pending email verification expiring at expiresAt and returns its token.
+func startTestVerification(t *testing.T, app *Application, account testAccount, expiresAt time.Time) string {
+	t.Helper()
+
+	user := &domain.User{}
+	token, err := user.StartEmailVerification()
+	if err != nil {
+		t.Fatalf("StartEmailVerification: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"email_verified":          false,
+		"verification_token":      user.VerificationToken,
+		"verification_expires_at": expiresAt,
+	}}
+	if _, err := repository.UserData(app.db, "user").UpdateByID(ctx, account.ID, update); err != nil {
+		t.Fatalf("saving verification: %v", err)
+	}
+	return token
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Get("/api/verify-email", app.VerifyEmailHandler())
+
+	pending := newTestAccount(t, app)
+	token := startTestVerification(t, app, pending, time.Now().Add(domain.EmailVerificationTTL))
+	expired := startTestVerification(t, app, newTestAccount(t, app), time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "missing token", wantStatus: fiber.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "verified", query: "?token=" + token, wantStatus: fiber.StatusOK},
+		{name: "reused", query: "?token=" + token, wantStatus: fiber.StatusBadRequest, wantCode: CodeInvalidVerificationToken},
+		{name: "unknown", query: "?token=" + strings.Repeat("0", 64), wantStatus: fiber.StatusBadRequest, wantCode: CodeInvalidVerificationToken},
+		{name: "expired", query: "?token=" + expired, wantStatus: fiber.StatusGone, wantCode: CodeVerificationTokenExpired},
+	}
+
+	// the reuse case follows the verification of the same token, so the cases run in order
+	for _, tt := range tests {
+		status, body := doJSON(t, srv, fiber.MethodGet, "/api/verify-email"+tt.query, "", nil)
+		if status != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.wantStatus)
+		}
+		if code, _ := body["code"].(string); code != tt.wantCode {
+			t.Errorf("%s: code = %q, want %q", tt.name, code, tt.wantCode)
+		}
+	}
+
+	user, err := app.userRepository.FindByID(app.db, pending.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if !user.EmailVerified {
+		t.Error("EmailVerified = false after verifying, want true")
+	}
+}
+
+func TestLoginRequiresVerifiedEmail(t *testing.T) {
+	app := newTestApplication(t)
+	app.SetRequireEmailVerification(true)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/verify-email", app.VerifyEmailHandler())
+
+	account := newTestAccount(t, app)
+	token := startTestVerification(t, app, account, time.Now().Add(domain.EmailVerificationTTL))
+
+	status, body := doJSON(t, srv, fiber.MethodPost, "/api/login", "", LoginRequestModel{Email: account.Email, Password: account.Password})
+	if status != fiber.StatusForbidden || body["code"] != CodeEmailNotVerified {
+		t.Errorf("login before verifying: status = %d, code = %v, want %d %s", status, body["code"], fiber.StatusForbidden, CodeEmailNotVerified)
+	}
+
+	if status, body := doJSON(t, srv, fiber.MethodGet, "/api/verify-email?token="+token, "", nil); status != fiber.StatusOK {
+		t.Fatalf("verify-email: status = %d, body = %v", status, body)
+	}
+	login(t, srv, account.Email, account.Password)
+}
+
+// countUserInvoices returns the number of invoices on the user document of account.
+func countUserInvoices(t *testing.T, app *Application, account testAccount) int {
+	t.Helper()
+
+	var user struct {
+		Invoices []domain.Invoice `bson:"invoices"`
+	}
+	if err := repository.UserData(app.db, "user").FindOne(context.Background(), bson.M{"_id": account.ID}).Decode(&user); err != nil {
+		t.Fatalf("finding user: %v", err)
+	}
+	return len(user.Invoices)
+}
+
+func TestBulkCreateInvoicesHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Post("/api/invoice/:userID/bulk", app.BulkCreateInvoicesHandler())
+
+	batch := func(account testAccount, size int) []*InvoiceRequestModel {
+		requests := make([]*InvoiceRequestModel, size)
+		for idx := range requests {
+			requests[idx] = newTestInvoiceRequest(account)
+		}
+		return requests
+	}
+
+	tests := []struct {
+		name         string
+		batch        func(account testAccount) []*InvoiceRequestModel
+		wantStatus   int
+		wantCode     string
+		wantCreated  int
+		wantInvalids []int
+	}{
+		{
+			name:        "valid batch",
+			batch:       func(account testAccount) []*InvoiceRequestModel { return batch(account, 3) },
+			wantStatus:  fiber.StatusCreated,
+			wantCreated: 3,
+		},
+		{
+			name: "one invalid invoice",
+			batch: func(account testAccount) []*InvoiceRequestModel {
+				requests := batch(account, 3)
+				requests[1].Items = nil
+				return requests
+			},
+			wantStatus:   fiber.StatusUnprocessableEntity,
+			wantCode:     CodeValidationFailed,
+			wantInvalids: []int{1},
+		},
+		{
+			name:       "empty batch",
+			batch:      func(account testAccount) []*InvoiceRequestModel { return []*InvoiceRequestModel{} },
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   CodeInvalidRequest,
+		},
+		{
+			name:       "oversized batch",
+			batch:      func(account testAccount) []*InvoiceRequestModel { return batch(account, maxBulkInvoices+1) },
+			wantStatus: fiber.StatusRequestEntityTooLarge,
+			wantCode:   CodeBatchTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := newTestAccount(t, app)
+			token := login(t, srv, account.Email, account.Password)
+
+			status, body := doJSON(t, srv, fiber.MethodPost, "/api/invoice/"+account.ID+"/bulk", token, tt.batch(account))
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %v", status, tt.wantStatus, body)
+			}
+			if code, _ := body["code"].(string); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+
+			results, _ := body["data"].([]any)
+			var invalids []int
+			for _, result := range results {
+				result, _ := result.(map[string]any)
+				if result["error"] != nil {
+					index, _ := result["index"].(float64)
+					invalids = append(invalids, int(index))
+				}
+			}
+			if fmt.Sprint(invalids) != fmt.Sprint(tt.wantInvalids) {
+				t.Errorf("invalid invoices = %v, want %v", invalids, tt.wantInvalids)
+			}
+
+			if created := countUserInvoices(t, app, account); created != tt.wantCreated {
+				t.Errorf("%d invoices created, want %d", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestInvoiceJSONExportImportRoundTrip(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/invoice/:userID/export.json", app.ExportInvoicesJSONHandler())
+	srv.Post("/api/invoice/:userID/import.json", app.ImportInvoicesJSONHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	user, err := app.userRepository.FindByID(app.db, account.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	for _, status := range []string{"draft", "pending", "draft"} {
+		request := newTestInvoiceRequest(account)
+		request.Status = status
+		invoice, err := app.buildInvoice(user, request)
+		if err != nil {
+			t.Fatalf("buildInvoice: %v", err)
+		}
+		if err := app.invoiceRepository.AddNewInvoice(app.db, account.ID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	// invoiceSet keys the saved invoices of the account by number
+	invoiceSet := func() map[string]*domain.Invoice {
+		invoices, err := app.invoiceRepository.FindAllInvoice(app.db, account.ID)
+		if err != nil {
+			t.Fatalf("FindAllInvoice: %v", err)
+		}
+		set := make(map[string]*domain.Invoice, len(invoices))
+		for _, invoice := range invoices {
+			set[invoice.InvoiceNumber] = invoice
+		}
+		return set
+	}
+	original := invoiceSet()
+
+	status, body := doJSON(t, srv, fiber.MethodGet, "/api/invoice/"+account.ID+"/export.json", token, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("export: status = %d, body = %v", status, body)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("encoding backup: %v", err)
+	}
+	var backup InvoiceBackup
+	if err := json.Unmarshal(payload, &backup); err != nil {
+		t.Fatalf("decoding backup: %v", err)
+	}
+	if len(backup.Invoices) != len(original) {
+		t.Fatalf("backup holds %d invoices, want %d", len(backup.Invoices), len(original))
+	}
+
+	// wipe the invoices of the account before restoring them
+	ctx := context.Background()
+	for _, invoice := range original {
+		if _, err := repository.InvoiceData(app.db, "invoice").DeleteOne(ctx, bson.M{"invoice_id": invoice.InvoiceID}); err != nil {
+			t.Fatalf("deleting invoice: %v", err)
+		}
+	}
+	if _, err := repository.UserData(app.db, "user").UpdateByID(ctx, account.ID, bson.M{"$set": bson.M{"invoices": bson.A{}}}); err != nil {
+		t.Fatalf("clearing user invoices: %v", err)
+	}
+	if wiped := invoiceSet(); len(wiped) != 0 {
+		t.Fatalf("%d invoices left after wiping", len(wiped))
+	}
+
+	if status, body := doJSON(t, srv, fiber.MethodPost, "/api/invoice/"+account.ID+"/import.json", token, backup); status != fiber.StatusOK {
+		t.Fatalf("import: status = %d, body = %v", status, body)
+	}
+
+	restored := invoiceSet()
+	if len(restored) != len(original) {
+		t.Fatalf("%d invoices restored, want %d", len(restored), len(original))
+	}
+	for number, want := range original {
+		got, ok := restored[number]
+		if !ok {
+			t.Errorf("invoice %s was not restored", number)
+			continue
+		}
+		if got.Status != want.Status || got.TotalAmountDue != want.TotalAmountDue || got.BillingCurrency != want.BillingCurrency ||
+			got.IssueDate != want.IssueDate || got.DueDate != want.DueDate || got.Customer.Email != want.Customer.Email ||
+			len(got.Items) != len(want.Items) {
+			t.Errorf("restored invoice %s = %+v, want %+v", number, got, want)
+		}
+	}
+
+	// importing the same backup again skips every invoice
+	status, body = doJSON(t, srv, fiber.MethodPost, "/api/invoice/"+account.ID+"/import.json", token, backup)
+	if status != fiber.StatusOK {
+		t.Fatalf("second import: status = %d, body = %v", status, body)
+	}
+	if results, _ := body["data"].([]any); len(results) != len(original) {
+		t.Errorf("second import reported %d invoices, want %d", len(results), len(original))
+	} else {
+		for _, result := range results {
+			if result, _ := result.(map[string]any); result["status"] != domain.ImportRowSkipped {
+				t.Errorf("second import result = %v, want %s", result, domain.ImportRowSkipped)
+			}
+		}
+	}
+	if again := invoiceSet(); len(again) != len(original) {
+		t.Errorf("%d invoices after importing twice, want %d", len(again), len(original))
+	}
+}
+
+func TestGetDashboardHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/user/:userID/dashboard", app.GetDashboardHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+	first := newTestInvoice(t, app, account)
+	second := newTestInvoice(t, app, account)
+
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	for idx, invoice := range []*domain.Invoice{first, second, first} {
+		activity := &domain.Activity{
+			UserID:    account.ID,
+			Action:    infra.UpdateInvoiceActivity,
+			Timestamp: start.Add(time.Duration(idx) * time.Minute),
+			Metadata:  map[string]interface{}{"invoiceID": invoice.InvoiceID},
+		}
+		if err := app.activityRepository.Save(app.db, activity); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	status, body := doJSON(t, srv, fiber.MethodGet, "/api/user/"+account.ID+"/dashboard?activities=2", token, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+
+	payload, err := json.Marshal(body["data"])
+	if err != nil {
+		t.Fatalf("encoding dashboard: %v", err)
+	}
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &sections); err != nil {
+		t.Fatalf("decoding dashboard: %v", err)
+	}
+	for _, section := range []string{"summary", "status_counts", "recent_activities", "upcoming"} {
+		if raw, ok := sections[section]; !ok || string(raw) == "null" {
+			t.Errorf("dashboard section %q is missing", section)
+		}
+	}
+
+	var dashboard domain.Dashboard
+	if err := json.Unmarshal(payload, &dashboard); err != nil {
+		t.Fatalf("decoding dashboard: %v", err)
+	}
+
+	// both invoices are drafts issued today, so they are counted, owed and upcoming
+	if dashboard.StatusCounts["draft"] != 2 {
+		t.Errorf("draft count = %d, want 2", dashboard.StatusCounts["draft"])
+	}
+	if want := first.TotalAmountDue + second.TotalAmountDue; dashboard.Summary.TotalDraft != want {
+		t.Errorf("TotalDraft = %v, want %v", dashboard.Summary.TotalDraft, want)
+	}
+	upcoming := map[string]bool{}
+	for _, invoice := range dashboard.Upcoming {
+		upcoming[invoice.InvoiceID] = true
+	}
+	if len(upcoming) != 2 || !upcoming[first.InvoiceID] || !upcoming[second.InvoiceID] {
+		t.Errorf("upcoming invoices = %v, want %s and %s", upcoming, first.InvoiceID, second.InvoiceID)
+	}
+
+	// the two most recent activities, newest first
+	if len(dashboard.RecentActivities) != 2 {
+		t.Fatalf("%d recent activities, want 2", len(dashboard.RecentActivities))
+	}
+	for idx, want := range []time.Time{start.Add(2 * time.Minute), start.Add(time.Minute)} {
+		if got := dashboard.RecentActivities[idx].Timestamp; !got.Equal(want) {
+			t.Errorf("recent activity %d at %v, want %v", idx, got, want)
+		}
+	}
+}
+
+func TestGetMonthlyRevenueHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/user/:userID/revenue", app.GetMonthlyRevenueHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	user, err := app.userRepository.FindByID(app.db, account.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	now := time.Now().UTC()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 12, 0, 0, 0, time.UTC)
+	for _, paid := range []struct {
+		monthsAgo int
+		total     float64
+	}{{0, 120}, {0, 30}, {2, 75}} {
+		invoice, err := app.buildInvoice(user, newTestInvoiceRequest(account))
+		if err != nil {
+			t.Fatalf("buildInvoice: %v", err)
+		}
+		invoice.Status = "paid"
+		paidAt := thisMonth.AddDate(0, -paid.monthsAgo, 0)
+		invoice.PaidAt = &paidAt
+		invoice.TotalAmountDue = paid.total
+		if err := app.invoiceRepository.AddNewInvoice(app.db, account.ID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       []domain.MonthlyRevenue
+	}{
+		{
+			name:       "last four months",
+			query:      "?months=4",
+			wantStatus: fiber.StatusOK,
+			want: []domain.MonthlyRevenue{
+				{Month: thisMonth.AddDate(0, -3, 0).Format("2006-01")},
+				{Month: thisMonth.AddDate(0, -2, 0).Format("2006-01"), Revenue: 75, InvoiceCount: 1},
+				{Month: thisMonth.AddDate(0, -1, 0).Format("2006-01")},
+				{Month: thisMonth.Format("2006-01"), Revenue: 150, InvoiceCount: 2},
+			},
+		},
+		{name: "too many months", query: "?months=61", wantStatus: fiber.StatusBadRequest},
+		{name: "malformed months", query: "?months=twelve", wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doJSON(t, srv, fiber.MethodGet, "/api/user/"+account.ID+"/revenue"+tt.query, token, nil)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %v", status, tt.wantStatus, body)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			payload, err := json.Marshal(body["data"])
+			if err != nil {
+				t.Fatalf("encoding series: %v", err)
+			}
+			var got []domain.MonthlyRevenue
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatalf("decoding series: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("series = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTopCustomersHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/user/:userID/top-customers", app.GetTopCustomersHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	user, err := app.userRepository.FindByID(app.db, account.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	for _, billed := range []struct {
+		status string
+		email  string
+		total  float64
+	}{
+		{"paid", "grace@example.com", 300},
+		{"paid", "alan@example.com", 200},
+		{"paid", "alan@example.com", 150},
+		{"paid", "katherine@example.com", 100},
+		{"issued", "katherine@example.com", 900},
+	} {
+		request := newTestInvoiceRequest(account)
+		request.Customer.Email = billed.email
+		invoice, err := app.buildInvoice(user, request)
+		if err != nil {
+			t.Fatalf("buildInvoice: %v", err)
+		}
+		invoice.Status = billed.status
+		invoice.TotalAmountDue = billed.total
+		if err := app.invoiceRepository.AddNewInvoice(app.db, account.ID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "paid", want: []string{"alan@example.com", "grace@example.com", "katherine@example.com"}},
+		{name: "limited", query: "?limit=2", want: []string{"alan@example.com", "grace@example.com"}},
+		{name: "all sent invoices", query: "?all=true&limit=1", want: []string{"katherine@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doJSON(t, srv, fiber.MethodGet, "/api/user/"+account.ID+"/top-customers"+tt.query, token, nil)
+			if status != fiber.StatusOK {
+				t.Fatalf("status = %d, body = %v", status, body)
+			}
+
+			customers, _ := body["data"].([]any)
+			var got []string
+			for _, customer := range customers {
+				customer, _ := customer.(map[string]any)
+				email, _ := customer["email"].(string)
+				got = append(got, email)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("top customers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchInvoiceHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Patch("/api/invoice/:userID/update/:invoiceID", app.PatchInvoiceHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	notes := "Paid by bank transfer"
+	discount := 12.5
+
+	tests := []struct {
+		name       string
+		issued     bool
+		body       PatchInvoiceRequestModel
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "notes only", body: PatchInvoiceRequestModel{Notes: &notes}, wantStatus: fiber.StatusOK},
+		{name: "discount", body: PatchInvoiceRequestModel{Discount: &discount}, wantStatus: fiber.StatusOK},
+		{name: "nothing to change", wantStatus: fiber.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "issued invoice", issued: true, body: PatchInvoiceRequestModel{Notes: &notes}, wantStatus: fiber.StatusConflict, wantCode: CodeInvoiceStatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := newTestInvoice(t, app, account)
+			if tt.issued {
+				if err := app.invoiceRepository.UpdateInvoiceStatusToIssued(app.db, account.ID, invoice.InvoiceID); err != nil {
+					t.Fatalf("UpdateInvoiceStatusToIssued: %v", err)
+				}
+			}
+			before, err := app.invoiceRepository.FindUserInvoiceByID(app.db, account.ID, invoice.InvoiceID)
+			if err != nil {
+				t.Fatalf("FindUserInvoiceByID: %v", err)
+			}
+
+			path := "/api/invoice/" + account.ID + "/update/" + invoice.InvoiceID
+			status, body := doJSON(t, srv, fiber.MethodPatch, path, token, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %v", status, tt.wantStatus, body)
+			}
+			if code, _ := body["code"].(string); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+
+			// the saved invoice matches the patch applied to the invoice before the request
+			want := *before
+			if tt.wantStatus == fiber.StatusOK {
+				if err := want.ApplyPatch(tt.body.domainPatch()); err != nil {
+					t.Fatalf("ApplyPatch: %v", err)
+				}
+			}
+			got, err := app.invoiceRepository.FindUserInvoiceByID(app.db, account.ID, invoice.InvoiceID)
+			if err != nil {
+				t.Fatalf("FindUserInvoiceByID: %v", err)
+			}
+			if got.Notes != want.Notes || got.Discount != want.Discount || got.TotalAmountDue != want.TotalAmountDue ||
+				got.DueDate != want.DueDate || got.Status != want.Status {
+				t.Errorf("saved invoice = notes %q, discount %v, total %v, due %s, %s; want notes %q, discount %v, total %v, due %s, %s",
+					got.Notes, got.Discount, got.TotalAmountDue, got.DueDate, got.Status,
+					want.Notes, want.Discount, want.TotalAmountDue, want.DueDate, want.Status)
+			}
+		})
+	}
+}
+
+// waitForActivity waits for an activity of action recorded for userID in the background and returns it.
+func waitForActivity(t *testing.T, app *Application, userID, action string) domain.Activity {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		activities, err := app.activityRepository.GetUserActivities(app.db, userID)
+		if err != nil {
+			t.Fatalf("GetUserActivities: %v", err)
+		}
+		for _, activity := range activities {
+			if activity.Action == action {
+				return activity
+			}
+		}
+	}
+	t.Fatalf("no %s activity recorded for user %s", action, userID)
+	return domain.Activity{}
+}
+
+func TestAmendInvoiceHandler(t *testing.T) {
+	app := newTestApplication(t)
+	app.SetAmendGracePeriod(15 * time.Minute)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Patch("/api/invoice/:userID/amend/:invoiceID", app.AmendInvoiceHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+	user, err := app.userRepository.FindByID(app.db, account.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+
+	notes := "Corrected the bank account"
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		status     string
+		issuedAt   time.Time
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "within the grace period", status: "issued", issuedAt: now.Add(-5 * time.Minute), wantStatus: fiber.StatusOK},
+		{name: "after the grace period", status: "issued", issuedAt: now.Add(-time.Hour), wantStatus: fiber.StatusConflict, wantCode: CodeAmendWindowClosed},
+		{name: "draft", status: "draft", wantStatus: fiber.StatusConflict, wantCode: CodeInvoiceStatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice, err := app.buildInvoice(user, newTestInvoiceRequest(account))
+			if err != nil {
+				t.Fatalf("buildInvoice: %v", err)
+			}
+			invoice.Status = tt.status
+			if !tt.issuedAt.IsZero() {
+				invoice.IssuedAt = &tt.issuedAt
+			}
+			if err := app.invoiceRepository.AddNewInvoice(app.db, account.ID, invoice); err != nil {
+				t.Fatalf("AddNewInvoice: %v", err)
+			}
+
+			path := "/api/invoice/" + account.ID + "/amend/" + invoice.InvoiceID
+			status, body := doJSON(t, srv, fiber.MethodPatch, path, token, PatchInvoiceRequestModel{Notes: &notes})
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %v", status, tt.wantStatus, body)
+			}
+			if code, _ := body["code"].(string); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+
+			got, err := app.invoiceRepository.FindUserInvoiceByID(app.db, account.ID, invoice.InvoiceID)
+			if err != nil {
+				t.Fatalf("FindUserInvoiceByID: %v", err)
+			}
+			if amended := got.Notes == notes; amended != (tt.wantStatus == fiber.StatusOK) {
+				t.Errorf("notes = %q, amended %v, want amended %v", got.Notes, amended, tt.wantStatus == fiber.StatusOK)
+			}
+		})
+	}
+
+	activity := waitForActivity(t, app, account.ID, infra.AmendInvoiceActivity)
+	if fields := fmt.Sprint(activity.Metadata["fields"]); fields != "[notes]" {
+		t.Errorf("amend activity fields = %s, want [notes]", fields)
+	}
+}
+
+func TestCloneInvoiceHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Post("/api/invoice/:userID/clone/:invoiceID", app.CloneInvoiceHandler())
+	srv.Patch("/api/invoice/:userID/update/:invoiceID", app.PatchInvoiceHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	original := newTestInvoice(t, app, account)
+	if err := app.invoiceRepository.UpdateInvoiceStatusToIssued(app.db, account.ID, original.InvoiceID); err != nil {
+		t.Fatalf("UpdateInvoiceStatusToIssued: %v", err)
+	}
+	before, err := app.invoiceRepository.FindUserInvoiceByID(app.db, account.ID, original.InvoiceID)
+	if err != nil {
+		t.Fatalf("FindUserInvoiceByID: %v", err)
+	}
+
+	status, body := doJSON(t, srv, fiber.MethodPost, "/api/invoice/"+account.ID+"/clone/"+original.InvoiceID, token, nil)
+	if status != fiber.StatusCreated {
+		t.Fatalf("clone: status = %d, body = %v", status, body)
+	}
+	data, _ := body["data"].(map[string]any)
+	cloneID, _ := data["invoice_id"].(string)
+
+	clone, err := app.invoiceRepository.FindUserInvoiceByID(app.db, account.ID, cloneID)
+	if err != nil {
+		t.Fatalf("FindUserInvoiceByID of the clone: %v", err)
+	}
+	if clone.Status != "draft" || clone.IssuedAt != nil {
+		t.Errorf("clone is %s issued at %v, want an unissued draft", clone.Status, clone.IssuedAt)
+	}
+	if clone.InvoiceNumber == "" || clone.InvoiceNumber == before.InvoiceNumber {
+		t.Errorf("clone number = %q, want a new number other than %q", clone.InvoiceNumber, before.InvoiceNumber)
+	}
+	if today := time.Now().UTC().Format("2006-01-02"); clone.IssueDate != today {
+		t.Errorf("clone issue date = %s, want %s", clone.IssueDate, today)
+	}
+	if clone.TotalAmountDue != before.TotalAmountDue || len(clone.Items) != len(before.Items) {
+		t.Errorf("clone = total %v with %d items, want total %v with %d items",
+			clone.TotalAmountDue, len(clone.Items), before.TotalAmountDue, len(before.Items))
+	}
+
+	// editing the clone leaves the original as it was
+	notes := "Edited on the clone"
+	discount := 20.0
+	path := "/api/invoice/" + account.ID + "/update/" + cloneID
+	if status, body := doJSON(t, srv, fiber.MethodPatch, path, token, PatchInvoiceRequestModel{Notes: &notes, Discount: &discount}); status != fiber.StatusOK {
+		t.Fatalf("patch clone: status = %d, body = %v", status, body)
+	}
+
+	after, err := app.invoiceRepository.FindUserInvoiceByID(app.db, account.ID, original.InvoiceID)
+	if err != nil {
+		t.Fatalf("FindUserInvoiceByID: %v", err)
+	}
+	if after.Notes != before.Notes || after.Discount != before.Discount || after.TotalAmountDue != before.TotalAmountDue ||
+		after.Status != before.Status || after.InvoiceNumber != before.InvoiceNumber {
+		t.Errorf("original after editing the clone = %+v, want %+v", after, before)
+	}
+}
+
+func TestGetInvoiceHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/invoice/:userID/get/:invoiceID", app.GetInvoiceHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	live := newTestInvoice(t, app, account)
+	deleted := newTestInvoice(t, app, account)
+	if err := app.invoiceRepository.SoftDeleteInvoice(app.db, account.ID, deleted.InvoiceID, time.Now()); err != nil {
+		t.Fatalf("SoftDeleteInvoice: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		invoiceID  string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "live invoice", invoiceID: live.InvoiceID, wantStatus: fiber.StatusOK},
+		{name: "soft-deleted invoice", invoiceID: deleted.InvoiceID, wantStatus: fiber.StatusNotFound, wantCode: CodeInvoiceNotFound},
+		{name: "unknown invoice", invoiceID: primitive.NewObjectID().Hex(), wantStatus: fiber.StatusNotFound, wantCode: CodeInvoiceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doJSON(t, srv, fiber.MethodGet, "/api/invoice/"+account.ID+"/get/"+tt.invoiceID, token, nil)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %v", status, tt.wantStatus, body)
+			}
+			if code, _ := body["code"].(string); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUpdateUnIssuedInvoiceHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Put("/api/invoice/:userID/update/:invoiceID", app.UpdateUnIssuedInvoiceHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	tests := []struct {
+		name       string
+		update     func(r *InvoiceRequestModel)
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "valid update", update: func(r *InvoiceRequestModel) { r.Notes = "Updated" }, wantStatus: fiber.StatusOK},
+		{
+			name: "due date before the issue date",
+			update: func(r *InvoiceRequestModel) {
+				r.DueDate = r.IssueDate
+				r.IssueDate = time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   CodeValidationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := newTestInvoice(t, app, account)
+			request := newTestInvoiceRequest(account)
+			request.InvoiceNumber = invoice.InvoiceNumber
+			tt.update(request)
+
+			status, body := doJSON(t, srv, fiber.MethodPut, "/api/invoice/"+account.ID+"/update/"+invoice.InvoiceID, token, request)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %v", status, tt.wantStatus, body)
+			}
+			if code, _ := body["code"].(string); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
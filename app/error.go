@@ -1,6 +1,13 @@
 package app
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
@@ -15,4 +22,138 @@ var (
 	ErrGenerateToken      = errors.New("cannot generate jwt token")
 	ErrInvalidUpdateToken = errors.New("invalid token update")
 	ErrUnauthorized       = errors.New("unauthorized access requested")
+	ErrMissingToken       = errors.New("no token provided")
+	ErrRevokedToken       = errors.New("token has been revoked")
+	ErrInvalidAuthHeader  = errors.New("invalid authorization header")
+	ErrForbidden          = errors.New("access to this resource is forbidden")
+)
+
+// Error codes returned in the code field of an APIError. They are part of the API contract:
+// clients branch on the code, while the message is only meant to be read by people.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeMissingToken       = "missing_token"
+	CodeInvalidAuthHeader  = "invalid_auth_header"
+	CodeTokenRevoked       = "token_revoked"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeLoginFailed        = "login_failed"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeUnprocessable      = "unprocessable_entity"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeInternal           = "internal_error"
+	CodeUnavailable        = "service_unavailable"
+
+	CodeUserAlreadyExists      = "user_already_exists"
+	CodeUserNotFound           = "user_not_found"
+	CodeInvoiceNotFound        = "invoice_not_found"
+	CodeInvoiceStatusConflict  = "invoice_status_conflict"
+	CodeDuplicateInvoiceNumber = "duplicate_invoice_number"
+	CodeDocumentNotFound       = "document_not_found"
+	CodeImportJobConflict      = "import_job_conflict"
+	CodeCreditLimitExceeded    = "credit_limit_exceeded"
+	CodeSenderEmailMismatch    = "sender_email_mismatch"
+	CodeEmailNotVerified       = "email_not_verified"
+	CodeBatchTooLarge          = "batch_too_large"
+	CodeAmendWindowClosed      = "amend_window_closed"
+
+	CodeInvalidVerificationToken = "invalid_verification_token"
+	CodeVerificationTokenExpired = "verification_token_expired"
 )
+
+// errorCodes maps the error values of the app, infra and domain packages to their error code.
+// It is checked in order with errors.Is, so more specific errors must come before the errors
+// they are wrapped with (a revoked token is also an unauthorized one).
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrRevokedToken, CodeTokenRevoked},
+	{ErrMissingToken, CodeMissingToken},
+	{ErrInvalidAuthHeader, CodeInvalidAuthHeader},
+	{ErrUnauthorized, CodeUnauthorized},
+	{ErrForbidden, CodeForbidden},
+	{ErrInvalidCredentials, CodeInvalidCredentials},
+	{ErrLoginFailed, CodeLoginFailed},
+	{ErrUserAlreadyExists, CodeUserAlreadyExists},
+	{ErrInvalidInputReceived, CodeInvalidRequest},
+
+	{infra.ErrUserNotFound, CodeUserNotFound},
+	{infra.ErrUnMatchedPassword, CodeInvalidCredentials},
+	{infra.ErrInvalidLoginDetails, CodeInvalidCredentials},
+	{infra.ErrInvoiceNotFound, CodeInvoiceNotFound},
+	{infra.ErrNoDataFound, CodeNotFound},
+	{infra.ErrInvoiceStatusConflict, CodeInvoiceStatusConflict},
+	{infra.ErrDuplicateInvoiceNumber, CodeDuplicateInvoiceNumber},
+	{infra.ErrDocumentNotFound, CodeDocumentNotFound},
+	{infra.ErrImportJobConflict, CodeImportJobConflict},
+	{infra.ErrInvalidVerificationToken, CodeInvalidVerificationToken},
+	{infra.ErrVerificationTokenExpired, CodeVerificationTokenExpired},
+
+	{domain.ErrCreditLimitExceeded, CodeCreditLimitExceeded},
+	{domain.ErrSenderEmailMismatch, CodeSenderEmailMismatch},
+	{domain.ErrAmendWindowClosed, CodeAmendWindowClosed},
+	{domain.ErrInvalidEmail, CodeValidationFailed},
+	{domain.ErrInvalidFirstName, CodeValidationFailed},
+	{domain.ErrInvalidLastName, CodeValidationFailed},
+	{domain.ErrInvalidPassword, CodeValidationFailed},
+	{domain.ErrInvalidPhoneNumber, CodeValidationFailed},
+}
+
+// APIError is the body of every error response, so clients can rely on one shape.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorCode returns the error code of err, or fallback when err is not one of the known errors.
+func errorCode(err error, fallback string) string {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return fallback
+}
+
+// statusCode returns the generic error code of an HTTP status, for responses whose status
+// is only known at run time.
+func statusCode(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return CodeInvalidRequest
+	case fiber.StatusUnauthorized:
+		return CodeUnauthorized
+	case fiber.StatusForbidden:
+		return CodeForbidden
+	case fiber.StatusNotFound:
+		return CodeNotFound
+	case fiber.StatusConflict:
+		return CodeConflict
+	case fiber.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case fiber.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case fiber.StatusServiceUnavailable:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
+
+// respondError writes an APIError with the given status.
+//
+// Parameters:
+//   - c: The Fiber context of the request.
+//   - status: The HTTP status of the response.
+//   - code: The stable error code, one of the Code constants.
+//   - message: A human-readable description of the error.
+//
+// Returns:
+//   - error: An error if the response cannot be written.
+func respondError(c *fiber.Ctx, status int, code, message string) error {
+	return c.Status(status).JSON(APIError{Code: code, Message: message})
+}
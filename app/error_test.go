@@ -0,0 +1,151 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "app error", err: ErrForbidden, want: CodeForbidden},
+		{name: "infra error", err: infra.ErrInvoiceNotFound, want: CodeInvoiceNotFound},
+		{name: "domain error", err: domain.ErrAmendWindowClosed, want: CodeAmendWindowClosed},
+		{name: "wrapped error", err: fmt.Errorf("transaction failed: %w", infra.ErrDuplicateInvoiceNumber), want: CodeDuplicateInvoiceNumber},
+		// a revoked token is wrapped in ErrUnauthorized, the more specific code wins
+		{name: "revoked token", err: fmt.Errorf("%w: %w", ErrUnauthorized, ErrRevokedToken), want: CodeTokenRevoked},
+		{name: "unknown error", err: errors.New("disk full"), want: CodeInternal},
+		{name: "nil", err: nil, want: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(tt.err, CodeInternal); got != tt.want {
+				t.Errorf("errorCode(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// assertEnvelope fails the test unless body is exactly an APIError with the given code and a message.
+func assertEnvelope(t *testing.T, body map[string]any, wantCode string) {
+	t.Helper()
+
+	keys := make([]string, 0, len(body))
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "code,message" {
+		t.Errorf("error body has keys %v, want code and message", keys)
+	}
+	if body["code"] != wantCode {
+		t.Errorf("code = %v, want %q", body["code"], wantCode)
+	}
+	if message, _ := body["message"].(string); message == "" {
+		t.Error("error body has no message")
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	app := newTokenOnlyApplication(t)
+	srv := fiber.New()
+	srv.Get("/api/user/:userID", app.GetUserProfileHandler())
+	srv.Post("/api/portal/:userID/link", app.RequestPortalLinkHandler())
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       any
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "missing token",
+			method:     fiber.MethodGet,
+			path:       "/api/user/" + primitive.NewObjectID().Hex(),
+			wantStatus: fiber.StatusUnauthorized,
+			wantCode:   CodeMissingToken,
+		},
+		{
+			name:       "invalid id",
+			method:     fiber.MethodPost,
+			path:       "/api/portal/not-an-object-id/link",
+			body:       PortalLinkRequestModel{Email: "grace@example.com"},
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doJSON(t, srv, tt.method, tt.path, "", tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			assertEnvelope(t, body, tt.wantCode)
+		})
+	}
+}
+
+func TestErrorEnvelopeForAccountErrors(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Post("/api/reset-password", app.ResetPasswordHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		body       any
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "wrong password",
+			path:       "/api/login",
+			body:       LoginRequestModel{Email: account.Email, Password: "not-the-password"},
+			wantStatus: fiber.StatusUnauthorized,
+			wantCode:   CodeInvalidCredentials,
+		},
+		{
+			name:  "validation failure",
+			path:  "/api/reset-password",
+			token: token,
+			body: ResetPasswordRequestModel{
+				CurrentPassword: account.Password,
+				Password:        "short",
+				ConfirmPassword: "short",
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   CodeValidationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doJSON(t, srv, fiber.MethodPost, tt.path, tt.token, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			assertEnvelope(t, body, tt.wantCode)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// healthCheckTimeout bounds the database ping of the health check, so load balancers get an answer quickly.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthHandler reports whether the server can reach the database by pinging the primary. It responds with
+// 200 when the ping succeeds and 503 with the error otherwise. It needs no authentication.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request.
+func (app *Application) HealthHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), healthCheckTimeout)
+		defer cancel()
+
+		if err := app.db.Ping(ctx, readpref.Primary()); err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"db":    "down",
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"db": "up",
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestHealthHandlerReportsUnreachableDatabase(t *testing.T) {
+	// no server listens on the address, so the ping fails once server selection gives up
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=100"))
+	if err != nil {
+		t.Fatalf("mongo.Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+
+	app := &Application{db: client}
+	srv := fiber.New()
+	srv.Get("/healthz", app.HealthHandler())
+
+	status, body := doJSON(t, srv, fiber.MethodGet, "/healthz", "", nil)
+	if status != fiber.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d (%v)", status, fiber.StatusServiceUnavailable, body)
+	}
+	if body["db"] != "down" || body["error"] == "" || body["error"] == nil {
+		t.Errorf("body = %v, want the database down with its error", body)
+	}
+}
+
+func TestHealthHandlerReportsReachableDatabase(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Get("/healthz", app.HealthHandler())
+
+	status, body := doJSON(t, srv, fiber.MethodGet, "/healthz", "", nil)
+	if status != fiber.StatusOK || body["db"] != "up" {
+		t.Errorf("status = %d, body = %v, want %d with the database up", status, body, fiber.StatusOK)
+	}
+}
@@ -0,0 +1,243 @@
+package app
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// importRequiredColumns are the columns an invoice import file must have. The billing_currency, discount and
+// notes columns are optional. Each row is a draft invoice with a single item; the sender's name, email and
+// phone are taken from the user's account.
+var importRequiredColumns = []string{
+	"invoice_number", "issue_date", "due_date",
+	"customer_name", "customer_email", "customer_phone", "customer_address",
+	"sender_address", "description", "quantity", "unit_price",
+	"account_name", "account_number", "routing_number", "bank_name",
+}
+
+// importColumns maps the column names of an import file to their position in a row.
+type importColumns map[string]int
+
+// value returns the trimmed value of a column in the record, or "" when the file has no such column.
+func (cols importColumns) value(record []string, name string) string {
+	idx, ok := cols[name]
+	if !ok || idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
+}
+
+// parseImportFile reads an invoice import CSV file. The header is matched case-insensitively and must name every
+// required column; rows with a different number of fields are returned as they are and fail on import.
+func parseImportFile(r io.Reader) ([]string, importColumns, [][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, nil, errors.New("import file is empty")
+		}
+		return nil, nil, nil, fmt.Errorf("invalid import file header: %v", err)
+	}
+
+	// spreadsheet programs may start the file with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	columns := make(importColumns, len(header))
+	for idx, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+	for _, name := range importRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, nil, fmt.Errorf("import file is missing the %s column", name)
+		}
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid import file: %v", err)
+	}
+	return header, columns, records, nil
+}
+
+// invoiceFromImportRow builds the draft invoice described by a row of an import file.
+func (app *Application) invoiceFromImportRow(user *domain.User, columns importColumns, header, record []string) (*domain.Invoice, error) {
+	if len(record) != len(header) {
+		return nil, fmt.Errorf("row has %d fields, expected %d", len(record), len(header))
+	}
+
+	// numbers are not generated for imported rows, since a resumed import finds inserted rows by their number
+	if columns.value(record, "invoice_number") == "" {
+		return nil, errors.New("invoice_number is required")
+	}
+
+	quantity, err := strconv.Atoi(columns.value(record, "quantity"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid quantity %q", columns.value(record, "quantity"))
+	}
+	unitPrice, err := strconv.ParseFloat(columns.value(record, "unit_price"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unit_price %q", columns.value(record, "unit_price"))
+	}
+	var discount float64
+	if value := columns.value(record, "discount"); value != "" {
+		if discount, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid discount %q", value)
+		}
+	}
+
+	billingCurrency, err := resolveBillingCurrency(columns.value(record, "billing_currency"), user.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	sender := domain.SenderDetails{
+		Name:    strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Email:   user.Email,
+		Phone:   user.PhoneNumber,
+		Address: columns.value(record, "sender_address"),
+	}
+	if err := app.senderPolicy.Check(user, sender.Email); err != nil {
+		return nil, err
+	}
+
+	invoice, err := domain.NewInvoice(
+		user.ID,
+		columns.value(record, "invoice_number"),
+		billingCurrency,
+		discount,
+		columns.value(record, "issue_date"),
+		columns.value(record, "due_date"),
+		[]domain.Item{{
+			Description: columns.value(record, "description"),
+			Quantity:    quantity,
+			UnitPrice:   unitPrice,
+		}},
+		domain.PaymentInformation{
+			AccountName:   columns.value(record, "account_name"),
+			AccountNumber: columns.value(record, "account_number"),
+			RoutingNumber: columns.value(record, "routing_number"),
+			BankName:      columns.value(record, "bank_name"),
+		},
+		domain.CustomerDetails{
+			Name:    columns.value(record, "customer_name"),
+			Phone:   columns.value(record, "customer_phone"),
+			Email:   columns.value(record, "customer_email"),
+			Address: columns.value(record, "customer_address"),
+		},
+		sender,
+		"draft",
+	)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Notes = columns.value(record, "notes")
+
+	app.applyBaseCurrency(invoice, user.DefaultCurrency)
+	return invoice, nil
+}
+
+// importRow adds the invoice of a row to the user's invoices. A row whose invoice number is already used is
+// skipped, so a row that was inserted before an interrupted import could record it is not inserted twice.
+// Invalid rows are reported as failed; the error is only returned when the row could not be processed and
+// should be retried.
+func (app *Application) importRow(user *domain.User, columns importColumns, header, record []string, row int) (domain.ImportRowResult, error) {
+	result := domain.ImportRowResult{Row: row, InvoiceNumber: columns.value(record, "invoice_number")}
+
+	if result.InvoiceNumber != "" {
+		exists, err := app.invoiceRepository.InvoiceNumberExists(app.db, user.ID, result.InvoiceNumber)
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			result.Status = domain.ImportRowSkipped
+			result.Error = fmt.Sprintf("invoice number %s already exists", result.InvoiceNumber)
+			return result, nil
+		}
+	}
+
+	invoice, err := app.invoiceFromImportRow(user, columns, header, record)
+	if err != nil {
+		result.Status = domain.ImportRowFailed
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	if _, err := app.checkCreditLimit(user, invoice); err != nil {
+		if !errors.Is(err, domain.ErrCreditLimitExceeded) {
+			return result, err
+		}
+		result.Status = domain.ImportRowFailed
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	if err := app.invoiceRepository.AddNewInvoice(app.db, user.ID, invoice); err != nil {
+		if !errors.Is(err, infra.ErrDuplicateInvoiceNumber) {
+			return result, err
+		}
+		result.Status = domain.ImportRowSkipped
+		result.Error = fmt.Sprintf("invoice number %s already exists", result.InvoiceNumber)
+		return result, nil
+	}
+
+	result.Status = domain.ImportRowCreated
+	result.InvoiceID = invoice.InvoiceID
+	return result, nil
+}
+
+// restoreInvoice adds an invoice of an InvoiceBackup to the user's invoices, keeping its number and status. Like
+// importRow, an invoice whose number is already used is skipped, so importing the same backup twice creates each
+// invoice once. Restored invoices are not checked against credit limits, as most were settled long ago.
+func (app *Application) restoreInvoice(user *domain.User, exported *domain.Invoice, row int) (domain.ImportRowResult, error) {
+	result := domain.ImportRowResult{Row: row, InvoiceNumber: strings.TrimSpace(exported.InvoiceNumber)}
+
+	invoice, err := domain.RestoreInvoice(*exported)
+	if err != nil {
+		result.Status = domain.ImportRowFailed
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	if err := app.senderPolicy.Check(user, invoice.Sender.Email); err != nil {
+		result.Status = domain.ImportRowFailed
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	app.applyBaseCurrency(invoice, user.DefaultCurrency)
+
+	if err := app.invoiceRepository.AddNewInvoice(app.db, user.ID, invoice); err != nil {
+		if !errors.Is(err, infra.ErrDuplicateInvoiceNumber) {
+			return result, err
+		}
+		result.Status = domain.ImportRowSkipped
+		result.Error = fmt.Sprintf("invoice number %s already exists", result.InvoiceNumber)
+		return result, nil
+	}
+
+	result.Status = domain.ImportRowCreated
+	result.InvoiceID = invoice.InvoiceID
+	return result, nil
+}
+
+// writeFailedImportRows writes the failed rows of an import job as CSV, under the file's header with an added
+// error column, for the user to fix and import again.
+func writeFailedImportRows(job *domain.ImportJob, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string(nil), job.Header...), "error")); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(job.FailedRecords); err != nil {
+		return err
+	}
+	return writer.Error()
+}
@@ -2,39 +2,124 @@ package app
 
 import (
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+
+	infra "github.com/thebravebyte/numeris/db"
 )
 
-// contextWithAuth returns the authorization information to give user access to private routes
+// contextWithAuth validates the bearer token of the request and stores its claims in the context locals.
+// The token must also be the one last saved for the user, so tokens replaced by a newer login or cleared
+// by a logout are rejected. It never writes a response: a non-nil error means the request is not
+// authenticated, and the caller must stop processing and respond with 401.
 func (app *Application) contextWithAuth(c *fiber.Ctx, forceAuth bool) error {
 	// getting the authorization header from the request
 	if forceAuth {
 		authString := c.Get("Authorization")
 		if authString == "" {
-			return c.Status(fiber.StatusNoContent).JSON(fiber.Map{"error": "No token provided"})
+			return ErrMissingToken
 		}
 
-		tokenSlices := strings.Split(authString, "")
-		// slog.Info("Checking token", tokenSlices, len(tokenSlices))
-		if len(tokenSlices) != 2 || tokenSlices[0] != "Bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authorization header"})
+		tokenSlices := strings.SplitN(authString, " ", 2)
+		if len(tokenSlices) != 2 || tokenSlices[0] != "Bearer" || tokenSlices[1] == "" {
+			return ErrInvalidAuthHeader
 		}
 
 		// getting the token to parse to the access token
 		token := tokenSlices[1]
 		parse, err := app.authorizeJWT.ParseToken(token)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+			return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+
+		current, err := app.userRepository.IsCurrentToken(app.db, parse.UserUUID, token)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+		if !current {
+			return fmt.Errorf("%w: %w", ErrUnauthorized, ErrRevokedToken)
 		}
 
 		c.Locals("token", token)
-		c.Locals("id", parse.ID)
+		c.Locals("id", parse.UserUUID)
 		c.Locals("email", parse.Email)
+		c.Locals("logger", requestLogger(c).With("user_id", parse.UserUUID))
 
-		return c.Next()
+		return nil
 	}
 
 	return fmt.Errorf("UnAuthorized Access")
 }
+
+// AuthUserID returns the id of the user authenticated by contextWithAuth.
+// The boolean is false when the request has not been authenticated.
+func AuthUserID(c *fiber.Ctx) (string, bool) {
+	id, ok := c.Locals("id").(string)
+	return id, ok && id != ""
+}
+
+// authorizeOwner checks that the user authenticated by contextWithAuth is the user named by the
+// :userID path parameter, so one user can never act on another user's invoices.
+func (app *Application) authorizeOwner(c *fiber.Ctx) error {
+	authUserID, ok := AuthUserID(c)
+	if !ok || authUserID != c.Params("userID") {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// contextWithCustomerAuth validates a customer portal token and returns its claims.
+// User tokens are rejected here, and customer tokens are rejected by contextWithAuth.
+func (app *Application) contextWithCustomerAuth(c *fiber.Ctx) (*infra.CustomerAccessToken, error) {
+	authString := c.Get("Authorization")
+	if authString == "" {
+		return nil, fmt.Errorf("no token provided")
+	}
+
+	tokenSlices := strings.SplitN(authString, " ", 2)
+	if len(tokenSlices) != 2 || tokenSlices[0] != "Bearer" {
+		return nil, fmt.Errorf("invalid authorization header")
+	}
+
+	claims, err := app.authorizeJWT.ParseCustomerToken(tokenSlices[1])
+	if err != nil {
+		return nil, err
+	}
+
+	c.Locals("customerEmail", claims.CustomerEmail)
+	return claims, nil
+}
+
+// RequestLogger stores a logger carrying the request id in the context locals, so everything a handler
+// logs about a request can be correlated. It must run after the requestid middleware; contextWithAuth
+// adds the authenticated user id to the logger.
+func (app *Application) RequestLogger() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		logger := slog.Default()
+		if id, ok := c.Locals("requestid").(string); ok && id != "" {
+			logger = logger.With("request_id", id)
+		}
+		c.Locals("logger", logger)
+		return c.Next()
+	}
+}
+
+// requestLogger returns the logger of the request stored by RequestLogger and contextWithAuth, or the
+// default logger when there is none. Goroutines outliving the handler must take it before they start.
+func requestLogger(c *fiber.Ctx) *slog.Logger {
+	if logger, ok := c.Locals("logger").(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// APIVersionHeader sets the API-Version header on every response, so clients can detect a
+// server running a different API schema than they expect.
+func (app *Application) APIVersionHeader() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("API-Version", APIVersion)
+		return c.Next()
+	}
+}
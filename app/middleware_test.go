@@ -0,0 +1,309 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/thebravebyte/numeris/db/service"
+)
+
+// newTokenOnlyApplication returns an Application that can only verify tokens. It is enough for requests that
+// are rejected before the database is used.
+func newTokenOnlyApplication(t *testing.T) *Application {
+	t.Helper()
+
+	authenticateJWT, err := service.NewAuthenticateJWT("numeris-test-token-key-0123456789", "HS256")
+	if err != nil {
+		t.Fatalf("NewAuthenticateJWT: %v", err)
+	}
+	return &Application{authorizeJWT: *authenticateJWT}
+}
+
+// getWithAuthHeader sends a GET request with the Authorization header set to header, when it is not empty,
+// and returns the status and the decoded error envelope.
+func getWithAuthHeader(t *testing.T, srv *fiber.App, path, header string) (int, APIError) {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodGet, path, nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+
+	resp, err := srv.Test(req, 10_000)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope APIError
+	_ = json.NewDecoder(resp.Body).Decode(&envelope)
+	return resp.StatusCode, envelope
+}
+
+func TestContextWithAuthRejectsBadHeaders(t *testing.T) {
+	app := newTokenOnlyApplication(t)
+	srv := fiber.New()
+	srv.Get("/api/user/:userID", app.GetUserProfileHandler())
+
+	path := "/api/user/" + primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name     string
+		header   string
+		wantCode string
+	}{
+		{name: "no header", header: "", wantCode: CodeMissingToken},
+		{name: "not a bearer token", header: "Basic dXNlcjpwYXNz", wantCode: CodeInvalidAuthHeader},
+		{name: "bearer without token", header: "Bearer ", wantCode: CodeInvalidAuthHeader},
+		{name: "malformed token", header: "Bearer not-a-token", wantCode: CodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, envelope := getWithAuthHeader(t, srv, path, tt.header)
+			if status != fiber.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", status, fiber.StatusUnauthorized)
+			}
+			if envelope.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", envelope.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestContextWithAuthAcceptsValidToken(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/user/:userID", app.GetUserProfileHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	if status, body := doJSON(t, srv, fiber.MethodGet, "/api/user/"+account.ID, token, nil); status != fiber.StatusOK {
+		t.Errorf("status = %d, want %d (%v)", status, fiber.StatusOK, body)
+	}
+}
+
+func TestAuthorizeOwner(t *testing.T) {
+	owner := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name       string
+		authUserID string
+		pathUserID string
+		wantErr    error
+	}{
+		{name: "matching ids", authUserID: owner, pathUserID: owner},
+		{name: "mismatched ids", authUserID: primitive.NewObjectID().Hex(), pathUserID: owner, wantErr: ErrForbidden},
+		{name: "not authenticated", pathUserID: owner, wantErr: ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &Application{}
+			srv := fiber.New()
+			var gotErr error
+			var gotID string
+			srv.Get("/api/user/:userID", func(c *fiber.Ctx) error {
+				if tt.authUserID != "" {
+					c.Locals("id", tt.authUserID)
+				}
+				gotID, _ = AuthUserID(c)
+				gotErr = app.authorizeOwner(c)
+				return nil
+			})
+
+			if _, err := srv.Test(httptest.NewRequest(fiber.MethodGet, "/api/user/"+tt.pathUserID, nil)); err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("authorizeOwner() = %v, want %v", gotErr, tt.wantErr)
+			}
+			if gotID != tt.authUserID {
+				t.Errorf("AuthUserID() = %q, want %q", gotID, tt.authUserID)
+			}
+		})
+	}
+}
+
+func TestCreateInvoiceHandlerRejectsAnotherUser(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Post("/api/invoice/:userID/create", app.CreateInvoiceHandler())
+
+	account := newTestAccount(t, app)
+	other := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	request := newTestInvoiceRequest(account)
+
+	status, body := doJSON(t, srv, fiber.MethodPost, "/api/invoice/"+other.ID+"/create", token, request)
+	if status != fiber.StatusForbidden {
+		t.Errorf("creating an invoice for another user: status = %d, want %d (%v)", status, fiber.StatusForbidden, body)
+	}
+
+	status, body = doJSON(t, srv, fiber.MethodPost, "/api/invoice/"+account.ID+"/create", token, request)
+	if status != fiber.StatusCreated {
+		t.Errorf("creating an invoice for the authenticated user: status = %d, want %d (%v)", status, fiber.StatusCreated, body)
+	}
+}
+
+func TestInvoiceRoutesRejectAnotherUser(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/invoice/:userID/get/:invoiceID", app.GetInvoiceHandler())
+	srv.Get("/api/invoice/:userID/all", app.ListAllInvoiceHandler())
+	srv.Put("/api/invoice/:userID/update/:invoiceID", app.UpdateUnIssuedInvoiceHandler())
+	srv.Delete("/api/invoice/:userID/delete/:invoiceID", app.DeleteInvoiceHandler())
+	srv.Get("/api/invoice/:userID/download/:invoiceID", app.DownloadInvoicePDFHandler())
+
+	owner := newTestAccount(t, app)
+	invoice := newTestInvoice(t, app, owner)
+	intruder := newTestAccount(t, app)
+	token := login(t, srv, intruder.Email, intruder.Password)
+
+	tests := []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{method: fiber.MethodGet, path: "/api/invoice/" + owner.ID + "/get/" + invoice.InvoiceID},
+		{method: fiber.MethodGet, path: "/api/invoice/" + owner.ID + "/all"},
+		{method: fiber.MethodPut, path: "/api/invoice/" + owner.ID + "/update/" + invoice.InvoiceID, body: newTestInvoiceRequest(intruder)},
+		{method: fiber.MethodDelete, path: "/api/invoice/" + owner.ID + "/delete/" + invoice.InvoiceID},
+		{method: fiber.MethodGet, path: "/api/invoice/" + owner.ID + "/download/" + invoice.InvoiceID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if status, _ := doJSON(t, srv, tt.method, tt.path, token, tt.body); status != fiber.StatusForbidden {
+				t.Errorf("status = %d, want %d", status, fiber.StatusForbidden)
+			}
+		})
+	}
+
+	if _, err := app.invoiceRepository.FindUserInvoiceByID(app.db, owner.ID, invoice.InvoiceID); err != nil {
+		t.Errorf("the owner's invoice is gone: %v", err)
+	}
+}
+
+func TestLogoutRevokesToken(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Post("/api/logout", app.LogoutHandler())
+	srv.Get("/api/user/:userID", app.GetUserProfileHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+	profile := "/api/user/" + account.ID
+
+	if status, body := doJSON(t, srv, fiber.MethodGet, profile, token, nil); status != fiber.StatusOK {
+		t.Fatalf("before logout: status = %d, want %d (%v)", status, fiber.StatusOK, body)
+	}
+
+	if status, body := doJSON(t, srv, fiber.MethodPost, "/api/logout", token, nil); status != fiber.StatusOK {
+		t.Fatalf("logout: status = %d, want %d (%v)", status, fiber.StatusOK, body)
+	}
+
+	status, envelope := getWithAuthHeader(t, srv, profile, "Bearer "+token)
+	if status != fiber.StatusUnauthorized || envelope.Code != CodeTokenRevoked {
+		t.Errorf("after logout: status = %d, code = %q, want %d %q", status, envelope.Code, fiber.StatusUnauthorized, CodeTokenRevoked)
+	}
+	if status, _ := doJSON(t, srv, fiber.MethodPost, "/api/logout", token, nil); status != fiber.StatusUnauthorized {
+		t.Errorf("second logout: status = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+
+	// logging in again issues a token that works
+	token = login(t, srv, account.Email, account.Password)
+	if status, body := doJSON(t, srv, fiber.MethodGet, profile, token, nil); status != fiber.StatusOK {
+		t.Errorf("after logging in again: status = %d, want %d (%v)", status, fiber.StatusOK, body)
+	}
+}
+
+// captureLogs makes the default logger write JSON lines to the returned buffer until the test ends.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logLine returns the captured log line with the given message, decoded.
+func logLine(t *testing.T, logs *bytes.Buffer, message string) map[string]any {
+	t.Helper()
+
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		entry := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &entry); err == nil && entry["msg"] == message {
+			return entry
+		}
+	}
+	t.Fatalf("no %q log line in %s", message, logs.String())
+	return nil
+}
+
+func TestRequestLoggerAddsRequestID(t *testing.T) {
+	logs := captureLogs(t)
+	app := &Application{}
+	srv := fiber.New()
+	srv.Use(requestid.New(), app.RequestLogger())
+	srv.Get("/", func(c *fiber.Ctx) error {
+		requestLogger(c).Info("handled")
+		return nil
+	})
+
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "req-42")
+	if _, err := srv.Test(req); err != nil {
+		t.Fatalf("GET /: %v", err)
+	}
+
+	if entry := logLine(t, logs, "handled"); entry["request_id"] != "req-42" {
+		t.Errorf("request_id = %v, want %q", entry["request_id"], "req-42")
+	}
+}
+
+func TestRequestLoggerAddsUserID(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Use(requestid.New(), app.RequestLogger())
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Get("/api/user/:userID/probe", func(c *fiber.Ctx) error {
+		if err := app.contextWithAuth(c, true); err != nil {
+			return err
+		}
+		requestLogger(c).Info("handled")
+		return nil
+	})
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+
+	logs := captureLogs(t)
+	if status, _ := getWithAuthHeader(t, srv, "/api/user/"+account.ID+"/probe", "Bearer "+token); status != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", status, fiber.StatusOK)
+	}
+
+	entry := logLine(t, logs, "handled")
+	if entry["user_id"] != account.ID {
+		t.Errorf("user_id = %v, want %q", entry["user_id"], account.ID)
+	}
+	if id, _ := entry["request_id"].(string); id == "" {
+		t.Error("log line has no request_id")
+	}
+}
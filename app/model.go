@@ -4,19 +4,51 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/thebravebyte/numeris/domain"
 )
 
 // User: user details and informations
 type User struct {
-	ID             string         `json:"id" bson:"_id,omitempty" validate:"required"`
-	Name           string         `json:"name" bson:"name" validate:"required"`
-	Email          string         `json:"email" bson:"email" validate:"required,email"`
-	PhoneNumber    string         `json:"phone_number" bson:"phone_number" validate:"required"`
-	CreatedAt      time.Time      `json:"created_at" bson:"created_at" validate:"required"`
-	UpdatedAt      time.Time      `json:"updated_at" bson:"updated_at" validate:"required"`
-	InvoiceSummary InvoiceSummary `json:"invoice_summary" bson:"invoice_summary"`
-	ActivityLog    []Activity     `json:"activity_log" bson:"activity_log"`
-	Token          string         `json:"token,omitempty" bson:"token,omitempty"`
+	ID             string                `json:"id" bson:"_id,omitempty" validate:"required"`
+	Name           string                `json:"name" bson:"name" validate:"required"`
+	Email          string                `json:"email" bson:"email" validate:"required,email"`
+	PhoneNumber    string                `json:"phone_number" bson:"phone_number" validate:"required"`
+	CreatedAt      time.Time             `json:"created_at" bson:"created_at" validate:"required"`
+	UpdatedAt      time.Time             `json:"updated_at" bson:"updated_at" validate:"required"`
+	InvoiceSummary domain.InvoiceSummary `json:"invoice_summary" bson:"invoice_summary"`
+	ActivityLog    []domain.Activity     `json:"activity_log" bson:"activity_log"`
+	Token          string                `json:"-" bson:"token,omitempty"`
+}
+
+// UserProfile is the user returned to clients, without the password or token
+type UserProfile struct {
+	ID              string                `json:"id"`
+	FirstName       string                `json:"first_name"`
+	LastName        string                `json:"last_name"`
+	Email           string                `json:"email"`
+	PhoneNumber     string                `json:"phone_number"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+	InvoiceSummary  domain.InvoiceSummary `json:"invoice_summary"`
+	DefaultCurrency string                `json:"default_currency,omitempty"`
+	EmailIdentity   domain.EmailIdentity  `json:"email_identity"`
+}
+
+// newUserProfile copies the user fields that are safe to return to clients
+func newUserProfile(user *domain.User) UserProfile {
+	return UserProfile{
+		ID:              user.ID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		PhoneNumber:     user.PhoneNumber,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		InvoiceSummary:  user.InvoiceSummary,
+		DefaultCurrency: user.DefaultCurrency,
+		EmailIdentity:   user.EmailIdentity,
+	}
 }
 
 // Invoice: invoice information for every user activities
@@ -31,7 +63,7 @@ type Invoice struct {
 	Discount        float64            `json:"discount,omitempty" bson:"discount,omitempty"`
 	TotalAmountDue  float64            `json:"total_amount_due" bson:"total_amount_due" validate:"required"`
 	PaymentInfo     PaymentInformation `json:"payment_info" bson:"payment_info" validate:"required"`
-	ActivityLog     []Activity         `json:"activity_log,omitempty" bson:"activity_log,omitempty"`
+	ActivityLog     []domain.Activity  `json:"activity_log,omitempty" bson:"activity_log,omitempty"`
 	Customer        CustomerDetails    `json:"customer" bson:"customer" validate:"required"`
 	Sender          SenderDetails      `json:"sender" bson:"sender" validate:"required"`
 	Notes           string             `json:"notes,omitempty" bson:"notes,omitempty"`
@@ -40,11 +72,29 @@ type Invoice struct {
 	UpdatedAt       time.Time          `json:"updated_at" bson:"updated_at" validate:"required"`
 }
 
+// Item is a line item; Billable defaults to true when omitted, and non-billable
+// items may carry a zero quantity or price because they never count towards the total.
+// TotalPrice is accepted for compatibility but ignored; it is computed from Quantity and UnitPrice.
 type Item struct {
 	Description string  `json:"description" bson:"description" validate:"required"`
-	Quantity    int     `json:"quantity" bson:"quantity" validate:"required,min=1"`
-	UnitPrice   float64 `json:"unit_price" bson:"unit_price" validate:"required"`
-	TotalPrice  float64 `json:"total_price" bson:"total_price" validate:"required"`
+	Quantity    int     `json:"quantity" bson:"quantity" validate:"min=0"`
+	UnitPrice   float64 `json:"unit_price" bson:"unit_price" validate:"min=0"`
+	TotalPrice  float64 `json:"total_price" bson:"total_price" validate:"min=0"`
+	Billable    *bool   `json:"billable,omitempty" bson:"billable,omitempty"`
+	Order       int     `json:"order,omitempty" bson:"order,omitempty" validate:"min=0"`
+}
+
+// IsBillable reports whether the item counts towards the invoice total.
+func (i Item) IsBillable() bool {
+	return i.Billable == nil || *i.Billable
+}
+
+// Expense is a reimbursable cost billed separately from service items
+type Expense struct {
+	Description string  `json:"description" bson:"description" validate:"required"`
+	Amount      float64 `json:"amount" bson:"amount" validate:"required,gt=0"`
+	ReceiptURL  string  `json:"receipt_url,omitempty" bson:"receipt_url,omitempty" validate:"omitempty,url"`
+	Taxable     bool    `json:"taxable" bson:"taxable"`
 }
 
 type PaymentInformation struct {
@@ -54,12 +104,18 @@ type PaymentInformation struct {
 	BankName      string `json:"bank_name" bson:"bank_name" validate:"required"`
 }
 
-// Activity this describes the activities of the user invoices created, issues and the likes
-type Activity struct {
-	Actor     string    `json:"actor" bson:"actor" validate:"required"`
-	Action    string    `json:"action" bson:"action" validate:"required"`
-	Timestamp time.Time `json:"timestamp" bson:"timestamp" validate:"required"`
-	Details   string    `json:"details,omitempty" bson:"details,omitempty"`
+// PaymentMethod is one way to pay an invoice; the fields required depend on Type
+type PaymentMethod struct {
+	Type          string `json:"type" bson:"type" validate:"required,oneof=bank_transfer paypal crypto pay_link"`
+	Label         string `json:"label,omitempty" bson:"label,omitempty" validate:"max=64"`
+	AccountName   string `json:"account_name,omitempty" bson:"account_name,omitempty"`
+	AccountNumber string `json:"account_number,omitempty" bson:"account_number,omitempty"`
+	RoutingNumber string `json:"routing_number,omitempty" bson:"routing_number,omitempty"`
+	BankName      string `json:"bank_name,omitempty" bson:"bank_name,omitempty"`
+	Email         string `json:"email,omitempty" bson:"email,omitempty"`
+	Network       string `json:"network,omitempty" bson:"network,omitempty"`
+	Address       string `json:"address,omitempty" bson:"address,omitempty"`
+	URL           string `json:"url,omitempty" bson:"url,omitempty"`
 }
 
 // CustomerDetails details of a customer an invoices is created for
@@ -80,16 +136,8 @@ type SenderDetails struct {
 
 // InvoiceReminder this is more like a notification for the invoice for the user.
 type InvoiceReminder struct {
-	DaysBeforeDueDate int    `json:"days_before_due_date" bson:"days_before_due_date" validate:"required,min=1"`
-	Message           string `json:"message" bson:"message" validate:"required"`
-}
-
-// InvoiceSummary this is the overall summary of the invoice for the users
-type InvoiceSummary struct {
-	TotalPaid    float64 `json:"total_paid" bson:"total_paid"`
-	TotalOverdue float64 `json:"total_overdue" bson:"total_overdue"`
-	TotalDraft   float64 `json:"total_draft" bson:"total_draft"`
-	TotalUnpaid  float64 `json:"total_unpaid" bson:"total_unpaid"`
+	DaysBeforeDueDate int    `json:"days_before_due_date" bson:"days_before_due_date" validate:"required,min=1,max=365"`
+	Message           string `json:"message" bson:"message" validate:"required,max=1000"`
 }
 
 type EmailTemplate struct {
@@ -117,3 +165,67 @@ type IPInfo struct {
 	Latitude  string `json:"latitude" bson:"latitude,omitempty"`
 	Longitude string `json:"longitude" bson:"longitude,omitempty"`
 }
+
+// invoiceBackupVersion is the version of the InvoiceBackup format written by the JSON export.
+const invoiceBackupVersion = 1
+
+// InvoiceBackup is the JSON export of a user's invoices, which can be imported again to restore them
+type InvoiceBackup struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	UserID     string            `json:"user_id"`
+	Invoices   []*domain.Invoice `json:"invoices"`
+}
+
+// BulkInvoiceResult reports the outcome of one invoice of a bulk creation, by its position in the batch
+type BulkInvoiceResult struct {
+	Index         int    `json:"index"`
+	InvoiceID     string `json:"invoice_id,omitempty"`
+	InvoiceNumber string `json:"invoice_number,omitempty"`
+	Warning       string `json:"warning,omitempty"`
+	Code          string `json:"code,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ReminderResult reports the outcome of sending a reminder for one invoice
+type ReminderResult struct {
+	InvoiceID     string `json:"invoice_id"`
+	InvoiceNumber string `json:"invoice_number"`
+	CustomerEmail string `json:"customer_email"`
+	Sent          bool   `json:"sent"`
+	Error         string `json:"error,omitempty"`
+}
+
+// IssueResult reports the outcome of issuing one invoice in a batch; Status is "issued", "skipped" or "failed"
+type IssueResult struct {
+	InvoiceID     string `json:"invoice_id"`
+	InvoiceNumber string `json:"invoice_number"`
+	CustomerEmail string `json:"customer_email"`
+	Status        string `json:"status"`
+	Warning       string `json:"warning,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ConversionPreview is an amount converted at the current rate, returned without being stored
+type ConversionPreview struct {
+	Amount          float64   `json:"amount"`
+	From            string    `json:"from"`
+	To              string    `json:"to"`
+	ConvertedAmount float64   `json:"converted_amount"`
+	Rate            float64   `json:"rate"`
+	RateUpdatedAt   time.Time `json:"rate_updated_at"`
+}
+
+// Pagination is the page metadata returned alongside paginated list responses
+type Pagination struct {
+	Total  int64 `json:"total"`
+	Limit  int64 `json:"limit"`
+	Offset int64 `json:"offset"`
+}
+
+// ExpandedInvoice is an invoice with the optional profiles requested through `?expand=`
+type ExpandedInvoice struct {
+	*domain.Invoice
+	CustomerProfile *domain.CustomerProfile `json:"customer_profile,omitempty"`
+	SenderProfile   *domain.SenderProfile   `json:"sender_profile,omitempty"`
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/thebravebyte/numeris/domain"
@@ -8,14 +10,58 @@ import (
 
 type InvoiceRepository interface {
 	AddNewInvoice(db *mongo.Client, userID string, invoice *domain.Invoice) error
+	AddNewInvoices(db *mongo.Client, userID string, invoices []*domain.Invoice) error
 	FindUserInvoiceByID(db *mongo.Client, userID, invoiceID string) (*domain.Invoice, error)
 	FindAllInvoice(db *mongo.Client, userID string) ([]*domain.Invoice, error)
+	UsedInvoiceNumbers(db *mongo.Client, userID string) (map[string]bool, error)
+	FindInvoicePage(db *mongo.Client, userID string, filter domain.InvoiceFilter, limit, offset int64) ([]*domain.Invoice, int64, error)
+	DistinctTags(db *mongo.Client, userID, prefix string) ([]string, error)
 	InvoiceStatSummary(db *mongo.Client, userID string) (*domain.InvoiceSummary, error)
+	InvoiceDashboard(db *mongo.Client, userID string, upcomingLimit int64) (*domain.Dashboard, error)
+	RecalculateSummary(db *mongo.Client, userID string) (*domain.SummaryReconciliation, error)
 	InvoiceItemSummary(db *mongo.Client, userID string, invoiceID string) ([]domain.Item, error)
 
 	UpdateInvoiceBeforeDueDate(db *mongo.Client, userID string, invoiceID string, updatedInvoice *domain.Invoice) error
+	FindIssuedInvoicesBetween(db *mongo.Client, userID string, from, to time.Time) ([]domain.Invoice, error)
 	GetIssueInvoiceList(db *mongo.Client, userID string) ([]domain.Invoice, error)
 	UpdateInvoiceStatusToIssued(db *mongo.Client, userID string, invoiceID string) error
+	MarkOverdueInvoices(db *mongo.Client, today time.Time) (int64, error)
+	UpdateInvoiceStatusToPaid(db *mongo.Client, userID string, invoiceID string, payment domain.Payment) error
+	SetInvoiceReminders(db *mongo.Client, userID string, invoice *domain.Invoice) error
+	FindScheduledReminders(db *mongo.Client, today time.Time) ([]domain.ScheduledReminder, error)
+	ClaimReminder(db *mongo.Client, userID, invoiceID string, daysBeforeDueDate int, today time.Time) (bool, error)
+	ScheduleInvoiceSend(db *mongo.Client, userID string, invoice *domain.Invoice) error
+	UnscheduleInvoiceSend(db *mongo.Client, userID string, invoice *domain.Invoice) error
+	FindDueScheduledSends(db *mongo.Client, now time.Time) ([]domain.ScheduledSend, error)
+
+	InvoiceNumberExists(db *mongo.Client, userID, invoiceNumber string) (bool, error)
+	CreateImportJob(db *mongo.Client, job *domain.ImportJob) error
+	FindImportJob(db *mongo.Client, userID, idempotencyKey string) (*domain.ImportJob, error)
+	FindImportJobByID(db *mongo.Client, userID, jobID string) (*domain.ImportJob, error)
+	RecordImportRow(db *mongo.Client, job *domain.ImportJob) error
 
 	DeleteInvoice(db *mongo.Client, userID, invoiceID string) error
+	SoftDeleteInvoice(db *mongo.Client, userID, invoiceID string, deletedAt time.Time) error
+	RestoreDeletedInvoice(db *mongo.Client, userID, invoiceID string) error
+	FindCustomerInvoices(db *mongo.Client, userID, customerEmail string) ([]domain.Invoice, error)
+	FindLatestCustomerInvoice(db *mongo.Client, userID, customerEmail string) (*domain.Invoice, error)
+	OutstandingInvoices(db *mongo.Client, userID string) (*domain.OutstandingBalances, error)
+	FindPaymentPage(db *mongo.Client, userID string, filter domain.PaymentFilter, limit, offset int64) ([]domain.LedgerPayment, int64, error)
+	OutstandingByCustomer(db *mongo.Client, userID string) (map[string]float64, error)
+	RefundInvoice(db *mongo.Client, userID string, invoice *domain.Invoice) error
+	CancelInvoice(db *mongo.Client, userID string, invoice *domain.Invoice) error
+	VoidInvoice(db *mongo.Client, userID string, invoice *domain.Invoice) error
+	ChangeInvoiceCustomer(db *mongo.Client, userID string, invoice *domain.Invoice) error
+	PatchInvoice(db *mongo.Client, userID string, invoice *domain.Invoice, patch domain.InvoicePatch) error
+	AmendInvoice(db *mongo.Client, userID string, invoice *domain.Invoice, patch domain.InvoicePatch, issuedAfter time.Time) error
+	SaveInvoicePDF(db *mongo.Client, userID string, invoice *domain.Invoice, content []byte) (string, error)
+	LoadInvoicePDF(db *mongo.Client, fileID string) ([]byte, error)
+	FindPotentialDuplicates(db *mongo.Client, userID string, windowDays int) ([]domain.DuplicateSet, error)
+	AverageDaysToPayment(db *mongo.Client, userID string, from, to time.Time) (*domain.PaymentTimeMetric, error)
+	RevenueByPeriod(db *mongo.Client, userID string, from, to time.Time) (*domain.RevenuePeriod, error)
+	MonthlyRevenue(db *mongo.Client, userID string, from, to time.Time) ([]domain.MonthlyRevenue, error)
+	TopCustomers(db *mongo.Client, userID string, paidOnly bool, limit int64) ([]domain.TopCustomer, error)
+	SetInvoiceCustomStatus(db *mongo.Client, userID, invoiceID, status string, label *domain.CustomStatus) error
+	TaxByJurisdiction(db *mongo.Client, userID string, from, to time.Time) ([]domain.JurisdictionTax, error)
+	ActionNeededInvoices(db *mongo.Client, userID string, dueSoonDays int) (*domain.ActionNeeded, error)
 }
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/thebravebyte/numeris/domain"
@@ -8,5 +10,8 @@ import (
 
 type ActivityRepository interface {
 	Save(db *mongo.Client, activity *domain.Activity) error
-	GetInvoiceActivities(db *mongo.Client, userID string, limit int64) ([]domain.Activity, error)
+	GetInvoiceActivities(db *mongo.Client, userID string, from, to time.Time, limit, offset int64) ([]domain.Activity, int64, error)
+	GetUserActivities(db *mongo.Client, userID string) ([]domain.Activity, error)
+	FindActivities(db *mongo.Client, userID string, actions []string, limit, offset int64) ([]domain.Activity, int64, error)
+	CountByAction(db *mongo.Client, userID string, from, to time.Time) (map[string]int64, error)
 }
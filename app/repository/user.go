@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/thebravebyte/numeris/domain"
@@ -10,5 +12,15 @@ type UserRepository interface {
 	AddUser(db *mongo.Client, user *domain.User, email string) (*domain.User, error)
 	VerifyLogin(db *mongo.Client, email, password string) (*domain.User, error)
 	SaveToken(db *mongo.Client, id string, accessToken string) error
-	UpdatePassword(db *mongo.Client, email, password string) error
+	IsCurrentToken(db *mongo.Client, id string, accessToken string) (bool, error)
+	ClearToken(db *mongo.Client, id string) error
+	VerifyEmail(db *mongo.Client, token string) (*domain.User, error)
+	UpdatePassword(db *mongo.Client, id, password string) error
+	FindByID(db *mongo.Client, id string) (*domain.User, error)
+	UpdateEmailIdentity(db *mongo.Client, id string, identity *domain.EmailIdentity) error
+	SetStatusConfig(db *mongo.Client, id string, config domain.StatusConfig) error
+	UpdateProfile(db *mongo.Client, user *domain.User) error
+	SetCreditLimit(db *mongo.Client, id string, limit *domain.CreditLimit) error
+	RemoveCreditLimit(db *mongo.Client, id, customerEmail string) error
+	ClaimRateLimit(db *mongo.Client, id, action string, interval time.Duration, now time.Time) (bool, error)
 }
@@ -1,5 +1,7 @@
 package app
 
+import "github.com/thebravebyte/numeris/domain"
+
 // LoginRequestModel represents a request to login
 type LoginRequestModel struct {
 	Email    string `json:"email" Usage:"required,email"`
@@ -8,39 +10,208 @@ type LoginRequestModel struct {
 
 // SignUpRequestModel represents a request to sign up a user
 type SignUpRequestModel struct {
-	FirstName   string `json:"first_name" Usage:"required,alpha"`
-	LastName    string `json:"last_name" Usage:"required,alpha"`
-	Email       string `json:"email" Usage:"required,email"`
-	Password    string `json:"password" Usage:"min=8,max=20"`
-	PhoneNumber string `json:"phone_number" Usage:"required"`
-	Profession  string `json:"profession"`
+	FirstName       string `json:"first_name" Usage:"required,alpha"`
+	LastName        string `json:"last_name" Usage:"required,alpha"`
+	Email           string `json:"email" Usage:"required,email"`
+	Password        string `json:"password" Usage:"min=8,max=20"`
+	PhoneNumber     string `json:"phone_number" Usage:"required"`
+	Profession      string `json:"profession"`
+	DefaultCurrency string `json:"default_currency"`
 }
 
-// ResetPasswordRequestModel to reset user password
+// ResetPasswordRequestModel to reset user password. The current password must be given, and the email, when
+// given, must be the email of the account.
 type ResetPasswordRequestModel struct {
-	Email           string `json:"email" Usage:"required,email"`
-	Password        string `json:"password" Usage:"min=8,max=20"`
-	ConfirmPassword string `json:"confirm_password" Usage:"required"`
+	Email           string `json:"email" validate:"omitempty,email"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Password        string `json:"password" validate:"required,min=8,max=20"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
 }
 
-// InvoiceRequestModel to create a invoice
+// InvoiceRequestModel to create a invoice. An invoice created without an invoice number gets the next
+// sequential number of the user, e.g. "INV-000123".
 type InvoiceRequestModel struct {
 	// NetDayRange     int                `json:"net_range" validate:"required"`
-	BillingCurrency string             `json:"billing_currency"`
-	Items           []Item             `json:"items" validate:"required"`
-	InvoiceNumber   string             `json:"invoice_number"`
-	Discount        float64            `json:"discount"`
-	PaymentInfo     PaymentInformation `json:"payment_info" validate:"required"`
-	Notes           string             `json:"notes"`
-	Customer        CustomerDetails    `json:"customer" validate:"required"`
-	Sender          SenderDetails      `json:"sender" validate:"required"`
-	IssueDate       string             `json:"issue_date" validate:"required"`
-	DueDate         string             `json:"due_date"`
-	Status string `json:"status" validate:"required"`
+	BillingCurrency string              `json:"billing_currency"`
+	Items           []Item              `json:"items" validate:"required"`
+	InvoiceNumber   string              `json:"invoice_number"`
+	Discount        float64             `json:"discount"`
+	TaxRate         float64             `json:"tax_rate" validate:"gte=0,lte=100"`
+	TaxJurisdiction string              `json:"tax_jurisdiction" validate:"max=6"`
+	PaymentInfo     *PaymentInformation `json:"payment_info" validate:"required_without=PaymentMethods"`
+	PaymentMethods  []PaymentMethod     `json:"payment_methods" validate:"omitempty,max=5,dive"`
+	Notes           string              `json:"notes"`
+	Customer        CustomerDetails     `json:"customer" validate:"required"`
+	Sender          SenderDetails       `json:"sender" validate:"required"`
+	IssueDate       string              `json:"issue_date" validate:"required"`
+	DueDate         string              `json:"due_date"`
+	Expenses        []Expense           `json:"expenses" validate:"omitempty,dive"`
+	Status          string              `json:"status" validate:"omitempty,oneof=draft pending"`
+	Tags            []string            `json:"tags" validate:"omitempty,max=10,dive,max=32"`
+	ItemSort        string              `json:"item_sort"`
+}
+
+// invoiceStatus returns the requested initial status, defaulting to "draft" when omitted
+func (m *InvoiceRequestModel) invoiceStatus() string {
+	if m.Status == "" {
+		return "draft"
+	}
+	return m.Status
+}
+
+// domainExpenses converts the requested expenses into domain expenses
+func (m *InvoiceRequestModel) domainExpenses() []domain.Expense {
+	expenses := make([]domain.Expense, 0, len(m.Expenses))
+	for _, expense := range m.Expenses {
+		expenses = append(expenses, domain.Expense(expense))
+	}
+	return expenses
+}
 
+// domainPaymentInfo returns the requested bank details, empty when only other payment methods are given
+func (m *InvoiceRequestModel) domainPaymentInfo() domain.PaymentInformation {
+	if m.PaymentInfo == nil {
+		return domain.PaymentInformation{}
+	}
+	return domain.PaymentInformation(*m.PaymentInfo)
+}
 
+// domainPaymentMethods converts the requested payment methods into domain payment methods
+func (m *InvoiceRequestModel) domainPaymentMethods() []domain.PaymentMethod {
+	methods := make([]domain.PaymentMethod, 0, len(m.PaymentMethods))
+	for _, method := range m.PaymentMethods {
+		methods = append(methods, domain.PaymentMethod(method))
+	}
+	return methods
 }
 
 type UpdateInvoiceStatusRequestModel struct {
 	Status string `json:"status" validate:"required"`
+	// Message is an optional cover note for the customer, kept apart from the invoice notes
+	Message string `json:"message" validate:"max=1000"`
+}
+
+// MarkPaidRequestModel optionally backdates the payment of an invoice, which defaults to now, and names the
+// method it was paid with
+type MarkPaidRequestModel struct {
+	PaidAt string `json:"paid_at" validate:"omitempty,datetime=2006-01-02"`
+	Method string `json:"method" validate:"max=32"`
+}
+
+// RefundInvoiceRequestModel optionally limits the refund of a paid invoice to an amount; it defaults to
+// everything paid
+type RefundInvoiceRequestModel struct {
+	Amount float64 `json:"amount" validate:"omitempty,gt=0"`
+	Reason string  `json:"reason" validate:"max=500"`
+}
+
+// VoidInvoiceRequestModel carries the reason for voiding an issued invoice
+type VoidInvoiceRequestModel struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// ScheduleSendRequestModel sets when an invoice is issued and emailed to its customer
+type ScheduleSendRequestModel struct {
+	ScheduledSendAt string `json:"scheduled_send_at" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// EmailIdentityRequestModel sets how the user's outgoing emails present themselves
+type EmailIdentityRequestModel struct {
+	FromName string `json:"from_name" validate:"max=64"`
+	ReplyTo  string `json:"reply_to" validate:"omitempty,email"`
+}
+
+// ProfileRequestModel updates the user's profile. Email and Password are only read to reject attempts
+// to change them through this request.
+type ProfileRequestModel struct {
+	FirstName   string `json:"first_name" validate:"required,max=64"`
+	LastName    string `json:"last_name" validate:"required,max=64"`
+	PhoneNumber string `json:"phone_number" validate:"required,max=20"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+}
+
+// QuickCreateRequestModel creates a draft invoice from the latest invoice of a customer. The invoice number
+// defaults to the one following the latest invoice and the issue date to today.
+type QuickCreateRequestModel struct {
+	CustomerEmail string `json:"customer_email" validate:"required,email"`
+	InvoiceNumber string `json:"invoice_number" validate:"omitempty,max=64"`
+	IssueDate     string `json:"issue_date" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// CreditLimitRequestModel sets the credit limit of a customer
+type CreditLimitRequestModel struct {
+	CustomerEmail string  `json:"customer_email" validate:"required,email"`
+	Limit         float64 `json:"limit" validate:"required,gt=0"`
+	Enforcement   string  `json:"enforcement" validate:"omitempty,oneof=warn block"`
+}
+
+// PortalLinkRequestModel requests a customer portal magic link
+type PortalLinkRequestModel struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// PatchInvoiceRequestModel changes some fields of an unissued invoice; fields left out are not changed
+type PatchInvoiceRequestModel struct {
+	Notes    *string  `json:"notes" validate:"omitempty,max=2000"`
+	Discount *float64 `json:"discount" validate:"omitempty,min=0,max=100"`
+	Items    []Item   `json:"items" validate:"omitempty,dive"`
+	DueDate  *string  `json:"due_date" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// domainPatch converts the request into a domain invoice patch
+func (m *PatchInvoiceRequestModel) domainPatch() domain.InvoicePatch {
+	patch := domain.InvoicePatch{
+		Notes:    m.Notes,
+		Discount: m.Discount,
+		DueDate:  m.DueDate,
+	}
+	if m.Items != nil {
+		patch.Items = make([]domain.Item, len(m.Items))
+		for idx, item := range m.Items {
+			patch.Items[idx] = domain.Item{
+				Description: item.Description,
+				Quantity:    item.Quantity,
+				UnitPrice:   item.UnitPrice,
+				Billable:    item.Billable,
+				Order:       item.Order,
+			}
+		}
+	}
+	return patch
+}
+
+// ConfirmPasswordRequestModel re-confirms the user's password before a sensitive action
+type ConfirmPasswordRequestModel struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// CustomStatusRequestModel defines a custom invoice status and the core status it counts as
+type CustomStatusRequestModel struct {
+	Name   string `json:"name" validate:"required,max=32"`
+	Bucket string `json:"bucket" validate:"required"`
+}
+
+// StatusConfigRequestModel replaces the custom invoice statuses of the user and the transitions into them
+type StatusConfigRequestModel struct {
+	Statuses    []CustomStatusRequestModel `json:"statuses" validate:"max=20,dive"`
+	Transitions map[string][]string        `json:"transitions"`
+}
+
+// domainStatusConfig converts the request into a domain status configuration
+func (m *StatusConfigRequestModel) domainStatusConfig() domain.StatusConfig {
+	config := domain.StatusConfig{
+		Statuses:    make([]domain.CustomStatus, len(m.Statuses)),
+		Transitions: m.Transitions,
+	}
+	for idx, status := range m.Statuses {
+		config.Statuses[idx] = domain.CustomStatus{Name: status.Name, Bucket: status.Bucket}
+	}
+	return config
+}
+
+// InvoiceCustomStatusRequestModel labels an invoice with a custom status, or removes its label when Status
+// is the core status of the invoice
+type InvoiceCustomStatusRequestModel struct {
+	Status string `json:"status" validate:"required,max=32"`
 }
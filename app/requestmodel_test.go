@@ -0,0 +1,65 @@
+package app
+
+import "testing"
+
+func TestInvoiceRequestModelInvoiceStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   string
+	}{
+		{name: "omitted defaults to draft", status: "", want: "draft"},
+		{name: "draft", status: "draft", want: "draft"},
+		{name: "pending", status: "pending", want: "pending"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &InvoiceRequestModel{Status: tt.status}
+			if got := model.invoiceStatus(); got != tt.want {
+				t.Errorf("invoiceStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvoiceRequestModelStatusValidation(t *testing.T) {
+	tests := []struct {
+		status  string
+		wantErr bool
+	}{
+		{status: "", wantErr: false},
+		{status: "draft", wantErr: false},
+		{status: "pending", wantErr: false},
+		{status: "issued", wantErr: true},
+		{status: "paid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			model := validInvoiceRequest()
+			model.Status = tt.status
+
+			if got := len(FieldValidator(model)) > 0; got != tt.wantErr {
+				t.Errorf("FieldValidator() failed = %v, want %v (%v)", got, tt.wantErr, FieldValidator(model))
+			}
+		})
+	}
+}
+
+// validInvoiceRequest returns an invoice request that passes validation.
+func validInvoiceRequest() *InvoiceRequestModel {
+	return &InvoiceRequestModel{
+		BillingCurrency: "USD",
+		Items:           []Item{{Description: "Design work", Quantity: 2, UnitPrice: 150}},
+		PaymentInfo: &PaymentInformation{
+			AccountName:   "Ada Lovelace",
+			AccountNumber: "01234567890",
+			RoutingNumber: "1234567",
+			BankName:      "Numeris Bank",
+		},
+		Customer:  CustomerDetails{Name: "Grace Hopper", Phone: "+15550100", Email: "grace@example.com", Address: "1 Harbor Road"},
+		Sender:    SenderDetails{Name: "Ada Lovelace", Phone: "+15550101", Email: "ada@example.com", Address: "2 Engine Street"},
+		IssueDate: "2030-01-01",
+	}
+}
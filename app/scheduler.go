@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// StartReminderScheduler sends the invoice reminders due today every interval until ctx is cancelled.
+// It runs once right away so a restart does not delay the day's reminders.
+func (app *Application) StartReminderScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.SendScheduledReminders(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// StartOverdueScheduler marks past-due invoices as overdue every interval until ctx is cancelled.
+// Like the reminder scheduler it runs once right away.
+func (app *Application) StartOverdueScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.MarkOverdueInvoices(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// StartScheduledSendScheduler issues and emails the invoices whose scheduled send time has come every interval
+// until ctx is cancelled. Like the other schedulers it runs once right away.
+func (app *Application) StartScheduledSendScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.SendScheduledInvoices(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendScheduledInvoices issues and emails every scheduled invoice due by now and returns how many were issued.
+// Invoices go through the same checks as a manual issue; one that fails them goes back to draft so it is not
+// retried on every run. An invoice another scheduler issued first is skipped.
+func (app *Application) SendScheduledInvoices(now time.Time) int {
+	due, err := app.invoiceRepository.FindDueScheduledSends(app.db, now)
+	if err != nil {
+		slog.Error("Failed to find scheduled invoices", "error", err)
+		return 0
+	}
+
+	issued := 0
+	for _, send := range due {
+		if _, err := app.issueInvoice(slog.Default(), send.UserID, send.InvoiceID); err != nil {
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				continue
+			}
+			slog.Error("Failed to issue scheduled invoice", "invoiceID", send.InvoiceID, "error", err)
+			app.unscheduleFailedSend(send, err)
+			continue
+		}
+		issued++
+
+		emailErr := app.sendInvoiceEmail(slog.Default(), send.UserID, send.InvoiceID, "")
+		if emailErr != nil {
+			slog.Error("Failed to email scheduled invoice", "invoiceID", send.InvoiceID, "error", emailErr)
+		}
+
+		activity := &domain.Activity{
+			UserID:    send.UserID,
+			Action:    infra.IssueInvoiceActivity,
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				"invoiceID":       send.InvoiceID,
+				"scheduledSendAt": send.SendAt,
+				"emailSent":       emailErr == nil,
+			},
+		}
+		if err := app.activityRepository.Save(app.db, activity); err != nil {
+			slog.Error("Failed to record user activity", "error", err)
+		}
+	}
+
+	if issued > 0 {
+		slog.Info("Scheduled invoices issued", "count", issued)
+	}
+	return issued
+}
+
+// unscheduleFailedSend returns a scheduled invoice that could not be issued to draft and records why.
+func (app *Application) unscheduleFailedSend(send domain.ScheduledSend, cause error) {
+	invoice, err := app.invoiceRepository.FindUserInvoiceByID(app.db, send.UserID, send.InvoiceID)
+	if err != nil {
+		slog.Error("Failed to load scheduled invoice", "invoiceID", send.InvoiceID, "error", err)
+		return
+	}
+	if err := invoice.UnscheduleSend(); err != nil {
+		return
+	}
+	if err := app.invoiceRepository.UnscheduleInvoiceSend(app.db, send.UserID, invoice); err != nil {
+		slog.Error("Failed to unschedule invoice", "invoiceID", send.InvoiceID, "error", err)
+		return
+	}
+
+	activity := &domain.Activity{
+		UserID:    send.UserID,
+		Action:    infra.InvoiceSendScheduledActivity,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"invoiceID":       send.InvoiceID,
+			"action":          "failed",
+			"scheduledSendAt": send.SendAt,
+			"error":           cause.Error(),
+		},
+	}
+	if err := app.activityRepository.Save(app.db, activity); err != nil {
+		slog.Error("Failed to record user activity", "error", err)
+	}
+}
+
+// MarkOverdueInvoices moves the issued and pending invoices due before today to "overdue" and returns how
+// many were updated.
+func (app *Application) MarkOverdueInvoices(today time.Time) int64 {
+	marked, err := app.invoiceRepository.MarkOverdueInvoices(app.db, today)
+	if err != nil {
+		slog.Error("Failed to mark overdue invoices", "error", err)
+		return 0
+	}
+
+	if marked > 0 {
+		slog.Info("Invoices marked overdue", "count", marked)
+	}
+	return marked
+}
+
+// SendScheduledReminders sends every reminder due on today and returns how many were sent. Each reminder is
+// claimed before it is sent, so it goes out at most once a day even when several schedulers run.
+func (app *Application) SendScheduledReminders(today time.Time) int {
+	scheduled, err := app.invoiceRepository.FindScheduledReminders(app.db, today)
+	if err != nil {
+		slog.Error("Failed to find scheduled reminders", "error", err)
+		return 0
+	}
+
+	sent := 0
+	for _, reminder := range scheduled {
+		claimed, err := app.invoiceRepository.ClaimReminder(app.db, reminder.UserID, reminder.Invoice.InvoiceID, reminder.Reminder.DaysBeforeDueDate, today)
+		if err != nil {
+			slog.Error("Failed to claim reminder", "invoiceID", reminder.Invoice.InvoiceID, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := app.notification.SendReminder(&reminder.Invoice, reminder.Reminder.Message, reminder.Identity); err != nil {
+			slog.Error("Failed to send scheduled reminder", "invoiceID", reminder.Invoice.InvoiceID, "error", err)
+			continue
+		}
+		sent++
+
+		activity := &domain.Activity{
+			UserID:    reminder.UserID,
+			Action:    infra.InvoiceReminderActivity,
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				"invoiceID":         reminder.Invoice.InvoiceID,
+				"customerEmail":     reminder.Invoice.Customer.Email,
+				"daysBeforeDueDate": reminder.Reminder.DaysBeforeDueDate,
+				"manual":            false,
+			},
+		}
+		if err := app.activityRepository.Save(app.db, activity); err != nil {
+			slog.Error("Failed to record user activity", "error", err)
+		}
+	}
+
+	if sent > 0 {
+		slog.Info("Scheduled reminders sent", "count", sent)
+	}
+	return sent
+}
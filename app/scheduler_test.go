@@ -0,0 +1,103 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/thebravebyte/numeris/db/service"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// reminderRecorder is a NotificationService recording the invoices reminders are sent for.
+type reminderRecorder struct {
+	service.NotificationService
+	invoiceIDs []string
+}
+
+func (r *reminderRecorder) SendReminder(invoice *domain.Invoice, message string, identity domain.EmailIdentity) error {
+	r.invoiceIDs = append(r.invoiceIDs, invoice.InvoiceID)
+	return nil
+}
+
+// newIssuedTestInvoice saves an invoice sent by account that was issued and is due on dueDate, with reminders.
+func newIssuedTestInvoice(t *testing.T, app *Application, account testAccount, number, dueDate string, reminders []domain.InvoiceReminder) *domain.Invoice {
+	t.Helper()
+
+	user, err := app.userRepository.FindByID(app.db, account.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	invoice, err := app.buildInvoice(user, newTestInvoiceRequest(account))
+	if err != nil {
+		t.Fatalf("buildInvoice: %v", err)
+	}
+	invoice.InvoiceNumber = number
+	invoice.DueDate = dueDate
+	invoice.Status = "issued"
+	invoice.Reminders = reminders
+	if err := app.invoiceRepository.AddNewInvoice(app.db, account.ID, invoice); err != nil {
+		t.Fatalf("AddNewInvoice: %v", err)
+	}
+	return invoice
+}
+
+func TestSendScheduledReminders(t *testing.T) {
+	app := newTestApplication(t)
+	recorder := &reminderRecorder{}
+	app.notification = recorder
+
+	account := newTestAccount(t, app)
+
+	// the clock is fixed far ahead, so invoices of other tests are never due on it
+	today := time.Date(2031, 3, 10, 9, 0, 0, 0, time.UTC)
+	reminders := []domain.InvoiceReminder{{DaysBeforeDueDate: 3, Message: "Due in three days"}}
+
+	due := newIssuedTestInvoice(t, app, account, "INV-REMIND-1", "2031-03-13", reminders)
+	newIssuedTestInvoice(t, app, account, "INV-REMIND-2", "2031-03-14", reminders)
+	newIssuedTestInvoice(t, app, account, "INV-REMIND-3", "2031-03-13", nil)
+
+	app.SendScheduledReminders(today)
+	if len(recorder.invoiceIDs) != 1 || recorder.invoiceIDs[0] != due.InvoiceID {
+		t.Fatalf("reminders sent for %v, want only %s", recorder.invoiceIDs, due.InvoiceID)
+	}
+
+	// a reminder goes out once a day
+	recorder.invoiceIDs = nil
+	app.SendScheduledReminders(today.Add(time.Hour))
+	if len(recorder.invoiceIDs) != 0 {
+		t.Errorf("reminders sent again on the same day for %v", recorder.invoiceIDs)
+	}
+}
+
+func TestAddInvoiceReminderHandler(t *testing.T) {
+	app := newTestApplication(t)
+	srv := fiber.New()
+	srv.Post("/api/login", app.LoginHandler())
+	srv.Post("/api/invoice/:userID/reminders/:invoiceID", app.AddInvoiceReminderHandler())
+
+	account := newTestAccount(t, app)
+	token := login(t, srv, account.Email, account.Password)
+	invoice := newTestInvoice(t, app, account)
+	path := "/api/invoice/" + account.ID + "/reminders/" + invoice.InvoiceID
+
+	request := InvoiceReminder{DaysBeforeDueDate: 3, Message: "Due in three days"}
+	if status, body := doJSON(t, srv, fiber.MethodPost, path, token, request); status != fiber.StatusCreated {
+		t.Fatalf("status = %d, want %d (%v)", status, fiber.StatusCreated, body)
+	}
+
+	stored, err := app.invoiceRepository.FindUserInvoiceByID(app.db, account.ID, invoice.InvoiceID)
+	if err != nil {
+		t.Fatalf("FindUserInvoiceByID: %v", err)
+	}
+	want := domain.InvoiceReminder{DaysBeforeDueDate: 3, Message: "Due in three days"}
+	if len(stored.Reminders) != 1 || stored.Reminders[0] != want {
+		t.Errorf("stored reminders = %+v, want [%+v]", stored.Reminders, want)
+	}
+
+	// the same day cannot be used twice
+	if status, body := doJSON(t, srv, fiber.MethodPost, path, token, request); status != fiber.StatusBadRequest {
+		t.Errorf("second reminder: status = %d, want %d (%v)", status, fiber.StatusBadRequest, body)
+	}
+}
@@ -1,8 +1,15 @@
 package service
 
-import infra "github.com/thebravebyte/numeris/db"
+import (
+	"time"
+
+	infra "github.com/thebravebyte/numeris/db"
+)
 
 type AuthenticateJWT interface {
 	GenerateJWTToken(userUUID, email string) (string, error)
 	ParseToken(tokenValue string) (*infra.AuthAccessToken, error)
+	ParseTokenWithGrace(tokenValue string, grace time.Duration) (*infra.AuthAccessToken, error)
+	GenerateCustomerToken(userUUID, customerEmail string) (string, error)
+	ParseCustomerToken(tokenValue string) (*infra.CustomerAccessToken, error)
 }
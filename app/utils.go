@@ -1,15 +1,28 @@
 package app
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	en_translate "github.com/go-playground/validator/v10/translations/en"
+	"github.com/gofiber/fiber/v2"
 	"github.com/jung-kurt/gofpdf"
 
+	infra "github.com/thebravebyte/numeris/db"
 	"github.com/thebravebyte/numeris/domain"
 )
 
@@ -80,6 +93,8 @@ func FieldErrorsChecker(err validator.FieldError) FieldResult {
 		msg = fmt.Sprintf("the minimum length is %s", param)
 	case "max":
 		msg = fmt.Sprintf("the maximum length is %s", param)
+	case "oneof":
+		msg = fmt.Sprintf("the value must be one of: %s", param)
 	}
 	return FieldResult{
 		NameSpace: err.Namespace(),
@@ -98,6 +113,42 @@ func FieldErrorsChecker(err validator.FieldError) FieldResult {
 // Returns:
 //   - error: An error if the PDF generation or saving process fails, nil otherwise.
 func GenerateInvoicePDF(invoice *domain.Invoice, filePath string) error {
+	pdf, err := newInvoicePDF(invoice)
+	if err != nil {
+		return err
+	}
+
+	err = pdf.OutputFileAndClose(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to save PDF: %w", err)
+	}
+
+	return nil
+}
+
+// WriteInvoicePDF renders the invoice document of GenerateInvoicePDF and writes it to w, without going
+// through a file.
+//
+// Parameters:
+//   - invoice: *domain.Invoice - A pointer to the Invoice struct containing all the invoice data.
+//   - w: io.Writer - The writer the generated PDF is written to.
+//
+// Returns:
+//   - error: An error if the PDF generation or writing fails, nil otherwise.
+func WriteInvoicePDF(invoice *domain.Invoice, w io.Writer) error {
+	pdf, err := newInvoicePDF(invoice)
+	if err != nil {
+		return err
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return nil
+}
+
+// newInvoicePDF lays out the invoice document written by GenerateInvoicePDF and WriteInvoicePDF.
+func newInvoicePDF(invoice *domain.Invoice) (*gofpdf.Fpdf, error) {
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.SetMargins(10, 10, 10)
 	pdf.AddPage()
@@ -105,6 +156,15 @@ func GenerateInvoicePDF(invoice *domain.Invoice, filePath string) error {
 	pdf.SetFont("Arial", "B", 16)
 	pdf.SetTextColor(33, 37, 41)
 	pdf.CellFormat(0, 10, fmt.Sprintf("Invoice #%s", invoice.InvoiceNumber), "0", 1, "C", false, 0, "")
+
+	// the status badge tells the recipient whether the invoice is a draft, still due or settled
+	red, green, blue := invoiceStatusColor(invoice.Status)
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetFillColor(red, green, blue)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetX((210 - 40) / 2)
+	pdf.CellFormat(40, 7, strings.ToUpper(invoice.Status), "0", 1, "C", true, 0, "")
+	pdf.SetTextColor(33, 37, 41)
 	pdf.Ln(5)
 
 	pdf.SetFont("Arial", "B", 12)
@@ -124,12 +184,12 @@ func GenerateInvoicePDF(invoice *domain.Invoice, filePath string) error {
 	pdf.SetFont("Arial", "", 11)
 	issueDate, err := time.Parse(inputDateFormat, invoice.IssueDate)
 	if err != nil {
-		return fmt.Errorf("invalid issue date format: %v", err)
+		return nil, fmt.Errorf("invalid issue date format: %v", err)
 	}
 
 	dueDate, err := time.Parse(inputDateFormat, invoice.DueDate)
 	if err != nil {
-		return fmt.Errorf("invalid due date format: %v", err)
+		return nil, fmt.Errorf("invalid due date format: %v", err)
 	}
 
 	pdf.CellFormat(0, 6, fmt.Sprintf("Issue Date: %s", issueDate.Format(outputDateFormat)), "0", 1, "L", false, 0, "")
@@ -138,46 +198,721 @@ func GenerateInvoicePDF(invoice *domain.Invoice, filePath string) error {
 	pdf.CellFormat(0, 6, fmt.Sprintf("Billing Currency: %s", invoice.BillingCurrency), "0", 1, "L", false, 0, "")
 	pdf.Ln(5)
 
-	pdf.SetFont("Arial", "B", 12)
 	pdf.SetFillColor(220, 220, 220)
-	pdf.CellFormat(80, 8, "Description", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(30, 8, "Quantity", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(40, 8, "Unit Price", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(40, 8, "Total Price", "1", 1, "C", true, 0, "")
+	itemHeader := func() {
+		pdf.SetFont("Arial", "B", 12)
+		pdf.CellFormat(80, 8, "Description", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(30, 8, "Quantity", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(40, 8, "Unit Price", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(40, 8, "Total Price", "1", 1, "C", true, 0, "")
+		pdf.SetFont("Arial", "", 11)
+	}
+	if len(invoice.Items) == 0 {
+		pdf.SetFont("Arial", "I", 11)
+		pdf.CellFormat(190, 8, "No items", "1", 1, "C", false, 0, "")
+	} else {
+		itemHeader()
+	}
 
 	pdf.SetFont("Arial", "", 11)
+	_, pageHeight := pdf.GetPageSize()
+	_, bottomMargin := pdf.GetAutoPageBreak()
 	for _, item := range invoice.Items {
-		pdf.CellFormat(80, 8, item.Description, "1", 0, "L", false, 0, "")
+		// break before a row that would not fit, so every page of the table starts with its header
+		if pdf.GetY()+8 > pageHeight-bottomMargin {
+			pdf.AddPage()
+			itemHeader()
+		}
+
+		description := item.Description
+		if !item.IsBillable() {
+			// informational lines are shown but marked so the reader knows they are not charged
+			description += " (non-billable)"
+			pdf.SetTextColor(108, 117, 125)
+		}
+		pdf.CellFormat(80, 8, description, "1", 0, "L", false, 0, "")
 		pdf.CellFormat(30, 8, fmt.Sprintf("%d", item.Quantity), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(40, 8, fmt.Sprintf("%.2f", item.UnitPrice), "1", 0, "R", false, 0, "")
-		pdf.CellFormat(40, 8, fmt.Sprintf("%.2f", item.TotalPrice), "1", 1, "R", false, 0, "")
+		pdf.CellFormat(40, 8, fmt.Sprintf("%s %.2f", invoice.BillingCurrency, item.UnitPrice), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 8, fmt.Sprintf("%s %.2f", invoice.BillingCurrency, item.TotalPrice), "1", 1, "R", false, 0, "")
+		pdf.SetTextColor(33, 37, 41)
 	}
 
-	pdf.SetFont("Arial", "B", 12)
+	if len(invoice.Expenses) > 0 {
+		pdf.Ln(5)
+		pdf.SetFont("Arial", "B", 12)
+		pdf.CellFormat(0, 8, "Reimbursable Expenses:", "0", 1, "L", false, 0, "")
+		pdf.CellFormat(110, 8, "Description", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(40, 8, "Taxable", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(40, 8, "Amount", "1", 1, "C", true, 0, "")
+
+		pdf.SetFont("Arial", "", 11)
+		for _, expense := range invoice.Expenses {
+			taxable := "No"
+			if expense.Taxable {
+				taxable = "Yes"
+			}
+			pdf.CellFormat(110, 8, expense.Description, "1", 0, "L", false, 0, "")
+			pdf.CellFormat(40, 8, taxable, "1", 0, "C", false, 0, "")
+			pdf.CellFormat(40, 8, fmt.Sprintf("%s %.2f", invoice.BillingCurrency, expense.Amount), "1", 1, "R", false, 0, "")
+		}
+	}
+
+	// the breakdown lets the customer check the total: subtotal less the discount, plus the tax and expenses
 	pdf.Ln(5)
+	pdf.SetFont("Arial", "", 11)
+	pdf.CellFormat(150, 8, "Subtotal:", "0", 0, "R", false, 0, "")
+	pdf.CellFormat(40, 8, fmt.Sprintf("%s %.2f", invoice.BillingCurrency, invoice.Subtotal()), "1", 1, "R", false, 0, "")
+	if invoice.Discount > 0 {
+		pdf.CellFormat(150, 8, fmt.Sprintf("Discount (%s%%):", strconv.FormatFloat(invoice.Discount, 'f', -1, 64)), "0", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 8, fmt.Sprintf("%s -%.2f", invoice.BillingCurrency, invoice.DiscountAmount()), "1", 1, "R", false, 0, "")
+	}
+	if invoice.Tax != nil {
+		label := fmt.Sprintf("Tax (%s%%):", strconv.FormatFloat(invoice.Tax.Rate, 'f', -1, 64))
+		if invoice.Tax.Jurisdiction != "" {
+			label = fmt.Sprintf("Tax %s (%s%%):", invoice.Tax.Jurisdiction, strconv.FormatFloat(invoice.Tax.Rate, 'f', -1, 64))
+		}
+		pdf.CellFormat(150, 8, label, "0", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 8, fmt.Sprintf("%s %.2f", invoice.BillingCurrency, invoice.TaxAmount()), "1", 1, "R", false, 0, "")
+	}
+	if len(invoice.Expenses) > 0 {
+		pdf.CellFormat(150, 8, "Expenses:", "0", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 8, fmt.Sprintf("%s %.2f", invoice.BillingCurrency, invoice.ExpenseAmount()), "1", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Arial", "B", 12)
 	pdf.CellFormat(150, 8, "Total Amount Due:", "0", 0, "R", false, 0, "")
-	pdf.CellFormat(40, 8, fmt.Sprintf("%.2f", invoice.TotalAmountDue), "1", 1, "R", false, 0, "")
+	pdf.CellFormat(40, 8, fmt.Sprintf("%s %.2f", invoice.BillingCurrency, invoice.TotalAmountDue), "1", 1, "R", false, 0, "")
 	pdf.Ln(5)
 
 	pdf.SetFont("Arial", "B", 12)
 	pdf.CellFormat(0, 8, "Payment Information:", "0", 1, "L", false, 0, "")
 	pdf.SetFont("Arial", "", 11)
-	pdf.MultiCell(0, 6, fmt.Sprintf("Account Name: %s\nAccount Number: %s\nRouting Number: %s\nBank Name: %s",
-		invoice.PaymentInfo.AccountName, invoice.PaymentInfo.AccountNumber, invoice.PaymentInfo.RoutingNumber, invoice.PaymentInfo.BankName), "", "L", false)
-	pdf.Ln(5)
+	for _, method := range invoice.PaymentMethodList() {
+		pdf.SetFont("Arial", "B", 11)
+		pdf.CellFormat(0, 6, method.Name(), "0", 1, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 11)
+		pdf.MultiCell(0, 6, method.Details(), "", "L", false)
+		pdf.Ln(2)
+	}
+	pdf.Ln(3)
 
-	if invoice.Notes != "" {
+	if notes := strings.TrimSpace(strings.ReplaceAll(invoice.Notes, "\r\n", "\n")); notes != "" {
 		pdf.Ln(10)
 		pdf.SetFont("Arial", "B", 12)
 		pdf.CellFormat(0, 8, "Notes:", "0", 1, "L", false, 0, "")
 		pdf.SetFont("Arial", "", 11)
-		pdf.MultiCell(0, 6, invoice.Notes, "", "L", false)
+		// MultiCell wraps long lines to the page width and breaks onto a new page when needed
+		pdf.MultiCell(0, 6, notes, "", "L", false)
 	}
 
-	err = pdf.OutputFileAndClose(filePath)
+	return pdf, nil
+}
+
+// invoiceStatusColor returns the fill color of the status badge of an invoice PDF.
+func invoiceStatusColor(status string) (int, int, int) {
+	switch status {
+	case "paid":
+		return 25, 135, 84
+	case "overdue":
+		return 220, 53, 69
+	case "issued", "pending":
+		return 13, 110, 253
+	case "scheduled":
+		return 111, 66, 193
+	case "draft":
+		return 108, 117, 125
+	default:
+		// refunded, voided and cancelled invoices are no longer due
+		return 73, 80, 87
+	}
+}
+
+// renderInvoicePDF renders the invoice in memory and returns the PDF content.
+func renderInvoicePDF(invoice *domain.Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteInvoicePDF(invoice, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseDateRange reads the optional `from` and `to` query values (in the input date format) of a request.
+// A missing `from` defaults to the zero time and a missing `to` defaults to now; `to` covers its whole day.
+//
+// Returns:
+//   - time.Time, time.Time: The start and end of the range.
+//   - error: An error if either value is malformed or the range is inverted.
+func parseDateRange(c *fiber.Ctx) (time.Time, time.Time, error) {
+	var from time.Time
+	to := time.Now()
+
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(inputDateFormat, value)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid from date, expected %s: %v", inputDateFormat, err)
+		}
+		from = parsed
+	}
+
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(inputDateFormat, value)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid to date, expected %s: %v", inputDateFormat, err)
+		}
+		to = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	if from.After(to) {
+		return from, to, fmt.Errorf("from date cannot be after to date")
+	}
+
+	return from, to, nil
+}
+
+// parseTimeWindow reads the optional `from` and `to` query values of a request as RFC3339 timestamps.
+// A missing bound is returned as the zero time and left open.
+//
+// Returns:
+//   - time.Time, time.Time: The start and end of the window.
+//   - error: An error if either value is malformed or the window is inverted.
+func parseTimeWindow(c *fiber.Ctx) (time.Time, time.Time, error) {
+	var from, to time.Time
+
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid from time, expected RFC3339: %v", err)
+		}
+		from = parsed
+	}
+
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid to time, expected RFC3339: %v", err)
+		}
+		to = parsed
+	}
+
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return from, to, fmt.Errorf("from time cannot be after to time")
+	}
+
+	return from, to, nil
+}
+
+// parseInvoiceFilter reads the optional `status`, `tag`, `from` and `to` query values of an invoice list request.
+// Unlike parseDateRange, a missing bound is left open, so invoices issued in the future can still be listed.
+//
+// Returns:
+//   - domain.InvoiceFilter: The filter to apply.
+//   - error: An error if the status is unknown, the tag or a date is malformed, or the range is inverted.
+func parseInvoiceFilter(c *fiber.Ctx) (domain.InvoiceFilter, error) {
+	var filter domain.InvoiceFilter
+
+	if status := strings.ToLower(strings.TrimSpace(c.Query("status"))); status != "" {
+		if !domain.IsInvoiceStatus(status) {
+			return filter, fmt.Errorf("unknown status %q, expected one of %s", status, strings.Join(domain.InvoiceStatuses, ", "))
+		}
+		filter.Status = status
+	}
+
+	if value := c.Query("tag"); value != "" {
+		tags, err := domain.NormalizeTags([]string{value})
+		if err != nil {
+			return filter, err
+		}
+		filter.Tag = tags[0]
+	}
+
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(inputDateFormat, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date, expected %s: %v", inputDateFormat, err)
+		}
+		filter.From = parsed
+	}
+
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(inputDateFormat, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date, expected %s: %v", inputDateFormat, err)
+		}
+		filter.To = parsed
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return filter, fmt.Errorf("from date cannot be after to date")
+	}
+
+	return filter, nil
+}
+
+// sortInvoiceItems orders the items of every invoice by the `item_sort` query value, or by the invoice's
+// own item sort when the query value is missing.
+func sortInvoiceItems(c *fiber.Ctx, invoices ...*domain.Invoice) error {
+	option := strings.ToLower(strings.TrimSpace(c.Query("item_sort")))
+	if err := domain.ValidateItemSort(option); err != nil {
+		return err
+	}
+
+	for _, invoice := range invoices {
+		sortBy := option
+		if sortBy == "" {
+			sortBy = invoice.ItemSort
+		}
+		if err := invoice.SortItems(sortBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteReceiptPDF renders a payment receipt for a fully paid invoice and writes it to w.
+// The receipt uses its own layout, showing the amount paid, the payment method and date, and a PAID stamp.
+//
+// Parameters:
+//   - invoice: *domain.Invoice - A pointer to the paid invoice.
+//   - w: io.Writer - The destination of the rendered PDF.
+//
+// Returns:
+//   - error: An error if the invoice is not paid or the PDF cannot be rendered, nil otherwise.
+func WriteReceiptPDF(invoice *domain.Invoice, w io.Writer) error {
+	if invoice.Status != "paid" {
+		return fmt.Errorf("cannot generate a receipt for an invoice with status %q", invoice.Status)
+	}
+
+	paidAt := invoice.UpdatedAt
+	if invoice.PaidAt != nil {
+		paidAt = *invoice.PaidAt
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 18)
+	pdf.SetTextColor(33, 37, 41)
+	pdf.CellFormat(0, 10, "Payment Receipt", "0", 1, "C", false, 0, "")
+	pdf.SetFont("Arial", "", 11)
+	pdf.CellFormat(0, 6, fmt.Sprintf("For Invoice #%s", invoice.InvoiceNumber), "0", 1, "C", false, 0, "")
+	pdf.Ln(5)
+
+	pdf.SetFont("Arial", "B", 28)
+	pdf.SetTextColor(25, 135, 84)
+	pdf.CellFormat(0, 14, "PAID", "0", 1, "C", false, 0, "")
+	pdf.SetTextColor(33, 37, 41)
+	pdf.Ln(5)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, "Received From:", "0", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 11)
+	pdf.MultiCell(0, 6, fmt.Sprintf("%s\n%s\n%s", invoice.Customer.Name, invoice.Customer.Address, invoice.Customer.Email), "", "L", false)
+	pdf.Ln(5)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, "Received By:", "0", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 11)
+	pdf.MultiCell(0, 6, fmt.Sprintf("%s\n%s\n%s", invoice.Sender.Name, invoice.Sender.Address, invoice.Sender.Email), "", "L", false)
+	pdf.Ln(5)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, "Payment Details:", "0", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 11)
+	pdf.CellFormat(60, 8, "Amount Paid", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 8, fmt.Sprintf("%s %.2f", invoice.BillingCurrency, invoice.TotalAmountDue), "1", 1, "R", false, 0, "")
+	pdf.CellFormat(60, 8, "Payment Method", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 8, receiptPaymentMethod(invoice), "1", 1, "R", false, 0, "")
+	pdf.CellFormat(60, 8, "Payment Date", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 8, paidAt.Format(outputDateFormat), "1", 1, "R", false, 0, "")
+	pdf.CellFormat(60, 8, "Status", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 8, "PAID", "1", 1, "R", false, 0, "")
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to write receipt PDF: %w", err)
+	}
+
+	return nil
+}
+
+// WriteMonthlyReportPDF renders the monthly invoice report: a table of the month's invoices, the totals by status
+// and the totals of the month, and writes it to w.
+//
+// Parameters:
+//   - report: *domain.MonthlyReport - The report to render.
+//   - w: io.Writer - The destination of the rendered PDF.
+//
+// Returns:
+//   - error: An error if the PDF cannot be rendered, nil otherwise.
+func WriteMonthlyReportPDF(report *domain.MonthlyReport, w io.Writer) error {
+	month, err := time.Parse("2006-01", report.Month)
 	if err != nil {
-		return fmt.Errorf("failed to save PDF: %w", err)
+		return fmt.Errorf("invalid report month: %v", err)
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.SetTextColor(33, 37, 41)
+	pdf.CellFormat(0, 10, fmt.Sprintf("Invoice Report - %s", month.Format("January 2006")), "0", 1, "C", false, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Generated on %s", report.GeneratedAt.Format("Jan 02, 2006 15:04 MST")), "0", 1, "C", false, 0, "")
+	pdf.Ln(5)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, "Invoices:", "0", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "B", 11)
+	pdf.SetFillColor(220, 220, 220)
+	pdf.CellFormat(30, 8, "Number", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(30, 8, "Issue Date", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(60, 8, "Customer", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(25, 8, "Status", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(45, 8, "Amount", "1", 1, "C", true, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	if len(report.Invoices) == 0 {
+		pdf.CellFormat(190, 8, "No invoices were issued this month.", "1", 1, "C", false, 0, "")
+	}
+	for idx := range report.Invoices {
+		invoice := &report.Invoices[idx]
+		issueDate := invoice.IssueDate
+		if parsed, err := time.Parse(inputDateFormat, invoice.IssueDate); err == nil {
+			issueDate = parsed.Format(outputDateFormat)
+		}
+		amount, currency := invoice.ReportAmount(report.Currency)
+
+		if invoice.IsWithdrawn() {
+			// withdrawn invoices are listed for completeness but are not part of the totals
+			pdf.SetTextColor(108, 117, 125)
+		}
+		pdf.CellFormat(30, 8, invoice.InvoiceNumber, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 8, issueDate, "1", 0, "C", false, 0, "")
+		pdf.CellFormat(60, 8, invoice.Customer.Name, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(25, 8, invoice.Status, "1", 0, "C", false, 0, "")
+		pdf.CellFormat(45, 8, fmt.Sprintf("%s %.2f", currency, amount), "1", 1, "R", false, 0, "")
+		pdf.SetTextColor(33, 37, 41)
+	}
+	pdf.Ln(5)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, "By Status:", "0", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "B", 11)
+	pdf.CellFormat(60, 8, "Status", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(30, 8, "Invoices", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(50, 8, "Amount", "1", 1, "C", true, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	for _, total := range report.ByStatus {
+		pdf.CellFormat(60, 8, total.Status, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 8, fmt.Sprintf("%d", total.Count), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(50, 8, fmt.Sprintf("%s %.2f", total.Currency, total.Amount), "1", 1, "R", false, 0, "")
+	}
+	pdf.Ln(5)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, "Month Total:", "0", 1, "L", false, 0, "")
+	for _, total := range report.Totals {
+		pdf.CellFormat(90, 8, fmt.Sprintf("%d invoices", total.Count), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(50, 8, fmt.Sprintf("%s %.2f", total.Currency, total.Amount), "1", 1, "R", false, 0, "")
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to write monthly report PDF: %w", err)
 	}
 
 	return nil
 }
+
+// archiveFile is a single JSON document written into a data archive.
+type archiveFile struct {
+	Name string
+	Data any
+}
+
+// buildJSONArchive encodes every file as indented JSON and packs them into a ZIP archive.
+//
+// Returns:
+//   - []byte: The ZIP archive.
+//   - error: An error if any file cannot be encoded or written.
+func buildJSONArchive(files []archiveFile) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.Data, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", file.Name, err)
+		}
+
+		w, err := zw.Create(file.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to archive: %w", file.Name, err)
+		}
+
+		if _, err := w.Write(content); err != nil {
+			return nil, fmt.Errorf("failed to write %s to archive: %w", file.Name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// reminderMessage builds the reminder text for an unpaid invoice, depending on whether it is already overdue.
+func reminderMessage(invoice *domain.Invoice) string {
+	if invoice.DueDate < time.Now().Format(inputDateFormat) {
+		return fmt.Sprintf("Invoice #%s for %s %.2f was due on %s and is now overdue. Please arrange payment at your earliest convenience.",
+			invoice.InvoiceNumber, invoice.BillingCurrency, invoice.TotalAmountDue, invoice.DueDate)
+	}
+	return fmt.Sprintf("This is a friendly reminder that invoice #%s for %s %.2f is due on %s.",
+		invoice.InvoiceNumber, invoice.BillingCurrency, invoice.TotalAmountDue, invoice.DueDate)
+}
+
+// htmlTagPattern matches HTML tags so they can be stripped from user supplied email text
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// sanitizeCoverNote strips HTML tags and control characters, other than line breaks and tabs, from a cover
+// note so it can be placed in an email body as plain text.
+func sanitizeCoverNote(message string) string {
+	message = htmlTagPattern.ReplaceAllString(message, "")
+	message = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, message)
+	return strings.TrimSpace(message)
+}
+
+// invoiceMessage returns the email body sent with an issued invoice, opened by the cover note when there is one.
+func invoiceMessage(invoice *domain.Invoice, coverNote string) string {
+	body := fmt.Sprintf("Please find attached invoice #%s for %s %.2f, due on %s.",
+		invoice.InvoiceNumber, invoice.BillingCurrency, invoice.TotalAmountDue, invoice.DueDate)
+	if coverNote == "" {
+		return body
+	}
+	return coverNote + "\n\n" + body
+}
+
+// receiptPaymentMethod names the payment method shown on a receipt. The payment itself does not record the
+// method used, so a single offered method is named and several are summarised.
+func receiptPaymentMethod(invoice *domain.Invoice) string {
+	methods := invoice.PaymentMethodList()
+	switch len(methods) {
+	case 0:
+		return "-"
+	case 1:
+		return methods[0].Name()
+	}
+	names := make([]string, 0, len(methods))
+	for _, method := range methods {
+		names = append(names, method.Name())
+	}
+	return strings.Join(names, " / ")
+}
+
+// resolveBillingCurrency returns the currency of a new invoice: the requested currency when one is given,
+// otherwise the user's default currency. The result is a supported, upper-cased ISO 4217 code.
+func resolveBillingCurrency(requested, userDefault string) (string, error) {
+	currency := requested
+	if strings.TrimSpace(currency) == "" {
+		currency = userDefault
+	}
+	if strings.TrimSpace(currency) == "" {
+		return "", fmt.Errorf("billing_currency is required when no default currency is set")
+	}
+	return domain.NormalizeCurrency(currency)
+}
+
+// expandOptions are the values accepted by the `expand` query of invoice responses.
+var expandOptions = map[string]bool{"customer": true, "sender": true}
+
+// parseExpand reads the comma separated `expand` query value of a request.
+// Values are matched case-insensitively and unknown values are ignored.
+func parseExpand(c *fiber.Ctx) map[string]bool {
+	expand := make(map[string]bool)
+	for _, value := range strings.Split(c.Query("expand"), ",") {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if expandOptions[value] {
+			expand[value] = true
+		}
+	}
+	return expand
+}
+
+// parseActivityActions reads the comma separated `actions` query value of an activities request. Every action
+// must be one of infra.KnownActivities; no value means every action.
+//
+// Returns:
+//   - []string: The actions to return, without duplicates.
+//   - error: An error naming the first unknown action.
+func parseActivityActions(c *fiber.Ctx) ([]string, error) {
+	known := make(map[string]bool, len(infra.KnownActivities))
+	for _, action := range infra.KnownActivities {
+		known[action] = true
+	}
+
+	actions := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, value := range strings.Split(c.Query("actions"), ",") {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			continue
+		}
+		if !known[value] {
+			return nil, fmt.Errorf("unknown action %q", value)
+		}
+		seen[value] = true
+		actions = append(actions, value)
+	}
+	return actions, nil
+}
+
+// parsePaymentFilter reads the optional `method`, `from` and `to` query values of a payments ledger request.
+// A missing bound is left open and `to` covers its whole day.
+//
+// Returns:
+//   - domain.PaymentFilter: The filter to apply.
+//   - error: An error if a date is malformed or the range is inverted.
+func parsePaymentFilter(c *fiber.Ctx) (domain.PaymentFilter, error) {
+	filter := domain.PaymentFilter{Method: domain.NormalizePaymentMethod(c.Query("method"))}
+
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(inputDateFormat, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date, expected %s: %v", inputDateFormat, err)
+		}
+		filter.From = parsed
+	}
+
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(inputDateFormat, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date, expected %s: %v", inputDateFormat, err)
+		}
+		filter.To = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return filter, fmt.Errorf("from date cannot be after to date")
+	}
+
+	return filter, nil
+}
+
+// writePaymentsCSV writes the payments ledger as CSV, one payment per row.
+func writePaymentsCSV(payments []domain.LedgerPayment, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"paid_at", "amount", "currency", "method", "invoice_id", "invoice_number", "invoice_status", "customer_name", "customer_email"}); err != nil {
+		return err
+	}
+	for _, payment := range payments {
+		if err := writer.Write([]string{
+			payment.PaidAt.Format(time.RFC3339),
+			strconv.FormatFloat(payment.Amount, 'f', 2, 64),
+			payment.Currency,
+			payment.Method,
+			payment.InvoiceID,
+			payment.InvoiceNumber,
+			payment.InvoiceStatus,
+			payment.Customer.Name,
+			payment.Customer.Email,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// parsePagination reads the `limit` and `offset` query values of a request.
+// Invalid or missing values fall back to defaultLimit and 0, and limit is capped at maxLimit.
+func parsePagination(c *fiber.Ctx, defaultLimit, maxLimit int64) (int64, int64) {
+	limit := parseLimit(c, "limit", defaultLimit, maxLimit)
+
+	var offset int64
+	if parsed, err := strconv.ParseInt(c.Query("offset"), 10, 64); err == nil && parsed > 0 {
+		offset = parsed
+	}
+
+	return limit, offset
+}
+
+// parseLimit reads a positive count from the query value key. An invalid or missing value falls back to
+// defaultLimit, and the count is capped at maxLimit.
+func parseLimit(c *fiber.Ctx, key string, defaultLimit, maxLimit int64) int64 {
+	limit := defaultLimit
+	if parsed, err := strconv.ParseInt(c.Query(key), 10, 64); err == nil && parsed > 0 {
+		limit = parsed
+	}
+	return min(limit, maxLimit)
+}
+
+// setPaginationHeaders sets the X-Total-Count header and an RFC 5988 Link header with the
+// first, prev, next and last pages, keeping every other query value of the current request.
+func setPaginationHeaders(c *fiber.Ctx, page Pagination) {
+	c.Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if page.Limit <= 0 {
+		return
+	}
+
+	link := func(offset int64, rel string) string {
+		query := url.Values{}
+		for key, value := range c.Queries() {
+			query.Set(key, value)
+		}
+		query.Set("limit", strconv.FormatInt(page.Limit, 10))
+		query.Set("offset", strconv.FormatInt(offset, 10))
+		return fmt.Sprintf(`<%s%s?%s>; rel="%s"`, c.BaseURL(), c.Path(), query.Encode(), rel)
+	}
+
+	var last int64
+	if page.Total > 0 {
+		last = ((page.Total - 1) / page.Limit) * page.Limit
+	}
+
+	links := []string{link(0, "first")}
+	if page.Offset > 0 {
+		links = append(links, link(max(page.Offset-page.Limit, 0), "prev"))
+	}
+	if page.Offset+page.Limit < page.Total {
+		links = append(links, link(page.Offset+page.Limit, "next"))
+	}
+	links = append(links, link(last, "last"))
+
+	c.Set("Link", strings.Join(links, ", "))
+}
+
+// clientIP returns the IP address of the client. Behind a proxy the address of the client is the
+// first entry of the X-Forwarded-For header, so it is preferred over the address of the connection.
+func clientIP(c *fiber.Ctx) string {
+	if forwarded := c.Get(fiber.HeaderXForwardedFor); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		if ip := net.ParseIP(strings.TrimSpace(first)); ip != nil {
+			return ip.String()
+		}
+	}
+	return c.IP()
+}
+
+// locateIP resolves the location of ip with the configured GeoLocator. A failed lookup is only
+// logged, so the returned IPInfo always holds at least the IP address.
+func (app *Application) locateIP(logger *slog.Logger, ip string) *infra.IPInfo {
+	if app.geoLocator == nil {
+		return &infra.IPInfo{IP: ip}
+	}
+
+	info, err := app.geoLocator.Locate(ip)
+	if err != nil || info == nil {
+		logger.Warn("Failed to locate client IP", "ip", ip, "error", err)
+		return &infra.IPInfo{IP: ip}
+	}
+	info.IP = ip
+	return info
+}
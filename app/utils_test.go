@@ -0,0 +1,495 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/db/service"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+func TestResolveBillingCurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		requested   string
+		userDefault string
+		want        string
+		wantErr     bool
+	}{
+		{name: "override", requested: "eur", userDefault: "NGN", want: "EUR"},
+		{name: "default", requested: "", userDefault: "NGN", want: "NGN"},
+		{name: "blank override uses the default", requested: "  ", userDefault: "USD", want: "USD"},
+		{name: "override without default", requested: "USD", want: "USD"},
+		{name: "neither", wantErr: true},
+		{name: "unsupported override", requested: "XYZ", userDefault: "NGN", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveBillingCurrency(tt.requested, tt.userDefault)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveBillingCurrency() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveBillingCurrency() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fixedRateConverter converts between any two currencies at a fixed rate, or fails with err.
+type fixedRateConverter struct {
+	rate float64
+	err  error
+}
+
+func (f fixedRateConverter) Convert(amount float64, from, to string) (float64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return amount * f.rate, nil
+}
+
+func (f fixedRateConverter) Rate(from, to string) (float64, time.Time, error) {
+	return f.rate, time.Time{}, f.err
+}
+
+func TestApplyBaseCurrency(t *testing.T) {
+	tests := []struct {
+		name         string
+		converter    fixedRateConverter
+		noConverter  bool
+		billing      string
+		base         string
+		wantCurrency string
+		wantAmount   float64
+	}{
+		{name: "billed in the base currency", billing: "NGN", base: "NGN", noConverter: true, wantCurrency: "NGN", wantAmount: 100},
+		{name: "override converted", billing: "USD", base: "NGN", converter: fixedRateConverter{rate: 1500}, wantCurrency: "NGN", wantAmount: 150000},
+		{name: "override without converter", billing: "USD", base: "NGN", noConverter: true},
+		{name: "failed conversion", billing: "USD", base: "NGN", converter: fixedRateConverter{err: errors.New("rate unavailable")}},
+		{name: "no base currency", billing: "USD", converter: fixedRateConverter{rate: 1500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &Application{}
+			if !tt.noConverter {
+				app.currencyConverter = tt.converter
+			}
+			invoice := &domain.Invoice{BillingCurrency: tt.billing, TotalAmountDue: 100}
+
+			app.applyBaseCurrency(invoice, tt.base)
+
+			if invoice.BillingCurrency != tt.billing {
+				t.Errorf("BillingCurrency = %q, want %q", invoice.BillingCurrency, tt.billing)
+			}
+			if invoice.BaseCurrency != tt.wantCurrency || invoice.BaseAmountDue != tt.wantAmount {
+				t.Errorf("base = %v %q, want %v %q", invoice.BaseAmountDue, invoice.BaseCurrency, tt.wantAmount, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantLimit  int64
+		wantOffset int64
+	}{
+		{query: "", wantLimit: 20, wantOffset: 0},
+		{query: "limit=5&offset=10", wantLimit: 5, wantOffset: 10},
+		{query: "limit=500", wantLimit: 100, wantOffset: 0},
+		{query: "limit=0&offset=-3", wantLimit: 20, wantOffset: 0},
+		{query: "limit=ten&offset=two", wantLimit: 20, wantOffset: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			withQuery(t, tt.query, func(c *fiber.Ctx) {
+				limit, offset := parsePagination(c, 20, 100)
+				if limit != tt.wantLimit || offset != tt.wantOffset {
+					t.Errorf("parsePagination() = %d, %d, want %d, %d", limit, offset, tt.wantLimit, tt.wantOffset)
+				}
+			})
+		})
+	}
+}
+
+func TestParseInvoiceFilter(t *testing.T) {
+	day := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+
+	tests := []struct {
+		query   string
+		want    domain.InvoiceFilter
+		wantErr bool
+	}{
+		{query: "", want: domain.InvoiceFilter{}},
+		{query: "status=Overdue", want: domain.InvoiceFilter{Status: "overdue"}},
+		{query: "from=2024-01-01", want: domain.InvoiceFilter{From: day("2024-01-01")}},
+		{query: "to=2024-01-31", want: domain.InvoiceFilter{To: day("2024-01-31")}},
+		{query: "status=paid&from=2024-01-01&to=2024-01-31", want: domain.InvoiceFilter{Status: "paid", From: day("2024-01-01"), To: day("2024-01-31")}},
+		{query: "status=archived", wantErr: true},
+		{query: "from=01/02/2024", wantErr: true},
+		{query: "from=2024-02-01&to=2024-01-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			withQuery(t, tt.query, func(c *fiber.Ctx) {
+				got, err := parseInvoiceFilter(c)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("parseInvoiceFilter() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err == nil && got != tt.want {
+					t.Errorf("parseInvoiceFilter() = %+v, want %+v", got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+// newTestPDFInvoice returns an issued invoice with the given number of items, priced the way NewInvoice
+// prices them.
+func newTestPDFInvoice(t *testing.T, itemCount int, discount float64) *domain.Invoice {
+	t.Helper()
+
+	items := make([]domain.Item, 0, itemCount)
+	for i := 1; i <= itemCount; i++ {
+		items = append(items, domain.Item{Description: fmt.Sprintf("Design work, part %d", i), Quantity: 2, UnitPrice: 150})
+	}
+
+	invoice, err := domain.NewInvoice(
+		"user-1",
+		"INV-000042",
+		"USD",
+		discount,
+		"2030-01-01",
+		"2030-01-15",
+		items,
+		domain.PaymentInformation{AccountName: "Ada Lovelace", AccountNumber: "01234567890", RoutingNumber: "1234567", BankName: "Numeris Bank"},
+		domain.CustomerDetails{Name: "Grace Hopper", Phone: "+15550100", Email: "grace@example.com", Address: "1 Harbor Road"},
+		domain.SenderDetails{Name: "Ada Lovelace", Phone: "+15550101", Email: "ada@example.com", Address: "2 Engine Street"},
+		"draft",
+	)
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+	invoice.Status = "issued"
+	return invoice
+}
+
+func TestWriteInvoicePDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteInvoicePDF(newTestPDFInvoice(t, 3, 0), &buf); err != nil {
+		t.Fatalf("WriteInvoicePDF: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Errorf("output starts with %q, want the PDF header", buf.Bytes()[:min(buf.Len(), 8)])
+	}
+
+	malformed := newTestPDFInvoice(t, 3, 0)
+	malformed.DueDate = "15/01/2030"
+	buf.Reset()
+	if err := WriteInvoicePDF(malformed, &buf); err == nil {
+		t.Error("WriteInvoicePDF() with a malformed due date succeeded")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes written for an invoice that failed to render", buf.Len())
+	}
+}
+
+// invoicePDFText renders the invoice without compressing the page content, so the text drawn on the pages can
+// be searched.
+func invoicePDFText(t *testing.T, invoice *domain.Invoice) (string, int) {
+	t.Helper()
+
+	pdf, err := newInvoicePDF(invoice)
+	if err != nil {
+		t.Fatalf("newInvoicePDF: %v", err)
+	}
+	pdf.SetCompression(false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("Output: %v", err)
+	}
+	return buf.String(), pdf.PageCount()
+}
+
+func TestInvoicePDFDiscountBreakdown(t *testing.T) {
+	invoice := newTestPDFInvoice(t, 2, 10)
+
+	// two items of 2 x 150, less 10%
+	if invoice.Subtotal() != 600 || invoice.DiscountAmount() != 60 || invoice.TotalAmountDue != 540 {
+		t.Fatalf("subtotal %v less discount %v is %v, want 600 less 60 is 540", invoice.Subtotal(), invoice.DiscountAmount(), invoice.TotalAmountDue)
+	}
+
+	text, _ := invoicePDFText(t, invoice)
+	for _, want := range []string{"(Subtotal:)", "(USD 600.00)", "(Discount \\(10%\\):)", "(USD -60.00)", "(Total Amount Due:)", "(USD 540.00)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("PDF does not show %s", want)
+		}
+	}
+}
+
+func TestInvoicePDFStatusNotesAndItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     int
+		status    string
+		notes     string
+		want      []string
+		wantNot   []string
+		wantPages int
+	}{
+		{name: "overdue badge", items: 2, status: "overdue", want: []string{"(OVERDUE)"}, wantPages: 1},
+		{name: "empty notes", items: 2, status: "issued", notes: " \r\n ", want: []string{"(ISSUED)"}, wantNot: []string{"(Notes:)"}, wantPages: 1},
+		{name: "long notes", items: 2, status: "issued", notes: strings.Repeat("Payment is due within fourteen days. ", 20), want: []string{"(Notes:)"}, wantPages: 1},
+		{name: "no items", items: 0, status: "draft", want: []string{"(DRAFT)", "(No items)"}, wantNot: []string{"(Description)"}, wantPages: 1},
+		{name: "many items", items: 40, status: "paid", want: []string{"(PAID)", "(Design work, part 40)"}, wantPages: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := newTestPDFInvoice(t, max(tt.items, 1), 0)
+			if tt.items == 0 {
+				// NewInvoice requires an item, but an invoice whose items were all removed is still rendered
+				invoice.Items = nil
+			}
+			invoice.Status = tt.status
+			invoice.Notes = tt.notes
+
+			text, pages := invoicePDFText(t, invoice)
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("PDF does not show %s", want)
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(text, unwanted) {
+					t.Errorf("PDF shows %s", unwanted)
+				}
+			}
+			if pages < tt.wantPages {
+				t.Errorf("PDF has %d pages, want at least %d", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestInvoicePDFRepeatsItemHeaderOnEveryPage(t *testing.T) {
+	invoice := newTestPDFInvoice(t, 65, 0)
+
+	text, pages := invoicePDFText(t, invoice)
+	if pages < 2 {
+		t.Fatalf("PDF of %d items has %d page, want several", len(invoice.Items), pages)
+	}
+	// the item table starts every page except any holding only the totals and payment details
+	if headers := strings.Count(text, "(Description)"); headers < 2 || headers > pages {
+		t.Errorf("item table header drawn %d times on %d pages, want once per page of the table", headers, pages)
+	}
+	if !strings.Contains(text, "(Design work, part 65)") {
+		t.Error("PDF does not show the last item")
+	}
+}
+
+func TestParseActivityActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "omitted", query: "", want: []string{}},
+		{name: "one action", query: "actions=user_login_activity", want: []string{"user_login_activity"}},
+		{
+			name:  "several actions",
+			query: "actions=user_login_activity,+Create_Invoice_Activity,user_login_activity,",
+			want:  []string{"user_login_activity", "create_invoice_activity"},
+		},
+		{name: "unknown action", query: "actions=user_login_activity,dance", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withQuery(t, tt.query, func(c *fiber.Ctx) {
+				got, err := parseActivityActions(c)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("parseActivityActions() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+					t.Errorf("parseActivityActions() = %v, want %v", got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestParseTimeWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{name: "omitted"},
+		{
+			name:     "both bounds",
+			query:    "from=2024-05-01T09:00:00Z&to=2024-05-01T17:30:00%2B01:00",
+			wantFrom: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 5, 1, 16, 30, 0, 0, time.UTC),
+		},
+		{name: "from only", query: "from=2024-05-01T09:00:00Z", wantFrom: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "date without time", query: "from=2024-05-01", wantErr: true},
+		{name: "malformed to", query: "to=yesterday", wantErr: true},
+		{name: "inverted", query: "from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withQuery(t, tt.query, func(c *fiber.Ctx) {
+				from, to, err := parseTimeWindow(c)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("parseTimeWindow() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if tt.wantErr {
+					return
+				}
+				if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+					t.Errorf("parseTimeWindow() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+				}
+			})
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded string
+		want      string // empty means the address of the connection
+	}{
+		{name: "no proxy"},
+		{name: "single proxy", forwarded: "203.0.113.7", want: "203.0.113.7"},
+		{name: "proxy chain", forwarded: " 203.0.113.7 , 198.51.100.2, 10.0.0.1", want: "203.0.113.7"},
+		{name: "ipv6", forwarded: "2001:db8::1", want: "2001:db8::1"},
+		{name: "malformed header", forwarded: "unknown, 198.51.100.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fiber.New()
+			srv.Get("/", func(c *fiber.Ctx) error {
+				want := tt.want
+				if want == "" {
+					want = c.IP()
+				}
+				if got := clientIP(c); got != want {
+					t.Errorf("clientIP() = %q, want %q", got, want)
+				}
+				return nil
+			})
+
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			if tt.forwarded != "" {
+				req.Header.Set(fiber.HeaderXForwardedFor, tt.forwarded)
+			}
+			if _, err := srv.Test(req); err != nil {
+				t.Fatalf("GET /: %v", err)
+			}
+		})
+	}
+}
+
+// fixedGeoLocator locates every IP address in Lagos, or fails with err when it is set.
+type fixedGeoLocator struct {
+	err error
+}
+
+func (l *fixedGeoLocator) Locate(ip string) (*infra.IPInfo, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	return &infra.IPInfo{IP: ip, City: "Lagos", Region: "Lagos", Country: "Nigeria"}, nil
+}
+
+func TestLocateIP(t *testing.T) {
+	tests := []struct {
+		name        string
+		locator     service.GeoLocator
+		want        infra.IPInfo
+		wantWarning bool
+	}{
+		{name: "no locator", want: infra.IPInfo{IP: "203.0.113.7"}},
+		{name: "located", locator: &fixedGeoLocator{}, want: infra.IPInfo{IP: "203.0.113.7", City: "Lagos", Region: "Lagos", Country: "Nigeria"}},
+		{name: "lookup failed", locator: &fixedGeoLocator{err: errors.New("provider unavailable")}, want: infra.IPInfo{IP: "203.0.113.7"}, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &Application{geoLocator: tt.locator}
+			logs := captureLogs(t)
+
+			got := app.locateIP(slog.Default(), "203.0.113.7")
+			if *got != tt.want {
+				t.Errorf("locateIP() = %+v, want %+v", got, tt.want)
+			}
+			if failed := strings.Contains(logs.String(), "Failed to locate client IP"); failed != tt.wantWarning {
+				t.Errorf("failed lookup logged = %v, want %v", failed, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// withQuery calls fn with the context of a request carrying the given query string.
+func withQuery(t *testing.T, query string, fn func(c *fiber.Ctx)) {
+	t.Helper()
+
+	srv := fiber.New()
+	srv.Get("/", func(c *fiber.Ctx) error {
+		fn(c)
+		return nil
+	})
+	if _, err := srv.Test(httptest.NewRequest(fiber.MethodGet, "/?"+query, nil)); err != nil {
+		t.Fatalf("GET /?%s: %v", query, err)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int64
+	}{
+		{query: "", want: 5},
+		{query: "limit=3", want: 3},
+		{query: "limit=50", want: 50},
+		{query: "limit=51", want: 50},
+		{query: "limit=0", want: 5},
+		{query: "limit=-2", want: 5},
+		{query: "limit=ten", want: 5},
+	}
+
+	for _, tt := range tests {
+		withQuery(t, tt.query, func(c *fiber.Ctx) {
+			if got := parseLimit(c, "limit", 5, 50); got != tt.want {
+				t.Errorf("parseLimit(%q) = %d, want %d", tt.query, got, tt.want)
+			}
+		})
+	}
+}
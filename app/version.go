@@ -0,0 +1,36 @@
+package app
+
+import (
+	"runtime"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIVersion is the version of the API schema. It changes when responses change in a way clients must notice.
+const APIVersion = "1"
+
+// Version and Commit describe the running build. They are set at build time with
+// -ldflags "-X github.com/thebravebyte/numeris/app.Version=... -X github.com/thebravebyte/numeris/app.Commit=...".
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+// VersionHandler returns the build version and commit, the Go version and the API schema version.
+// Nothing else about the configuration is exposed.
+//
+// Returns:
+//   - fiber.Handler: A function that processes the request.
+func (app *Application) VersionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Version retrieved successfully",
+			"data": fiber.Map{
+				"version":     Version,
+				"commit":      Commit,
+				"go_version":  runtime.Version(),
+				"api_version": APIVersion,
+			},
+		})
+	}
+}
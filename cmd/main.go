@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,6 +17,7 @@ import (
 	infra "github.com/thebravebyte/numeris/db"
 	"github.com/thebravebyte/numeris/db/repository"
 	"github.com/thebravebyte/numeris/db/service"
+	"github.com/thebravebyte/numeris/domain"
 )
 
 // main is the entry point of the Numeris application. It sets up the server,
@@ -33,6 +37,10 @@ func main() {
 		CaseSensitive:     true,
 		AppName:           "Numeris Test App",
 		EnablePrintRoutes: true,
+		// guard against slow clients and hung connections; WriteTimeout also bounds PDF downloads
+		ReadTimeout:  envDuration("SERVER_READ_TIMEOUT", 10*time.Second),
+		WriteTimeout: envDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:  envDuration("SERVER_IDLE_TIMEOUT", 60*time.Second),
 	})
 
 	// add configurations for environment variables
@@ -44,34 +52,112 @@ func main() {
 		},
 	))
 
-	// get connected to the database
-	client := infra.Init(os.Getenv("DATABASE_URI"))
+	// get connected to the database, giving up when it is not reachable within DB_STARTUP_TIMEOUT
+	startupCtx, cancelStartup := context.WithTimeout(context.Background(), envDuration("DB_STARTUP_TIMEOUT", 2*time.Minute))
+	client, err := infra.Init(startupCtx, os.Getenv("DATABASE_URI"), poolConfig())
+	cancelStartup()
+	if err != nil {
+		slog.Error("Failed to connect to the database", "error", err)
+		os.Exit(1)
+	}
 	// deferring the disconnection of the database
 	defer infra.ShutDown(client)
 
+	// queries slower than this are logged with a warning
+	repository.SetSlowQueryThreshold(envDuration("SLOW_QUERY_THRESHOLD", 500*time.Millisecond))
+
+	if err := repository.EnsureInvoiceIndexes(client); err != nil {
+		slog.Error("Failed to create invoice indexes", "error", err)
+	}
+
+	if migrated, err := repository.MigrateActivityUserID(client); err != nil {
+		slog.Error("Failed to migrate activities", "error", err)
+	} else if migrated > 0 {
+		slog.Info("Migrated activities to the user_id field", "count", migrated)
+	}
+
 	// initialize all the services and repository
 	// initialize the user, invoice and activity repository and any serivce available
 	userRepository := repository.UserRepository{}
 	invoiceRepository := repository.InvoiceRepository{}
 	activityRepository := repository.ActivityRepository{}
 	passwordHasher := service.PasswordHasher{}
-	authenticatejwt := service.AuthenticateJWT{}
+	// tokens are signed with AUTH_TOKEN_KEY, so refuse to start without a usable key
+	authenticatejwt, err := service.NewAuthenticateJWTFromEnv()
+	if err != nil {
+		slog.Error("Invalid token signing configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// initialize the notification
+	// the email provider is selected with EMAIL_PROVIDER and only logs messages by default
+	emailSender, err := service.NewEmailSenderFromEnv()
+	if err != nil {
+		slog.Error("Invalid email provider configuration", "error", err)
+		os.Exit(1)
+	}
+	notificationService := service.NewEmailNotification(emailSender, os.Getenv("EMAIL_FROM"))
+
+	// bank account verification is optional and only enabled when a provider key is configured
+	var accountVerifier service.AccountVerifier = &service.NoopAccountVerifier{}
+	if key := os.Getenv("PAYSTACK_SECRET_KEY"); key != "" {
+		accountVerifier = service.NewPaystackAccountVerifier(key)
+	}
+
+	// currency conversion is only enabled when exchange rates are configured
+	var currencyConverter service.CurrencyConverter
+	rates, err := service.NewStaticRateConverterFromEnv()
+	if err != nil {
+		slog.Error("Invalid currency rate configuration", "error", err)
+		os.Exit(1)
+	}
+	if rates != nil {
+		currencyConverter = rates
+	}
 
 	// connect to the database and other services to the application server
 	app := app.NewApplication(
 		client,
 		passwordHasher,
-		authenticatejwt,
+		*authenticatejwt,
 		activityRepository,
 		userRepository,
 		invoiceRepository,
+		notificationService,
+		accountVerifier,
+		currencyConverter,
 	)
 
-	// initialize the notification
-	// TODO [Yusuf]
+	// sender emails are only restricted to the account email when ENFORCE_SENDER_EMAIL is set
+	enforceSender, _ := strconv.ParseBool(os.Getenv("ENFORCE_SENDER_EMAIL"))
+	app.SetSenderPolicy(domain.NewSenderPolicy(enforceSender, strings.Split(os.Getenv("SENDER_TEAM_DOMAINS"), ",")))
+
+	// logins are only refused for unverified emails when REQUIRE_EMAIL_VERIFICATION is set
+	requireVerification, _ := strconv.ParseBool(os.Getenv("REQUIRE_EMAIL_VERIFICATION"))
+	app.SetRequireEmailVerification(requireVerification)
+
+	// issued invoices can be amended for INVOICE_AMEND_GRACE_PERIOD after they are issued
+	app.SetAmendGracePeriod(envDuration("INVOICE_AMEND_GRACE_PERIOD", 15*time.Minute))
+
+	// logins are located with the provider selected by GEO_PROVIDER, or only record the IP by default
+	geoLocator, err := service.NewGeoLocatorFromEnv()
+	if err != nil {
+		slog.Error("Invalid geo provider configuration", "error", err)
+		os.Exit(1)
+	}
+	app.SetGeoLocator(geoLocator)
 
 	Router(srv, app)
 
+	// with Prefork every child runs main, so only the parent process runs the schedulers
+	if !fiber.IsChild() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go app.StartReminderScheduler(ctx, envDuration("REMINDER_INTERVAL", time.Hour))
+		go app.StartOverdueScheduler(ctx, envDuration("OVERDUE_CHECK_INTERVAL", time.Hour))
+		go app.StartScheduledSendScheduler(ctx, envDuration("SCHEDULED_SEND_INTERVAL", time.Minute))
+	}
+
 	err = srv.Listen(":8080")
 	if err != nil && err != http.ErrServerClosed {
 		panic(err)
@@ -82,3 +168,53 @@ func main() {
 		return
 	}
 }
+
+// poolConfig reads the database connection pool configuration from the environment, falling back to
+// infra.DefaultPoolConfig for unset or malformed values.
+func poolConfig() infra.PoolConfig {
+	pool := infra.PoolConfig{
+		MaxPoolSize:     envUint("DB_MAX_POOL_SIZE", infra.DefaultPoolConfig.MaxPoolSize),
+		MinPoolSize:     envUint("DB_MIN_POOL_SIZE", infra.DefaultPoolConfig.MinPoolSize),
+		MaxConnIdleTime: envDuration("DB_MAX_CONN_IDLE_TIME", infra.DefaultPoolConfig.MaxConnIdleTime),
+		ConnectTimeout:  envDuration("DB_CONNECT_TIMEOUT", infra.DefaultPoolConfig.ConnectTimeout),
+	}
+	if pool.MaxPoolSize > 0 && pool.MinPoolSize > pool.MaxPoolSize {
+		slog.Error("DB_MIN_POOL_SIZE exceeds DB_MAX_POOL_SIZE, using the maximum", "min", pool.MinPoolSize, "max", pool.MaxPoolSize)
+		pool.MinPoolSize = pool.MaxPoolSize
+	}
+	return pool
+}
+
+// envUint reads a non-negative integer from the environment variable key.
+// It falls back to the given default when the variable is unset or malformed.
+func envUint(key string, fallback uint64) uint64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		slog.Error("Invalid number in environment, using default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+
+	return number
+}
+
+// envDuration reads a duration such as "30s" or "2m" from the environment variable key.
+// It falls back to the given default when the variable is unset or malformed.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		slog.Error("Invalid duration in environment, using default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+
+	return duration
+}
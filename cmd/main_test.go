@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	infra "github.com/thebravebyte/numeris/db"
+)
+
+func TestPoolConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want infra.PoolConfig
+	}{
+		{name: "defaults", want: infra.DefaultPoolConfig},
+		{
+			name: "configured",
+			env: map[string]string{
+				"DB_MAX_POOL_SIZE":      "20",
+				"DB_MIN_POOL_SIZE":      "4",
+				"DB_MAX_CONN_IDLE_TIME": "90s",
+				"DB_CONNECT_TIMEOUT":    "7s",
+			},
+			want: infra.PoolConfig{MaxPoolSize: 20, MinPoolSize: 4, MaxConnIdleTime: 90 * time.Second, ConnectTimeout: 7 * time.Second},
+		},
+		{
+			name: "malformed values",
+			env: map[string]string{
+				"DB_MAX_POOL_SIZE":      "-1",
+				"DB_MAX_CONN_IDLE_TIME": "ninety",
+				"DB_CONNECT_TIMEOUT":    "-7s",
+			},
+			want: infra.DefaultPoolConfig,
+		},
+		{
+			name: "minimum above maximum",
+			env:  map[string]string{"DB_MAX_POOL_SIZE": "4", "DB_MIN_POOL_SIZE": "10"},
+			want: infra.PoolConfig{MaxPoolSize: 4, MinPoolSize: 4, MaxConnIdleTime: infra.DefaultPoolConfig.MaxConnIdleTime, ConnectTimeout: infra.DefaultPoolConfig.ConnectTimeout},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DB_MAX_POOL_SIZE", "DB_MIN_POOL_SIZE", "DB_MAX_CONN_IDLE_TIME", "DB_CONNECT_TIMEOUT"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := poolConfig(); got != tt.want {
+				t.Errorf("poolConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 15 * time.Minute},
+		{value: "5m", want: 5 * time.Minute},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "0s", want: 15 * time.Minute},
+		{value: "-5m", want: 15 * time.Minute},
+		{value: "fifteen", want: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("INVOICE_AMEND_GRACE_PERIOD", tt.value)
+			if got := envDuration("INVOICE_AMEND_GRACE_PERIOD", 15*time.Minute); got != tt.want {
+				t.Errorf("envDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
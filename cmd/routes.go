@@ -23,8 +23,10 @@ import (
 // sets up the HTTP routes for handling user registration, login, invoice management, and activity tracking.//
 func Router(srv *fiber.App, app *app.Application) {
 	router := srv.Use(requestid.New())
+	srv.Use(app.RequestLogger())
+	srv.Use(app.APIVersionHeader())
 	srv.Use(logger.New(logger.Config{
-		Format:        "${pid} ${locals:requestid} ${status} - ${method} ${path}​\n",
+		Format:        "${pid} ${locals:requestid} ${locals:id} ${status} - ${method} ${path}​\n",
 		TimeFormat:    time.RFC3339Nano,
 		TimeInterval:  time.Nanosecond,
 		Output:        nil,
@@ -46,7 +48,7 @@ func Router(srv *fiber.App, app *app.Application) {
 		}, ","),
 		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Content-Length, Accept-Encoding, X-CSRF-Token, X-HTTP-Method-Override, X-Requested-With",
 		AllowCredentials: false,
-		ExposeHeaders:    "Content-Length",
+		ExposeHeaders:    "Content-Length, Link, X-Total-Count, API-Version",
 		MaxAge:           int(24 * time.Hour),
 	}
 	// recall am using a wildcard format to allow external origin
@@ -57,23 +59,93 @@ func Router(srv *fiber.App, app *app.Application) {
 		return c.SendString("Welcome to the numeris API!")
 	})
 
+	router.Get("/healthz", app.HealthHandler())
+	router.Get("/api/version", app.VersionHandler())
+	router.Get("/api/currencies", app.ListCurrenciesHandler())
+
 	// let configure the endpoints with the routers http methods
 	router.Post("/api/register", app.SignUpHandler())
 	router.Post("/api/login", app.LoginHandler())
+	router.Post("/api/token/refresh", app.RefreshTokenHandler())
+	router.Post("/api/logout", app.LogoutHandler())
+	router.Get("/api/verify-email", app.VerifyEmailHandler())
+	router.Post("/api/reset-password", app.ResetPasswordHandler())
 
 	// invoices routes
 	router.Post("/api/invoice/:userID/create", app.CreateInvoiceHandler())
+	router.Post("/api/invoice/:userID/quick-create", app.QuickCreateFromLastHandler())
+	router.Post("/api/invoice/:userID/clone/:invoiceID", app.CloneInvoiceHandler())
+	router.Post("/api/invoice/:userID/bulk", app.BulkCreateInvoicesHandler())
+	router.Post("/api/invoice/:userID/import", app.ImportInvoicesHandler())
+	router.Get("/api/invoice/:userID/import/:jobID", app.GetImportJobHandler())
+	router.Get("/api/invoice/:userID/import/:jobID/failed", app.DownloadFailedImportRowsHandler())
+	router.Get("/api/invoice/:userID/export.json", app.ExportInvoicesJSONHandler())
+	router.Post("/api/invoice/:userID/import.json", app.ImportInvoicesJSONHandler())
+	router.Post("/api/invoice/:userID/payment-info/verify", app.VerifyPaymentInfoHandler())
 	router.Get("/api/invoice/:userID/get/:invoiceID", app.GetInvoiceHandler())
 	router.Get("/api/invoice/:userID/all", app.ListAllInvoiceHandler())
+	router.Get("/api/invoice/:userID/tags", app.ListInvoiceTagsHandler())
 
 	router.Put("/api/invoice/:userID/update/:invoiceID", app.UpdateUnIssuedInvoiceHandler())
+	router.Patch("/api/invoice/:userID/update/:invoiceID", app.PatchInvoiceHandler())
+	router.Patch("/api/invoice/:userID/amend/:invoiceID", app.AmendInvoiceHandler())
 	router.Delete("/api/invoice/:userID/delete/:invoiceID", app.DeleteInvoiceHandler())
+	router.Post("/api/invoice/:userID/restore/:invoiceID", app.RestoreInvoiceHandler())
+	router.Post("/api/invoice/:userID/purge/:invoiceID", app.PurgeInvoiceHandler())
+	router.Post("/api/invoice/:userID/pay/:invoiceID", app.MarkInvoicePaidHandler())
+	router.Post("/api/invoice/:userID/refund/:invoiceID", app.RefundInvoiceHandler())
+	router.Post("/api/invoice/:userID/void/:invoiceID", app.VoidInvoiceHandler())
+	router.Post("/api/invoice/:userID/cancel/:invoiceID", app.CancelInvoiceHandler())
+	router.Put("/api/invoice/:userID/schedule/:invoiceID", app.ScheduleInvoiceSendHandler())
+	router.Delete("/api/invoice/:userID/schedule/:invoiceID", app.UnscheduleInvoiceSendHandler())
+	router.Put("/api/invoice/:userID/customer/:invoiceID", app.ChangeInvoiceCustomerHandler())
+	router.Put("/api/invoice/:userID/custom-status/:invoiceID", app.SetInvoiceCustomStatusHandler())
 
 	router.Get("/api/invoice/:userID/stats", app.GetUserInvoiceStatHandler())
+	router.Post("/api/invoice/:userID/stats/recalculate", app.RecalculateSummaryHandler())
+	router.Get("/api/invoice/:userID/convert", app.PreviewConversionHandler())
+	router.Get("/api/invoice/:userID/metrics/days-to-payment", app.GetDaysToPaymentHandler())
+	router.Get("/api/invoice/:userID/metrics/revenue-comparison", app.CompareRevenueHandler())
+	router.Get("/api/invoice/:userID/action-needed", app.ActionNeededHandler())
+	router.Get("/api/invoice/:userID/outstanding", app.ListOutstandingInvoicesHandler())
+	router.Get("/api/invoice/:userID/payments", app.ListPaymentsHandler())
+	router.Get("/api/invoice/:userID/payments/export", app.ExportPaymentsHandler())
+	router.Get("/api/invoice/:userID/reports/monthly", app.MonthlyReportHandler())
+	router.Get("/api/invoice/:userID/reports/tax", app.GetTaxByJurisdictionHandler())
+	router.Get("/api/invoice/:userID/duplicates", app.PotentialDuplicatesHandler())
+	// manual reminders are limited by the handler so customers are not spammed
+	router.Post("/api/invoice/:userID/reminders/send", app.SendAllRemindersHandler())
+	router.Post("/api/invoice/:userID/reminders/:invoiceID", app.AddInvoiceReminderHandler())
 	router.Post("/api/invoice/:userID/send/:invoiceID", app.SendIssuedInvoiceToCustomer())
+	router.Post("/api/invoice/:userID/issue-ready", app.IssueAllReadyHandler())
 	router.Get("/api/invoice/:userID/download/:invoiceID", app.DownloadInvoicePDFHandler())
+	router.Post("/api/invoice/:userID/download/:invoiceID/regenerate", app.RegenerateInvoicePDFHandler())
+	router.Get("/api/invoice/:userID/receipt/:invoiceID", app.DownloadReceiptHandler())
 
 	// activity routes
 	router.Get("/api/invoice/:userID/activities", app.GetInvoiceActivitiesHandler())
+	router.Get("/api/user/:userID/activities", app.GetAllActivitiesHandler())
+	router.Get("/api/user/:userID/activities/counts", app.GetActivityCountsHandler())
+	router.Get("/api/user/:userID/dashboard", app.GetDashboardHandler())
+	router.Get("/api/user/:userID/revenue", app.GetMonthlyRevenueHandler())
+	router.Get("/api/user/:userID/top-customers", app.GetTopCustomersHandler())
+	router.Get("/api/user/:userID", app.GetUserProfileHandler())
+	router.Put("/api/user/:userID/profile", app.UpdateProfileHandler())
+	router.Put("/api/user/:userID/email-identity", app.UpdateEmailIdentityHandler())
+	router.Get("/api/user/:userID/statuses", app.GetStatusConfigHandler())
+	router.Put("/api/user/:userID/statuses", app.UpdateStatusConfigHandler())
+	router.Get("/api/user/:userID/credit-limits", app.ListCreditLimitsHandler())
+	router.Put("/api/user/:userID/credit-limits", app.SetCreditLimitHandler())
+	router.Delete("/api/user/:userID/credit-limits/:email", app.RemoveCreditLimitHandler())
+
+	// user routes
+	// a data export is expensive, so the handler lets each user request one per hour
+	router.Post("/api/user/:userID/export", app.ExportUserDataHandler())
+
+	// customer portal routes, authorised with customer tokens only
+	router.Post("/api/portal/:userID/link", app.RequestPortalLinkHandler())
+	router.Get("/api/portal/invoices", app.PortalInvoicesHandler())
+	router.Get("/api/portal/invoices/:invoiceID", app.PortalInvoiceHandler())
+	router.Get("/api/portal/statement", app.PortalStatementHandler())
 
 }
@@ -3,25 +3,87 @@ package infra
 const (
 	UserCreatedAccountActivity string = "user_created_account"
 	UserLoginActivity          string = "user_login_activity"
+	UserLogoutActivity         string = "user_logout_activity"
+	TokenRefreshedActivity     string = "token_refreshed_activity"
 	CreateInvoiceActivity      string = "create_invoice_activity"
 	ViewInvoiceActivity        string = "view_invoice_activity"
 	ListInvoicesActivity       string = "list_invoices_activity"
 	UpdateInvoiceActivity      string = "update_invoice_activity"
+	AmendInvoiceActivity       string = "amend_invoice_activity"
 
-	IssueInvoiceActivity  string = "issue_invoice_activity"
-	DeleteInvoiceActivity string = "delete_invoice_activity"
+	IssueInvoiceActivity   string = "issue_invoice_activity"
+	DeleteInvoiceActivity  string = "delete_invoice_activity"
+	RestoreInvoiceActivity string = "restore_invoice_activity"
 
-	DownloadInvoiceActivity string = "download_invoice_activity"
+	DownloadInvoiceActivity      string = "download_invoice_activity"
+	RegenerateInvoicePDFActivity string = "regenerate_invoice_pdf_activity"
 
 	UserUpdatedAccountActivity string = "user_updated_account"
+	UserExportedDataActivity   string = "user_exported_data"
+	UserVerifiedEmailActivity  string = "user_verified_email"
+	InvoicesRestoredActivity   string = "invoices_restored_activity"
 
 	InvoiceReminderActivity  string = "invoice_reminder_activity"
 	InvoicePaidActivity      string = "invoice_paid_activity"
 	InvoiceCancelledActivity string = "invoice_cancelled_activity"
+	InvoiceVoidedActivity    string = "invoice_voided_activity"
+
+	InvoiceCustomerChangedActivity string = "invoice_customer_changed_activity"
+	InvoiceCustomStatusActivity    string = "invoice_custom_status_activity"
 
 	InvoiceRefundedActivity string = "invoice_refunded_activity"
 
+	ReceiptGeneratedActivity string = "receipt_generated_activity"
+
+	CustomerPortalLinkActivity string = "customer_portal_link_activity"
+
+	SummaryRecalculatedActivity string = "summary_recalculated_activity"
+
+	ImportInvoicesActivity string = "import_invoices_activity"
+
+	InvoiceSendScheduledActivity string = "invoice_send_scheduled_activity"
+
+	ListPaymentsActivity   string = "list_payments_activity"
+	ExportPaymentsActivity string = "export_payments_activity"
+
 	// i dont need this now
 	// PaymentFailedActivity    string = "payment_failed_activity"
 	// PaymentMadeActivity        string = "payment_made_activity"
 )
+
+// KnownActivities lists every activity recorded by the application, so reports can include actions
+// that have not happened yet.
+var KnownActivities = []string{
+	UserCreatedAccountActivity,
+	UserLoginActivity,
+	UserLogoutActivity,
+	TokenRefreshedActivity,
+	CreateInvoiceActivity,
+	ViewInvoiceActivity,
+	ListInvoicesActivity,
+	UpdateInvoiceActivity,
+	AmendInvoiceActivity,
+	IssueInvoiceActivity,
+	DeleteInvoiceActivity,
+	RestoreInvoiceActivity,
+	DownloadInvoiceActivity,
+	RegenerateInvoicePDFActivity,
+	UserUpdatedAccountActivity,
+	UserExportedDataActivity,
+	UserVerifiedEmailActivity,
+	InvoicesRestoredActivity,
+	InvoiceReminderActivity,
+	InvoicePaidActivity,
+	InvoiceCancelledActivity,
+	InvoiceVoidedActivity,
+	InvoiceCustomerChangedActivity,
+	InvoiceCustomStatusActivity,
+	InvoiceRefundedActivity,
+	ReceiptGeneratedActivity,
+	CustomerPortalLinkActivity,
+	SummaryRecalculatedActivity,
+	ImportInvoicesActivity,
+	InvoiceSendScheduledActivity,
+	ListPaymentsActivity,
+	ExportPaymentsActivity,
+}
@@ -11,50 +11,63 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-// Database connection pool configuration
-var (
-	MaxIdleTime  time.Duration = 2 * time.Minute
-	MaxLifeTime  time.Duration = 5 * time.Minute
-	MaxOpenConns uint64        = 5
-	MaxIdleConns uint64        = 5
-)
+// PoolConfig configures the connection pool of the database client.
+type PoolConfig struct {
+	// MaxPoolSize is the maximum number of open connections to each server.
+	MaxPoolSize uint64
+	// MinPoolSize is the number of connections kept open to each server, even when idle.
+	MinPoolSize uint64
+	// MaxConnIdleTime is how long a connection may stay idle in the pool before it is closed.
+	MaxConnIdleTime time.Duration
+	// ConnectTimeout is how long opening a new connection may take.
+	ConnectTimeout time.Duration
+}
+
+// DefaultPoolConfig is the connection pool configuration used unless it is overridden.
+var DefaultPoolConfig = PoolConfig{
+	MaxPoolSize:     5,
+	MinPoolSize:     0,
+	MaxConnIdleTime: 2 * time.Minute,
+	ConnectTimeout:  30 * time.Second,
+}
 
-// InitDB initializes the database connection
 // Init initializes the database connection.
 // It attempts to connect to the MongoDB database using the provided database URI.
 // If the connection fails, it logs the error and retries up to 10 times with a 5-second delay between attempts.
-// If all attempts fail, it panics with the last error.
-// If the connection is successful, it returns the MongoDB client connection.
+// Retrying stops as soon as ctx is cancelled, so callers can bound the time spent starting up.
 //
 // Parameters:
+// - ctx: The context bounding every connection attempt and the delays between them.
 // - databaseURI: A string representing the URI of the MongoDB database.
+// - pool: The connection pool configuration of the client.
 //
 // Return:
 // - A pointer to the MongoDB client connection if the connection is successful.
-// - An error if the connection fails.
-func Init(databaseURI string) *mongo.Client {
+// - An error with the last connection error if every attempt fails or ctx is cancelled.
+func Init(ctx context.Context, databaseURI string, pool PoolConfig) (*mongo.Client, error) {
 	// attempting to connect to the database with a maximum of 10 trials
 	var trial int
 
 	for {
-		db, err := Connect(databaseURI)
-		if err != nil {
-			trial++
-
-			slog.Error("cannot connect to database", "error", err, "connect attempt: ", trial)
+		db, err := Connect(ctx, databaseURI, pool)
+		if err == nil {
+			// if the application successfully connects to the database, return the database client
+			return db, nil
+		}
 
-			// if the application tries to connect to the database 10 times and fails, log the error and panic
-			if trial == 10 {
-				slog.Error("cannot connect to database", "count attempt", trial)
-				panic(err)
-			}
+		trial++
+		slog.Error("cannot connect to database", "error", err, "connect attempt: ", trial)
 
-			time.Sleep(5 * time.Second)
-			continue
+		// if the application tries to connect to the database 10 times and fails, give up
+		if trial == 10 {
+			return nil, fmt.Errorf("cannot connect to database after %d attempts: %w", trial, err)
 		}
 
-		// if the application successfully connects to the database, return the database client
-		return db
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("cannot connect to database after %d attempts: %w: %v", trial, ctx.Err(), err)
+		case <-time.After(5 * time.Second):
+		}
 	}
 }
 
@@ -65,24 +78,20 @@ func Init(databaseURI string) *mongo.Client {
 // If an error occurs during the connection process, it returns nil and an error message.
 //
 // Parameters:
+// - ctx: The context of the connection; the attempt is also bounded by a 30-second timeout.
 // - databaseURI: A string representing the URI of the MongoDB database.
+// - pool: The connection pool configuration of the client.
 //
 // Return:
 // - A pointer to the MongoDB client connection if the connection is successful.
 // - An error if the connection fails.
-func Connect(databaseURI string) (*mongo.Client, error) {
+func Connect(ctx context.Context, databaseURI string, pool PoolConfig) (*mongo.Client, error) {
 	// set a timeout for the database connection
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	// // configure the database pool
-	// opts := &options.ClientOptions{
-	// 	ConnectTimeout: &MaxLifeTime,
-	// 	MaxPoolSize:    &MaxOpenConns,
-	// }
-
 	// try to connect to the database
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(databaseURI))
+	client, err := mongo.Connect(ctx, clientOptions(databaseURI, pool))
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to database: %v", err)
 	}
@@ -91,26 +100,35 @@ func Connect(databaseURI string) (*mongo.Client, error) {
 	err = client.Ping(ctx, readpref.Primary())
 	if err != nil {
 		slog.Error("unable to ping database", "Error:", err)
+		// release the client, a retry connects a new one
+		_ = client.Disconnect(context.Background())
 		return nil, fmt.Errorf("unable to ping database: %v", err)
 	}
 
 	return client, nil
 }
 
-// ShutDown gracefully closes the MongoDB client connection.
-// This function uses a deferred function to ensure that the client connection is closed
-// even if an error occurs during the execution of the main function.
+// clientOptions returns the options of a client connecting to databaseURI with the pool configuration.
+// Settings given in the URI itself take precedence over the pool configuration.
+func clientOptions(databaseURI string, pool PoolConfig) *options.ClientOptions {
+	return options.Client().
+		SetMaxPoolSize(pool.MaxPoolSize).
+		SetMinPoolSize(pool.MinPoolSize).
+		SetMaxConnIdleTime(pool.MaxConnIdleTime).
+		SetConnectTimeout(pool.ConnectTimeout).
+		ApplyURI(databaseURI)
+}
+
+// ShutDown gracefully closes the MongoDB client connection, waiting up to 10 seconds for operations in
+// progress. Callers defer it right after connecting, so the connection is closed when they return.
 //
 // Parameters:
 // - client: A pointer to the MongoDB client connection. This parameter is required and cannot be nil.
-// //+
-// Return:
-// This function does not return any value.//
 func ShutDown(client *mongo.Client) {
-	defer func(ctx context.Context, client *mongo.Client) {
-		if err := client.Disconnect(context.TODO()); err != nil {
-			slog.Error("Unable to disconnect from database", "Error", err)
-			panic(err)
-		}
-	}(context.TODO(), client)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := client.Disconnect(ctx); err != nil {
+		slog.Error("Unable to disconnect from database", "Error", err)
+	}
 }
@@ -0,0 +1,107 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestClientOptions(t *testing.T) {
+	pool := PoolConfig{MaxPoolSize: 20, MinPoolSize: 4, MaxConnIdleTime: 90 * time.Second, ConnectTimeout: 7 * time.Second}
+
+	opts := clientOptions("mongodb://127.0.0.1:27017", pool)
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if opts.MaxPoolSize == nil || *opts.MaxPoolSize != 20 {
+		t.Errorf("MaxPoolSize = %v, want 20", opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize == nil || *opts.MinPoolSize != 4 {
+		t.Errorf("MinPoolSize = %v, want 4", opts.MinPoolSize)
+	}
+	if opts.MaxConnIdleTime == nil || *opts.MaxConnIdleTime != 90*time.Second {
+		t.Errorf("MaxConnIdleTime = %v, want 1m30s", opts.MaxConnIdleTime)
+	}
+	if opts.ConnectTimeout == nil || *opts.ConnectTimeout != 7*time.Second {
+		t.Errorf("ConnectTimeout = %v, want 7s", opts.ConnectTimeout)
+	}
+
+	// settings in the URI win over the pool configuration
+	opts = clientOptions("mongodb://127.0.0.1:27017/?maxPoolSize=50", pool)
+	if opts.MaxPoolSize == nil || *opts.MaxPoolSize != 50 {
+		t.Errorf("MaxPoolSize with the URI setting = %v, want 50", opts.MaxPoolSize)
+	}
+}
+
+// unreachableURI names a server no one listens on, failing each connection attempt after 100ms.
+const unreachableURI = "mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=100&connectTimeoutMS=100"
+
+func TestInitStopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	// the first attempt fails quickly, so the cancellation lands in the delay before the second
+	time.AfterFunc(500*time.Millisecond, cancel)
+
+	start := time.Now()
+	client, err := Init(ctx, unreachableURI, DefaultPoolConfig)
+	if client != nil {
+		t.Error("Init() returned a client for an unreachable server")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Init() error = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Init() returned after %v, want it to stop at the cancellation", elapsed)
+	}
+}
+
+func TestConnectRespectsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := Connect(ctx, "mongodb://127.0.0.1:1", DefaultPoolConfig); err == nil {
+		t.Fatal("Connect() to an unreachable server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Connect() returned after %v, want it to stop at the deadline", elapsed)
+	}
+}
+
+func TestInitConnects(t *testing.T) {
+	uri := os.Getenv("NUMERIS_TEST_DATABASE_URI")
+	if uri == "" {
+		t.Skip("NUMERIS_TEST_DATABASE_URI is not set, skipping database test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := Init(ctx, uri, DefaultPoolConfig)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer ShutDown(client)
+
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+}
+
+func TestShutDownDisconnects(t *testing.T) {
+	// connecting does not reach the server, so no database is needed
+	client, err := mongo.Connect(context.Background(), clientOptions(unreachableURI, DefaultPoolConfig))
+	if err != nil {
+		t.Fatalf("mongo.Connect: %v", err)
+	}
+
+	ShutDown(client)
+
+	// a second disconnect finds the client already disconnected by ShutDown
+	if err := client.Disconnect(context.Background()); !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("Disconnect() after ShutDown: error = %v, want %v", err, mongo.ErrClientDisconnected)
+	}
+}
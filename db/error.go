@@ -9,4 +9,17 @@ var (
 
 	ErrUnMatchedPassword   = errors.New("invalid input password")
 	ErrInvalidLoginDetails = errors.New("invalid login details")
+
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	ErrNoDataFound     = errors.New("no data found")
+
+	ErrInvoiceStatusConflict  = errors.New("invoice status does not allow this action")
+	ErrDuplicateInvoiceNumber = errors.New("invoice number is already used")
+
+	ErrDocumentNotFound = errors.New("stored document not found")
+
+	ErrImportJobConflict = errors.New("import job is already being processed")
+
+	ErrInvalidVerificationToken = errors.New("invalid email verification token")
+	ErrVerificationTokenExpired = errors.New("email verification token has expired")
 )
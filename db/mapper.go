@@ -5,12 +5,19 @@ import "github.com/thebravebyte/numeris/domain"
 // User : Master struct model for user data to use in the application
 func UserFromDB(user User) *domain.User {
 	return &domain.User{
-		ID:          user.ID,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		Email:       user.Email,
-		Password:    user.Password,
-		PhoneNumber: user.PhoneNumber,
-		
+		ID:              user.ID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		Password:        user.Password,
+		PhoneNumber:     user.PhoneNumber,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		InvoiceSummary:  user.InvoiceSummary,
+		DefaultCurrency: user.DefaultCurrency,
+		EmailIdentity:   user.EmailIdentity,
+		CreditLimits:    user.CreditLimits,
+		EmailVerified:   user.EmailVerified,
+		StatusConfig:    user.StatusConfig,
 	}
 }
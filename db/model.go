@@ -4,21 +4,28 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/thebravebyte/numeris/domain"
 )
 
 // User: user details and informations
 type User struct {
-	ID             string         `json:"id" bson:"_id,omitempty" validate:"required"`
-	FirstName      string         `json:"first_name" bson:"first_name" validate:"required"`
-	LastName       string         `json:"last_name" bson:"last_name" validate:"required"`
-	Email          string         `json:"email" bson:"email" validate:"required,email"`
-	Password       string         `json:"password" bson:"password" validate:"required"`
-	PhoneNumber    string         `json:"phone_number" bson:"phone_number" validate:"required"`
-	CreatedAt      time.Time      `json:"created_at" bson:"created_at" validate:"required"`
-	UpdatedAt      time.Time      `json:"updated_at" bson:"updated_at" validate:"required"`
-	InvoiceSummary InvoiceSummary `json:"invoice_summary" bson:"invoice_summary"`
-	Invoices       []Invoice      `json:"invoices" bson:"invoices"`
-	Token          string         `json:"token,omitempty" bson:"token,omitempty"`
+	ID              string                `json:"id" bson:"_id,omitempty" validate:"required"`
+	FirstName       string                `json:"first_name" bson:"first_name" validate:"required"`
+	LastName        string                `json:"last_name" bson:"last_name" validate:"required"`
+	Email           string                `json:"email" bson:"email" validate:"required,email"`
+	Password        string                `json:"-" bson:"password" validate:"required"`
+	PhoneNumber     string                `json:"phone_number" bson:"phone_number" validate:"required"`
+	CreatedAt       time.Time             `json:"created_at" bson:"created_at" validate:"required"`
+	UpdatedAt       time.Time             `json:"updated_at" bson:"updated_at" validate:"required"`
+	InvoiceSummary  domain.InvoiceSummary `json:"invoice_summary" bson:"invoice_summary"`
+	Invoices        []Invoice             `json:"invoices" bson:"invoices"`
+	DefaultCurrency string                `json:"default_currency,omitempty" bson:"default_currency,omitempty"`
+	EmailIdentity   domain.EmailIdentity  `json:"email_identity" bson:"email_identity,omitempty"`
+	CreditLimits    []domain.CreditLimit  `json:"credit_limits,omitempty" bson:"credit_limits,omitempty"`
+	Token           string                `json:"-" bson:"token,omitempty"`
+	EmailVerified   bool                  `json:"email_verified" bson:"email_verified"`
+	StatusConfig    domain.StatusConfig   `json:"status_config" bson:"status_config,omitempty"`
 }
 
 // Invoice: invoice information for every user activities
@@ -34,7 +41,7 @@ type Invoice struct {
 	Discount        float64            `json:"discount,omitempty" bson:"discount,omitempty"`
 	TotalAmountDue  float64            `json:"total_amount_due" bson:"total_amount_due" validate:"required"`
 	PaymentInfo     PaymentInformation `json:"payment_info" bson:"payment_info" validate:"required"`
-	Activity        []Activity         `json:"activity_log,omitempty" bson:"activity_log,omitempty"`
+	Activity        []domain.Activity  `json:"activity_log,omitempty" bson:"activity_log,omitempty"`
 	Customer        CustomerDetails    `json:"customer" bson:"customer" validate:"required"`
 	Sender          SenderDetails      `json:"sender" bson:"sender" validate:"required"`
 	Notes           string             `json:"notes,omitempty" bson:"notes,omitempty"`
@@ -57,14 +64,6 @@ type PaymentInformation struct {
 	BankName      string `json:"bank_name" bson:"bank_name" validate:"required"`
 }
 
-// Activity this describes the activities of the user invoices created, issues and the likes
-type Activity struct {
-	Actor     string    `json:"actor" bson:"actor" validate:"required"`
-	Action    string    `json:"action" bson:"action" validate:"required"`
-	Timestamp time.Time `json:"timestamp" bson:"timestamp" validate:"required"`
-	Metadata  string    `json:"metadata,omitempty" bson:"meta_data,omitempty"`
-}
-
 // CustomerDetails details of a customer an invoices is created for
 type CustomerDetails struct {
 	Name    string `json:"name" bson:"name" validate:"required"`
@@ -87,14 +86,6 @@ type InvoiceReminder struct {
 	Message           string `json:"message" bson:"message" validate:"required"`
 }
 
-// InvoiceSummary this is the overall summary of the invoice for the users
-type InvoiceSummary struct {
-	TotalPaid    float64 `json:"total_paid" bson:"total_paid"`
-	TotalOverdue float64 `json:"total_overdue" bson:"total_overdue"`
-	TotalDraft   float64 `json:"total_draft" bson:"total_draft"`
-	TotalUnpaid  float64 `json:"total_unpaid" bson:"total_unpaid"`
-}
-
 type EmailTemplate struct {
 	UUID     string `json:"uuid" bson:"uuid" validate:"required"`
 	Subject  string `json:"subject" bson:"subject" validate:"required"`
@@ -102,6 +93,17 @@ type EmailTemplate struct {
 	Receiver string `json:"receiver" bson:"receiver" validate:"required"`
 	Sender   string `json:"sender" bson:"sender" validate:"required"`
 	Template string `json:"template,omitempty" bson:"template,omitempty"`
+	FromName string `json:"from_name,omitempty" bson:"from_name,omitempty"`
+	ReplyTo  string `json:"reply_to,omitempty" bson:"reply_to,omitempty"`
+
+	Attachments []EmailAttachment `json:"attachments,omitempty" bson:"attachments,omitempty"`
+}
+
+// EmailAttachment is a file sent along with an email message.
+type EmailAttachment struct {
+	FileName    string `json:"file_name" bson:"file_name"`
+	ContentType string `json:"content_type" bson:"content_type"`
+	Content     []byte `json:"content" bson:"content"`
 }
 
 // AuthAccessToken type struct which is used to create/generate JWT tokens.
@@ -111,6 +113,16 @@ type AuthAccessToken struct {
 	jwt.RegisteredClaims
 }
 
+// CustomerAccessToken is the claim set of a customer portal token. It only
+// identifies a customer of a given user and never grants access to the user's account.
+type CustomerAccessToken struct {
+	UserUUID      string `json:"uid"`
+	CustomerEmail string `json:"customer_email"`
+	Scope         string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+// IPInfo holds the IP address of a client and, when it could be resolved, its location.
 type IPInfo struct {
 	IP        string `json:"ip" bson:"ip" validate:"required"`
 	City      string `json:"city" bson:"city,omitempty"`
@@ -1,6 +1,10 @@
 package repository
 
-import "go.mongodb.org/mongo-driver/mongo"
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
 
 // UserCol Setting up the database for the user data collection
 func UserData(db *mongo.Client, collectionName string) *mongo.Collection {
@@ -11,6 +15,17 @@ func InvoiceData(db *mongo.Client, collectionName string) *mongo.Collection {
 	return db.Database("numeris_book").Collection(collectionName)
 }
 
+// RecordActivityData returns the collection user activities are recorded in
 func RecordActivityData(db *mongo.Client, collectionName string) *mongo.Collection {
-	return db.Database("numeris_book").Collection("activity")
+	return db.Database("numeris_book").Collection(collectionName)
+}
+
+// InvoiceDocumentBucket is the GridFS bucket holding the stored PDF of issued invoices
+func InvoiceDocumentBucket(db *mongo.Client) (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(db.Database("numeris_book"), options.GridFSBucket().SetName("invoice_pdf"))
+}
+
+// ImportJobData returns the collection invoice import jobs are tracked in
+func ImportJobData(db *mongo.Client, collectionName string) *mongo.Collection {
+	return db.Database("numeris_book").Collection(collectionName)
 }
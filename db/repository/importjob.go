@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+const importJobCollection = "import_job"
+
+// CreateImportJob stores a new import job.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - job: The import job to store.
+//
+// Returns:
+// - An error wrapping infra.ErrImportJobConflict if the user already has a job with the same idempotency key,
+// or any database error.
+func (i *InvoiceRepository) CreateImportJob(db *mongo.Client, job *domain.ImportJob) error {
+	defer logSlowQuery("CreateImportJob", job.UserID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	if _, err := ImportJobData(db, importJobCollection).InsertOne(ctx, job); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%w: idempotency key %q is already used", infra.ErrImportJobConflict, job.IdempotencyKey)
+		}
+		return fmt.Errorf("error creating import job: %v", err)
+	}
+	return nil
+}
+
+// FindImportJob retrieves the import job of a user by its idempotency key.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who started the import.
+// - idempotencyKey: The idempotency key the import was submitted with.
+//
+// Returns:
+// - A pointer to the domain.ImportJob found.
+// - An error wrapping infra.ErrNoDataFound if there is no such job, or any database error.
+func (i *InvoiceRepository) FindImportJob(db *mongo.Client, userID, idempotencyKey string) (*domain.ImportJob, error) {
+	return findImportJob(db, bson.M{"user_id": userID, "idempotency_key": idempotencyKey})
+}
+
+// FindImportJobByID retrieves an import job of a user by its ID.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who started the import.
+// - jobID: The unique identifier of the import job.
+//
+// Returns:
+// - A pointer to the domain.ImportJob found.
+// - An error wrapping infra.ErrNoDataFound if there is no such job, or any database error.
+func (i *InvoiceRepository) FindImportJobByID(db *mongo.Client, userID, jobID string) (*domain.ImportJob, error) {
+	return findImportJob(db, bson.M{"_id": jobID, "user_id": userID})
+}
+
+func findImportJob(db *mongo.Client, filter bson.M) (*domain.ImportJob, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	var job domain.ImportJob
+	if err := ImportJobData(db, importJobCollection).FindOne(ctx, filter).Decode(&job); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: import job not found", infra.ErrNoDataFound)
+		}
+		return nil, fmt.Errorf("error finding import job: %v", err)
+	}
+	return &job, nil
+}
+
+// RecordImportRow saves the outcome of the row just added to the job with domain.ImportJob.Record. The update
+// only applies while the stored job has not moved past the previous row, so two requests resuming the same
+// import cannot both record it.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - job: The import job, with the outcome of its last processed row recorded.
+//
+// Returns:
+// - An error wrapping infra.ErrImportJobConflict if another request recorded the row first, or any database error.
+func (i *InvoiceRepository) RecordImportRow(db *mongo.Client, job *domain.ImportJob) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	result := job.Rows[len(job.Rows)-1]
+	push := bson.M{"rows": result}
+	if result.Status == domain.ImportRowFailed {
+		push["failed_records"] = job.FailedRecords[len(job.FailedRecords)-1]
+	}
+
+	filter := bson.M{"_id": job.ID, "processed_rows": job.ProcessedRows - 1}
+	update := bson.M{
+		"$set": bson.M{
+			"status":         job.Status,
+			"processed_rows": job.ProcessedRows,
+			"created_rows":   job.CreatedRows,
+			"skipped_rows":   job.SkippedRows,
+			"failed_rows":    job.FailedRows,
+			"updated_at":     job.UpdatedAt,
+		},
+		"$push": push,
+	}
+
+	updated, err := ImportJobData(db, importJobCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("error recording import row: %v", err)
+	}
+	if updated.MatchedCount == 0 {
+		return fmt.Errorf("%w: row %d was already recorded", infra.ErrImportJobConflict, result.Row)
+	}
+	return nil
+}
+
+// InvoiceNumberExists reports whether a user already has an invoice with the given number.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are searched.
+// - invoiceNumber: The invoice number to look for.
+//
+// Returns:
+// - true if an invoice of the user has the number.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) InvoiceNumberExists(db *mongo.Client, userID, invoiceNumber string) (bool, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	count, err := UserData(db, "user").CountDocuments(ctx, bson.M{"_id": userID, "invoices.invoice_number": invoiceNumber})
+	if err != nil {
+		return false, fmt.Errorf("error checking invoice number: %v", err)
+	}
+	return count > 0, nil
+}
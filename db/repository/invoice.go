@@ -1,23 +1,33 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	infra "github.com/thebravebyte/numeris/db"
 	"github.com/thebravebyte/numeris/domain"
 )
 
 type InvoiceRepository struct{}
 
 // AddNewInvoice adds a new invoice to the user's document and synchronizes it with the invoices collection.
-// It uses a MongoDB transaction to ensure data consistency and integrity.
+// It uses a MongoDB transaction to ensure data consistency and integrity. The invoice is only pushed when none
+// of the user's invoices has the same invoice number, so two concurrent requests cannot both use it.
+// An invoice without a number gets the next one from the user's invoice counter, which is incremented in the
+// same transaction, so an aborted insert does not leave a gap; numbers already used manually are skipped.
 //
 // Parameters:
 // - db: A pointer to the MongoDB client.
@@ -25,8 +35,11 @@ type InvoiceRepository struct{}
 // - invoice: A pointer to the Invoice struct representing the new invoice to be added.
 //
 // Returns:
-// - An error if any error occurs during the process, otherwise nil.
+// - An error wrapping infra.ErrDuplicateInvoiceNumber if the user already has an invoice with the same number.
+// - An error if any other error occurs during the process, otherwise nil.
 func (i *InvoiceRepository) AddNewInvoice(db *mongo.Client, userID string, invoice *domain.Invoice) error {
+	defer logSlowQuery("AddNewInvoice", userID, time.Now())
+
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelCtx()
 
@@ -37,34 +50,164 @@ func (i *InvoiceRepository) AddNewInvoice(db *mongo.Client, userID string, invoi
 	}
 	defer session.EndSession(ctx)
 
+	generateNumber := invoice.InvoiceNumber == ""
+
 	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
-		filter := bson.D{{Key: "_id", Value: userID}}
-		update := bson.D{{Key: "$push", Value: bson.D{{Key: "invoices", Value: invoice}}}}
+		return nil, insertInvoice(sessCtx, db, userID, invoice, generateNumber)
+	}
 
-		_, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
-		if err != nil {
-			panic("error while inserting new invoice")
+	// execute the transaction
+	_, err = session.WithTransaction(ctx, callback)
+	if err != nil {
+		if generateNumber {
+			invoice.InvoiceNumber = ""
 		}
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+	slog.Info("Invoice created and synchronized successfully.")
 
-		// insert into the invoices collection
-		_, err = InvoiceData(db, "invoice").InsertOne(sessCtx, invoice)
-		if err != nil {
-			return nil, fmt.Errorf("error inserting into invoices: %v", err)
+	return nil
+}
+
+// AddNewInvoices adds a batch of invoices to the user's document in a single transaction, so either every
+// invoice is added or none is. Each invoice is added the same way as with AddNewInvoice.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client.
+// - userID: The unique identifier of the user.
+// - invoices: The invoices to add, in order.
+//
+// Returns:
+// - An error wrapping infra.ErrDuplicateInvoiceNumber if an invoice number is already used, naming the
+// position of the invoice in the batch.
+// - An error if any other error occurs during the process, otherwise nil.
+func (i *InvoiceRepository) AddNewInvoices(db *mongo.Client, userID string, invoices []*domain.Invoice) error {
+	defer logSlowQuery("AddNewInvoices", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelCtx()
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	generateNumber := make([]bool, len(invoices))
+	for idx, invoice := range invoices {
+		generateNumber[idx] = invoice.InvoiceNumber == ""
+	}
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		for idx, invoice := range invoices {
+			if err := insertInvoice(sessCtx, db, userID, invoice, generateNumber[idx]); err != nil {
+				return nil, fmt.Errorf("invoice %d: %w", idx, err)
+			}
 		}
 		return nil, nil
-
 	}
 
-	// execute the transaction
 	_, err = session.WithTransaction(ctx, callback)
 	if err != nil {
-		return fmt.Errorf("transaction failed: %v", err)
+		// a retried or aborted transaction leaves generated numbers behind, clear them
+		for idx, invoice := range invoices {
+			if generateNumber[idx] {
+				invoice.InvoiceNumber = ""
+			}
+		}
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+	slog.Info("Invoices created and synchronized successfully.", "count", len(invoices))
+
+	return nil
+}
+
+// insertInvoice pushes an invoice to the user's document and inserts it into the invoices collection, within
+// the transaction of sessCtx. When generateNumber is set the invoice gets the next number of the user's
+// invoice counter, skipping numbers already used manually.
+func insertInvoice(sessCtx mongo.SessionContext, db *mongo.Client, userID string, invoice *domain.Invoice, generateNumber bool) error {
+	// WithTransaction retries transient errors, such as an unreachable server, for up to two minutes; the
+	// caller's timeout ends the retries instead
+	if err := sessCtx.Err(); err != nil {
+		return err
+	}
+
+	for {
+		if generateNumber {
+			number, err := nextInvoiceNumber(sessCtx, db, userID)
+			if err != nil {
+				return err
+			}
+			invoice.InvoiceNumber = number
+		}
+
+		filter := bson.D{
+			{Key: "_id", Value: userID},
+			{Key: "invoices.invoice_number", Value: bson.D{{Key: "$ne", Value: invoice.InvoiceNumber}}},
+		}
+		update := bson.D{{Key: "$push", Value: bson.D{{Key: "invoices", Value: invoice}}}}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
+		if err != nil {
+			return fmt.Errorf("error adding invoice to user document: %w", err)
+		}
+		if result.MatchedCount > 0 {
+			break
+		}
+		if generateNumber {
+			// the generated number was already used manually, take the next one
+			continue
+		}
+
+		// tell a missing user apart from one that already used the invoice number
+		count, err := UserData(db, "user").CountDocuments(sessCtx, bson.D{{Key: "_id", Value: userID}})
+		if err != nil {
+			return fmt.Errorf("error finding user: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("%w:%q", infra.ErrUserNotFound, userID)
+		}
+		return fmt.Errorf("%w: %q", infra.ErrDuplicateInvoiceNumber, invoice.InvoiceNumber)
 	}
-	slog.Info("Invoice created and synchronized successfully.")
 
+	// insert into the invoices collection
+	if _, err := InvoiceData(db, "invoice").InsertOne(sessCtx, invoice); err != nil {
+		return fmt.Errorf("error inserting into invoices: %w", err)
+	}
 	return nil
 }
 
+// nextInvoiceNumber increments the invoice counter of a user and returns the invoice number it gives.
+// Concurrent increments of the same counter conflict, so the transaction of one of them is retried.
+//
+// Parameters:
+// - sessCtx: The session context of the transaction the counter is incremented in.
+// - db: A pointer to the MongoDB client.
+// - userID: The unique identifier of the user.
+//
+// Returns:
+// - The generated invoice number.
+// - An error wrapping infra.ErrUserNotFound if the user does not exist, or any database error.
+func nextInvoiceNumber(sessCtx mongo.SessionContext, db *mongo.Client, userID string) (string, error) {
+	var user struct {
+		InvoiceCounter int64 `bson:"invoice_counter"`
+	}
+	err := UserData(db, "user").FindOneAndUpdate(sessCtx,
+		bson.D{{Key: "_id", Value: userID}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "invoice_counter", Value: 1}}}},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(bson.D{{Key: "invoice_counter", Value: 1}}),
+	).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", fmt.Errorf("%w:%q", infra.ErrUserNotFound, userID)
+		}
+		return "", fmt.Errorf("error incrementing invoice counter: %w", err)
+	}
+	return domain.SequentialInvoiceNumber(user.InvoiceCounter), nil
+}
+
 // FindUserInvoice retrieves a specific invoice for a given user from the database.
 // “
 // Parameters:
@@ -79,7 +222,10 @@ func (i *InvoiceRepository) FindUserInvoiceByID(db *mongo.Client, userID, invoic
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelCtx()
 
-	filter := bson.M{"_id": userID, "invoices.invoice_id": invoiceID}
+	filter := bson.M{
+		"_id":      userID,
+		"invoices": bson.M{"$elemMatch": bson.M{"invoice_id": invoiceID, "deleted_at": notDeleted}},
+	}
 	projection := bson.M{"invoices.$": 1}
 
 	var result struct {
@@ -90,7 +236,7 @@ func (i *InvoiceRepository) FindUserInvoiceByID(db *mongo.Client, userID, invoic
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("invoice not found for userID %s and invoiceID %s", userID, invoiceID)
+			return nil, fmt.Errorf("%w: no invoice %s for userID %s", infra.ErrInvoiceNotFound, invoiceID, userID)
 		}
 		return nil, fmt.Errorf("error finding invoice: %v", err)
 	}
@@ -98,11 +244,28 @@ func (i *InvoiceRepository) FindUserInvoiceByID(db *mongo.Client, userID, invoic
 	if len(result.Invoices) > 0 {
 		return &result.Invoices[0], nil
 	}
-	return nil, fmt.Errorf("invoice not found in user document")
+	return nil, fmt.Errorf("%w: invoice missing from user document", infra.ErrInvoiceNotFound)
 }
 
-// UpdatePreviousInvoice updates the details of a previous invoice for a given user.
+// UpdateInvoiceBeforeDueDate replaces a draft or pending invoice of a user with updatedInvoice, provided its
+// issue date and due date have not passed. The invoice keeps its ID and creation time, and an updated invoice
+// without a status keeps the stored one.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoiceID: The unique identifier of the invoice to update.
+// - updatedInvoice: The new details of the invoice.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceNotFound if the user has no such invoice.
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice is no longer a draft or pending, or its dates have passed.
+// - An error if any other database error occurs, otherwise nil.
 func (i *InvoiceRepository) UpdateInvoiceBeforeDueDate(db *mongo.Client, userID string, invoiceID string, updatedInvoice *domain.Invoice) error {
+	defer logSlowQuery("UpdateInvoiceBeforeDueDate", userID, time.Now())
+
+	editableStatus := []string{"draft", "pending"}
+
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelCtx()
 
@@ -122,12 +285,12 @@ func (i *InvoiceRepository) UpdateInvoiceBeforeDueDate(db *mongo.Client, userID
 		currentInvoice, err := i.FindUserInvoiceByID(db, userID, invoiceID)
 		if err != nil {
 			session.AbortTransaction(sessCtx)
-			return fmt.Errorf("error fetching current invoice: %v", err)
+			return fmt.Errorf("error fetching current invoice: %w", err)
 		}
 
-		if currentInvoice.Status != "pending" {
+		if !slices.Contains(editableStatus, currentInvoice.Status) {
 			session.AbortTransaction(sessCtx)
-			return fmt.Errorf("invoice cannot be updated: it is paid or drafted")
+			return fmt.Errorf("%w: invoice %s is %s", infra.ErrInvoiceStatusConflict, invoiceID, currentInvoice.Status)
 		}
 
 		// check if the invoice can be updated
@@ -135,25 +298,40 @@ func (i *InvoiceRepository) UpdateInvoiceBeforeDueDate(db *mongo.Client, userID
 		// Parse IssueDate
 		issueDate, err := time.Parse("2006-01-02", currentInvoice.IssueDate)
 		if err != nil {
-			log.Fatalf("invalid issue date format: %v", err)
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("invalid issue date format on invoice %s: %v", invoiceID, err)
 		}
 
 		// parse DueDate
 		dueDate, err := time.Parse("2006-01-02", currentInvoice.DueDate)
 		if err != nil {
-			log.Fatalf("invalid due date format: %v", err)
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("invalid due date format on invoice %s: %v", invoiceID, err)
 		}
 
 		if issueDate.Before(now) || now.After(dueDate) {
 			session.AbortTransaction(sessCtx)
-			return fmt.Errorf("invoice cannot be updated: it has been issued or the due date has passed")
+			return fmt.Errorf("%w: invoice %s has been issued or its due date has passed", infra.ErrInvoiceStatusConflict, invoiceID)
+		}
+
+		updatedInvoice.InvoiceID = currentInvoice.InvoiceID
+		updatedInvoice.CreatedAt = currentInvoice.CreatedAt
+		if updatedInvoice.Status == "" {
+			updatedInvoice.Status = currentInvoice.Status
 		}
 
-		// Proceed with the update
-		filter := bson.M{"_id": userID, "invoices.invoice_id": invoiceID}
+		// Proceed with the update, unless the invoice was issued in the meantime
+		filter := bson.M{
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoiceID,
+				"deleted_at": notDeleted,
+				"status":     bson.M{"$in": editableStatus},
+			}},
+		}
 		update := bson.M{"$set": bson.M{"invoices.$": updatedInvoice}}
 
-		result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
 		if err != nil {
 			session.AbortTransaction(sessCtx)
 			return fmt.Errorf("error updating invoice in user collection: %v", err)
@@ -161,13 +339,13 @@ func (i *InvoiceRepository) UpdateInvoiceBeforeDueDate(db *mongo.Client, userID
 
 		if result.MatchedCount == 0 {
 			session.AbortTransaction(sessCtx)
-			return fmt.Errorf("no invoice found with ID %s for user %s", invoiceID, userID)
+			return fmt.Errorf("%w: invoice %s is no longer a draft or pending", infra.ErrInvoiceStatusConflict, invoiceID)
 		}
 
 		// Update the external invoice collection
 		filter = bson.M{"invoice_id": invoiceID}
 		update = bson.M{"$set": updatedInvoice}
-		result, err = InvoiceData(db, "invoice").UpdateOne(ctx, filter, update)
+		result, err = InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, update)
 
 		if err != nil {
 			session.AbortTransaction(sessCtx)
@@ -200,6 +378,8 @@ func (i *InvoiceRepository) UpdateInvoiceBeforeDueDate(db *mongo.Client, userID
 // - db: A pointer to the MongoDB client used for database operations.
 // - userID: The unique identifier of the user whose invoices are being searched.
 //
+// Soft-deleted invoices are left out.
+//
 // Returns:
 // - A slice of pointers to domain.Invoice representing the invoices found for the user.
 // - An error if any error occurs during the database operation. If no invoices are found, the function returns nil for the error.
@@ -222,11 +402,221 @@ func (i *InvoiceRepository) FindAllInvoice(db *mongo.Client, userID string) ([]*
 		return nil, fmt.Errorf("error finding invoices: %v", err)
 	}
 
-	return result.Invoices, nil
+	invoices := make([]*domain.Invoice, 0, len(result.Invoices))
+	for _, invoice := range result.Invoices {
+		if !invoice.IsDeleted() {
+			invoices = append(invoices, invoice)
+		}
+	}
+
+	return invoices, nil
+}
+
+// UsedInvoiceNumbers retrieves the invoice numbers of every invoice of a user, including soft-deleted invoices,
+// which keep their number until they are purged.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoice numbers are being retrieved.
+//
+// Returns:
+// - A set of the invoice numbers in use.
+// - An error if no user is found or any error occurs during the database operation.
+func (i *InvoiceRepository) UsedInvoiceNumbers(db *mongo.Client, userID string) (map[string]bool, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	var result struct {
+		Invoices []struct {
+			InvoiceNumber string `bson:"invoice_number"`
+		} `bson:"invoices"`
+	}
+
+	projection := options.FindOne().SetProjection(bson.M{"invoices.invoice_number": 1})
+	if err := UserData(db, "user").FindOne(ctx, bson.M{"_id": userID}, projection).Decode(&result); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("no user found with ID %s", userID)
+		}
+		return nil, fmt.Errorf("error finding invoice numbers: %v", err)
+	}
+
+	used := make(map[string]bool, len(result.Invoices))
+	for _, invoice := range result.Invoices {
+		used[invoice.InvoiceNumber] = true
+	}
+	return used, nil
+}
+
+// FindInvoicePage retrieves one page of the invoices of a given user matching filter, in the order they were
+// created, together with the total number of matching invoices. Only the requested page is read out of the
+// user's document.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are being searched.
+// - filter: The status, tag and issue date window the invoices must match.
+// - limit: The maximum number of invoices to return.
+// - offset: How many invoices to skip.
+//
+// Returns:
+// - A slice of pointers to domain.Invoice with the invoices of the page, empty past the last page.
+// - The total number of matching invoices, ignoring limit and offset.
+// - An error if no user is found or any error occurs during the database operation.
+func (i *InvoiceRepository) FindInvoicePage(db *mongo.Client, userID string, filter domain.InvoiceFilter, limit, offset int64) ([]*domain.Invoice, int64, error) {
+	defer logSlowQuery("FindInvoicePage", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	// issue dates are stored as "2006-01-02" strings, which compare correctly as strings
+	conditions := bson.A{}
+	if filter.Status != "" {
+		conditions = append(conditions, bson.M{"$eq": bson.A{"$$invoice.status", filter.Status}})
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, bson.M{"$in": bson.A{filter.Tag, bson.M{"$ifNull": bson.A{"$$invoice.tags", bson.A{}}}}})
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, bson.M{"$gte": bson.A{"$$invoice.issue_date", filter.From.Format("2006-01-02")}})
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, bson.M{"$lte": bson.A{"$$invoice.issue_date", filter.To.Format("2006-01-02")}})
+	}
+
+	invoices := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$invoices", bson.A{}}},
+		"as":    "invoice",
+		"cond":  bson.M{"$and": conditions},
+	}}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":      0,
+			"total":    bson.M{"$size": invoices},
+			"invoices": bson.M{"$slice": bson.A{invoices, offset, limit}},
+		}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error aggregating invoice page: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return nil, 0, fmt.Errorf("error reading invoice page: %v", err)
+		}
+		return nil, 0, fmt.Errorf("no user found with ID %s", userID)
+	}
+
+	var result struct {
+		Total    int64             `bson:"total"`
+		Invoices []*domain.Invoice `bson:"invoices"`
+	}
+	if err := cursor.Decode(&result); err != nil {
+		return nil, 0, fmt.Errorf("error decoding invoice page: %v", err)
+	}
+	if result.Invoices == nil {
+		result.Invoices = make([]*domain.Invoice, 0)
+	}
+
+	return result.Invoices, result.Total, nil
+}
+
+// DistinctTags retrieves the tags a user has put on their invoices, sorted alphabetically.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose tags are being retrieved.
+// - prefix: When not empty, only tags starting with prefix are returned.
+//
+// Returns:
+// - A slice of the distinct tags, empty when the user has not tagged any invoice.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) DistinctTags(db *mongo.Client, userID, prefix string) ([]string, error) {
+	defer logSlowQuery("DistinctTags", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	match := bson.M{}
+	if prefix != "" {
+		match["invoices.tags"] = bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$unwind", Value: "$invoices.tags"}},
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$invoices.tags"}}},
+		bson.D{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating invoice tags: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Tag string `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("error decoding invoice tags: %v", err)
+	}
+
+	tags := make([]string, len(results))
+	for idx, result := range results {
+		tags[idx] = result.Tag
+	}
+
+	return tags, nil
+}
+
+// EnsureInvoiceIndexes creates the indexes used to look up invoices by tag. Creating an index that
+// already exists is a no-op, so it is safe to call on every start.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+//
+// Returns:
+// - An error if any index cannot be created.
+func EnsureInvoiceIndexes(db *mongo.Client) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelCtx()
+
+	if _, err := UserData(db, "user").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "invoices.tags", Value: 1}},
+	}); err != nil {
+		return fmt.Errorf("error creating user invoice tags index: %v", err)
+	}
+
+	if _, err := InvoiceData(db, "invoice").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tags", Value: 1}},
+	}); err != nil {
+		return fmt.Errorf("error creating invoice tags index: %v", err)
+	}
+
+	// an idempotency key identifies a single import of a user
+	if _, err := ImportJobData(db, importJobCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "idempotency_key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("error creating import job index: %v", err)
+	}
+
+	return nil
 }
 
 // InvoiceStatSummary retrieves a summary of invoice statistics for a given user.
-// The summary includes the total amount paid, total amount overdue, and total amount of draft invoices.
+// The summary includes the total amount paid, overdue, draft, pending and unpaid. An invoice counts as overdue
+// when it is marked overdue, or when it is issued and its due date has passed. Unpaid covers every issued,
+// pending or overdue invoice. Refunded invoices count towards the total paid minus their refund, and voided and
+// cancelled invoices are not counted in any total.
 //
 // Parameters:
 // - db: A pointer to the MongoDB client used for database operations.
@@ -234,49 +624,19 @@ func (i *InvoiceRepository) FindAllInvoice(db *mongo.Client, userID string) ([]*
 //
 // Returns:
 // - A pointer to domain.InvoiceSummary containing the invoice statistics.
-// - An error if any error occurs during the database operation. If no invoice statistics are found, the function returns nil for the error.
+// - An error wrapping infra.ErrNoDataFound if the user does not exist, or any database error.
 func (i *InvoiceRepository) InvoiceStatSummary(db *mongo.Client, userID string) (*domain.InvoiceSummary, error) {
+	defer logSlowQuery("InvoiceStatSummary", userID, time.Now())
+
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelCtx()
 
 	pipeline := mongo.Pipeline{
 		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
-		bson.D{{Key: "$unwind", Value: "$invoices"}},
-		bson.D{
-			{Key: "$group", Value: bson.M{
-				"_id": nil,
-				"totalPaid": bson.M{
-					"$sum": bson.M{
-						"$cond": bson.A{
-							bson.M{"$eq": bson.A{"$invoices.status", "paid"}},
-							"$invoices.total_amount_due",
-							0,
-						},
-					},
-				},
-				"totalOverdue": bson.M{
-					"$sum": bson.M{
-						"$cond": bson.A{
-							bson.M{"$and": bson.A{
-								bson.M{"$eq": bson.A{"$invoices.status", "overdue"}},
-								bson.M{"$lt": bson.A{"$invoices.due_date", time.Now()}},
-							}},
-							"$invoices.total_amount_due",
-							0,
-						},
-					},
-				},
-				"totalPending": bson.M{
-					"$sum": bson.M{
-						"$cond": bson.A{
-							bson.M{"$eq": bson.A{"$invoices.status", "pending"}},
-							"$invoices.total_amount_due",
-							0,
-						},
-					},
-				},
-			}},
-		},
+		activeInvoices(),
+		// keep users without invoices so they get a zeroed summary instead of no result
+		bson.D{{Key: "$unwind", Value: bson.M{"path": "$invoices", "preserveNullAndEmptyArrays": true}}},
+		bson.D{{Key: "$group", Value: invoiceSummaryGroup(time.Now())}},
 	}
 
 	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
@@ -285,16 +645,155 @@ func (i *InvoiceRepository) InvoiceStatSummary(db *mongo.Client, userID string)
 	}
 	defer cursor.Close(ctx)
 
-	var results []domain.InvoiceSummary
-	if err = cursor.All(ctx, &results); err != nil {
+	// grouping on a nil _id yields at most one document
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return nil, fmt.Errorf("error reading invoice stats: %v", err)
+		}
+		return nil, fmt.Errorf("%w: no user found with ID %s", infra.ErrNoDataFound, userID)
+	}
+
+	var summary domain.InvoiceSummary
+	if err := cursor.Decode(&summary); err != nil {
 		return nil, fmt.Errorf("error decoding invoice stats: %v", err)
 	}
 
-	if len(results) == 0 {
-		return nil, fmt.Errorf("no invoice stats found for user %s", userID)
+	return &summary, nil
+}
+
+// invoiceSummaryGroup returns the $group stage computing a domain.InvoiceSummary from the unwound invoices of a
+// user, as described on InvoiceStatSummary.
+func invoiceSummaryGroup(now time.Time) bson.M {
+	// due dates are stored as YYYY-MM-DD strings, so they compare correctly against today's date string
+	today := now.Format("2006-01-02")
+
+	sumWhen := func(condition bson.M) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{condition, "$invoices.total_amount_due", 0}}}
+	}
+	statusIs := func(status string) bson.M {
+		return bson.M{"$eq": bson.A{"$invoices.status", status}}
+	}
+
+	// a refunded invoice only keeps what was not given back
+	keptAmount := bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": statusIs("paid"), "then": "$invoices.total_amount_due"},
+			bson.M{"case": statusIs("refunded"), "then": bson.M{"$subtract": bson.A{
+				"$invoices.total_amount_due",
+				bson.M{"$ifNull": bson.A{"$invoices.refund_amount", 0}},
+			}}},
+		},
+		"default": 0,
+	}}
+
+	return bson.M{
+		"_id":        nil,
+		"total_paid": bson.M{"$sum": keptAmount},
+		"total_overdue": sumWhen(bson.M{"$or": bson.A{
+			statusIs("overdue"),
+			bson.M{"$and": bson.A{
+				statusIs("issued"),
+				bson.M{"$lt": bson.A{"$invoices.due_date", today}},
+			}},
+		}}),
+		"total_draft":   sumWhen(statusIs("draft")),
+		"total_pending": sumWhen(statusIs("pending")),
+		"total_unpaid": sumWhen(bson.M{"$in": bson.A{
+			"$invoices.status", bson.A{"issued", "pending", "overdue"},
+		}}),
+	}
+}
+
+// InvoiceDashboard computes the invoice part of a user's dashboard in a single aggregation: the invoice summary
+// of InvoiceStatSummary, the number of invoices of each status, and the first upcomingLimit invoices that
+// GetIssueInvoiceList lists as ready to be issued. The recent activities of the dashboard are left empty.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user.
+// - upcomingLimit: The maximum number of upcoming invoices to list.
+//
+// Returns:
+// - A pointer to domain.Dashboard holding the summary, status counts and upcoming invoices.
+// - An error wrapping infra.ErrNoDataFound if the user does not exist, or any database error.
+func (i *InvoiceRepository) InvoiceDashboard(db *mongo.Client, userID string, upcomingLimit int64) (*domain.Dashboard, error) {
+	defer logSlowQuery("InvoiceDashboard", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	now := time.Now()
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		// keep users without invoices so they get a zeroed dashboard instead of no result
+		bson.D{{Key: "$unwind", Value: bson.M{"path": "$invoices", "preserveNullAndEmptyArrays": true}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"summary": bson.A{bson.M{"$group": invoiceSummaryGroup(now)}},
+			"status_counts": bson.A{
+				bson.M{"$match": bson.M{"invoices.status": bson.M{"$exists": true}}},
+				bson.M{"$group": bson.M{"_id": "$invoices.status", "count": bson.M{"$sum": 1}}},
+			},
+			"upcoming": bson.A{
+				bson.M{"$match": readyToIssueFilter("invoices.", now)},
+				bson.M{"$sort": bson.M{"invoices.issue_date": 1}},
+				bson.M{"$limit": upcomingLimit},
+				bson.M{"$project": bson.M{
+					"_id":              0,
+					"invoice_id":       "$invoices.invoice_id",
+					"invoice_number":   "$invoices.invoice_number",
+					"status":           "$invoices.status",
+					"issue_date":       "$invoices.issue_date",
+					"due_date":         "$invoices.due_date",
+					"customer_name":    "$invoices.customer.name",
+					"customer_email":   "$invoices.customer.email",
+					"billing_currency": "$invoices.billing_currency",
+					"total_amount_due": "$invoices.total_amount_due",
+				}},
+			},
+		}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating invoice dashboard: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Summary      []domain.InvoiceSummary `bson:"summary"`
+		StatusCounts []struct {
+			Status string `bson:"_id"`
+			Count  int64  `bson:"count"`
+		} `bson:"status_counts"`
+		Upcoming []domain.UpcomingInvoice `bson:"upcoming"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("error decoding invoice dashboard: %v", err)
+	}
+
+	// $facet always yields one document, whose summary is empty when the user does not exist
+	if len(results) == 0 || len(results[0].Summary) == 0 {
+		return nil, fmt.Errorf("%w: no user found with ID %s", infra.ErrNoDataFound, userID)
+	}
+	result := results[0]
+
+	counts := make(map[string]int64, len(result.StatusCounts))
+	for _, count := range result.StatusCounts {
+		counts[count.Status] = count.Count
+	}
+
+	upcoming := result.Upcoming
+	if upcoming == nil {
+		upcoming = make([]domain.UpcomingInvoice, 0)
 	}
 
-	return &results[0], nil
+	return &domain.Dashboard{
+		Summary:          result.Summary[0],
+		StatusCounts:     domain.NewStatusCounts(counts),
+		RecentActivities: make([]domain.Activity, 0),
+		Upcoming:         upcoming,
+	}, nil
 }
 
 // InvoiceItemSummary retrieves a summary of items in a specific invoice for a given user.
@@ -311,7 +810,10 @@ func (i *InvoiceRepository) InvoiceItemSummary(db *mongo.Client, userID string,
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelCtx()
 
-	filter := bson.M{"_id": userID, "invoices.invoice_id": invoiceID}
+	filter := bson.M{
+		"_id":      userID,
+		"invoices": bson.M{"$elemMatch": bson.M{"invoice_id": invoiceID, "deleted_at": notDeleted}},
+	}
 	projection := bson.M{"invoices.$": 1}
 
 	var result struct {
@@ -335,67 +837,460 @@ func (i *InvoiceRepository) InvoiceItemSummary(db *mongo.Client, userID string,
 			Quantity:    item.Quantity,
 			UnitPrice:   item.UnitPrice,
 			TotalPrice:  item.TotalPrice,
+			Billable:    item.Billable,
 		}
 	}
 
 	return summary, nil
 }
 
-// GetIssueInvoiceList retrieves a list of invoices that are ready to be issued for a given user within the next 30 days.
-// The function filters invoices based on their status (pending, draft, or overdue) and issue date.
+// RecalculateSummary recomputes the invoice summary of a user from the invoices with InvoiceStatSummary and
+// overwrites the copy cached on the user document, so totals that drifted are corrected.
 //
 // Parameters:
 // - db: A pointer to the MongoDB client used for database operations.
-// - userID: The unique identifier of the user whose invoices are being searched.
+// - userID: The unique identifier of the user whose summary is recalculated.
 //
 // Returns:
-// - A slice of domain.Invoice representing the invoices that are ready to be issued.
-// - An error if any error occurs during the database operation. If no invoices are found, the function returns nil for the error.
-func (i *InvoiceRepository) GetIssueInvoiceList(db *mongo.Client, userID string) ([]domain.Invoice, error) {
+// - A pointer to domain.SummaryReconciliation holding the cached summary before and after the update.
+// - An error wrapping infra.ErrNoDataFound if the user does not exist, or any database error.
+func (i *InvoiceRepository) RecalculateSummary(db *mongo.Client, userID string) (*domain.SummaryReconciliation, error) {
+	defer logSlowQuery("RecalculateSummary", userID, time.Now())
+
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelCtx()
 
-	readyToIssueStatus := []string{"pending", "draft", "overdue"}
+	var cached struct {
+		InvoiceSummary domain.InvoiceSummary `bson:"invoice_summary"`
+	}
+	projection := options.FindOne().SetProjection(bson.M{"invoice_summary": 1})
+	if err := UserData(db, "user").FindOne(ctx, bson.M{"_id": userID}, projection).Decode(&cached); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no user found with ID %s", infra.ErrNoDataFound, userID)
+		}
+		return nil, fmt.Errorf("error reading cached invoice summary: %v", err)
+	}
 
-	today := time.Now()
-	startDate := today
-	endDate := today.AddDate(0, 0, 30)
+	summary, err := i.InvoiceStatSummary(db, userID)
+	if err != nil {
+		return nil, err
+	}
 
-	filter := bson.M{
-		"_id":             userID,
-		"invoices.status": bson.M{"$in": readyToIssueStatus},
-		"invoices.issueDate": bson.M{
-			"$gte": startDate,
-			"$lte": endDate,
-		},
+	update := bson.M{"$set": bson.M{"invoice_summary": summary}}
+	if _, err := UserData(db, "user").UpdateOne(ctx, bson.M{"_id": userID}, update); err != nil {
+		return nil, fmt.Errorf("error updating cached invoice summary: %v", err)
 	}
 
-	projection := bson.M{"invoices.$": 1}
+	return &domain.SummaryReconciliation{
+		Before:  cached.InvoiceSummary,
+		After:   *summary,
+		Changed: cached.InvoiceSummary != *summary,
+	}, nil
+}
 
-	var result struct {
-		Invoices []domain.Invoice `bson:"invoices"`
+// SetInvoiceReminders saves the reminders of an invoice changed with domain.Invoice.AddReminder in the user's
+// document and the invoice collection. Paid, voided and cancelled invoices are left untouched.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The invoice holding the reminders to save.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice is paid, voided or cancelled, or any database error.
+func (i *InvoiceRepository) SetInvoiceReminders(db *mongo.Client, userID string, invoice *domain.Invoice) error {
+	defer logSlowQuery("SetInvoiceReminders", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	closedStatus := []string{"paid", "voided", "cancelled"}
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
 	}
+	defer session.EndSession(ctx)
 
-	err := UserData(db, "user").FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&result)
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoice.InvoiceID,
+				"deleted_at": notDeleted,
+				"status":     bson.M{"$nin": closedStatus},
+			}},
+		}
+		update := bson.M{"$set": bson.M{
+			"invoices.$.reminders":  invoice.Reminders,
+			"invoices.$.updated_at": invoice.UpdatedAt,
+		}}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error saving invoice reminders: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q is paid or voided", infra.ErrInvoiceStatusConflict, invoice.InvoiceID)
+		}
+
+		filter = bson.M{"invoice_id": invoice.InvoiceID}
+		update = bson.M{"$set": bson.M{
+			"reminders":  invoice.Reminders,
+			"updated_at": invoice.UpdatedAt,
+		}}
+
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, update); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error saving invoice reminders in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
 
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// FindScheduledReminders returns the reminders of every user that are due to be sent on today, see
+// domain.Invoice.DueReminders.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - today: The day to find reminders for.
+//
+// Returns:
+// - A slice of domain.ScheduledReminder, empty when nothing is due.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) FindScheduledReminders(db *mongo.Client, today time.Time) ([]domain.ScheduledReminder, error) {
+	defer logSlowQuery("FindScheduledReminders", "", time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelCtx()
+
+	// due dates are stored as "2006-01-02" strings, which compare correctly as strings
+	from := today.Format("2006-01-02")
+	to := today.AddDate(0, 0, domain.MaxReminderDays).Format("2006-01-02")
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"invoices.reminders.0": bson.M{"$exists": true}}}},
+		bson.D{{Key: "$project", Value: bson.M{"email_identity": 1, "invoices": 1}}},
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"invoices.status":      "issued",
+			"invoices.reminders.0": bson.M{"$exists": true},
+			"invoices.due_date":    bson.M{"$gte": from, "$lte": to},
+			"invoices.deleted_at":  bson.M{"$exists": false},
+		}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error finding scheduled reminders: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	scheduled := make([]domain.ScheduledReminder, 0)
+	for cursor.Next(ctx) {
+		var candidate struct {
+			UserID        string               `bson:"_id"`
+			EmailIdentity domain.EmailIdentity `bson:"email_identity"`
+			Invoice       domain.Invoice       `bson:"invoices"`
+		}
+		if err := cursor.Decode(&candidate); err != nil {
+			return nil, fmt.Errorf("error decoding scheduled reminder: %v", err)
+		}
+		for _, reminder := range candidate.Invoice.DueReminders(today) {
+			scheduled = append(scheduled, domain.ScheduledReminder{
+				UserID:   candidate.UserID,
+				Identity: candidate.EmailIdentity,
+				Invoice:  candidate.Invoice,
+				Reminder: reminder,
+			})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error reading scheduled reminders: %v", err)
+	}
+
+	return scheduled, nil
+}
+
+// ClaimReminder marks a reminder as sent on today, unless it was already sent today. Claiming before
+// sending keeps a reminder from going out twice when several schedulers run at once.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoiceID: The unique identifier of the invoice.
+// - daysBeforeDueDate: The reminder to claim.
+// - today: The day the reminder is sent.
+//
+// Returns:
+// - true if the reminder was claimed and should be sent, false if it was already sent today.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) ClaimReminder(db *mongo.Client, userID, invoiceID string, daysBeforeDueDate int, today time.Time) (bool, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	day := today.Format("2006-01-02")
+
+	filter := bson.M{
+		"_id": userID,
+		"invoices": bson.M{"$elemMatch": bson.M{
+			"invoice_id": invoiceID,
+			"deleted_at": notDeleted,
+			"reminders": bson.M{"$elemMatch": bson.M{
+				"days_before_due_date": daysBeforeDueDate,
+				"last_sent_on":         bson.M{"$ne": day},
+			}},
+		}},
+	}
+	update := bson.M{"$set": bson.M{"invoices.$[invoice].reminders.$[reminder].last_sent_on": day}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"invoice.invoice_id": invoiceID},
+		bson.M{"reminder.days_before_due_date": daysBeforeDueDate},
+	}})
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return false, fmt.Errorf("error claiming reminder: %v", err)
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// FindIssuedInvoicesBetween retrieves the invoices a user issued between from and to, both inclusive, ordered
+// by issue date and invoice number. Drafts and pending invoices are left out because they were never issued.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are being searched.
+// - from: The first issue date included.
+// - to: The last issue date included.
+//
+// Returns:
+// - A slice of domain.Invoice issued in the range, empty when there are none.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) FindIssuedInvoicesBetween(db *mongo.Client, userID string, from, to time.Time) ([]domain.Invoice, error) {
+	defer logSlowQuery("FindIssuedInvoicesBetween", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	// issue dates are stored as "2006-01-02" strings, which compare correctly as strings
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$invoices"}}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"status": bson.M{"$nin": bson.A{"draft", "pending", "scheduled"}},
+			"issue_date": bson.M{
+				"$gte": from.Format("2006-01-02"),
+				"$lte": to.Format("2006-01-02"),
+			},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "issue_date", Value: 1}, {Key: "invoice_number", Value: 1}}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error finding issued invoices for user %s: %v", userID, err)
+	}
+	defer cursor.Close(ctx)
+
+	invoices := make([]domain.Invoice, 0)
+	if err = cursor.All(ctx, &invoices); err != nil {
+		return nil, fmt.Errorf("error decoding issued invoices for user %s: %v", userID, err)
+	}
+
+	return invoices, nil
+}
+
+// MarkOverdueInvoices moves every issued or pending invoice whose due date is before today to "overdue", across
+// all users, in the users' documents and the invoice collection within a transaction.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - today: The current date; invoices due on this day are not overdue yet.
+//
+// Returns:
+// - The number of invoices marked overdue.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) MarkOverdueInvoices(db *mongo.Client, today time.Time) (int64, error) {
+	defer logSlowQuery("MarkOverdueInvoices", "", time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelCtx()
+
+	// due dates are stored as YYYY-MM-DD strings, so they compare correctly against today's date string
+	pastDue := bson.M{
+		"status":     bson.M{"$in": bson.A{"issued", "pending"}},
+		"due_date":   bson.M{"$lt": today.Format("2006-01-02")},
+		"deleted_at": notDeleted,
+	}
+	now := time.Now()
+
+	session, err := db.StartSession()
+	if err != nil {
+		return 0, fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	var marked int64
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{"invoices": bson.M{"$elemMatch": pastDue}}
+		update := bson.M{"$set": bson.M{
+			"invoices.$[invoice].status":     "overdue",
+			"invoices.$[invoice].updated_at": now,
+		}}
+		opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+			bson.M{
+				"invoice.status":     pastDue["status"],
+				"invoice.due_date":   pastDue["due_date"],
+				"invoice.deleted_at": notDeleted,
+			},
+		}})
+
+		if _, err := UserData(db, "user").UpdateMany(sessCtx, filter, update, opts); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error marking invoices overdue: %v", err)
+		}
+
+		// the invoice collection holds one document per invoice, so its count is the number of invoices
+		result, err := InvoiceData(db, "invoice").UpdateMany(sessCtx, pastDue, bson.M{"$set": bson.M{
+			"status":     "overdue",
+			"updated_at": now,
+		}})
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error marking invoices overdue in invoice collection: %v", err)
+		}
+		marked = result.ModifiedCount
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
 		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return marked, nil
+}
+
+// GetIssueInvoiceList retrieves the invoices that are ready to be issued for a given user within the next 30 days.
+// The function filters invoices based on their status (pending, draft, or overdue) and issue date, and returns
+// every matching invoice ordered by issue date. Cancelled invoices are never listed.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are being searched.
+//
+// Returns:
+// - A slice of domain.Invoice representing the invoices that are ready to be issued, empty when there are none.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) GetIssueInvoiceList(db *mongo.Client, userID string) ([]domain.Invoice, error) {
+	defer logSlowQuery("GetIssueInvoiceList", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$invoices"}}},
+		bson.D{{Key: "$match", Value: readyToIssueFilter("", time.Now())}},
+		bson.D{{Key: "$sort", Value: bson.M{"issue_date": 1}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
 		return nil, fmt.Errorf("error finding invoices for user %s: %v", userID, err)
 	}
+	defer cursor.Close(ctx)
+
+	invoices := make([]domain.Invoice, 0)
+	if err = cursor.All(ctx, &invoices); err != nil {
+		return nil, fmt.Errorf("error decoding invoices for user %s: %v", userID, err)
+	}
+
+	return invoices, nil
+}
+
+// notDeleted matches invoices that have not been soft-deleted. Lookups and updates of a single invoice include
+// it, so a deleted invoice cannot be read or changed until it is restored.
+var notDeleted = bson.M{"$exists": false}
+
+// activeInvoices returns the stage that drops soft-deleted invoices from the invoices of the matched user, so
+// lists and stats never include them. Users without invoices keep an empty list.
+func activeInvoices() bson.D {
+	return bson.D{{Key: "$set", Value: bson.M{"invoices": bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$invoices", bson.A{}}},
+		"as":    "invoice",
+		"cond":  bson.M{"$not": bson.A{"$$invoice.deleted_at"}},
+	}}}}}
+}
 
-	return result.Invoices, nil
+// readyToIssueFilter matches the invoices GetIssueInvoiceList lists as ready to be issued: pending, draft or
+// overdue invoices with an issue date within the next 30 days. prefix is prepended to the field names, e.g.
+// "invoices." to match invoices that are still embedded in the user document.
+func readyToIssueFilter(prefix string, now time.Time) bson.M {
+	// scheduled invoices wait for their send time
+	readyToIssueStatus := []string{"pending", "draft", "overdue"}
+
+	// issue dates are stored as "2006-01-02" strings, which compare correctly as strings
+	startDate := now.Format("2006-01-02")
+	endDate := now.AddDate(0, 0, 30).Format("2006-01-02")
+
+	return bson.M{
+		prefix + "status": bson.M{"$in": readyToIssueStatus},
+		prefix + "issue_date": bson.M{
+			"$gte": startDate,
+			"$lte": endDate,
+		},
+	}
 }
 
 // UpdateInvoiceStatusToIssued updates the status of an invoice to "issued" for a given user and invoice ID.
 // It starts a MongoDB session and performs the update operation within a transaction.
-// If the update is successful, it commits the transaction and returns nil.
-// If any error occurs during the operation, it returns an error message.
+// Only pending, draft or overdue invoices are issued, so issuing the same invoice twice is rejected
+// instead of moving its issue date.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice is not ready to be issued, or any database error.
 func (i *InvoiceRepository) UpdateInvoiceStatusToIssued(db *mongo.Client, userID string, invoiceID string) error {
+	defer logSlowQuery("UpdateInvoiceStatusToIssued", userID, time.Now())
+
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelCtx()
 
+	readyToIssueStatus := []string{"pending", "draft", "scheduled", "overdue"}
+	issuedAt := time.Now()
+	issueDate := issuedAt.Format("2006-01-02")
+
 	session, err := db.StartSession()
 	if err != nil {
 		return fmt.Errorf("error starting session: %v", err)
@@ -408,38 +1303,46 @@ func (i *InvoiceRepository) UpdateInvoiceStatusToIssued(db *mongo.Client, userID
 		}
 
 		filter := bson.M{
-			"_id":                 userID,
-			"invoices.invoice_id": invoiceID,
-			"invoices.status":     bson.M{"$in": []string{"pending", "draft", "overdue"}},
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoiceID,
+				"deleted_at": notDeleted,
+				"status":     bson.M{"$in": readyToIssueStatus},
+			}},
 		}
 
 		update := bson.M{
 			"$set": bson.M{
 				"invoices.$.status":     "issued",
-				"invoices.$.issue_date": time.Now(),
+				"invoices.$.issue_date": issueDate,
+				"invoices.$.issued_at":  issuedAt,
 			},
 		}
 
-		_, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
 		if err != nil {
-			session.AbortTransaction(ctx)
+			session.AbortTransaction(sessCtx)
 			return fmt.Errorf("error updating invoice status: %v", err)
 		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q is not ready to be issued", infra.ErrInvoiceStatusConflict, invoiceID)
+		}
 
 		// Update the external invoice collection
 		filter = bson.M{
 			"invoice_id": invoiceID,
-			"status":     bson.M{"$in": []string{"pending", "draft", "overdue"}},
+			"status":     bson.M{"$in": readyToIssueStatus},
 		}
-		update = bson.M{"$set": bson.M{"issued_date": time.Now()}}
+		update = bson.M{"$set": bson.M{"status": "issued", "issue_date": issueDate, "issued_at": issuedAt}}
 
-		_, err = InvoiceData(db, "invoices").UpdateOne(ctx, filter, update)
+		_, err = InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, update)
 		if err != nil {
-			session.AbortTransaction(ctx)
+			session.AbortTransaction(sessCtx)
 			return fmt.Errorf("error updating invoices status: %v", err)
 		}
 
-		if err := session.CommitTransaction(ctx); err != nil {
+		if err := session.CommitTransaction(sessCtx); err != nil {
 			return fmt.Errorf("failed to commit transaction: %v", err)
 		}
 
@@ -447,16 +1350,32 @@ func (i *InvoiceRepository) UpdateInvoiceStatusToIssued(db *mongo.Client, userID
 	})
 
 	if err != nil {
-		return fmt.Errorf("transaction failed: %v", err)
+		return fmt.Errorf("transaction failed: %w", err)
 	}
 
 	return nil
 }
 
-func (i *InvoiceRepository) DeleteInvoice(db *mongo.Client, userID, invoiceID string) error {
+// UpdateInvoiceStatusToPaid sets the status of an issued or overdue invoice to "paid" and records the payment
+// that settled it, in the user's document and the invoice collection within a transaction.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoiceID: The unique identifier of the paid invoice.
+// - payment: The payment that settled the invoice.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice is not issued or overdue, or any database error.
+func (i *InvoiceRepository) UpdateInvoiceStatusToPaid(db *mongo.Client, userID string, invoiceID string, payment domain.Payment) error {
+	defer logSlowQuery("UpdateInvoiceStatusToPaid", userID, time.Now())
+
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelCtx()
 
+	payableStatus := []string{"issued", "overdue"}
+	now := time.Now()
+
 	session, err := db.StartSession()
 	if err != nil {
 		return fmt.Errorf("error starting session: %v", err)
@@ -468,31 +1387,1723 @@ func (i *InvoiceRepository) DeleteInvoice(db *mongo.Client, userID, invoiceID st
 			return fmt.Errorf("failed to start transaction: %v", err)
 		}
 
-		// Remove the invoice from the user's document
 		filter := bson.M{
 			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoiceID,
+				"deleted_at": notDeleted,
+				"status":     bson.M{"$in": payableStatus},
+			}},
 		}
 		update := bson.M{
-			"$pull": bson.M{
-				"invoices": bson.M{"invoice_id": invoiceID},
+			"$set": bson.M{
+				"invoices.$.status":     "paid",
+				"invoices.$.paid_at":    payment.PaidAt,
+				"invoices.$.updated_at": now,
 			},
+			"$push": bson.M{"invoices.$.payments": payment},
 		}
 
-		_, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
 		if err != nil {
 			session.AbortTransaction(sessCtx)
-			return fmt.Errorf("error deleting invoice from user document: %v", err)
+			return fmt.Errorf("error marking invoice as paid: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q is not issued", infra.ErrInvoiceStatusConflict, invoiceID)
 		}
 
-		// Delete the invoice from the external invoice collection
-		filter = bson.M{
-			"invoice_id": invoiceID,
+		filter = bson.M{"invoice_id": invoiceID}
+		update = bson.M{
+			"$set": bson.M{
+				"status":     "paid",
+				"paid_at":    payment.PaidAt,
+				"updated_at": now,
+			},
+			"$push": bson.M{"payments": payment},
 		}
 
-		_, err = InvoiceData(db, "invoices").DeleteOne(sessCtx, filter)
-		if err != nil {
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, update); err != nil {
 			session.AbortTransaction(sessCtx)
-			return fmt.Errorf("error deleting invoice from invoices collection: %v", err)
+			return fmt.Errorf("error marking invoice as paid in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// CancelInvoice persists an invoice cancelled with domain.Invoice.Cancel in the user's document and the invoice
+// collection. The update only applies while the stored invoice is still unpaid and not withdrawn.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The cancelled invoice.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice can no longer be cancelled, or any database error.
+func (i *InvoiceRepository) CancelInvoice(db *mongo.Client, userID string, invoice *domain.Invoice) error {
+	defer logSlowQuery("CancelInvoice", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	cancellableStatus := []string{"draft", "pending", "scheduled", "issued", "overdue"}
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoice.InvoiceID,
+				"deleted_at": notDeleted,
+				"status":     bson.M{"$in": cancellableStatus},
+			}},
+		}
+		update := bson.M{"$set": bson.M{
+			"invoices.$.status":       invoice.Status,
+			"invoices.$.cancelled_at": invoice.CancelledAt,
+			"invoices.$.updated_at":   invoice.UpdatedAt,
+		}}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error cancelling invoice: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q is paid or already withdrawn", infra.ErrInvoiceStatusConflict, invoice.InvoiceID)
+		}
+
+		filter = bson.M{"invoice_id": invoice.InvoiceID}
+		update = bson.M{"$set": bson.M{
+			"status":       invoice.Status,
+			"cancelled_at": invoice.CancelledAt,
+			"updated_at":   invoice.UpdatedAt,
+		}}
+
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, update); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error cancelling invoice in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// RefundInvoice persists an invoice refunded with domain.Invoice.Refund in the user's document and the invoice
+// collection. The update only applies while the stored invoice is still paid.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The refunded invoice.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice is not paid, or any database error.
+func (i *InvoiceRepository) RefundInvoice(db *mongo.Client, userID string, invoice *domain.Invoice) error {
+	defer logSlowQuery("RefundInvoice", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoice.InvoiceID,
+				"deleted_at": notDeleted,
+				"status":     "paid",
+			}},
+		}
+		update := bson.M{"$set": bson.M{
+			"invoices.$.status":        invoice.Status,
+			"invoices.$.refunded_at":   invoice.RefundedAt,
+			"invoices.$.refund_amount": invoice.RefundAmount,
+			"invoices.$.refund_reason": invoice.RefundReason,
+			"invoices.$.updated_at":    invoice.UpdatedAt,
+		}}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error refunding invoice: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q is not paid", infra.ErrInvoiceStatusConflict, invoice.InvoiceID)
+		}
+
+		filter = bson.M{"invoice_id": invoice.InvoiceID}
+		update = bson.M{"$set": bson.M{
+			"status":        invoice.Status,
+			"refunded_at":   invoice.RefundedAt,
+			"refund_amount": invoice.RefundAmount,
+			"refund_reason": invoice.RefundReason,
+			"updated_at":    invoice.UpdatedAt,
+		}}
+
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, update); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error refunding invoice in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// VoidInvoice persists an invoice voided with domain.Invoice.Void in the user's document and the invoice
+// collection. The update only applies while the stored invoice is still issued or overdue.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The voided invoice.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice can no longer be voided, or any database error.
+func (i *InvoiceRepository) VoidInvoice(db *mongo.Client, userID string, invoice *domain.Invoice) error {
+	defer logSlowQuery("VoidInvoice", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	voidableStatus := []string{"issued", "overdue"}
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoice.InvoiceID,
+				"deleted_at": notDeleted,
+				"status":     bson.M{"$in": voidableStatus},
+			}},
+		}
+		update := bson.M{"$set": bson.M{
+			"invoices.$.status":      invoice.Status,
+			"invoices.$.voided_at":   invoice.VoidedAt,
+			"invoices.$.voided_by":   invoice.VoidedBy,
+			"invoices.$.void_reason": invoice.VoidReason,
+			"invoices.$.updated_at":  invoice.UpdatedAt,
+		}}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error voiding invoice: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q is not issued", infra.ErrInvoiceStatusConflict, invoice.InvoiceID)
+		}
+
+		filter = bson.M{"invoice_id": invoice.InvoiceID}
+		update = bson.M{"$set": bson.M{
+			"status":      invoice.Status,
+			"voided_at":   invoice.VoidedAt,
+			"voided_by":   invoice.VoidedBy,
+			"void_reason": invoice.VoidReason,
+			"updated_at":  invoice.UpdatedAt,
+		}}
+
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, update); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error voiding invoice in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// ChangeInvoiceCustomer persists the customer set with domain.Invoice.ChangeCustomer in the user's document
+// and the invoice collection. The update only applies while the stored invoice is still a draft or pending.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The invoice with its new customer.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice has been issued meanwhile, or any database error.
+func (i *InvoiceRepository) ChangeInvoiceCustomer(db *mongo.Client, userID string, invoice *domain.Invoice) error {
+	defer logSlowQuery("ChangeInvoiceCustomer", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	unissuedStatus := []string{"draft", "pending", "scheduled"}
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoice.InvoiceID,
+				"deleted_at": notDeleted,
+				"status":     bson.M{"$in": unissuedStatus},
+			}},
+		}
+		update := bson.M{"$set": bson.M{
+			"invoices.$.customer":   invoice.Customer,
+			"invoices.$.updated_at": invoice.UpdatedAt,
+		}}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error changing invoice customer: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q has been issued", infra.ErrInvoiceStatusConflict, invoice.InvoiceID)
+		}
+
+		filter = bson.M{"invoice_id": invoice.InvoiceID}
+		update = bson.M{"$set": bson.M{
+			"customer":   invoice.Customer,
+			"updated_at": invoice.UpdatedAt,
+		}}
+
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, update); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error changing invoice customer in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// PatchInvoice persists the fields changed with domain.Invoice.ApplyPatch in the user's document and the
+// invoice collection, leaving every other field untouched. The total amount due is written along with the items
+// or discount it depends on. The update only applies while the stored invoice is still a draft or pending.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The invoice with the patch applied.
+// - patch: The patch that was applied, naming the fields to write.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice has been issued meanwhile, or any database error.
+func (i *InvoiceRepository) PatchInvoice(db *mongo.Client, userID string, invoice *domain.Invoice, patch domain.InvoicePatch) error {
+	defer logSlowQuery("PatchInvoice", userID, time.Now())
+
+	match := bson.M{"status": bson.M{"$in": []string{"draft", "pending", "scheduled"}}}
+	return i.patchInvoice(db, userID, invoice, patch, match, "has been issued")
+}
+
+// AmendInvoice persists the fields changed with domain.Invoice.Amend on an issued invoice, like PatchInvoice.
+// The update only applies while the stored invoice is still issued and was issued at or after issuedAfter,
+// the start of the grace period.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The invoice with the amendment applied.
+// - patch: The patch that was applied, naming the fields to write.
+// - issuedAfter: The earliest issue time an invoice can still be amended from.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice can no longer be amended, or any database error.
+func (i *InvoiceRepository) AmendInvoice(db *mongo.Client, userID string, invoice *domain.Invoice, patch domain.InvoicePatch, issuedAfter time.Time) error {
+	defer logSlowQuery("AmendInvoice", userID, time.Now())
+
+	match := bson.M{"status": "issued", "issued_at": bson.M{"$gte": issuedAfter}}
+	return i.patchInvoice(db, userID, invoice, patch, match, "can no longer be amended")
+}
+
+// patchInvoice writes the fields named by patch to the invoice in the user's document and the invoice
+// collection within a transaction, provided the stored invoice matches match.
+func (i *InvoiceRepository) patchInvoice(db *mongo.Client, userID string, invoice *domain.Invoice, patch domain.InvoicePatch, match bson.M, conflict string) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	fields := bson.M{"updated_at": invoice.UpdatedAt}
+	if patch.Notes != nil {
+		fields["notes"] = invoice.Notes
+	}
+	if patch.Discount != nil {
+		fields["discount"] = invoice.Discount
+	}
+	if patch.Items != nil {
+		fields["items"] = invoice.Items
+	}
+	if patch.DueDate != nil {
+		fields["due_date"] = invoice.DueDate
+	}
+	if patch.ChangesTotal() {
+		fields["total_amount_due"] = invoice.TotalAmountDue
+		fields["base_amount_due"] = invoice.BaseAmountDue
+		if invoice.Tax != nil {
+			fields["tax"] = invoice.Tax
+		}
+	}
+
+	embedded := bson.M{}
+	for field, value := range fields {
+		embedded["invoices.$."+field] = value
+	}
+
+	elemMatch := bson.M{"invoice_id": invoice.InvoiceID, "deleted_at": notDeleted}
+	for field, value := range match {
+		elemMatch[field] = value
+	}
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id":      userID,
+			"invoices": bson.M{"$elemMatch": elemMatch},
+		}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, bson.M{"$set": embedded})
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error patching invoice: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q %s", infra.ErrInvoiceStatusConflict, invoice.InvoiceID, conflict)
+		}
+
+		filter = bson.M{"invoice_id": invoice.InvoiceID}
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, filter, bson.M{"$set": fields}); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error patching invoice in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// SaveInvoicePDF stores the rendered PDF of an invoice in GridFS and records its file id on the invoice in the
+// user's document and the invoice collection. A previously stored copy is replaced and deleted.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The invoice the PDF was rendered from.
+// - content: The rendered PDF.
+//
+// Returns:
+// - The id of the stored file.
+// - An error wrapping infra.ErrInvoiceNotFound if the user has no such invoice, or any database error.
+func (i *InvoiceRepository) SaveInvoicePDF(db *mongo.Client, userID string, invoice *domain.Invoice, content []byte) (string, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelCtx()
+
+	bucket, err := InvoiceDocumentBucket(db)
+	if err != nil {
+		return "", fmt.Errorf("error opening invoice document bucket: %v", err)
+	}
+	if err := bucket.SetWriteDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		return "", fmt.Errorf("error setting upload deadline: %v", err)
+	}
+
+	filename := fmt.Sprintf("invoice_%s_%s.pdf", userID, invoice.InvoiceID)
+	opts := options.GridFSUpload().SetMetadata(bson.M{
+		"user_id":        userID,
+		"invoice_id":     invoice.InvoiceID,
+		"invoice_number": invoice.InvoiceNumber,
+	})
+
+	fileID, err := bucket.UploadFromStream(filename, bytes.NewReader(content), opts)
+	if err != nil {
+		return "", fmt.Errorf("error uploading invoice document: %v", err)
+	}
+
+	filter := bson.M{
+		"_id":      userID,
+		"invoices": bson.M{"$elemMatch": bson.M{"invoice_id": invoice.InvoiceID, "deleted_at": notDeleted}},
+	}
+	update := bson.M{"$set": bson.M{"invoices.$.pdf_file_id": fileID.Hex()}}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+	if err == nil && result.MatchedCount == 0 {
+		err = fmt.Errorf("%w: %q", infra.ErrInvoiceNotFound, invoice.InvoiceID)
+	}
+	if err != nil {
+		// the upload is not referenced by any invoice, so it must not be left behind
+		if delErr := bucket.DeleteContext(ctx, fileID); delErr != nil {
+			slog.Error("Failed to delete orphaned invoice document", "fileID", fileID.Hex(), "error", delErr)
+		}
+		return "", fmt.Errorf("error recording invoice document: %w", err)
+	}
+
+	filter = bson.M{"invoice_id": invoice.InvoiceID}
+	update = bson.M{"$set": bson.M{"pdf_file_id": fileID.Hex()}}
+	if _, err := InvoiceData(db, "invoice").UpdateOne(ctx, filter, update); err != nil {
+		slog.Error("Failed to record invoice document in invoice collection", "invoiceID", invoice.InvoiceID, "error", err)
+	}
+
+	if invoice.PDFFileID != "" {
+		if previousID, err := primitive.ObjectIDFromHex(invoice.PDFFileID); err == nil {
+			if err := bucket.DeleteContext(ctx, previousID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+				slog.Error("Failed to delete previous invoice document", "fileID", invoice.PDFFileID, "error", err)
+			}
+		}
+	}
+
+	return fileID.Hex(), nil
+}
+
+// LoadInvoicePDF reads a PDF stored with SaveInvoicePDF.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - fileID: The id of the stored file.
+//
+// Returns:
+// - The stored PDF.
+// - An error wrapping infra.ErrDocumentNotFound if no such file is stored, or any database error.
+func (i *InvoiceRepository) LoadInvoicePDF(db *mongo.Client, fileID string) ([]byte, error) {
+	id, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", infra.ErrDocumentNotFound, fileID)
+	}
+
+	bucket, err := InvoiceDocumentBucket(db)
+	if err != nil {
+		return nil, fmt.Errorf("error opening invoice document bucket: %v", err)
+	}
+	if err := bucket.SetReadDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		return nil, fmt.Errorf("error setting download deadline: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := bucket.DownloadToStream(id, &buf); err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return nil, fmt.Errorf("%w: %q", infra.ErrDocumentNotFound, fileID)
+		}
+		return nil, fmt.Errorf("error downloading invoice document: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DeleteInvoice permanently removes a single invoice from the user's invoices and from the invoice collection.
+// Use SoftDeleteInvoice for deletes that can be undone.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoiceID: The unique identifier of the invoice to delete.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceNotFound if the user has no such invoice, or any database error.
+func (i *InvoiceRepository) DeleteInvoice(db *mongo.Client, userID, invoiceID string) error {
+	defer logSlowQuery("DeleteInvoice", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		// Remove only the matching invoice from the user's document, the user itself is kept
+		filter := bson.M{
+			"_id":                 userID,
+			"invoices.invoice_id": invoiceID,
+		}
+		update := bson.M{
+			"$pull": bson.M{
+				"invoices": bson.M{"invoice_id": invoiceID},
+			},
+		}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update)
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error deleting invoice from user document: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: %q", infra.ErrInvoiceNotFound, invoiceID)
+		}
+
+		// Delete the invoice from the external invoice collection
+		filter = bson.M{
+			"invoice_id": invoiceID,
+		}
+
+		_, err = InvoiceData(db, "invoice").DeleteOne(sessCtx, filter)
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error deleting invoice from invoices collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// SoftDeleteInvoice marks an invoice as deleted in the user's document and the invoice collection. The invoice
+// is kept, but left out of lists and stats until RestoreDeletedInvoice is called.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoiceID: The unique identifier of the invoice to delete.
+// - deletedAt: When the invoice was deleted.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceNotFound if the user has no such invoice that is not already deleted, or any database error.
+func (i *InvoiceRepository) SoftDeleteInvoice(db *mongo.Client, userID, invoiceID string, deletedAt time.Time) error {
+	defer logSlowQuery("SoftDeleteInvoice", userID, time.Now())
+
+	match := bson.M{"deleted_at": bson.M{"$exists": false}}
+	update := func(prefix string) bson.M {
+		return bson.M{"$set": bson.M{prefix + "deleted_at": deletedAt, prefix + "updated_at": deletedAt}}
+	}
+	return i.setInvoiceDeleted(db, userID, invoiceID, match, update)
+}
+
+// RestoreDeletedInvoice clears the deletion mark set by SoftDeleteInvoice, so the invoice shows up in lists and
+// stats again.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoiceID: The unique identifier of the invoice to restore.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceNotFound if the user has no such deleted invoice, or any database error.
+func (i *InvoiceRepository) RestoreDeletedInvoice(db *mongo.Client, userID, invoiceID string) error {
+	defer logSlowQuery("RestoreDeletedInvoice", userID, time.Now())
+
+	now := time.Now()
+	match := bson.M{"deleted_at": bson.M{"$exists": true}}
+	update := func(prefix string) bson.M {
+		return bson.M{
+			"$unset": bson.M{prefix + "deleted_at": ""},
+			"$set":   bson.M{prefix + "updated_at": now},
+		}
+	}
+	return i.setInvoiceDeleted(db, userID, invoiceID, match, update)
+}
+
+// setInvoiceDeleted applies the update built by update to the invoice in the user's document and the invoice
+// collection within a transaction, provided the stored invoice matches match. The update is built with the
+// field prefix of each collection.
+func (i *InvoiceRepository) setInvoiceDeleted(db *mongo.Client, userID, invoiceID string, match bson.M, update func(prefix string) bson.M) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	elemMatch := bson.M{"invoice_id": invoiceID}
+	for field, value := range match {
+		elemMatch[field] = value
+	}
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id":      userID,
+			"invoices": bson.M{"$elemMatch": elemMatch},
+		}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update("invoices.$."))
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error updating invoice deletion: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: %q", infra.ErrInvoiceNotFound, invoiceID)
+		}
+
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, bson.M{"invoice_id": invoiceID}, update("")); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error updating invoice deletion in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// FindCustomerInvoices retrieves the invoices a user has sent to a given customer.
+// Drafts and pending invoices are never returned because the customer has not received them.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who issued the invoices.
+// - customerEmail: The email address of the customer, matched case-insensitively.
+//
+// Returns:
+// - A slice of domain.Invoice sent to the customer.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) FindCustomerInvoices(db *mongo.Client, userID, customerEmail string) ([]domain.Invoice, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"invoices.status": bson.M{"$nin": []string{"draft", "pending", "scheduled"}},
+			"$expr": bson.M{"$eq": bson.A{
+				bson.M{"$toLower": "$invoices.customer.email"},
+				strings.ToLower(customerEmail),
+			}},
+		}}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$invoices"}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error finding customer invoices: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	invoices := make([]domain.Invoice, 0)
+	if err = cursor.All(ctx, &invoices); err != nil {
+		return nil, fmt.Errorf("error decoding customer invoices: %v", err)
+	}
+
+	return invoices, nil
+}
+
+// OutstandingInvoices lists the issued and overdue invoices of a user that still have a balance due, which is
+// the total minus the recorded payments, ordered by due date with the earliest first.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are listed.
+//
+// Returns:
+// - A pointer to domain.OutstandingBalances holding the invoices and the total outstanding.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) OutstandingInvoices(db *mongo.Client, userID string) (*domain.OutstandingBalances, error) {
+	defer logSlowQuery("OutstandingInvoices", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	amountPaid := bson.M{"$round": bson.A{
+		bson.M{"$sum": bson.M{"$ifNull": bson.A{"$payments.amount", bson.A{}}}}, 2,
+	}}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$invoices"}}},
+		bson.D{{Key: "$match", Value: bson.M{"status": bson.M{"$in": bson.A{"issued", "overdue"}}}}},
+		bson.D{{Key: "$addFields", Value: bson.M{"amount_paid": amountPaid}}},
+		bson.D{{Key: "$addFields", Value: bson.M{
+			"balance_due": bson.M{"$round": bson.A{bson.M{"$subtract": bson.A{"$total_amount_due", "$amount_paid"}}, 2}},
+		}}},
+		bson.D{{Key: "$match", Value: bson.M{"balance_due": bson.M{"$gt": 0}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "due_date", Value: 1}, {Key: "invoice_number", Value: 1}}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating outstanding invoices: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	invoices := make([]domain.OutstandingInvoice, 0)
+	if err = cursor.All(ctx, &invoices); err != nil {
+		return nil, fmt.Errorf("error decoding outstanding invoices: %v", err)
+	}
+
+	return domain.NewOutstandingBalances(invoices), nil
+}
+
+// FindLatestCustomerInvoice retrieves the most recently created invoice a user made for a given customer,
+// whatever its status. Voided and cancelled invoices are skipped.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who made the invoices.
+// - customerEmail: The email address of the customer, matched case-insensitively.
+//
+// Returns:
+// - A pointer to the latest domain.Invoice of the customer.
+// - An error wrapping infra.ErrInvoiceNotFound if the customer has no invoice, or any database error.
+func (i *InvoiceRepository) FindLatestCustomerInvoice(db *mongo.Client, userID, customerEmail string) (*domain.Invoice, error) {
+	defer logSlowQuery("FindLatestCustomerInvoice", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$invoices"}}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"status": bson.M{"$nin": bson.A{"voided", "cancelled"}},
+			"$expr": bson.M{"$eq": bson.A{
+				bson.M{"$toLower": "$customer.email"},
+				strings.ToLower(strings.TrimSpace(customerEmail)),
+			}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.M{"created_at": -1}}},
+		bson.D{{Key: "$limit", Value: 1}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error finding latest customer invoice: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return nil, fmt.Errorf("error reading latest customer invoice: %v", err)
+		}
+		return nil, fmt.Errorf("%w: no invoice found for customer %s", infra.ErrInvoiceNotFound, customerEmail)
+	}
+
+	var invoice domain.Invoice
+	if err := cursor.Decode(&invoice); err != nil {
+		return nil, fmt.Errorf("error decoding latest customer invoice: %v", err)
+	}
+
+	return &invoice, nil
+}
+
+// OutstandingByCustomer sums the unpaid issued and overdue invoices of a user per customer email.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose customers are summed.
+//
+// Returns:
+// - A map from lower-cased customer email to the outstanding amount; customers owing nothing are left out.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) OutstandingByCustomer(db *mongo.Client, userID string) (map[string]float64, error) {
+	defer logSlowQuery("OutstandingByCustomer", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: bson.M{"invoices.status": bson.M{"$in": []string{"issued", "overdue"}}}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":         bson.M{"$toLower": "$invoices.customer.email"},
+			"outstanding": bson.M{"$sum": "$invoices.total_amount_due"},
+		}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating outstanding balances: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		CustomerEmail string  `bson:"_id"`
+		Outstanding   float64 `bson:"outstanding"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("error decoding outstanding balances: %v", err)
+	}
+
+	outstanding := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		outstanding[row.CustomerEmail] = row.Outstanding
+	}
+	return outstanding, nil
+}
+
+// AverageDaysToPayment computes the mean and median number of days between the issue date and the
+// recorded payment date of a user's paid invoices. Invoices without a payment date are excluded.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are measured.
+// - from, to: The window the payment date must fall into.
+//
+// Returns:
+// - A pointer to domain.PaymentTimeMetric with the metric and the sample size.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) AverageDaysToPayment(db *mongo.Client, userID string, from, to time.Time) (*domain.PaymentTimeMetric, error) {
+	defer logSlowQuery("AverageDaysToPayment", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"invoices.status":  "paid",
+			"invoices.paid_at": bson.M{"$exists": true, "$gte": from, "$lte": to},
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id": 0,
+			"days": bson.M{"$dateDiff": bson.M{
+				"startDate": bson.M{"$convert": bson.M{
+					"input": "$invoices.issue_date", "to": "date", "onError": nil, "onNull": nil,
+				}},
+				"endDate": "$invoices.paid_at",
+				"unit":    "day",
+			}},
+		}}},
+		bson.D{{Key: "$match", Value: bson.M{"days": bson.M{"$ne": nil}}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating days to payment: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Days int64 `bson:"days"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("error decoding days to payment: %v", err)
+	}
+
+	days := make([]int64, len(results))
+	for idx, result := range results {
+		days[idx] = result.Days
+	}
+
+	return domain.NewPaymentTimeMetric(days), nil
+}
+
+// RevenueByPeriod sums the amount and number of invoices paid between from (inclusive) and to (exclusive).
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose revenue is being computed.
+// - from: The start of the period.
+// - to: The end of the period, excluded.
+//
+// Returns:
+// - A pointer to domain.RevenuePeriod, zeroed when nothing was paid in the period.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) RevenueByPeriod(db *mongo.Client, userID string, from, to time.Time) (*domain.RevenuePeriod, error) {
+	defer logSlowQuery("RevenueByPeriod", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"invoices.status":  "paid",
+			"invoices.paid_at": bson.M{"$gte": from, "$lt": to},
+		}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"revenue":       bson.M{"$sum": "$invoices.total_amount_due"},
+			"invoice_count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating revenue: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	period := &domain.RevenuePeriod{From: from, To: to}
+	if cursor.Next(ctx) {
+		var result struct {
+			Revenue      float64 `bson:"revenue"`
+			InvoiceCount int64   `bson:"invoice_count"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("error decoding revenue: %v", err)
+		}
+		period.Revenue = result.Revenue
+		period.InvoiceCount = result.InvoiceCount
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error reading revenue: %v", err)
+	}
+
+	return period, nil
+}
+
+// TopCustomers ranks the customers of a user by the total amount due of their invoices, highest first.
+// Customers are grouped by email address, case-insensitively. Only paid invoices count unless paidOnly is
+// false, in which case every invoice sent to the customer counts, leaving out drafts, pending and scheduled ones.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose customers are being ranked.
+// - paidOnly: Whether to count only paid invoices.
+// - limit: The maximum number of customers to return.
+//
+// Returns:
+// - A slice of domain.TopCustomer ordered by total, then by invoice count, highest first.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) TopCustomers(db *mongo.Client, userID string, paidOnly bool, limit int64) ([]domain.TopCustomer, error) {
+	defer logSlowQuery("TopCustomers", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	match := bson.M{"invoices.status": bson.M{"$nin": []string{"draft", "pending", "scheduled"}}}
+	if paidOnly {
+		match = bson.M{"invoices.status": "paid"}
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":           bson.M{"$toLower": "$invoices.customer.email"},
+			"name":          bson.M{"$last": "$invoices.customer.name"},
+			"total":         bson.M{"$sum": "$invoices.total_amount_due"},
+			"invoice_count": bson.M{"$sum": 1},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{
+			{Key: "total", Value: -1},
+			{Key: "invoice_count", Value: -1},
+			{Key: "_id", Value: 1},
+		}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating top customers: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	customers := make([]domain.TopCustomer, 0)
+	if err := cursor.All(ctx, &customers); err != nil {
+		return nil, fmt.Errorf("error decoding top customers: %v", err)
+	}
+
+	return customers, nil
+}
+
+// MonthlyRevenue sums the amount and number of invoices paid between from (inclusive) and to (exclusive) per
+// calendar month of their payment date, in UTC. Months without paid invoices are left out.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose revenue is being computed.
+// - from: The start of the first month.
+// - to: The end of the last month, excluded.
+//
+// Returns:
+// - A slice of domain.MonthlyRevenue ordered by month, empty when nothing was paid.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) MonthlyRevenue(db *mongo.Client, userID string, from, to time.Time) ([]domain.MonthlyRevenue, error) {
+	defer logSlowQuery("MonthlyRevenue", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"invoices.status":  "paid",
+			"invoices.paid_at": bson.M{"$gte": from, "$lt": to},
+		}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":           bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$invoices.paid_at"}},
+			"revenue":       bson.M{"$sum": "$invoices.total_amount_due"},
+			"invoice_count": bson.M{"$sum": 1},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating monthly revenue: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	revenue := make([]domain.MonthlyRevenue, 0)
+	if err := cursor.All(ctx, &revenue); err != nil {
+		return nil, fmt.Errorf("error decoding monthly revenue: %v", err)
+	}
+
+	return revenue, nil
+}
+
+// TaxByJurisdiction computes the tax collected on the invoices a user was paid for between from and to, grouped
+// by jurisdiction, tax rate and billing currency. Tax on invoices without a jurisdiction is reported under
+// domain.UnassignedJurisdiction, and refunded invoices are left out since their tax was given back.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are summed.
+// - from, to: The window the payment date must fall into.
+//
+// Returns:
+// - A slice of domain.JurisdictionTax ordered by jurisdiction, rate and currency.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) TaxByJurisdiction(db *mongo.Client, userID string, from, to time.Time) ([]domain.JurisdictionTax, error) {
+	defer logSlowQuery("TaxByJurisdiction", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	// the tax applies to the discounted items and the taxable expenses, so the other expenses are taken off
+	// what is left after the tax
+	untaxedExpenses := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$invoices.expenses", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.taxable", true}},
+	}}
+	taxable := bson.M{"$subtract": bson.A{
+		"$invoices.total_amount_due",
+		bson.M{"$add": bson.A{"$invoices.tax.amount", bson.M{"$sum": bson.M{"$map": bson.M{
+			"input": untaxedExpenses,
+			"in":    "$$this.amount",
+		}}}}},
+	}}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"invoices.status":  "paid",
+			"invoices.paid_at": bson.M{"$gte": from, "$lte": to},
+			"invoices.tax":     bson.M{"$exists": true},
+		}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"jurisdiction": bson.M{"$ifNull": bson.A{"$invoices.tax.jurisdiction", domain.UnassignedJurisdiction}},
+				"rate":         "$invoices.tax.rate",
+				"currency":     "$invoices.billing_currency",
+			},
+			"taxable_amount": bson.M{"$sum": taxable},
+			"tax_collected":  bson.M{"$sum": "$invoices.tax.amount"},
+			"invoice_count":  bson.M{"$sum": 1},
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":            0,
+			"jurisdiction":   "$_id.jurisdiction",
+			"rate":           "$_id.rate",
+			"currency":       "$_id.currency",
+			"taxable_amount": bson.M{"$round": bson.A{"$taxable_amount", 2}},
+			"tax_collected":  bson.M{"$round": bson.A{"$tax_collected", 2}},
+			"invoice_count":  1,
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{
+			{Key: "jurisdiction", Value: 1},
+			{Key: "rate", Value: 1},
+			{Key: "currency", Value: 1},
+		}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating tax by jurisdiction: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	taxes := make([]domain.JurisdictionTax, 0)
+	if err := cursor.All(ctx, &taxes); err != nil {
+		return nil, fmt.Errorf("error decoding tax by jurisdiction: %v", err)
+	}
+
+	return taxes, nil
+}
+
+// ActionNeededInvoices retrieves, in a single aggregation, the invoices that need the user's attention:
+// drafts ready to issue, issued invoices past their due date, and issued invoices due within dueSoonDays.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are being searched.
+// - dueSoonDays: How many days ahead an issued invoice counts as nearly due.
+//
+// Returns:
+// - A pointer to domain.ActionNeeded with each category and its count.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) ActionNeededInvoices(db *mongo.Client, userID string, dueSoonDays int) (*domain.ActionNeeded, error) {
+	defer logSlowQuery("ActionNeededInvoices", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	// due dates are stored as "2006-01-02" strings, which compare correctly as strings
+	today := time.Now().Format("2006-01-02")
+	horizon := time.Now().AddDate(0, 0, dueSoonDays).Format("2006-01-02")
+
+	facet := func(match bson.M) bson.A {
+		return bson.A{
+			bson.M{"$match": match},
+			bson.M{"$sort": bson.M{"due_date": 1}},
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$invoices"}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"ready_to_issue": facet(bson.M{"status": bson.M{"$in": []string{"draft", "pending"}}}),
+			"overdue": facet(bson.M{
+				"status":   bson.M{"$in": []string{"issued", "overdue"}},
+				"due_date": bson.M{"$lt": today},
+			}),
+			"due_soon": facet(bson.M{
+				"status":   "issued",
+				"due_date": bson.M{"$gte": today, "$lte": horizon},
+			}),
+		}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating invoices needing action: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		ReadyToIssue []domain.Invoice `bson:"ready_to_issue"`
+		Overdue      []domain.Invoice `bson:"overdue"`
+		DueSoon      []domain.Invoice `bson:"due_soon"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("error decoding invoices needing action: %v", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error reading invoices needing action: %v", err)
+	}
+
+	return &domain.ActionNeeded{
+		ReadyToIssue: domain.NewInvoiceGroup(result.ReadyToIssue),
+		Overdue:      domain.NewInvoiceGroup(result.Overdue),
+		DueSoon:      domain.NewInvoiceGroup(result.DueSoon),
+	}, nil
+}
+
+// FindPotentialDuplicates reports sets of invoices that may bill the same work twice: invoices to the same
+// customer, for the same amount, issued within windowDays of each other. Voided and cancelled invoices are ignored.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose invoices are being checked.
+// - windowDays: The maximum number of days between the issue dates of two suspected duplicates.
+//
+// Returns:
+// - A slice of domain.DuplicateSet, empty when no duplicates are suspected.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) FindPotentialDuplicates(db *mongo.Client, userID string, windowDays int) ([]domain.DuplicateSet, error) {
+	defer logSlowQuery("FindPotentialDuplicates", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$invoices"}}},
+		bson.D{{Key: "$match", Value: bson.M{"status": bson.M{"$nin": bson.A{"voided", "cancelled"}}}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"customer": bson.M{"$toLower": "$customer.email"},
+				"amount":   "$total_amount_due",
+			},
+			"count":    bson.M{"$sum": 1},
+			"invoices": bson.M{"$push": "$$ROOT"},
+		}}},
+		bson.D{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating potential duplicates: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Key struct {
+			Customer string  `bson:"customer"`
+			Amount   float64 `bson:"amount"`
+		} `bson:"_id"`
+		Invoices []domain.Invoice `bson:"invoices"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("error decoding potential duplicates: %v", err)
+	}
+
+	// the date window is applied per group, since close dates do not form fixed buckets
+	duplicates := make([]domain.DuplicateSet, 0)
+	for _, group := range groups {
+		duplicates = append(duplicates, domain.NewDuplicateSets(group.Key.Customer, group.Key.Amount, group.Invoices, windowDays)...)
+	}
+
+	return duplicates, nil
+}
+
+// FindPaymentPage retrieves a page of the payments recorded against a user's invoices, latest first.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user whose payments are being searched.
+// - filter: The method and payment time window the payments must match.
+// - limit: The maximum number of payments to return, all of them when not positive.
+// - offset: How many payments to skip.
+//
+// Returns:
+// - A slice of domain.LedgerPayment with the payments of the page, empty past the last page.
+// - The total number of matching payments, ignoring limit and offset.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) FindPaymentPage(db *mongo.Client, userID string, filter domain.PaymentFilter, limit, offset int64) ([]domain.LedgerPayment, int64, error) {
+	defer logSlowQuery("FindPaymentPage", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	match := bson.M{}
+	if filter.Method != "" {
+		match["method"] = filter.Method
+	}
+	paidAt := bson.M{}
+	if !filter.From.IsZero() {
+		paidAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		paidAt["$lte"] = filter.To
+	}
+	if len(paidAt) > 0 {
+		match["paid_at"] = paidAt
+	}
+
+	page := bson.A{bson.M{"$skip": offset}}
+	if limit > 0 {
+		page = append(page, bson.M{"$limit": limit})
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": userID}}},
+		activeInvoices(),
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$unwind", Value: "$invoices.payments"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": bson.M{
+			"invoice_id":     "$invoices.invoice_id",
+			"invoice_number": "$invoices.invoice_number",
+			"invoice_status": "$invoices.status",
+			"customer":       "$invoices.customer",
+			"currency":       "$invoices.billing_currency",
+			"amount":         "$invoices.payments.amount",
+			"paid_at":        "$invoices.payments.paid_at",
+			"method":         "$invoices.payments.method",
+		}}}},
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "paid_at", Value: -1}, {Key: "invoice_number", Value: 1}}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"total":    bson.A{bson.M{"$count": "count"}},
+			"payments": page,
+		}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error aggregating payments: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Payments []domain.LedgerPayment `bson:"payments"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, 0, fmt.Errorf("error decoding payments: %v", err)
+		}
+	} else if err := cursor.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error reading payments: %v", err)
+	}
+
+	if result.Payments == nil {
+		result.Payments = make([]domain.LedgerPayment, 0)
+	}
+	var total int64
+	if len(result.Total) > 0 {
+		total = result.Total[0].Count
+	}
+	return result.Payments, total, nil
+}
+
+// ScheduleInvoiceSend persists an invoice scheduled with domain.Invoice.ScheduleSend in the user's document and
+// the invoice collection. The update only applies while the stored invoice has not been issued.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The scheduled invoice.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice has been issued meanwhile, or any database error.
+func (i *InvoiceRepository) ScheduleInvoiceSend(db *mongo.Client, userID string, invoice *domain.Invoice) error {
+	defer logSlowQuery("ScheduleInvoiceSend", userID, time.Now())
+
+	return updateInvoiceSchedule(db, userID, invoice, []string{"draft", "pending", "scheduled"}, bson.M{
+		"status":            invoice.Status,
+		"scheduled_send_at": invoice.ScheduledSendAt,
+		"updated_at":        invoice.UpdatedAt,
+	}, nil)
+}
+
+// UnscheduleInvoiceSend persists an invoice unscheduled with domain.Invoice.UnscheduleSend in the user's document
+// and the invoice collection. The update only applies while the stored invoice is still scheduled.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoice: The unscheduled invoice.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice is no longer scheduled, or any database error.
+func (i *InvoiceRepository) UnscheduleInvoiceSend(db *mongo.Client, userID string, invoice *domain.Invoice) error {
+	defer logSlowQuery("UnscheduleInvoiceSend", userID, time.Now())
+
+	return updateInvoiceSchedule(db, userID, invoice, []string{"scheduled"}, bson.M{
+		"status":     invoice.Status,
+		"updated_at": invoice.UpdatedAt,
+	}, []string{"scheduled_send_at"})
+}
+
+// updateInvoiceSchedule sets and unsets the given invoice fields in the user's document and the invoice
+// collection, while the stored invoice has one of fromStatus.
+func updateInvoiceSchedule(db *mongo.Client, userID string, invoice *domain.Invoice, fromStatus []string, set bson.M, unset []string) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	userUpdate := bson.M{}
+	invoiceUpdate := bson.M{}
+	userSet := bson.M{}
+	for field, value := range set {
+		userSet["invoices.$."+field] = value
+	}
+	userUpdate["$set"] = userSet
+	invoiceUpdate["$set"] = set
+	if len(unset) > 0 {
+		userUnset := bson.M{}
+		invoiceUnset := bson.M{}
+		for _, field := range unset {
+			userUnset["invoices.$."+field] = ""
+			invoiceUnset[field] = ""
+		}
+		userUpdate["$unset"] = userUnset
+		invoiceUpdate["$unset"] = invoiceUnset
+	}
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoice.InvoiceID,
+				"deleted_at": notDeleted,
+				"status":     bson.M{"$in": fromStatus},
+			}},
+		}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, userUpdate)
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error updating invoice schedule: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q is not %s", infra.ErrInvoiceStatusConflict, invoice.InvoiceID, strings.Join(fromStatus, " or "))
+		}
+
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, bson.M{"invoice_id": invoice.InvoiceID}, invoiceUpdate); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error updating invoice schedule in invoice collection: %v", err)
+		}
+
+		if err := session.CommitTransaction(sessCtx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+// FindDueScheduledSends returns the scheduled invoices of every user whose send time is not after now,
+// earliest first.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - now: The time to compare the scheduled send times with.
+//
+// Returns:
+// - A slice of domain.ScheduledSend, empty when nothing is due.
+// - An error if any error occurs during the database operation.
+func (i *InvoiceRepository) FindDueScheduledSends(db *mongo.Client, now time.Time) ([]domain.ScheduledSend, error) {
+	defer logSlowQuery("FindDueScheduledSends", "", time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelCtx()
+
+	due := bson.M{"invoices.status": "scheduled", "invoices.scheduled_send_at": bson.M{"$lte": now}}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: due}},
+		bson.D{{Key: "$unwind", Value: "$invoices"}},
+		bson.D{{Key: "$match", Value: due}},
+		bson.D{{Key: "$match", Value: bson.M{"invoices.deleted_at": bson.M{"$exists": false}}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":               0,
+			"user_id":           "$_id",
+			"invoice_id":        "$invoices.invoice_id",
+			"scheduled_send_at": "$invoices.scheduled_send_at",
+		}}},
+		bson.D{{Key: "$sort", Value: bson.M{"scheduled_send_at": 1}}},
+	}
+
+	cursor, err := UserData(db, "user").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error finding scheduled sends: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	sends := make([]domain.ScheduledSend, 0)
+	if err := cursor.All(ctx, &sends); err != nil {
+		return nil, fmt.Errorf("error decoding scheduled sends: %v", err)
+	}
+	return sends, nil
+}
+
+// SetInvoiceCustomStatus labels an invoice with a custom status of the user, or removes its label when label is
+// nil. The label is only set while the invoice still has the core status it was checked against, so a concurrent
+// status change is not overwritten.
+//
+// Parameters:
+// - db: A pointer to the MongoDB client used for database operations.
+// - userID: The unique identifier of the user who owns the invoice.
+// - invoiceID: The unique identifier of the invoice to label.
+// - status: The core status the invoice must still have.
+// - label: The custom status to label the invoice with, or nil to remove the label.
+//
+// Returns:
+// - An error wrapping infra.ErrInvoiceStatusConflict if the invoice no longer has status, or any database error.
+func (i *InvoiceRepository) SetInvoiceCustomStatus(db *mongo.Client, userID, invoiceID, status string, label *domain.CustomStatus) error {
+	defer logSlowQuery("SetInvoiceCustomStatus", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	now := time.Now()
+	update := func(prefix string) bson.M {
+		if label == nil {
+			return bson.M{
+				"$unset": bson.M{prefix + "custom_status": ""},
+				"$set":   bson.M{prefix + "updated_at": now},
+			}
+		}
+		return bson.M{"$set": bson.M{prefix + "custom_status": label, prefix + "updated_at": now}}
+	}
+
+	session, err := db.StartSession()
+	if err != nil {
+		return fmt.Errorf("error starting session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %v", err)
+		}
+
+		filter := bson.M{
+			"_id": userID,
+			"invoices": bson.M{"$elemMatch": bson.M{
+				"invoice_id": invoiceID,
+				"status":     status,
+				"deleted_at": notDeleted,
+			}},
+		}
+
+		result, err := UserData(db, "user").UpdateOne(sessCtx, filter, update("invoices.$."))
+		if err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error updating invoice custom status: %v", err)
+		}
+		if result.MatchedCount == 0 {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("%w: invoice %q is no longer %s", infra.ErrInvoiceStatusConflict, invoiceID, status)
+		}
+
+		if _, err := InvoiceData(db, "invoice").UpdateOne(sessCtx, bson.M{"invoice_id": invoiceID}, update("")); err != nil {
+			session.AbortTransaction(sessCtx)
+			return fmt.Errorf("error updating invoice custom status in invoice collection: %v", err)
 		}
 
 		if err := session.CommitTransaction(sessCtx); err != nil {
@@ -503,7 +3114,7 @@ func (i *InvoiceRepository) DeleteInvoice(db *mongo.Client, userID, invoiceID st
 	})
 
 	if err != nil {
-		return fmt.Errorf("transaction failed: %v", err)
+		return fmt.Errorf("transaction failed: %w", err)
 	}
 
 	return nil
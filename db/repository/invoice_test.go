@@ -0,0 +1,1306 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// newTestInvoice returns an invoice of the given status and total that can be added to a test user.
+func newTestInvoice(status string, total float64) *domain.Invoice {
+	now := time.Now().UTC()
+	return &domain.Invoice{
+		InvoiceID:       primitive.NewObjectID().Hex(),
+		IssueDate:       now.Format("2006-01-02"),
+		DueDate:         now.AddDate(0, 0, 30).Format("2006-01-02"),
+		BillingCurrency: "USD",
+		TotalAmountDue:  total,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Status:          status,
+	}
+}
+
+func TestInvoiceStatSummary(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	pastDue := newTestInvoice("issued", 80)
+	pastDue.DueDate = time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	refunded := newTestInvoice("refunded", 640)
+	refunded.RefundAmount = 40
+
+	invoices := []*domain.Invoice{
+		newTestInvoice("draft", 10),
+		newTestInvoice("pending", 20),
+		newTestInvoice("issued", 40),
+		pastDue,
+		newTestInvoice("overdue", 160),
+		newTestInvoice("paid", 320),
+		refunded,
+		newTestInvoice("cancelled", 1280),
+		newTestInvoice("voided", 2560),
+	}
+	for _, invoice := range invoices {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	got, err := repo.InvoiceStatSummary(db, userID)
+	if err != nil {
+		t.Fatalf("InvoiceStatSummary: %v", err)
+	}
+
+	want := domain.InvoiceSummary{
+		// paid, and the 600 a refunded invoice kept
+		TotalPaid: 920,
+		// overdue, and issued past its due date
+		TotalOverdue: 240,
+		TotalDraft:   10,
+		TotalPending: 20,
+		// issued, pending and overdue
+		TotalUnpaid: 300,
+	}
+	if *got != want {
+		t.Errorf("InvoiceStatSummary() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestDeleteInvoice(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	deleted := newTestInvoice("draft", 100)
+	kept := newTestInvoice("draft", 200)
+	for _, invoice := range []*domain.Invoice{deleted, kept} {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	if err := repo.DeleteInvoice(db, userID, deleted.InvoiceID); err != nil {
+		t.Fatalf("DeleteInvoice: %v", err)
+	}
+
+	if _, err := (&UserRepository{}).FindByID(db, userID); err != nil {
+		t.Fatalf("user was removed with its invoice: %v", err)
+	}
+	if _, err := repo.FindUserInvoiceByID(db, userID, kept.InvoiceID); err != nil {
+		t.Errorf("the other invoice was removed: %v", err)
+	}
+	if _, err := repo.FindUserInvoiceByID(db, userID, deleted.InvoiceID); !errors.Is(err, infra.ErrInvoiceNotFound) {
+		t.Errorf("FindUserInvoiceByID() of the deleted invoice: error = %v, want %v", err, infra.ErrInvoiceNotFound)
+	}
+
+	if err := repo.DeleteInvoice(db, userID, deleted.InvoiceID); !errors.Is(err, infra.ErrInvoiceNotFound) {
+		t.Errorf("deleting the invoice again: error = %v, want %v", err, infra.ErrInvoiceNotFound)
+	}
+}
+
+func TestInvoiceStatSummaryWithoutInvoices(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	got, err := repo.InvoiceStatSummary(db, userID)
+	if err != nil {
+		t.Fatalf("InvoiceStatSummary: %v", err)
+	}
+	if *got != (domain.InvoiceSummary{}) {
+		t.Errorf("InvoiceStatSummary() = %+v, want a zeroed summary", *got)
+	}
+
+	if _, err := repo.InvoiceStatSummary(db, primitive.NewObjectID().Hex()); !errors.Is(err, infra.ErrNoDataFound) {
+		t.Errorf("InvoiceStatSummary() of an unknown user: error = %v, want %v", err, infra.ErrNoDataFound)
+	}
+}
+
+func TestAddNewInvoiceReturnsDatabaseErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the 10s repository timeout")
+	}
+	db := failingClient(t)
+	repo := &InvoiceRepository{}
+
+	invoice := newTestInvoice("draft", 100)
+	start := time.Now()
+	if err := repo.AddNewInvoice(db, primitive.NewObjectID().Hex(), invoice); err == nil {
+		t.Fatal("AddNewInvoice() returned no error for a failing database")
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Errorf("AddNewInvoice() kept retrying for %v, past its 10s timeout", elapsed)
+	}
+	if invoice.InvoiceNumber != "" {
+		t.Errorf("InvoiceNumber = %q after a failed insert, want it left empty", invoice.InvoiceNumber)
+	}
+}
+
+func TestFindInvoicePage(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	invoices := make([]*domain.Invoice, 5)
+	for idx := range invoices {
+		invoices[idx] = newTestInvoice("draft", float64(idx+1))
+		if err := repo.AddNewInvoice(db, userID, invoices[idx]); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		limit  int64
+		offset int64
+		want   []*domain.Invoice
+	}{
+		{name: "first page", limit: 2, offset: 0, want: invoices[0:2]},
+		{name: "middle page", limit: 2, offset: 2, want: invoices[2:4]},
+		{name: "last partial page", limit: 2, offset: 4, want: invoices[4:]},
+		{name: "past the last page", limit: 2, offset: 6, want: nil},
+		{name: "single page", limit: 10, offset: 0, want: invoices},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, total, err := repo.FindInvoicePage(db, userID, domain.InvoiceFilter{}, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("FindInvoicePage: %v", err)
+			}
+			if total != int64(len(invoices)) {
+				t.Errorf("total = %d, want %d", total, len(invoices))
+			}
+			if len(page) != len(tt.want) {
+				t.Fatalf("page has %d invoices, want %d", len(page), len(tt.want))
+			}
+			for idx := range tt.want {
+				if page[idx].InvoiceID != tt.want[idx].InvoiceID {
+					t.Errorf("page[%d] = %s, want %s", idx, page[idx].InvoiceID, tt.want[idx].InvoiceID)
+				}
+			}
+		})
+	}
+}
+
+func TestFindInvoicePageFilters(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	issued := func(status, issueDate string) *domain.Invoice {
+		invoice := newTestInvoice(status, 100)
+		invoice.IssueDate = issueDate
+		return invoice
+	}
+	january := issued("paid", "2024-01-15")
+	february := issued("overdue", "2024-02-15")
+	march := issued("paid", "2024-03-15")
+	for _, invoice := range []*domain.Invoice{january, february, march} {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	day := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+
+	tests := []struct {
+		name   string
+		filter domain.InvoiceFilter
+		want   []*domain.Invoice
+	}{
+		{name: "no filter", filter: domain.InvoiceFilter{}, want: []*domain.Invoice{january, february, march}},
+		{name: "status", filter: domain.InvoiceFilter{Status: "paid"}, want: []*domain.Invoice{january, march}},
+		{name: "from", filter: domain.InvoiceFilter{From: day("2024-02-15")}, want: []*domain.Invoice{february, march}},
+		{name: "to", filter: domain.InvoiceFilter{To: day("2024-02-15")}, want: []*domain.Invoice{january, february}},
+		{name: "date range", filter: domain.InvoiceFilter{From: day("2024-02-01"), To: day("2024-02-29")}, want: []*domain.Invoice{february}},
+		{name: "status and date range", filter: domain.InvoiceFilter{Status: "paid", From: day("2024-02-01"), To: day("2024-12-31")}, want: []*domain.Invoice{march}},
+		{name: "no match", filter: domain.InvoiceFilter{Status: "overdue", From: day("2024-03-01")}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, total, err := repo.FindInvoicePage(db, userID, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("FindInvoicePage: %v", err)
+			}
+			if total != int64(len(tt.want)) || len(page) != len(tt.want) {
+				t.Fatalf("FindInvoicePage() = %d invoices of %d, want %d", len(page), total, len(tt.want))
+			}
+			for idx := range tt.want {
+				if page[idx].InvoiceID != tt.want[idx].InvoiceID {
+					t.Errorf("page[%d] = %s, want %s", idx, page[idx].InvoiceID, tt.want[idx].InvoiceID)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyToIssueFilter(t *testing.T) {
+	now := time.Date(2024, 1, 20, 15, 0, 0, 0, time.UTC)
+
+	filter := readyToIssueFilter("invoices.", now)
+	window, ok := filter["invoices.issue_date"].(bson.M)
+	if !ok {
+		t.Fatalf("readyToIssueFilter() = %v, has no invoices.issue_date window", filter)
+	}
+	if window["$gte"] != "2024-01-20" || window["$lte"] != "2024-02-19" {
+		t.Errorf("issue date window = %v, want 2024-01-20 to 2024-02-19", window)
+	}
+	if _, ok := filter["invoices.status"]; !ok {
+		t.Errorf("readyToIssueFilter() = %v, has no invoices.status condition", filter)
+	}
+}
+
+func TestGetIssueInvoiceList(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	today := time.Now()
+	issuedOn := func(status string, days int) *domain.Invoice {
+		invoice := newTestInvoice(status, 100)
+		invoice.IssueDate = today.AddDate(0, 0, days).Format("2006-01-02")
+		invoice.DueDate = today.AddDate(0, 0, days+30).Format("2006-01-02")
+		return invoice
+	}
+
+	lastDay := issuedOn("pending", 30)
+	midWindow := issuedOn("overdue", 15)
+	first := issuedOn("draft", 0)
+	invoices := []*domain.Invoice{
+		lastDay,
+		issuedOn("draft", -1),
+		midWindow,
+		issuedOn("draft", 31),
+		first,
+		issuedOn("cancelled", 5),
+		issuedOn("issued", 5),
+	}
+	for _, invoice := range invoices {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	got, err := repo.GetIssueInvoiceList(db, userID)
+	if err != nil {
+		t.Fatalf("GetIssueInvoiceList: %v", err)
+	}
+
+	want := []*domain.Invoice{first, midWindow, lastDay}
+	if len(got) != len(want) {
+		t.Fatalf("GetIssueInvoiceList() returned %d invoices, want %d", len(got), len(want))
+	}
+	for idx := range want {
+		if got[idx].InvoiceID != want[idx].InvoiceID {
+			t.Errorf("invoice %d = %s issued %s, want %s issued %s",
+				idx, got[idx].InvoiceID, got[idx].IssueDate, want[idx].InvoiceID, want[idx].IssueDate)
+		}
+	}
+}
+
+func TestUpdateInvoiceBeforeDueDateMalformedDates(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	tests := []struct {
+		name      string
+		issueDate string
+		dueDate   string
+	}{
+		{name: "malformed issue date", issueDate: "15/01/2030", dueDate: "2030-02-15"},
+		{name: "malformed due date", issueDate: "2030-01-15", dueDate: "Feb 15 2030"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := newTestInvoice("draft", 100)
+			stored.IssueDate = tt.issueDate
+			stored.DueDate = tt.dueDate
+			if err := repo.AddNewInvoice(db, userID, stored); err != nil {
+				t.Fatalf("AddNewInvoice: %v", err)
+			}
+
+			err := repo.UpdateInvoiceBeforeDueDate(db, userID, stored.InvoiceID, newTestInvoice("", 250))
+			if err == nil {
+				t.Fatal("UpdateInvoiceBeforeDueDate() returned no error for a malformed stored date")
+			}
+			if errors.Is(err, infra.ErrInvoiceStatusConflict) {
+				t.Errorf("UpdateInvoiceBeforeDueDate() error = %v, want a date error rather than a conflict", err)
+			}
+		})
+	}
+}
+
+func TestUpdateInvoiceStatusToPaid(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	issued := newTestInvoice("issued", 250)
+	draft := newTestInvoice("draft", 100)
+	for _, invoice := range []*domain.Invoice{issued, draft} {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	before, err := repo.InvoiceStatSummary(db, userID)
+	if err != nil {
+		t.Fatalf("InvoiceStatSummary: %v", err)
+	}
+
+	payment := domain.Payment{Amount: 250, PaidAt: time.Now().UTC().Truncate(time.Millisecond), Method: "bank_transfer"}
+	if err := repo.UpdateInvoiceStatusToPaid(db, userID, issued.InvoiceID, payment); err != nil {
+		t.Fatalf("UpdateInvoiceStatusToPaid: %v", err)
+	}
+
+	after, err := repo.InvoiceStatSummary(db, userID)
+	if err != nil {
+		t.Fatalf("InvoiceStatSummary: %v", err)
+	}
+	if after.TotalPaid != before.TotalPaid+250 {
+		t.Errorf("TotalPaid = %v, want %v", after.TotalPaid, before.TotalPaid+250)
+	}
+	if after.TotalUnpaid != before.TotalUnpaid-250 {
+		t.Errorf("TotalUnpaid = %v, want %v", after.TotalUnpaid, before.TotalUnpaid-250)
+	}
+
+	stored, err := repo.FindUserInvoiceByID(db, userID, issued.InvoiceID)
+	if err != nil {
+		t.Fatalf("FindUserInvoiceByID: %v", err)
+	}
+	if stored.Status != "paid" || stored.PaidAt == nil || !stored.PaidAt.Equal(payment.PaidAt) {
+		t.Errorf("stored invoice is %s paid at %v, want paid at %v", stored.Status, stored.PaidAt, payment.PaidAt)
+	}
+
+	// only issued or overdue invoices can be paid, and only once
+	for _, invoiceID := range []string{draft.InvoiceID, issued.InvoiceID} {
+		if err := repo.UpdateInvoiceStatusToPaid(db, userID, invoiceID, payment); !errors.Is(err, infra.ErrInvoiceStatusConflict) {
+			t.Errorf("UpdateInvoiceStatusToPaid(%s) error = %v, want %v", invoiceID, err, infra.ErrInvoiceStatusConflict)
+		}
+	}
+
+	if final, err := repo.InvoiceStatSummary(db, userID); err != nil || final.TotalPaid != after.TotalPaid {
+		t.Errorf("TotalPaid after rejected payments = %+v (%v), want %v", final, err, after.TotalPaid)
+	}
+}
+
+func TestCancelInvoice(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	draft := newTestInvoice("draft", 100)
+	paid := newTestInvoice("paid", 200)
+	for _, invoice := range []*domain.Invoice{draft, paid} {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	t.Run("draft", func(t *testing.T) {
+		if err := draft.Cancel(); err != nil {
+			t.Fatalf("Cancel: %v", err)
+		}
+		if err := repo.CancelInvoice(db, userID, draft); err != nil {
+			t.Fatalf("CancelInvoice: %v", err)
+		}
+
+		stored, err := repo.FindUserInvoiceByID(db, userID, draft.InvoiceID)
+		if err != nil {
+			t.Fatalf("FindUserInvoiceByID: %v", err)
+		}
+		if stored.Status != "cancelled" {
+			t.Errorf("stored status = %q, want %q", stored.Status, "cancelled")
+		}
+
+		issueList, err := repo.GetIssueInvoiceList(db, userID)
+		if err != nil {
+			t.Fatalf("GetIssueInvoiceList: %v", err)
+		}
+		for _, invoice := range issueList {
+			if invoice.InvoiceID == draft.InvoiceID {
+				t.Errorf("cancelled invoice %s is ready to issue", draft.InvoiceID)
+			}
+		}
+	})
+
+	t.Run("paid", func(t *testing.T) {
+		if err := paid.Cancel(); err == nil {
+			t.Error("Cancel() of a paid invoice succeeded")
+		}
+
+		// the stored status is checked too, in case the invoice was paid after it was read
+		cancelled := *paid
+		cancelled.Status = "cancelled"
+		cancelledAt := time.Now()
+		cancelled.CancelledAt = &cancelledAt
+		if err := repo.CancelInvoice(db, userID, &cancelled); !errors.Is(err, infra.ErrInvoiceStatusConflict) {
+			t.Errorf("CancelInvoice() error = %v, want %v", err, infra.ErrInvoiceStatusConflict)
+		}
+
+		stored, err := repo.FindUserInvoiceByID(db, userID, paid.InvoiceID)
+		if err != nil {
+			t.Fatalf("FindUserInvoiceByID: %v", err)
+		}
+		if stored.Status != "paid" {
+			t.Errorf("stored status = %q, want %q", stored.Status, "paid")
+		}
+	})
+}
+
+func TestRefundInvoice(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	paid := newTestInvoice("paid", 300)
+	issued := newTestInvoice("issued", 100)
+	for _, invoice := range []*domain.Invoice{paid, issued} {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	if err := paid.Refund(120, "duplicate charge"); err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if err := repo.RefundInvoice(db, userID, paid); err != nil {
+		t.Fatalf("RefundInvoice: %v", err)
+	}
+
+	stored, err := repo.FindUserInvoiceByID(db, userID, paid.InvoiceID)
+	if err != nil {
+		t.Fatalf("FindUserInvoiceByID: %v", err)
+	}
+	if stored.Status != "refunded" || stored.RefundAmount != 120 || stored.RefundReason != "duplicate charge" {
+		t.Errorf("stored invoice is %s with %v refunded for %q, want refunded with 120 for %q",
+			stored.Status, stored.RefundAmount, stored.RefundReason, "duplicate charge")
+	}
+
+	// the stored status is checked, so an unpaid invoice cannot be refunded even when the caller says it is
+	refundedIssued := *issued
+	refundedIssued.Status = "refunded"
+	refundedIssued.RefundAmount = 100
+	if err := repo.RefundInvoice(db, userID, &refundedIssued); !errors.Is(err, infra.ErrInvoiceStatusConflict) {
+		t.Errorf("RefundInvoice() of an issued invoice: error = %v, want %v", err, infra.ErrInvoiceStatusConflict)
+	}
+
+	summary, err := repo.InvoiceStatSummary(db, userID)
+	if err != nil {
+		t.Fatalf("InvoiceStatSummary: %v", err)
+	}
+	// the 180 kept from the refunded invoice is paid; the issued invoice is still unpaid
+	if summary.TotalPaid != 180 || summary.TotalUnpaid != 100 {
+		t.Errorf("TotalPaid = %v, TotalUnpaid = %v, want 180 and 100", summary.TotalPaid, summary.TotalUnpaid)
+	}
+}
+
+func TestMarkOverdueInvoices(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	// the clock is fixed in the past, so invoices of other tests are never past due on it
+	today := time.Date(2020, 1, 10, 9, 0, 0, 0, time.UTC)
+	dueOn := func(status, dueDate string) *domain.Invoice {
+		invoice := newTestInvoice(status, 100)
+		invoice.IssueDate = "2019-12-10"
+		invoice.DueDate = dueDate
+		return invoice
+	}
+
+	tests := []struct {
+		name       string
+		invoice    *domain.Invoice
+		wantStatus string
+	}{
+		{name: "issued past due", invoice: dueOn("issued", "2020-01-09"), wantStatus: "overdue"},
+		{name: "pending past due", invoice: dueOn("pending", "2019-12-31"), wantStatus: "overdue"},
+		{name: "issued due today", invoice: dueOn("issued", "2020-01-10"), wantStatus: "issued"},
+		{name: "issued due later", invoice: dueOn("issued", "2020-02-10"), wantStatus: "issued"},
+		{name: "paid past due", invoice: dueOn("paid", "2020-01-09"), wantStatus: "paid"},
+		{name: "draft past due", invoice: dueOn("draft", "2020-01-09"), wantStatus: "draft"},
+		{name: "cancelled past due", invoice: dueOn("cancelled", "2020-01-09"), wantStatus: "cancelled"},
+	}
+	for _, tt := range tests {
+		if err := repo.AddNewInvoice(db, userID, tt.invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	marked, err := repo.MarkOverdueInvoices(db, today)
+	if err != nil {
+		t.Fatalf("MarkOverdueInvoices: %v", err)
+	}
+	if marked < 2 {
+		t.Errorf("MarkOverdueInvoices() = %d, want at least the 2 past due invoices", marked)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored, err := repo.FindUserInvoiceByID(db, userID, tt.invoice.InvoiceID)
+			if err != nil {
+				t.Fatalf("FindUserInvoiceByID: %v", err)
+			}
+			if stored.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", stored.Status, tt.wantStatus)
+			}
+
+			var standalone domain.Invoice
+			if err := InvoiceData(db, "invoice").FindOne(context.Background(), bson.M{"invoice_id": tt.invoice.InvoiceID}).Decode(&standalone); err != nil {
+				t.Fatalf("finding invoice in the invoice collection: %v", err)
+			}
+			if standalone.Status != tt.wantStatus {
+				t.Errorf("invoice collection status = %q, want %q", standalone.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAddNewInvoiceDuplicateNumber(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	otherUserID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	numbered := func() *domain.Invoice {
+		invoice := newTestInvoice("draft", 100)
+		invoice.InvoiceNumber = "INV-DUPLICATE"
+		return invoice
+	}
+
+	if err := repo.AddNewInvoice(db, userID, numbered()); err != nil {
+		t.Fatalf("AddNewInvoice: %v", err)
+	}
+
+	duplicate := numbered()
+	if err := repo.AddNewInvoice(db, userID, duplicate); !errors.Is(err, infra.ErrDuplicateInvoiceNumber) {
+		t.Fatalf("AddNewInvoice() with a used number: error = %v, want %v", err, infra.ErrDuplicateInvoiceNumber)
+	}
+	// the rejected invoice was written nowhere
+	if _, err := repo.FindUserInvoiceByID(db, userID, duplicate.InvoiceID); !errors.Is(err, infra.ErrInvoiceNotFound) {
+		t.Errorf("FindUserInvoiceByID() of the rejected invoice: error = %v, want %v", err, infra.ErrInvoiceNotFound)
+	}
+	count, err := InvoiceData(db, "invoice").CountDocuments(context.Background(), bson.M{"invoice_id": duplicate.InvoiceID})
+	if err != nil {
+		t.Fatalf("CountDocuments: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("rejected invoice is in the invoice collection")
+	}
+
+	if err := repo.AddNewInvoice(db, otherUserID, numbered()); err != nil {
+		t.Errorf("AddNewInvoice() with the number of another user's invoice: %v", err)
+	}
+
+	if err := repo.AddNewInvoice(db, primitive.NewObjectID().Hex(), numbered()); !errors.Is(err, infra.ErrUserNotFound) {
+		t.Errorf("AddNewInvoice() for an unknown user: error = %v, want %v", err, infra.ErrUserNotFound)
+	}
+}
+
+func TestAddNewInvoiceGeneratesSequentialNumbers(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	// a manual number the counter will reach is skipped
+	manual := newTestInvoice("draft", 100)
+	manual.InvoiceNumber = domain.SequentialInvoiceNumber(2)
+	if err := repo.AddNewInvoice(db, userID, manual); err != nil {
+		t.Fatalf("AddNewInvoice: %v", err)
+	}
+
+	var numbers []string
+	for range 3 {
+		invoice := newTestInvoice("draft", 100)
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+		numbers = append(numbers, invoice.InvoiceNumber)
+	}
+
+	want := []string{"INV-000001", "INV-000003", "INV-000004"}
+	for idx := range want {
+		if numbers[idx] != want[idx] {
+			t.Errorf("generated numbers = %v, want %v", numbers, want)
+			break
+		}
+	}
+}
+
+func TestAddNewInvoiceGeneratesNumbersConcurrently(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	const creations = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, creations)
+	for range creations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.AddNewInvoice(db, userID, newTestInvoice("draft", 100))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	var user struct {
+		Invoices []domain.Invoice `bson:"invoices"`
+	}
+	if err := UserData(db, "user").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		t.Fatalf("finding user: %v", err)
+	}
+	used := map[string]bool{}
+	for _, invoice := range user.Invoices {
+		used[invoice.InvoiceNumber] = true
+	}
+	// every number from the first to the last was given out exactly once
+	for counter := int64(1); counter <= creations; counter++ {
+		if number := domain.SequentialInvoiceNumber(counter); !used[number] {
+			t.Errorf("invoice number %s was skipped; numbers used: %v", number, used)
+		}
+	}
+	if len(user.Invoices) != creations {
+		t.Errorf("%d invoices saved, want %d", len(user.Invoices), creations)
+	}
+}
+
+func TestAddNewInvoices(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	batch := []*domain.Invoice{newTestInvoice("draft", 100), newTestInvoice("pending", 200), newTestInvoice("draft", 300)}
+	if err := repo.AddNewInvoices(db, userID, batch); err != nil {
+		t.Fatalf("AddNewInvoices: %v", err)
+	}
+	for idx, invoice := range batch {
+		if want := domain.SequentialInvoiceNumber(int64(idx + 1)); invoice.InvoiceNumber != want {
+			t.Errorf("invoice %d number = %q, want %q", idx, invoice.InvoiceNumber, want)
+		}
+		if _, err := repo.FindUserInvoiceByID(db, userID, invoice.InvoiceID); err != nil {
+			t.Errorf("FindUserInvoiceByID() of invoice %d: %v", idx, err)
+		}
+	}
+
+	// the second invoice reuses a saved number, so the whole batch is rolled back
+	duplicate := newTestInvoice("draft", 100)
+	duplicate.InvoiceNumber = batch[0].InvoiceNumber
+	rejected := []*domain.Invoice{newTestInvoice("draft", 100), duplicate}
+	if err := repo.AddNewInvoices(db, userID, rejected); !errors.Is(err, infra.ErrDuplicateInvoiceNumber) {
+		t.Fatalf("AddNewInvoices() with a used number: error = %v, want %v", err, infra.ErrDuplicateInvoiceNumber)
+	}
+	for idx, invoice := range rejected {
+		if _, err := repo.FindUserInvoiceByID(db, userID, invoice.InvoiceID); !errors.Is(err, infra.ErrInvoiceNotFound) {
+			t.Errorf("FindUserInvoiceByID() of rejected invoice %d: error = %v, want %v", idx, err, infra.ErrInvoiceNotFound)
+		}
+	}
+}
+
+func TestInvoiceDashboard(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	today := time.Now()
+	issuedOn := func(status string, days int, total float64) *domain.Invoice {
+		invoice := newTestInvoice(status, total)
+		invoice.IssueDate = today.AddDate(0, 0, days).Format("2006-01-02")
+		invoice.DueDate = today.AddDate(0, 0, days+30).Format("2006-01-02")
+		return invoice
+	}
+
+	invoices := []*domain.Invoice{
+		issuedOn("draft", 3, 10),
+		issuedOn("draft", 1, 20),
+		issuedOn("pending", 2, 40),
+		issuedOn("issued", -5, 80),
+		issuedOn("paid", -20, 160),
+		issuedOn("paid", -40, 320),
+		issuedOn("cancelled", 4, 640),
+	}
+	for _, invoice := range invoices {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	got, err := repo.InvoiceDashboard(db, userID, 2)
+	if err != nil {
+		t.Fatalf("InvoiceDashboard: %v", err)
+	}
+
+	summary, err := repo.InvoiceStatSummary(db, userID)
+	if err != nil {
+		t.Fatalf("InvoiceStatSummary: %v", err)
+	}
+	if got.Summary != *summary {
+		t.Errorf("Summary = %+v, want the InvoiceStatSummary %+v", got.Summary, *summary)
+	}
+
+	wantCounts := map[string]int64{"draft": 2, "pending": 1, "issued": 1, "paid": 2, "cancelled": 1}
+	var counted int64
+	for _, status := range domain.InvoiceStatuses {
+		if got.StatusCounts[status] != wantCounts[status] {
+			t.Errorf("StatusCounts[%s] = %d, want %d", status, got.StatusCounts[status], wantCounts[status])
+		}
+		counted += got.StatusCounts[status]
+	}
+	if counted != int64(len(invoices)) {
+		t.Errorf("status counts add up to %d, want %d", counted, len(invoices))
+	}
+
+	// the first two invoices GetIssueInvoiceList lists, soonest first
+	want := []*domain.Invoice{invoices[1], invoices[2]}
+	if len(got.Upcoming) != len(want) {
+		t.Fatalf("Upcoming holds %d invoices, want %d", len(got.Upcoming), len(want))
+	}
+	for idx := range want {
+		if got.Upcoming[idx].InvoiceID != want[idx].InvoiceID || got.Upcoming[idx].TotalAmountDue != want[idx].TotalAmountDue {
+			t.Errorf("Upcoming[%d] = %s of %v, want %s of %v",
+				idx, got.Upcoming[idx].InvoiceID, got.Upcoming[idx].TotalAmountDue, want[idx].InvoiceID, want[idx].TotalAmountDue)
+		}
+	}
+	if got.RecentActivities == nil || len(got.RecentActivities) != 0 {
+		t.Errorf("RecentActivities = %v, want an empty list", got.RecentActivities)
+	}
+}
+
+func TestInvoiceDashboardWithoutInvoices(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	got, err := repo.InvoiceDashboard(db, userID, 5)
+	if err != nil {
+		t.Fatalf("InvoiceDashboard: %v", err)
+	}
+	if got.Summary != (domain.InvoiceSummary{}) || len(got.Upcoming) != 0 {
+		t.Errorf("InvoiceDashboard() = %+v, want a zeroed dashboard", got)
+	}
+	for _, status := range domain.InvoiceStatuses {
+		if count, ok := got.StatusCounts[status]; !ok || count != 0 {
+			t.Errorf("StatusCounts[%s] = %d (present %v), want 0", status, count, ok)
+		}
+	}
+
+	if _, err := repo.InvoiceDashboard(db, primitive.NewObjectID().Hex(), 5); !errors.Is(err, infra.ErrNoDataFound) {
+		t.Errorf("InvoiceDashboard() for an unknown user: error = %v, want %v", err, infra.ErrNoDataFound)
+	}
+}
+
+func TestTaxByJurisdiction(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	paidAt := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	paid := func(total float64, tax *domain.InvoiceTax) *domain.Invoice {
+		invoice := newTestInvoice("paid", total)
+		invoice.PaidAt = &paidAt
+		invoice.Tax = tax
+		return invoice
+	}
+	invoices := []*domain.Invoice{
+		paid(110, &domain.InvoiceTax{Jurisdiction: "US-CA", Rate: 10, Amount: 10}),
+		paid(220, &domain.InvoiceTax{Jurisdiction: "US-CA", Rate: 10, Amount: 20}),
+		paid(105, &domain.InvoiceTax{Rate: 5, Amount: 5}),
+		paid(100, nil),
+	}
+	// 100 of items and 50 of taxable expenses are taxed, the 25 courier expense is not
+	withExpenses := paid(190, &domain.InvoiceTax{Jurisdiction: "NG", Rate: 10, Amount: 15})
+	withExpenses.Expenses = []domain.Expense{
+		{Description: "Courier", Amount: 25},
+		{Description: "Materials", Amount: 50, Taxable: true},
+	}
+	invoices = append(invoices, withExpenses)
+	unpaid := newTestInvoice("issued", 110)
+	unpaid.Tax = &domain.InvoiceTax{Jurisdiction: "US-CA", Rate: 10, Amount: 10}
+	invoices = append(invoices, unpaid)
+
+	for _, invoice := range invoices {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	got, err := repo.TaxByJurisdiction(db, userID, paidAt.AddDate(0, -1, 0), paidAt.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("TaxByJurisdiction: %v", err)
+	}
+
+	want := []domain.JurisdictionTax{
+		{Jurisdiction: "NG", Rate: 10, Currency: "USD", TaxableAmount: 150, TaxCollected: 15, InvoiceCount: 1},
+		{Jurisdiction: "US-CA", Rate: 10, Currency: "USD", TaxableAmount: 300, TaxCollected: 30, InvoiceCount: 2},
+		{Jurisdiction: domain.UnassignedJurisdiction, Rate: 5, Currency: "USD", TaxableAmount: 100, TaxCollected: 5, InvoiceCount: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("TaxByJurisdiction() = %+v, want %+v", got, want)
+	}
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Errorf("TaxByJurisdiction()[%d] = %+v, want %+v", idx, got[idx], want[idx])
+		}
+	}
+
+	outside, err := repo.TaxByJurisdiction(db, userID, paidAt.AddDate(1, 0, 0), paidAt.AddDate(2, 0, 0))
+	if err != nil {
+		t.Fatalf("TaxByJurisdiction: %v", err)
+	}
+	if len(outside) != 0 {
+		t.Errorf("TaxByJurisdiction() outside the window = %+v, want none", outside)
+	}
+}
+
+func TestSetInvoiceCustomStatus(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	invoice := newTestInvoice("issued", 100)
+	if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+		t.Fatalf("AddNewInvoice: %v", err)
+	}
+	label := &domain.CustomStatus{Name: "partially_paid", Bucket: "issued"}
+
+	if err := repo.SetInvoiceCustomStatus(db, userID, invoice.InvoiceID, "paid", label); !errors.Is(err, infra.ErrInvoiceStatusConflict) {
+		t.Errorf("SetInvoiceCustomStatus() with a stale status: error = %v, want %v", err, infra.ErrInvoiceStatusConflict)
+	}
+
+	if err := repo.SetInvoiceCustomStatus(db, userID, invoice.InvoiceID, "issued", label); err != nil {
+		t.Fatalf("SetInvoiceCustomStatus: %v", err)
+	}
+	got, err := repo.FindUserInvoiceByID(db, userID, invoice.InvoiceID)
+	if err != nil {
+		t.Fatalf("FindUserInvoiceByID: %v", err)
+	}
+	if got.Status != "issued" || got.EffectiveStatus() != "partially_paid" {
+		t.Errorf("Status = %q, EffectiveStatus() = %q, want issued and partially_paid", got.Status, got.EffectiveStatus())
+	}
+
+	if err := repo.SetInvoiceCustomStatus(db, userID, invoice.InvoiceID, "issued", nil); err != nil {
+		t.Fatalf("SetInvoiceCustomStatus: %v", err)
+	}
+	got, err = repo.FindUserInvoiceByID(db, userID, invoice.InvoiceID)
+	if err != nil {
+		t.Fatalf("FindUserInvoiceByID: %v", err)
+	}
+	if got.CustomStatus != nil {
+		t.Errorf("CustomStatus = %+v after removing the label, want nil", got.CustomStatus)
+	}
+}
+
+func TestMonthlyRevenue(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	paidAt := func(total float64, at time.Time) *domain.Invoice {
+		invoice := newTestInvoice("paid", total)
+		invoice.PaidAt = &at
+		return invoice
+	}
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2023, month, day, 12, 0, 0, 0, time.UTC)
+	}
+
+	invoices := []*domain.Invoice{
+		paidAt(100, day(time.January, 3)),
+		paidAt(50.25, day(time.January, 31)),
+		paidAt(200, day(time.March, 1)),
+		paidAt(400, day(time.April, 30)),
+		// before and after the window
+		paidAt(800, day(time.December, 31).AddDate(-1, 0, 0)),
+		paidAt(1600, day(time.May, 1)),
+		// not paid
+		newTestInvoice("issued", 3200),
+	}
+	for _, invoice := range invoices {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	from := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, time.May, 1, 0, 0, 0, 0, time.UTC)
+	got, err := repo.MonthlyRevenue(db, userID, from, to)
+	if err != nil {
+		t.Fatalf("MonthlyRevenue: %v", err)
+	}
+
+	// February has nothing paid, so it is left for NewRevenueSeries to fill in
+	want := []domain.MonthlyRevenue{
+		{Month: "2023-01", Revenue: 150.25, InvoiceCount: 2},
+		{Month: "2023-03", Revenue: 200, InvoiceCount: 1},
+		{Month: "2023-04", Revenue: 400, InvoiceCount: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("MonthlyRevenue() = %+v, want %+v", got, want)
+	}
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Errorf("month %d = %+v, want %+v", idx, got[idx], want[idx])
+		}
+	}
+
+	series := domain.NewRevenueSeries(from, 4, got)
+	if series[1] != (domain.MonthlyRevenue{Month: "2023-02"}) {
+		t.Errorf("zero filled month = %+v, want an empty 2023-02", series[1])
+	}
+}
+
+func TestTopCustomers(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	billed := func(status, name, email string, total float64) *domain.Invoice {
+		invoice := newTestInvoice(status, total)
+		invoice.Customer = domain.CustomerDetails{Name: name, Email: email}
+		return invoice
+	}
+
+	invoices := []*domain.Invoice{
+		billed("paid", "Grace Hopper", "grace@example.com", 300),
+		billed("paid", "Grace Hopper", "Grace@Example.com", 200),
+		billed("paid", "Alan Turing", "alan@example.com", 450),
+		billed("paid", "Katherine Johnson", "katherine@example.com", 100),
+		billed("paid", "Edsger Dijkstra", "edsger@example.com", 100),
+		billed("paid", "Edsger Dijkstra", "edsger@example.com", 0),
+		billed("issued", "Katherine Johnson", "katherine@example.com", 900),
+		// drafts never count
+		billed("draft", "Barbara Liskov", "barbara@example.com", 5000),
+	}
+	for _, invoice := range invoices {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		paidOnly bool
+		limit    int64
+		want     []domain.TopCustomer
+	}{
+		{
+			name:     "paid",
+			paidOnly: true,
+			limit:    5,
+			want: []domain.TopCustomer{
+				{Email: "grace@example.com", Name: "Grace Hopper", Total: 500, InvoiceCount: 2},
+				{Email: "alan@example.com", Name: "Alan Turing", Total: 450, InvoiceCount: 1},
+				// a tie on the total goes to the customer with more invoices
+				{Email: "edsger@example.com", Name: "Edsger Dijkstra", Total: 100, InvoiceCount: 2},
+				{Email: "katherine@example.com", Name: "Katherine Johnson", Total: 100, InvoiceCount: 1},
+			},
+		},
+		{
+			name:     "paid, limited",
+			paidOnly: true,
+			limit:    2,
+			want: []domain.TopCustomer{
+				{Email: "grace@example.com", Name: "Grace Hopper", Total: 500, InvoiceCount: 2},
+				{Email: "alan@example.com", Name: "Alan Turing", Total: 450, InvoiceCount: 1},
+			},
+		},
+		{
+			name:  "all sent invoices",
+			limit: 2,
+			want: []domain.TopCustomer{
+				{Email: "katherine@example.com", Name: "Katherine Johnson", Total: 1000, InvoiceCount: 2},
+				{Email: "grace@example.com", Name: "Grace Hopper", Total: 500, InvoiceCount: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.TopCustomers(db, userID, tt.paidOnly, tt.limit)
+			if err != nil {
+				t.Fatalf("TopCustomers: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("TopCustomers() = %+v, want %+v", got, tt.want)
+			}
+			for idx := range tt.want {
+				if got[idx] != tt.want[idx] {
+					t.Errorf("customer %d = %+v, want %+v", idx, got[idx], tt.want[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestPatchInvoice(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	notes := "Paid by bank transfer"
+	discount := 10.0
+
+	tests := []struct {
+		name      string
+		status    string
+		patch     domain.InvoicePatch
+		wantNotes string
+		wantTotal float64
+		wantErr   error
+	}{
+		{name: "notes only", status: "draft", patch: domain.InvoicePatch{Notes: &notes}, wantNotes: notes, wantTotal: 300},
+		{name: "discount", status: "pending", patch: domain.InvoicePatch{Discount: &discount}, wantTotal: 270},
+		{name: "issued invoice", status: "issued", patch: domain.InvoicePatch{Notes: &notes}, wantTotal: 300, wantErr: infra.ErrInvoiceStatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := newTestInvoice(tt.status, 300)
+			saved.Items = []domain.Item{{Description: "Design work", Quantity: 2, UnitPrice: 150, TotalPrice: 300}}
+			if err := repo.AddNewInvoice(db, userID, saved); err != nil {
+				t.Fatalf("AddNewInvoice: %v", err)
+			}
+
+			// the patch is applied as a draft, so the repository has to catch the stored status
+			invoice := *saved
+			invoice.Status = "draft"
+			if err := invoice.ApplyPatch(tt.patch); err != nil {
+				t.Fatalf("ApplyPatch: %v", err)
+			}
+			if err := repo.PatchInvoice(db, userID, &invoice, tt.patch); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchInvoice() error = %v, want %v", err, tt.wantErr)
+			}
+
+			got, err := repo.FindUserInvoiceByID(db, userID, saved.InvoiceID)
+			if err != nil {
+				t.Fatalf("FindUserInvoiceByID: %v", err)
+			}
+			var stored domain.Invoice
+			if err := InvoiceData(db, "invoice").FindOne(context.Background(), bson.M{"invoice_id": saved.InvoiceID}).Decode(&stored); err != nil {
+				t.Fatalf("finding invoice: %v", err)
+			}
+			for where, invoice := range map[string]*domain.Invoice{"user document": got, "invoice collection": &stored} {
+				if invoice.Notes != tt.wantNotes || invoice.TotalAmountDue != tt.wantTotal {
+					t.Errorf("%s: notes %q, total %v; want notes %q, total %v", where, invoice.Notes, invoice.TotalAmountDue, tt.wantNotes, tt.wantTotal)
+				}
+				if invoice.Status != tt.status || invoice.DueDate != saved.DueDate || len(invoice.Items) != 1 {
+					t.Errorf("%s: fields outside the patch changed: %+v", where, invoice)
+				}
+			}
+		})
+	}
+}
+
+func TestAmendInvoice(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	grace := 15 * time.Minute
+	notes := "Corrected the bank account"
+
+	tests := []struct {
+		name      string
+		status    string
+		issuedAt  time.Time
+		wantNotes string
+		wantErr   error
+	}{
+		{name: "within the grace period", status: "issued", issuedAt: now.Add(-5 * time.Minute), wantNotes: notes},
+		{name: "after the grace period", status: "issued", issuedAt: now.Add(-30 * time.Minute), wantErr: infra.ErrInvoiceStatusConflict},
+		{name: "paid meanwhile", status: "paid", issuedAt: now.Add(-5 * time.Minute), wantErr: infra.ErrInvoiceStatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := newTestInvoice(tt.status, 300)
+			if !tt.issuedAt.IsZero() {
+				saved.IssuedAt = &tt.issuedAt
+			}
+			if err := repo.AddNewInvoice(db, userID, saved); err != nil {
+				t.Fatalf("AddNewInvoice: %v", err)
+			}
+
+			// the amendment is checked against the stored invoice, not the one it was applied to
+			invoice := *saved
+			invoice.Notes = notes
+			patch := domain.InvoicePatch{Notes: &notes}
+			if err := repo.AmendInvoice(db, userID, &invoice, patch, now.Add(-grace)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AmendInvoice() error = %v, want %v", err, tt.wantErr)
+			}
+
+			got, err := repo.FindUserInvoiceByID(db, userID, saved.InvoiceID)
+			if err != nil {
+				t.Fatalf("FindUserInvoiceByID: %v", err)
+			}
+			if got.Notes != tt.wantNotes || got.Status != tt.status {
+				t.Errorf("stored invoice = %s with notes %q, want %s with notes %q", got.Status, got.Notes, tt.status, tt.wantNotes)
+			}
+		})
+	}
+}
+
+func TestSoftDeletedInvoices(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	kept := newTestInvoice("draft", 100)
+	deleted := newTestInvoice("pending", 50)
+	for _, invoice := range []*domain.Invoice{kept, deleted} {
+		if err := repo.AddNewInvoice(db, userID, invoice); err != nil {
+			t.Fatalf("AddNewInvoice: %v", err)
+		}
+	}
+
+	if err := repo.SoftDeleteInvoice(db, userID, deleted.InvoiceID, time.Now()); err != nil {
+		t.Fatalf("SoftDeleteInvoice: %v", err)
+	}
+
+	t.Run("left out of lists", func(t *testing.T) {
+		invoices, err := repo.FindAllInvoice(db, userID)
+		if err != nil {
+			t.Fatalf("FindAllInvoice: %v", err)
+		}
+		if len(invoices) != 1 || invoices[0].InvoiceID != kept.InvoiceID {
+			t.Errorf("FindAllInvoice() returned %d invoices, want only %s", len(invoices), kept.InvoiceID)
+		}
+
+		page, total, err := repo.FindInvoicePage(db, userID, domain.InvoiceFilter{}, 10, 0)
+		if err != nil {
+			t.Fatalf("FindInvoicePage: %v", err)
+		}
+		if total != 1 || len(page) != 1 {
+			t.Errorf("FindInvoicePage() = %d invoices of %d, want 1 of 1", len(page), total)
+		}
+	})
+
+	t.Run("not found by id", func(t *testing.T) {
+		if _, err := repo.FindUserInvoiceByID(db, userID, deleted.InvoiceID); !errors.Is(err, infra.ErrInvoiceNotFound) {
+			t.Errorf("FindUserInvoiceByID() error = %v, want %v", err, infra.ErrInvoiceNotFound)
+		}
+	})
+
+	t.Run("cannot be changed", func(t *testing.T) {
+		if err := repo.UpdateInvoiceStatusToIssued(db, userID, deleted.InvoiceID); err == nil {
+			t.Error("UpdateInvoiceStatusToIssued() on a deleted invoice returned no error")
+		}
+	})
+
+	t.Run("excluded from stats", func(t *testing.T) {
+		summary, err := repo.InvoiceStatSummary(db, userID)
+		if err != nil {
+			t.Fatalf("InvoiceStatSummary: %v", err)
+		}
+		if summary.TotalDraft != 100 || summary.TotalPending != 0 {
+			t.Errorf("InvoiceStatSummary() draft = %v, pending = %v, want 100 and 0", summary.TotalDraft, summary.TotalPending)
+		}
+	})
+
+	t.Run("restored", func(t *testing.T) {
+		if err := repo.RestoreDeletedInvoice(db, userID, deleted.InvoiceID); err != nil {
+			t.Fatalf("RestoreDeletedInvoice: %v", err)
+		}
+
+		restored, err := repo.FindUserInvoiceByID(db, userID, deleted.InvoiceID)
+		if err != nil {
+			t.Fatalf("FindUserInvoiceByID: %v", err)
+		}
+		if restored.IsDeleted() {
+			t.Error("restored invoice is still marked as deleted")
+		}
+
+		summary, err := repo.InvoiceStatSummary(db, userID)
+		if err != nil {
+			t.Fatalf("InvoiceStatSummary: %v", err)
+		}
+		if summary.TotalPending != 50 {
+			t.Errorf("InvoiceStatSummary() pending = %v after restore, want 50", summary.TotalPending)
+		}
+
+		if err := repo.RestoreDeletedInvoice(db, userID, deleted.InvoiceID); !errors.Is(err, infra.ErrInvoiceNotFound) {
+			t.Errorf("restoring an invoice that is not deleted: error = %v, want %v", err, infra.ErrInvoiceNotFound)
+		}
+	})
+}
+
+func TestUpdateInvoiceBeforeDueDate(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &InvoiceRepository{}
+
+	tests := []struct {
+		name       string
+		stored     string
+		sent       string
+		wantStatus string
+		wantErr    error
+	}{
+		{name: "draft keeps its status", stored: "draft", sent: "", wantStatus: "draft"},
+		{name: "pending keeps its status", stored: "pending", sent: "", wantStatus: "pending"},
+		{name: "draft moved to pending", stored: "draft", sent: "pending", wantStatus: "pending"},
+		{name: "issued is rejected", stored: "issued", sent: "", wantErr: infra.ErrInvoiceStatusConflict},
+		{name: "unknown invoice", sent: "", wantErr: infra.ErrInvoiceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := newTestInvoice(tt.stored, 100)
+			if tt.stored != "" {
+				if err := repo.AddNewInvoice(db, userID, stored); err != nil {
+					t.Fatalf("AddNewInvoice: %v", err)
+				}
+			}
+
+			updated := newTestInvoice(tt.sent, 250)
+			updated.InvoiceNumber = stored.InvoiceNumber
+
+			err := repo.UpdateInvoiceBeforeDueDate(db, userID, stored.InvoiceID, updated)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UpdateInvoiceBeforeDueDate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateInvoiceBeforeDueDate: %v", err)
+			}
+
+			got, err := repo.FindUserInvoiceByID(db, userID, stored.InvoiceID)
+			if err != nil {
+				t.Fatalf("FindUserInvoiceByID: %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if got.TotalAmountDue != 250 {
+				t.Errorf("TotalAmountDue = %v, want 250", got.TotalAmountDue)
+			}
+		})
+	}
+}
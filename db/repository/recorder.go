@@ -9,9 +9,13 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	infra "github.com/thebravebyte/numeris/db"
 	"github.com/thebravebyte/numeris/domain"
 )
 
+// activityCollection is the collection ActivityRepository records activities in
+const activityCollection = "activity"
+
 type ActivityRepository struct {
 }
 
@@ -28,58 +32,234 @@ type ActivityRepository struct {
 //   - An error if there was a problem saving the activity. If successful, returns nil.
 //     Note that this function will panic if it encounters an error during the save operation.
 func (r *ActivityRepository) Save(db *mongo.Client, activity *domain.Activity) error {
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-    defer cancel()
-
-    _, err := RecordActivityData(db, "activity").InsertOne(ctx, activity)
-    if err != nil {
-        panic(fmt.Errorf("error while saving application activity: %v", err))
-    }
-    return nil
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := RecordActivityData(db, activityCollection).InsertOne(ctx, activity)
+	if err != nil {
+		panic(fmt.Errorf("error while saving application activity: %v", err))
+	}
+	return nil
 }
 
+// MigrateActivityUserID renames the userid field of activities saved before the activity schema used user_id,
+// so they are found by the queries of ActivityRepository. Activities already migrated are left alone.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//
+// Returns:
+//   - The number of activities migrated.
+//   - An error if there was a problem updating the activities.
+func MigrateActivityUserID(db *mongo.Client) (int64, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancelCtx()
+
+	result, err := RecordActivityData(db, activityCollection).UpdateMany(ctx,
+		bson.M{"userid": bson.M{"$exists": true}},
+		bson.M{"$rename": bson.M{"userid": "user_id"}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error migrating activity user ids: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
 
 // GetInvoiceActivities retrieves invoice-related activities for a specific user.
 //
 // This function queries the database for activities related to creating, issuing,
-// and updating invoices for a given user. The results are sorted by timestamp
-// in descending order and limited to the specified number of entries.
+// and updating invoices for a given user, optionally within a time window. The results
+// are sorted by timestamp in descending order and limited to the specified number of entries.
 //
 // Parameters:
 //   - db: A pointer to the MongoDB client used for database operations.
 //   - userID: A string representing the ID of the user whose activities are being retrieved.
+//   - from: The earliest timestamp to return, inclusive; the zero time leaves it open.
+//   - to: The latest timestamp to return, inclusive; the zero time leaves it open.
 //   - limit: An int64 value specifying the maximum number of activities to return.
+//   - offset: An int64 value specifying how many activities to skip.
 //
 // Returns:
 //   - A slice of domain.Activity containing the retrieved invoice activities.
+//   - The total number of matching activities, ignoring limit and offset.
+//   - An error if there was a problem querying the database or decoding the results.
+func (i *InvoiceRepository) GetInvoiceActivities(db *mongo.Client, userID string, from, to time.Time, limit, offset int64) ([]domain.Activity, int64, error) {
+	defer logSlowQuery("GetInvoiceActivities", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	filter := bson.M{
+		"user_id": userID,
+		"action": bson.M{
+			"$in": []string{
+				"create_invoice_activity",
+				"issue_invoice_activity",
+				"update_invoice_activity",
+			},
+		},
+	}
+	window := bson.M{}
+	if !from.IsZero() {
+		window["$gte"] = from
+	}
+	if !to.IsZero() {
+		window["$lte"] = to
+	}
+	if len(window) > 0 {
+		filter["timestamp"] = window
+	}
+
+	total, err := RecordActivityData(db, activityCollection).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error counting invoice activities: %v", err)
+	}
+
+	options := options.Find().SetSort(bson.M{"timestamp": -1}).SetSkip(offset).SetLimit(limit)
+
+	cursor, err := RecordActivityData(db, activityCollection).Find(ctx, filter, options)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error finding invoice activities: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var activities []domain.Activity
+	if err = cursor.All(ctx, &activities); err != nil {
+		return nil, 0, fmt.Errorf("error decoding invoice activities: %v", err)
+	}
+
+	return activities, total, nil
+}
+
+// GetUserActivities retrieves every activity recorded for a user, newest first.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//   - userID: A string representing the ID of the user whose activities are being retrieved.
+//
+// Returns:
+//   - A slice of domain.Activity containing the user's activities.
+//   - An error if there was a problem querying the database or decoding the results.
+func (r *ActivityRepository) GetUserActivities(db *mongo.Client, userID string) ([]domain.Activity, error) {
+	defer logSlowQuery("GetUserActivities", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelCtx()
+
+	opts := options.Find().SetSort(bson.M{"timestamp": -1})
+
+	cursor, err := RecordActivityData(db, activityCollection).Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding user activities: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	activities := make([]domain.Activity, 0)
+	if err = cursor.All(ctx, &activities); err != nil {
+		return nil, fmt.Errorf("error decoding user activities: %v", err)
+	}
+
+	return activities, nil
+}
+
+// FindActivities retrieves a page of the activities recorded for a user, newest first.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//   - userID: A string representing the ID of the user whose activities are being retrieved.
+//   - actions: The actions to return; every action is returned when it is empty.
+//   - limit: An int64 value specifying the maximum number of activities to return.
+//   - offset: An int64 value specifying how many activities to skip.
+//
+// Returns:
+//   - A slice of domain.Activity containing the retrieved activities.
+//   - The total number of matching activities, ignoring limit and offset.
 //   - An error if there was a problem querying the database or decoding the results.
-func (i *InvoiceRepository) GetInvoiceActivities(db *mongo.Client, userID string, limit int64) ([]domain.Activity, error) {
-    ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
-    defer cancelCtx()
-
-    filter := bson.M{
-        "userid": userID,
-        "action": bson.M{
-            "$in": []string{
-                "create_invoice_activity",
-                "issue_invoice_activity",
-                "update_invoice_activity",
-            },
-        },
-    }
-
-    options := options.Find().SetSort(bson.M{"timestamp": -1}).SetLimit(limit)
-
-    cursor, err := RecordActivityData(db, "activity").Find(ctx, filter, options)
-    if err != nil {
-        return nil, fmt.Errorf("error finding invoice activities: %v", err)
-    }
-    defer cursor.Close(ctx)
-
-    var activities []domain.Activity
-    if err = cursor.All(ctx, &activities); err != nil {
-        return nil, fmt.Errorf("error decoding invoice activities: %v", err)
-    }
-
-    return activities, nil
-}
\ No newline at end of file
+func (r *ActivityRepository) FindActivities(db *mongo.Client, userID string, actions []string, limit, offset int64) ([]domain.Activity, int64, error) {
+	defer logSlowQuery("FindActivities", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	filter := bson.M{"user_id": userID}
+	if len(actions) > 0 {
+		filter["action"] = bson.M{"$in": actions}
+	}
+
+	total, err := RecordActivityData(db, activityCollection).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error counting user activities: %v", err)
+	}
+
+	// the id breaks ties between activities recorded at the same time, so pages do not overlap
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(offset).
+		SetLimit(limit)
+
+	cursor, err := RecordActivityData(db, activityCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error finding user activities: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	activities := make([]domain.Activity, 0)
+	if err = cursor.All(ctx, &activities); err != nil {
+		return nil, 0, fmt.Errorf("error decoding user activities: %v", err)
+	}
+
+	return activities, total, nil
+}
+
+// CountByAction counts a user's activities between from and to, grouped by action.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//   - userID: A string representing the ID of the user whose activities are being counted.
+//   - from: The start of the period, inclusive.
+//   - to: The end of the period, inclusive.
+//
+// Returns:
+//   - A map of action to count. Every action in infra.KnownActivities is present, with zero when it did not occur.
+//   - An error if there was a problem querying the database or decoding the results.
+func (r *ActivityRepository) CountByAction(db *mongo.Client, userID string, from, to time.Time) (map[string]int64, error) {
+	defer logSlowQuery("CountByAction", userID, time.Now())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{
+			"user_id":   userID,
+			"timestamp": bson.M{"$gte": from, "$lte": to},
+		}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":   "$action",
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := RecordActivityData(db, activityCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating activity counts: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Action string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("error decoding activity counts: %v", err)
+	}
+
+	counts := make(map[string]int64, len(infra.KnownActivities))
+	for _, action := range infra.KnownActivities {
+		counts[action] = 0
+	}
+	for _, result := range results {
+		counts[result.Action] = result.Count
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// testActivityUser returns a user id to record activities for; its activities are removed when the test ends.
+func testActivityUser(t *testing.T, db *mongo.Client) string {
+	t.Helper()
+
+	userID := primitive.NewObjectID().Hex()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, _ = RecordActivityData(db, activityCollection).DeleteMany(ctx, bson.M{"user_id": userID})
+	})
+	return userID
+}
+
+func TestRecordActivityDataCollection(t *testing.T) {
+	db := failingClient(t)
+
+	for _, name := range []string{activityCollection, "activity_archive"} {
+		if got := RecordActivityData(db, name).Name(); got != name {
+			t.Errorf("RecordActivityData(%q) is collection %q", name, got)
+		}
+	}
+}
+
+func TestSaveRecordsInActivityCollection(t *testing.T) {
+	db := testClient(t)
+	userID := testActivityUser(t, db)
+	repo := &ActivityRepository{}
+
+	activity := &domain.Activity{UserID: userID, Action: infra.CreateInvoiceActivity, Timestamp: time.Now()}
+	if err := repo.Save(db, activity); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := db.Database("numeris_book").Collection(activityCollection).CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		t.Fatalf("CountDocuments: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("%d activities recorded in %q, want 1", count, activityCollection)
+	}
+}
+
+// saveTestActivities records action for userID once per entry of actions, a minute apart starting at start,
+// and returns the saved activities.
+func saveTestActivities(t *testing.T, db *mongo.Client, userID string, start time.Time, actions ...string) []domain.Activity {
+	t.Helper()
+
+	repo := &ActivityRepository{}
+	saved := make([]domain.Activity, 0, len(actions))
+	for idx, action := range actions {
+		activity := domain.Activity{UserID: userID, Action: action, Timestamp: start.Add(time.Duration(idx) * time.Minute)}
+		if err := repo.Save(db, &activity); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		saved = append(saved, activity)
+	}
+	return saved
+}
+
+func TestFindActivities(t *testing.T) {
+	db := testClient(t)
+	userID := testActivityUser(t, db)
+	otherUserID := testActivityUser(t, db)
+	repo := &ActivityRepository{}
+
+	start := time.Now().UTC().Truncate(time.Millisecond).Add(-time.Hour)
+	// saved oldest first, so they are returned in the reverse order
+	saveTestActivities(t, db, userID, start,
+		infra.UserCreatedAccountActivity,
+		infra.UserLoginActivity,
+		infra.CreateInvoiceActivity,
+		infra.DownloadInvoiceActivity,
+		infra.UserLoginActivity,
+		infra.DeleteInvoiceActivity,
+	)
+	saveTestActivities(t, db, otherUserID, start, infra.UserLoginActivity)
+
+	tests := []struct {
+		name        string
+		actions     []string
+		limit       int64
+		offset      int64
+		wantActions []string
+		wantTotal   int64
+	}{
+		{
+			name:  "every action",
+			limit: 10,
+			wantActions: []string{
+				infra.DeleteInvoiceActivity, infra.UserLoginActivity, infra.DownloadInvoiceActivity,
+				infra.CreateInvoiceActivity, infra.UserLoginActivity, infra.UserCreatedAccountActivity,
+			},
+			wantTotal: 6,
+		},
+		{
+			name:        "limited",
+			limit:       2,
+			wantActions: []string{infra.DeleteInvoiceActivity, infra.UserLoginActivity},
+			wantTotal:   6,
+		},
+		{
+			name:        "second page",
+			limit:       2,
+			offset:      2,
+			wantActions: []string{infra.DownloadInvoiceActivity, infra.CreateInvoiceActivity},
+			wantTotal:   6,
+		},
+		{
+			name:        "filtered",
+			actions:     []string{infra.UserLoginActivity, infra.UserCreatedAccountActivity},
+			limit:       10,
+			wantActions: []string{infra.UserLoginActivity, infra.UserLoginActivity, infra.UserCreatedAccountActivity},
+			wantTotal:   3,
+		},
+		{
+			name:        "action never recorded",
+			actions:     []string{infra.InvoicePaidActivity},
+			limit:       10,
+			wantActions: []string{},
+			wantTotal:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activities, total, err := repo.FindActivities(db, userID, tt.actions, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("FindActivities: %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+
+			got := make([]string, 0, len(activities))
+			for idx, activity := range activities {
+				if activity.UserID != userID {
+					t.Errorf("activity of user %q returned", activity.UserID)
+				}
+				if idx > 0 && activity.Timestamp.After(activities[idx-1].Timestamp) {
+					t.Errorf("activity at %v returned after the older one at %v", activity.Timestamp, activities[idx-1].Timestamp)
+				}
+				got = append(got, activity.Action)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantActions, ",") {
+				t.Errorf("actions = %v, want %v", got, tt.wantActions)
+			}
+		})
+	}
+}
+
+func TestActivityRoundTrip(t *testing.T) {
+	db := testClient(t)
+	userID := testActivityUser(t, db)
+
+	saved := &domain.Activity{
+		UserID:    userID,
+		Action:    infra.CreateInvoiceActivity,
+		Timestamp: time.Now().UTC().Truncate(time.Millisecond),
+		Metadata: map[string]interface{}{
+			"invoiceID":     "6650a1f2c3d4e5f601234567",
+			"invoiceNumber": "INV-000042",
+		},
+	}
+	if err := (&ActivityRepository{}).Save(db, saved); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	activities, total, err := (&InvoiceRepository{}).GetInvoiceActivities(db, userID, time.Time{}, time.Time{}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetInvoiceActivities: %v", err)
+	}
+	if total != 1 || len(activities) != 1 {
+		t.Fatalf("GetInvoiceActivities() returned %d of %d activities, want 1", len(activities), total)
+	}
+
+	got := activities[0]
+	if got.UserID != saved.UserID || got.Action != saved.Action || !got.Timestamp.Equal(saved.Timestamp) {
+		t.Errorf("activity = %s %s at %v, want %s %s at %v", got.UserID, got.Action, got.Timestamp, saved.UserID, saved.Action, saved.Timestamp)
+	}
+	for key, want := range saved.Metadata {
+		if got.Metadata[key] != want {
+			t.Errorf("metadata %s = %v, want %v", key, got.Metadata[key], want)
+		}
+	}
+}
+
+func TestGetInvoiceActivitiesWindow(t *testing.T) {
+	db := testClient(t)
+	userID := testActivityUser(t, db)
+	repo := &InvoiceRepository{}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	// one activity a minute, from 09:00 to 09:04
+	saved := saveTestActivities(t, db, userID, start,
+		infra.CreateInvoiceActivity,
+		infra.UpdateInvoiceActivity,
+		infra.IssueInvoiceActivity,
+		infra.UpdateInvoiceActivity,
+		infra.CreateInvoiceActivity,
+	)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []time.Time
+	}{
+		{name: "open", want: []time.Time{saved[4].Timestamp, saved[3].Timestamp, saved[2].Timestamp, saved[1].Timestamp, saved[0].Timestamp}},
+		{name: "bounds included", from: saved[1].Timestamp, to: saved[3].Timestamp, want: []time.Time{saved[3].Timestamp, saved[2].Timestamp, saved[1].Timestamp}},
+		{name: "from only", from: saved[3].Timestamp.Add(-time.Second), want: []time.Time{saved[4].Timestamp, saved[3].Timestamp}},
+		{name: "to only", to: saved[0].Timestamp.Add(time.Second), want: []time.Time{saved[0].Timestamp}},
+		{name: "nothing in the window", from: start.Add(time.Hour), to: start.Add(2 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activities, total, err := repo.GetInvoiceActivities(db, userID, tt.from, tt.to, 10, 0)
+			if err != nil {
+				t.Fatalf("GetInvoiceActivities: %v", err)
+			}
+			if total != int64(len(tt.want)) || len(activities) != len(tt.want) {
+				t.Fatalf("GetInvoiceActivities() returned %d of %d activities, want %d", len(activities), total, len(tt.want))
+			}
+			for idx, activity := range activities {
+				if !activity.Timestamp.Equal(tt.want[idx]) {
+					t.Errorf("activity %d at %v, want %v", idx, activity.Timestamp, tt.want[idx])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	infra "github.com/thebravebyte/numeris/db"
+)
+
+// testDatabaseURIEnv names the environment variable holding the URI of the MongoDB deployment the repository
+// tests run against. The invoice repository uses transactions, so it has to be a replica set.
+const testDatabaseURIEnv = "NUMERIS_TEST_DATABASE_URI"
+
+// testClient connects to the test database, skipping the test when none is configured.
+func testClient(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	uri := os.Getenv(testDatabaseURIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set, skipping database test", testDatabaseURIEnv)
+	}
+
+	client, err := infra.Connect(context.Background(), uri, infra.PoolConfig{MaxPoolSize: 5, ConnectTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { infra.ShutDown(client) })
+	return client
+}
+
+// testUser inserts an empty user document and returns its id; the user and its invoices are removed when the
+// test ends.
+func testUser(t *testing.T, db *mongo.Client) string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userID := primitive.NewObjectID().Hex()
+	doc := bson.M{"_id": userID, "email": userID + "@example.com", "invoices": bson.A{}}
+	if _, err := UserData(db, "user").InsertOne(ctx, doc); err != nil {
+		t.Fatalf("inserting user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var user struct {
+			Invoices []struct {
+				InvoiceID string `bson:"invoice_id"`
+			} `bson:"invoices"`
+		}
+		if err := UserData(db, "user").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err == nil {
+			for _, invoice := range user.Invoices {
+				_, _ = InvoiceData(db, "invoice").DeleteOne(ctx, bson.M{"invoice_id": invoice.InvoiceID})
+			}
+		}
+		_, _ = UserData(db, "user").DeleteOne(ctx, bson.M{"_id": userID})
+	})
+	return userID
+}
+
+// failingClient returns a client whose operations all fail, as no server listens on its address. It lets the
+// error paths of the repositories be tested without a database.
+func failingClient(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	opts := options.Client().
+		ApplyURI("mongodb://127.0.0.1:1/?connect=direct").
+		SetServerSelectionTimeout(200 * time.Millisecond)
+	client, err := mongo.Connect(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("mongo.Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"log/slog"
+	"time"
+)
+
+// slowQueryThreshold is how long a repository operation may take before it is logged as slow.
+var slowQueryThreshold = 500 * time.Millisecond
+
+// SetSlowQueryThreshold changes how long a repository operation may take before it is logged as slow.
+// It is meant to be called once at startup, before any request is served.
+func SetSlowQueryThreshold(threshold time.Duration) {
+	slowQueryThreshold = threshold
+}
+
+// logSlowQuery logs a warning when the operation started at start took longer than the slow query threshold.
+// It is deferred at the top of an operation:
+//
+//	defer logSlowQuery("InvoiceStatSummary", userID, time.Now())
+func logSlowQuery(operation, userID string, start time.Time) {
+	duration := time.Since(start)
+	if duration < slowQueryThreshold {
+		return
+	}
+	slog.Warn("Slow database query", "operation", operation, "userID", userID, "duration", duration, "threshold", slowQueryThreshold)
+}
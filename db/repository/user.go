@@ -10,6 +10,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	infra "github.com/thebravebyte/numeris/db"
 	"github.com/thebravebyte/numeris/domain"
@@ -17,7 +18,6 @@ import (
 
 type UserRepository struct{}
 
-
 // AddUser adds a new user to the database or retrieves an existing user.
 // It first checks if a user with the given email already exists. If not, it adds the new user.
 // If the user exists, it returns the existing user information.
@@ -31,31 +31,31 @@ type UserRepository struct{}
 //   - A pointer to the domain.User struct containing the user information (either newly added or existing).
 //   - An error if any database operation fails, or nil if successful.
 func (repo *UserRepository) AddUser(db *mongo.Client, user *domain.User, email string) (*domain.User, error) {
-    ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
-    defer cancelCtx()
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
 
-    // existingUser variable is more of a placeholder for data received from the database
-    var existingUser infra.User
+	// existingUser variable is more of a placeholder for data received from the database
+	var existingUser infra.User
 
-    filter := bson.D{{Key: "email", Value: email}}
+	filter := bson.D{{Key: "email", Value: email}}
 
-    if err := UserData(db, "user").FindOne(ctx, filter).Decode(&existingUser); err != nil {
+	if err := UserData(db, "user").FindOne(ctx, filter).Decode(&existingUser); err != nil {
 
-        if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, mongo.ErrNoDocuments) {
 
-            _, err := UserData(db, "user").InsertOne(ctx, user)
-            if err != nil {
-                panic(fmt.Errorf("error while inserting user: %v", err))
-            }
+			_, err := UserData(db, "user").InsertOne(ctx, user)
+			if err != nil {
+				return nil, fmt.Errorf("error while inserting user: %w", err)
+			}
 
-            log.Printf("Inserted a new user document: %v", user)
-            return user, nil
-        }
-        panic(fmt.Errorf("error while finding user: %v", err))
-    }
+			log.Printf("Inserted a new user document: %v", user)
+			return user, nil
+		}
+		return nil, fmt.Errorf("error while finding user: %w", err)
+	}
 
-    user = infra.UserFromDB(existingUser)
-    return user, nil
+	user = infra.UserFromDB(existingUser)
+	return user, nil
 }
 
 // VerifyLogin function to verify the user login details with respect to the database
@@ -103,22 +103,318 @@ func (repo *UserRepository) SaveToken(db *mongo.Client, id string, accessToken s
 	return nil
 }
 
-// UpdatePassword updates the password of the user with the provided password
-func (repo *UserRepository) UpdatePassword(db *mongo.Client, email, password string) error {
+// IsCurrentToken reports whether accessToken is the token last saved for the user, so tokens replaced by a
+// newer login or cleared by a logout are no longer accepted.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//   - id: The unique identifier of the user.
+//   - accessToken: The token presented by the client.
+//
+// Returns:
+//   - true if the token is the user's current token; false if it is not or the user does not exist.
+//   - An error if the database operation fails.
+func (repo *UserRepository) IsCurrentToken(db *mongo.Client, id string, accessToken string) (bool, error) {
 	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelCtx()
 
-	filter := bson.D{{Key: "email", Value: email}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: password}}}}
+	count, err := UserData(db, "user").CountDocuments(ctx, bson.D{{Key: "_id", Value: id}, {Key: "token", Value: accessToken}})
+	if err != nil {
+		return false, fmt.Errorf("error checking token: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ClearToken removes the token saved for the user, so it is rejected from then on.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//   - id: The unique identifier of the user.
+//
+// Returns:
+//   - An error wrapping infra.ErrUserNotFound if the user does not exist, or any database error.
+func (repo *UserRepository) ClearToken(db *mongo.Client, id string) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelCtx()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "token", Value: ""}}}}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		slog.Error("Error while clearing token", "error", err)
+		return fmt.Errorf("%w:%q", err, infra.ErrInvalidTokenUpdate)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w:%q", infra.ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// UpdatePassword replaces the password of the user with the provided password hash. The saved token is cleared
+// in the same update, so tokens issued before the password changed are rejected from then on.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//   - id: The unique identifier of the user.
+//   - password: The hash of the new password.
+//
+// Returns:
+//   - An error wrapping infra.ErrUserNotFound if the user does not exist, or any database error.
+func (repo *UserRepository) UpdatePassword(db *mongo.Client, id, password string) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelCtx()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "password", Value: password},
+			{Key: "updated_at", Value: time.Now()},
+		}},
+		{Key: "$unset", Value: bson.D{{Key: "token", Value: ""}}},
+	}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		slog.Error("Error while updating password", "error", err)
+		return fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w:%q", infra.ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// FindByID retrieves a user by their unique identifier.
+func (repo *UserRepository) FindByID(db *mongo.Client, id string) (*domain.User, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
 
-	var result bson.M
-	err := UserData(db, "user").FindOneAndUpdate(ctx, filter, update).Decode(&result)
+	var result infra.User
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	err := UserData(db, "user").FindOne(ctx, filter).Decode(&result)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("%v", "cannot reset user password! this acc does not exist")
+			return nil, fmt.Errorf("%w:%q", infra.ErrUserNotFound, id)
 		}
-		slog.Error("Error while updating password", "error", err)
-		panic(err)
+		return nil, fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+
+	return infra.UserFromDB(result), nil
+}
+
+// UpdateEmailIdentity replaces the identity used on the user's outgoing emails.
+func (repo *UserRepository) UpdateEmailIdentity(db *mongo.Client, id string, identity *domain.EmailIdentity) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "email_identity", Value: identity},
+		{Key: "updated_at", Value: time.Now()},
+	}}}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w:%q", infra.ErrUserNotFound, id)
 	}
 	return nil
 }
+
+// SetStatusConfig replaces the custom invoice statuses of the user, checked with domain.StatusConfig.Validate.
+func (repo *UserRepository) SetStatusConfig(db *mongo.Client, id string, config domain.StatusConfig) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status_config", Value: config},
+		{Key: "updated_at", Value: time.Now()},
+	}}}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w:%q", infra.ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// UpdateProfile saves the name and phone number of a user changed with domain.User.UpdateProfile.
+func (repo *UserRepository) UpdateProfile(db *mongo.Client, user *domain.User) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	filter := bson.D{{Key: "_id", Value: user.ID}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "first_name", Value: user.FirstName},
+		{Key: "last_name", Value: user.LastName},
+		{Key: "phone_number", Value: user.PhoneNumber},
+		{Key: "updated_at", Value: user.UpdatedAt},
+	}}}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w:%q", infra.ErrUserNotFound, user.ID)
+	}
+	return nil
+}
+
+// SetCreditLimit sets the credit limit of a customer, replacing any limit already set for the same email.
+func (repo *UserRepository) SetCreditLimit(db *mongo.Client, id string, limit *domain.CreditLimit) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	// drop the previous limit of the customer and append the new one in a single update
+	others := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$credit_limits", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.customer_email", limit.CustomerEmail}},
+	}}
+	update := mongo.Pipeline{
+		bson.D{{Key: "$set", Value: bson.M{
+			"credit_limits": bson.M{"$concatArrays": bson.A{others, bson.A{limit}}},
+			"updated_at":    time.Now(),
+		}}},
+	}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, update)
+	if err != nil {
+		return fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w:%q", infra.ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// RemoveCreditLimit removes the credit limit of a customer.
+func (repo *UserRepository) RemoveCreditLimit(db *mongo.Client, id, customerEmail string) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{
+		{Key: "$pull", Value: bson.D{{Key: "credit_limits", Value: bson.D{{Key: "customer_email", Value: customerEmail}}}}},
+		{Key: "$set", Value: bson.D{{Key: "updated_at", Value: time.Now()}}},
+	}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w:%q", infra.ErrUserNotFound, id)
+	}
+	return nil
+}
+
+// VerifyEmail marks the email of the user holding the verification token as verified. The token is
+// removed at the same time, so it cannot be used twice.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//   - token: The verification token sent to the user.
+//
+// Returns:
+//   - A pointer to the domain.User whose email was verified.
+//   - An error wrapping infra.ErrVerificationTokenExpired if the token has expired,
+//     infra.ErrInvalidVerificationToken if it is unknown or already used, or any database error.
+func (repo *UserRepository) VerifyEmail(db *mongo.Client, token string) (*domain.User, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCtx()
+
+	hash := domain.HashVerificationToken(token)
+	now := time.Now()
+
+	filter := bson.D{
+		{Key: "verification_token", Value: hash},
+		{Key: "verification_expires_at", Value: bson.D{{Key: "$gt", Value: now}}},
+	}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "email_verified", Value: true},
+			{Key: "updated_at", Value: now},
+		}},
+		{Key: "$unset", Value: bson.D{
+			{Key: "verification_token", Value: ""},
+			{Key: "verification_expires_at", Value: ""},
+		}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var result infra.User
+	err := UserData(db, "user").FindOneAndUpdate(ctx, filter, update, opts).Decode(&result)
+	if err == nil {
+		return infra.UserFromDB(result), nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		slog.Error("Error while verifying email", "error", err)
+		return nil, fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+
+	// the token did not match a pending verification, tell an expired token from an unknown one
+	count, err := UserData(db, "user").CountDocuments(ctx, bson.D{{Key: "verification_token", Value: hash}})
+	if err != nil {
+		return nil, fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if count > 0 {
+		return nil, infra.ErrVerificationTokenExpired
+	}
+	return nil, infra.ErrInvalidVerificationToken
+}
+
+// ClaimRateLimit records that the user performs action at now, unless they already did within the last interval.
+// The time of the last claim is stored on the user document and taken with a single conditional update, so the
+// limit holds across server processes and concurrent requests.
+//
+// Parameters:
+//   - db: A pointer to the MongoDB client used for database operations.
+//   - id: The unique identifier of the user the limit applies to.
+//   - action: The name of the limited action, e.g. "export_data".
+//   - interval: How long after a claim the action is refused.
+//   - now: The time of the claim.
+//
+// Returns:
+//   - true if the claim was taken and the action may proceed, false if it was already taken within interval.
+//   - An error wrapping infra.ErrUserNotFound if the user does not exist, or any database error.
+func (repo *UserRepository) ClaimRateLimit(db *mongo.Client, id, action string, interval time.Duration, now time.Time) (bool, error) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelCtx()
+
+	field := "rate_limits." + action
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: field, Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{{Key: field, Value: bson.D{{Key: "$lte", Value: now.Add(-interval)}}}},
+		}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: field, Value: now}}}}
+
+	result, err := UserData(db, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if result.MatchedCount > 0 {
+		return true, nil
+	}
+
+	// tell a missing user apart from one that already claimed the action
+	count, err := UserData(db, "user").CountDocuments(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return false, fmt.Errorf("%w:%q", err, infra.ErrInternalError)
+	}
+	if count == 0 {
+		return false, fmt.Errorf("%w:%q", infra.ErrUserNotFound, id)
+	}
+	return false, nil
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+func TestClaimRateLimit(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &UserRepository{}
+
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	tests := []struct {
+		name   string
+		action string
+		now    time.Time
+		want   bool
+	}{
+		{name: "first claim", action: "export_data", now: start, want: true},
+		{name: "within the interval", action: "export_data", now: start.Add(59 * time.Minute), want: false},
+		{name: "other action", action: "send_reminders", now: start.Add(time.Minute), want: true},
+		{name: "after the interval", action: "export_data", now: start.Add(time.Hour), want: true},
+	}
+
+	// the cases build on each other, so they run in order against the same user
+	for _, tt := range tests {
+		got, err := repo.ClaimRateLimit(db, userID, tt.action, time.Hour, tt.now)
+		if err != nil {
+			t.Fatalf("%s: ClaimRateLimit: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: ClaimRateLimit() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := repo.ClaimRateLimit(db, primitive.NewObjectID().Hex(), "export_data", time.Hour, start); !errors.Is(err, infra.ErrUserNotFound) {
+		t.Errorf("ClaimRateLimit() for an unknown user: error = %v, want %v", err, infra.ErrUserNotFound)
+	}
+}
+
+func TestAddUserReturnsDatabaseErrors(t *testing.T) {
+	db := failingClient(t)
+	repo := &UserRepository{}
+
+	userID := primitive.NewObjectID().Hex()
+	user := &domain.User{ID: userID, Email: userID + "@example.com"}
+	if _, err := repo.AddUser(db, user, user.Email); err == nil {
+		t.Fatal("AddUser() returned no error for a failing database")
+	}
+}
+
+func TestFindByID(t *testing.T) {
+	db := testClient(t)
+	userID := testUser(t, db)
+	repo := &UserRepository{}
+
+	user, err := repo.FindByID(db, userID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if user.ID != userID || user.Email != userID+"@example.com" {
+		t.Errorf("FindByID() = %q %q, want %q %q", user.ID, user.Email, userID, userID+"@example.com")
+	}
+
+	if _, err := repo.FindByID(db, primitive.NewObjectID().Hex()); !errors.Is(err, infra.ErrUserNotFound) {
+		t.Errorf("FindByID() for an unknown user: error = %v, want %v", err, infra.ErrUserNotFound)
+	}
+}
+
+// startTestVerification gives the user a pending email verification expiring at expiresAt and returns its token.
+func startTestVerification(t *testing.T, db *mongo.Client, userID string, expiresAt time.Time) string {
+	t.Helper()
+
+	user := &domain.User{}
+	token, err := user.StartEmailVerification()
+	if err != nil {
+		t.Fatalf("StartEmailVerification: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"email_verified":          false,
+		"verification_token":      user.VerificationToken,
+		"verification_expires_at": expiresAt,
+	}}
+	if _, err := UserData(db, "user").UpdateByID(ctx, userID, update); err != nil {
+		t.Fatalf("saving verification: %v", err)
+	}
+	return token
+}
+
+func TestVerifyEmail(t *testing.T) {
+	db := testClient(t)
+	repo := &UserRepository{}
+
+	pendingID := testUser(t, db)
+	pending := startTestVerification(t, db, pendingID, time.Now().Add(domain.EmailVerificationTTL))
+	expiredID := testUser(t, db)
+	expired := startTestVerification(t, db, expiredID, time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "verified", token: pending},
+		{name: "reused", token: pending, wantErr: infra.ErrInvalidVerificationToken},
+		{name: "unknown", token: strings.Repeat("0", 64), wantErr: infra.ErrInvalidVerificationToken},
+		{name: "expired", token: expired, wantErr: infra.ErrVerificationTokenExpired},
+	}
+
+	// the reuse case follows the verification of the same token, so the cases run in order
+	for _, tt := range tests {
+		user, err := repo.VerifyEmail(db, tt.token)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: VerifyEmail() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr != nil {
+			continue
+		}
+		if user.ID != pendingID || !user.EmailVerified {
+			t.Errorf("%s: VerifyEmail() = %s verified %v, want %s verified", tt.name, user.ID, user.EmailVerified, pendingID)
+		}
+		if user.VerificationToken != "" || !user.VerificationExpiresAt.IsZero() {
+			t.Errorf("%s: VerifyEmail() kept the token, want it removed", tt.name)
+		}
+	}
+
+	user, err := repo.FindByID(db, expiredID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if user.EmailVerified {
+		t.Error("an expired token verified the email")
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// ResolvedAccount is the account holder information returned by an AccountVerifier.
+type ResolvedAccount struct {
+	AccountName   string `json:"account_name"`
+	AccountNumber string `json:"account_number"`
+	BankName      string `json:"bank_name"`
+	Verified      bool   `json:"verified"`
+}
+
+// AccountVerifier resolves bank account details so the user can confirm them before saving.
+type AccountVerifier interface {
+	VerifyAccount(paymentInfo domain.PaymentInformation) (*ResolvedAccount, error)
+}
+
+// NoopAccountVerifier echoes the submitted details back unverified.
+// It is the default so the application works offline and in tests without a provider.
+type NoopAccountVerifier struct{}
+
+// VerifyAccount returns the submitted account details, marked as not verified.
+func (v *NoopAccountVerifier) VerifyAccount(paymentInfo domain.PaymentInformation) (*ResolvedAccount, error) {
+	return &ResolvedAccount{
+		AccountName:   paymentInfo.AccountName,
+		AccountNumber: paymentInfo.AccountNumber,
+		BankName:      paymentInfo.BankName,
+		Verified:      false,
+	}, nil
+}
+
+// PaystackAccountVerifier resolves account names with the Paystack account resolution API.
+// The routing number of the payment information is used as the Paystack bank code.
+type PaystackAccountVerifier struct {
+	SecretKey string
+	BaseURL   string
+	Client    *http.Client
+}
+
+// NewPaystackAccountVerifier creates a PaystackAccountVerifier with a bounded HTTP timeout.
+func NewPaystackAccountVerifier(secretKey string) *PaystackAccountVerifier {
+	return &PaystackAccountVerifier{
+		SecretKey: secretKey,
+		BaseURL:   "https://api.paystack.co",
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// VerifyAccount resolves the account holder name for the account number and bank code.
+func (v *PaystackAccountVerifier) VerifyAccount(paymentInfo domain.PaymentInformation) (*ResolvedAccount, error) {
+	query := url.Values{}
+	query.Set("account_number", paymentInfo.AccountNumber)
+	query.Set("bank_code", paymentInfo.RoutingNumber)
+
+	req, err := http.NewRequest(http.MethodGet, v.BaseURL+"/bank/resolve?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building account resolution request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+v.SecretKey)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		slog.Error("account resolution request failed", "error", err)
+		return nil, fmt.Errorf("error resolving account: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status  bool   `json:"status"`
+		Message string `json:"message"`
+		Data    struct {
+			AccountNumber string `json:"account_number"`
+			AccountName   string `json:"account_name"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error decoding account resolution response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || !body.Status {
+		return nil, errors.New("account could not be resolved: " + body.Message)
+	}
+
+	return &ResolvedAccount{
+		AccountName:   body.Data.AccountName,
+		AccountNumber: body.Data.AccountNumber,
+		BankName:      paymentInfo.BankName,
+		Verified:      true,
+	}, nil
+}
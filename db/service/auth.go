@@ -1,10 +1,14 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -13,10 +17,87 @@ import (
 	infra "github.com/thebravebyte/numeris/db"
 )
 
-type AuthenticateJWT struct{}
+// AuthenticateJWT signs and verifies user and customer portal tokens with an HMAC key. Use
+// NewAuthenticateJWT or NewAuthenticateJWTFromEnv to create one; the zero value has no key.
+type AuthenticateJWT struct {
+	key         []byte
+	customerKey []byte
+	method      jwt.SigningMethod
+}
+
+// MinTokenKeyLength is the minimum length in bytes of the key tokens are signed with.
+const MinTokenKeyLength = 32
+
+// tokenSigningMethods are the HMAC algorithms tokens can be signed with.
+var tokenSigningMethods = map[string]jwt.SigningMethod{
+	"HS256": jwt.SigningMethodHS256,
+	"HS384": jwt.SigningMethodHS384,
+	"HS512": jwt.SigningMethodHS512,
+}
+
+// NewAuthenticateJWT creates an AuthenticateJWT signing tokens with key and the given HMAC algorithm
+// (HS256, HS384 or HS512). Customer portal tokens are signed with a key derived from key, so a customer
+// token can never be accepted by ParseToken.
+//
+// Returns:
+//   - *AuthenticateJWT: The token service.
+//   - error: An error if the key is shorter than MinTokenKeyLength or the algorithm is not supported.
+func NewAuthenticateJWT(key, algorithm string) (*AuthenticateJWT, error) {
+	if len(key) < MinTokenKeyLength {
+		return nil, fmt.Errorf("token key must be at least %d bytes long", MinTokenKeyLength)
+	}
+	method, ok := tokenSigningMethods[strings.ToUpper(algorithm)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported token signing algorithm %q, expected HS256, HS384 or HS512", algorithm)
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(CustomerPortalScope))
+
+	return &AuthenticateJWT{
+		key:         []byte(key),
+		customerKey: mac.Sum(nil),
+		method:      method,
+	}, nil
+}
+
+// NewAuthenticateJWTFromEnv creates an AuthenticateJWT from AUTH_TOKEN_KEY and AUTH_TOKEN_ALGORITHM,
+// which defaults to HS256.
+//
+// Returns:
+//   - *AuthenticateJWT: The token service.
+//   - error: An error if AUTH_TOKEN_KEY is unset or too short, or the algorithm is not supported.
+func NewAuthenticateJWTFromEnv() (*AuthenticateJWT, error) {
+	key := os.Getenv("AUTH_TOKEN_KEY")
+	if key == "" {
+		return nil, errors.New("AUTH_TOKEN_KEY must be set")
+	}
+	algorithm := os.Getenv("AUTH_TOKEN_ALGORITHM")
+	if algorithm == "" {
+		algorithm = "HS256"
+	}
+	return NewAuthenticateJWT(key, algorithm)
+}
+
+// keyFunc returns the function verifying that a token was signed with the configured algorithm
+// before handing key to the parser.
+func (a *AuthenticateJWT) keyFunc(key []byte) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		if len(key) == 0 {
+			return nil, errors.New("token key is not configured")
+		}
+		if t.Method.Alg() != a.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key, nil
+	}
+}
 
 const emailRegex = `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
 
+// CustomerPortalScope is the only scope carried by customer portal tokens.
+const CustomerPortalScope = "customer_portal"
+
 // // AuthAccessToken type struct which is used to create/generate JWT tokens.
 // type AuthAccessToken struct {
 // 	UserUUID string `json:"_id"`
@@ -64,8 +145,12 @@ func (a *AuthenticateJWT) GenerateJWTToken(userUUID, email string) (string, erro
 		},
 	}
 
-	// GenerateJWTToken creates a JWT token using the ES256 algorithm and the claims.
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth).SignedString([]byte("numeris_auth_key"))
+	if len(a.key) == 0 {
+		return "", errors.New("token key is not configured")
+	}
+
+	// sign the claims with the configured HMAC algorithm
+	token, err := jwt.NewWithClaims(a.method, auth).SignedString(a.key)
 	if err != nil {
 		slog.Error("error while generating token", "error", err)
 		return "", err
@@ -76,9 +161,7 @@ func (a *AuthenticateJWT) GenerateJWTToken(userUUID, email string) (string, erro
 
 // ParseToken validates the JWT token and returns the claims if valid.
 func (a *AuthenticateJWT) ParseToken(tokenValue string) (*infra.AuthAccessToken, error) {
-	token, err := jwt.ParseWithClaims(tokenValue, &infra.AuthAccessToken{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte("numeris_auth_key"), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenValue, &infra.AuthAccessToken{}, a.keyFunc(a.key))
 
 	// parse and validate token created
 	if err != nil {
@@ -120,3 +203,79 @@ func (a *AuthenticateJWT) ParseToken(tokenValue string) (*infra.AuthAccessToken,
 		return nil, errors.New("invalid token claims or token")
 	}
 }
+
+// ParseTokenWithGrace validates the JWT token like ParseToken, but still accepts a token that expired
+// less than grace ago. It is only meant for refreshing tokens; the signature is always verified.
+func (a *AuthenticateJWT) ParseTokenWithGrace(tokenValue string, grace time.Duration) (*infra.AuthAccessToken, error) {
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.ParseWithClaims(tokenValue, &infra.AuthAccessToken{}, a.keyFunc(a.key))
+	if err != nil {
+		slog.Error("invalid token", "error", err)
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(*infra.AuthAccessToken)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims or token")
+	}
+
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Time.Add(grace).Before(time.Now()) {
+		slog.Error("token has expired beyond the grace period", "UUID", claims.UserUUID)
+		return nil, errors.New("token has expired")
+	}
+
+	return claims, nil
+}
+
+// GenerateCustomerToken creates a short-lived, read-only token for a customer of the given user.
+func (a *AuthenticateJWT) GenerateCustomerToken(userUUID, customerEmail string) (string, error) {
+	if err := validateEmail(customerEmail); err != nil {
+		slog.Error("invalid customer email format", "UUID", userUUID, "email", customerEmail)
+		return "", err
+	}
+
+	if _, err := primitive.ObjectIDFromHex(userUUID); err != nil {
+		slog.Error("invalid UUID", "UUID", userUUID)
+		return "", err
+	}
+
+	auth := &infra.CustomerAccessToken{
+		UserUUID:      userUUID,
+		CustomerEmail: customerEmail,
+		Scope:         CustomerPortalScope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   customerEmail,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			Issuer:    "https://github.com/thebravebyte/numeris",
+		},
+	}
+
+	if len(a.customerKey) == 0 {
+		return "", errors.New("token key is not configured")
+	}
+
+	token, err := jwt.NewWithClaims(a.method, auth).SignedString(a.customerKey)
+	if err != nil {
+		slog.Error("error while generating customer token", "error", err)
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ParseCustomerToken validates a customer portal token and returns its claims.
+func (a *AuthenticateJWT) ParseCustomerToken(tokenValue string) (*infra.CustomerAccessToken, error) {
+	token, err := jwt.ParseWithClaims(tokenValue, &infra.CustomerAccessToken{}, a.keyFunc(a.customerKey))
+	if err != nil {
+		slog.Error("invalid customer token", "error", err)
+		return nil, errors.New("invalid customer token")
+	}
+
+	claims, ok := token.Claims.(*infra.CustomerAccessToken)
+	if !ok || !token.Valid || claims.Scope != CustomerPortalScope {
+		return nil, errors.New("invalid customer token claims")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	infra "github.com/thebravebyte/numeris/db"
+)
+
+const testTokenKey = "numeris-test-token-key-0123456789"
+
+// newTestAuthenticateJWT returns an AuthenticateJWT signing with testTokenKey, failing the test otherwise.
+func newTestAuthenticateJWT(t *testing.T) *AuthenticateJWT {
+	t.Helper()
+
+	auth, err := NewAuthenticateJWT(testTokenKey, "HS256")
+	if err != nil {
+		t.Fatalf("NewAuthenticateJWT: %v", err)
+	}
+	return auth
+}
+
+// signTestToken signs a user token expiring at expiresAt, so tests can build tokens that already expired.
+func signTestToken(t *testing.T, auth *AuthenticateJWT, userID string, expiresAt time.Time) string {
+	t.Helper()
+
+	claims := &infra.AuthAccessToken{
+		UserUUID: userID,
+		Email:    "ada@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(expiresAt.Add(-48 * time.Hour)),
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(auth.method, claims).SignedString(auth.key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return token
+}
+
+func TestParseTokenWithGrace(t *testing.T) {
+	auth := newTestAuthenticateJWT(t)
+	userID := primitive.NewObjectID().Hex()
+	grace := 24 * time.Hour
+
+	otherKey, err := NewAuthenticateJWT("another-numeris-token-key-9876543210", "HS256")
+	if err != nil {
+		t.Fatalf("NewAuthenticateJWT: %v", err)
+	}
+
+	valid := signTestToken(t, auth, userID, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: valid},
+		{name: "expired within grace", token: signTestToken(t, auth, userID, time.Now().Add(-time.Hour))},
+		{name: "expired beyond grace", token: signTestToken(t, auth, userID, time.Now().Add(-grace-time.Hour)), wantErr: true},
+		{name: "tampered", token: valid[:len(valid)-4] + "AAAA", wantErr: true},
+		{name: "signed with another key", token: signTestToken(t, otherKey, userID, time.Now().Add(time.Hour)), wantErr: true},
+		{name: "malformed", token: "not-a-token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := auth.ParseTokenWithGrace(tt.token, grace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTokenWithGrace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && claims.UserUUID != userID {
+				t.Errorf("UserUUID = %q, want %q", claims.UserUUID, userID)
+			}
+		})
+	}
+
+	t.Run("expired within grace is rejected by ParseToken", func(t *testing.T) {
+		if _, err := auth.ParseToken(signTestToken(t, auth, userID, time.Now().Add(-time.Hour))); err == nil {
+			t.Error("ParseToken() accepted an expired token")
+		}
+	})
+}
+
+func TestNewAuthenticateJWT(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		algorithm string
+		wantErr   bool
+	}{
+		{name: "HS256", key: testTokenKey, algorithm: "HS256"},
+		{name: "lower-case HS512", key: testTokenKey, algorithm: "hs512"},
+		{name: "empty key", key: "", algorithm: "HS256", wantErr: true},
+		{name: "short key", key: testTokenKey[:MinTokenKeyLength-1], algorithm: "HS256", wantErr: true},
+		{name: "asymmetric algorithm", key: testTokenKey, algorithm: "ES256", wantErr: true},
+		{name: "none", key: testTokenKey, algorithm: "none", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := NewAuthenticateJWT(tt.key, tt.algorithm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAuthenticateJWT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && auth != nil {
+				t.Error("NewAuthenticateJWT() returned a token service along with its error")
+			}
+		})
+	}
+}
+
+func TestNewAuthenticateJWTFromEnv(t *testing.T) {
+	t.Setenv("AUTH_TOKEN_ALGORITHM", "")
+
+	t.Setenv("AUTH_TOKEN_KEY", "")
+	if _, err := NewAuthenticateJWTFromEnv(); err == nil {
+		t.Error("NewAuthenticateJWTFromEnv() without AUTH_TOKEN_KEY succeeded")
+	}
+
+	t.Setenv("AUTH_TOKEN_KEY", testTokenKey)
+	auth, err := NewAuthenticateJWTFromEnv()
+	if err != nil {
+		t.Fatalf("NewAuthenticateJWTFromEnv: %v", err)
+	}
+	if auth.method.Alg() != "HS256" {
+		t.Errorf("algorithm = %s, want the HS256 default", auth.method.Alg())
+	}
+}
+
+func TestGenerateAndParseToken(t *testing.T) {
+	for _, algorithm := range []string{"HS256", "HS384", "HS512"} {
+		t.Run(algorithm, func(t *testing.T) {
+			auth, err := NewAuthenticateJWT(testTokenKey, algorithm)
+			if err != nil {
+				t.Fatalf("NewAuthenticateJWT: %v", err)
+			}
+
+			userID := primitive.NewObjectID().Hex()
+			token, err := auth.GenerateJWTToken(userID, "ada@example.com")
+			if err != nil {
+				t.Fatalf("GenerateJWTToken: %v", err)
+			}
+
+			claims, err := auth.ParseToken(token)
+			if err != nil {
+				t.Fatalf("ParseToken: %v", err)
+			}
+			if claims.UserUUID != userID || claims.Email != "ada@example.com" {
+				t.Errorf("claims = %s %s, want %s ada@example.com", claims.UserUUID, claims.Email, userID)
+			}
+
+			// a token is only accepted with the key and algorithm it was signed with
+			otherKey, err := NewAuthenticateJWT(testTokenKey+"-rotated", algorithm)
+			if err != nil {
+				t.Fatalf("NewAuthenticateJWT: %v", err)
+			}
+			if _, err := otherKey.ParseToken(token); err == nil {
+				t.Error("ParseToken() with another key succeeded")
+			}
+		})
+	}
+
+	hs256 := newTestAuthenticateJWT(t)
+	hs512, err := NewAuthenticateJWT(testTokenKey, "HS512")
+	if err != nil {
+		t.Fatalf("NewAuthenticateJWT: %v", err)
+	}
+	token, err := hs512.GenerateJWTToken(primitive.NewObjectID().Hex(), "ada@example.com")
+	if err != nil {
+		t.Fatalf("GenerateJWTToken: %v", err)
+	}
+	if _, err := hs256.ParseToken(token); err == nil {
+		t.Error("ParseToken() of an HS512 token by an HS256 service succeeded")
+	}
+}
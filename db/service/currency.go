@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CurrencyConverter converts amounts between currencies.
+type CurrencyConverter interface {
+	Convert(amount float64, from, to string) (float64, error)
+	// Rate returns how many units of to one unit of from buys, and when that rate was last updated.
+	Rate(from, to string) (float64, time.Time, error)
+}
+
+// StaticRateConverter converts with fixed rates, each expressed as units of the currency per one
+// unit of a shared reference currency (e.g. USD=1, NGN=1500, EUR=0.92).
+type StaticRateConverter struct {
+	Rates map[string]float64
+	// UpdatedAt is when the rates were loaded
+	UpdatedAt time.Time
+}
+
+// Convert converts amount from one currency to another, rounded to two decimals.
+func (s *StaticRateConverter) Convert(amount float64, from, to string) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+
+	rate, _, err := s.Rate(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Round(amount*rate*100) / 100, nil
+}
+
+// Rate returns the exchange rate from one currency to another.
+func (s *StaticRateConverter) Rate(from, to string) (float64, time.Time, error) {
+	if from == to {
+		return 1, s.UpdatedAt, nil
+	}
+
+	fromRate, ok := s.Rates[from]
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("no exchange rate configured for %s", from)
+	}
+	toRate, ok := s.Rates[to]
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("no exchange rate configured for %s", to)
+	}
+
+	return toRate / fromRate, s.UpdatedAt, nil
+}
+
+// NewStaticRateConverterFromEnv reads rates from CURRENCY_RATES, formatted as "USD=1,NGN=1500,EUR=0.92".
+// It returns nil when no rates are configured, which disables conversion.
+func NewStaticRateConverterFromEnv() (*StaticRateConverter, error) {
+	value := os.Getenv("CURRENCY_RATES")
+	if value == "" {
+		return nil, nil
+	}
+
+	rates := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		code, rate, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid currency rate %q, expected CODE=RATE", pair)
+		}
+		parsed, err := strconv.ParseFloat(rate, 64)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %q", code, rate)
+		}
+		rates[strings.ToUpper(code)] = parsed
+	}
+
+	return &StaticRateConverter{Rates: rates, UpdatedAt: time.Now()}, nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	infra "github.com/thebravebyte/numeris/db"
+)
+
+// EmailSender delivers a single email message through a provider.
+type EmailSender interface {
+	Send(message *infra.EmailTemplate) error
+}
+
+// LogEmailSender only logs the messages it is given. It is meant for local development.
+type LogEmailSender struct{}
+
+// Send logs the message instead of delivering it.
+func (s *LogEmailSender) Send(message *infra.EmailTemplate) error {
+	slog.Info("Email message",
+		"uuid", message.UUID,
+		"from", message.Sender,
+		"fromName", message.FromName,
+		"replyTo", message.ReplyTo,
+		"to", message.Receiver,
+		"subject", message.Subject,
+		"content", message.Content,
+	)
+	for _, attachment := range message.Attachments {
+		slog.Info("Email attachment",
+			"uuid", message.UUID,
+			"fileName", attachment.FileName,
+			"contentType", attachment.ContentType,
+			"size", len(attachment.Content),
+		)
+	}
+	return nil
+}
+
+// SMTPEmailSender delivers messages through an SMTP server with plain authentication.
+type SMTPEmailSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// Send delivers the message through the SMTP server.
+func (s *SMTPEmailSender) Send(message *infra.EmailTemplate) error {
+	from := mail.Address{Name: message.FromName, Address: message.Sender}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", from.String())
+	if message.ReplyTo != "" {
+		fmt.Fprintf(&body, "Reply-To: %s\r\n", message.ReplyTo)
+	}
+	fmt.Fprintf(&body, "To: %s\r\n", message.Receiver)
+	fmt.Fprintf(&body, "Subject: %s\r\n", message.Subject)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	if len(message.Attachments) == 0 {
+		body.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+		body.WriteString(message.Content)
+	} else {
+		writeMultipartBody(&body, message)
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
+	if err := smtp.SendMail(addr, auth, message.Sender, []string{message.Receiver}, []byte(body.String())); err != nil {
+		return fmt.Errorf("error sending email via smtp: %w", err)
+	}
+	return nil
+}
+
+// writeMultipartBody writes the content of a message with attachments as a multipart/mixed body: the text
+// part first, then each attachment base64 encoded.
+func writeMultipartBody(body *strings.Builder, message *infra.EmailTemplate) {
+	boundary := "numeris-" + message.UUID
+	fmt.Fprintf(body, "Content-Type: multipart/mixed; boundary=\"%s\"\r\n\r\n", boundary)
+
+	fmt.Fprintf(body, "--%s\r\n", boundary)
+	body.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	body.WriteString(message.Content)
+	body.WriteString("\r\n")
+
+	for _, attachment := range message.Attachments {
+		fmt.Fprintf(body, "--%s\r\n", boundary)
+		fmt.Fprintf(body, "Content-Type: %s; name=\"%s\"\r\n", attachment.ContentType, attachment.FileName)
+		body.WriteString("Content-Transfer-Encoding: base64\r\n")
+		fmt.Fprintf(body, "Content-Disposition: attachment; filename=\"%s\"\r\n\r\n", attachment.FileName)
+
+		// encoded lines must not be longer than 76 characters
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			body.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		body.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(body, "--%s--\r\n", boundary)
+}
+
+// SendGridEmailSender delivers messages through the SendGrid v3 mail API.
+type SendGridEmailSender struct {
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewSendGridEmailSender creates a SendGridEmailSender with a bounded HTTP timeout.
+func NewSendGridEmailSender(apiKey string) *SendGridEmailSender {
+	return &SendGridEmailSender{
+		APIKey:  apiKey,
+		BaseURL: "https://api.sendgrid.com",
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send delivers the message through the SendGrid API.
+func (s *SendGridEmailSender) Send(message *infra.EmailTemplate) error {
+	type address struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	payload := map[string]interface{}{
+		"personalizations": []map[string]interface{}{
+			{"to": []address{{Email: message.Receiver}}},
+		},
+		"from":    address{Email: message.Sender, Name: message.FromName},
+		"subject": message.Subject,
+		"content": []content{{Type: "text/plain", Value: message.Content}},
+	}
+	if message.ReplyTo != "" {
+		payload["reply_to"] = address{Email: message.ReplyTo}
+	}
+	if len(message.Attachments) > 0 {
+		type attachment struct {
+			Content     string `json:"content"`
+			Type        string `json:"type"`
+			Filename    string `json:"filename"`
+			Disposition string `json:"disposition"`
+		}
+		attachments := make([]attachment, 0, len(message.Attachments))
+		for _, a := range message.Attachments {
+			attachments = append(attachments, attachment{
+				Content:     base64.StdEncoding.EncodeToString(a.Content),
+				Type:        a.ContentType,
+				Filename:    a.FileName,
+				Disposition: "attachment",
+			})
+		}
+		payload["attachments"] = attachments
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding sendgrid request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.BaseURL+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building sendgrid request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending email via sendgrid: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid rejected the email with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RetryEmailSender retries a failed delivery with a linear backoff between attempts.
+type RetryEmailSender struct {
+	Sender   EmailSender
+	Attempts int
+	Backoff  time.Duration
+}
+
+// Send delivers the message, retrying up to Attempts times before returning the last error.
+func (s *RetryEmailSender) Send(message *infra.EmailTemplate) error {
+	attempts := s.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = s.Sender.Send(message); err == nil {
+			return nil
+		}
+		slog.Warn("Email delivery failed", "attempt", attempt, "to", message.Receiver, "error", err)
+		if attempt < attempts {
+			time.Sleep(time.Duration(attempt) * s.Backoff)
+		}
+	}
+	return fmt.Errorf("email delivery failed after %d attempts: %w", attempts, err)
+}
+
+// NewEmailSenderFromEnv selects the email provider from EMAIL_PROVIDER ("log", "smtp" or "sendgrid").
+// The log sender is used when no provider is configured; real providers are wrapped with retries.
+//
+// Returns:
+//   - EmailSender: The configured sender.
+//   - error: An error if the provider is unknown or its settings are incomplete.
+func NewEmailSenderFromEnv() (EmailSender, error) {
+	var sender EmailSender
+
+	switch provider := strings.ToLower(os.Getenv("EMAIL_PROVIDER")); provider {
+	case "", "log":
+		return &LogEmailSender{}, nil
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil || os.Getenv("SMTP_HOST") == "" {
+			return nil, fmt.Errorf("smtp provider requires SMTP_HOST and a numeric SMTP_PORT")
+		}
+		sender = &SMTPEmailSender{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	case "sendgrid":
+		apiKey := os.Getenv("SENDGRID_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("sendgrid provider requires SENDGRID_API_KEY")
+		}
+		sender = NewSendGridEmailSender(apiKey)
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", provider)
+	}
+
+	return &RetryEmailSender{Sender: sender, Attempts: 3, Backoff: 2 * time.Second}, nil
+}
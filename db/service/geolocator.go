@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	infra "github.com/thebravebyte/numeris/db"
+)
+
+// GeoLocator resolves the location of a client IP address.
+type GeoLocator interface {
+	Locate(ip string) (*infra.IPInfo, error)
+}
+
+// NoopGeoLocator returns the IP address without a location.
+// It is the default so logins work offline and in tests without a provider.
+type NoopGeoLocator struct{}
+
+// Locate returns an IPInfo holding only the IP address.
+func (l *NoopGeoLocator) Locate(ip string) (*infra.IPInfo, error) {
+	return &infra.IPInfo{IP: ip}, nil
+}
+
+// IPAPIGeoLocator resolves locations with the ipapi.co lookup API.
+type IPAPIGeoLocator struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewIPAPIGeoLocator creates an IPAPIGeoLocator with a short HTTP timeout, so a slow provider
+// never holds up the login activity for long.
+func NewIPAPIGeoLocator() *IPAPIGeoLocator {
+	return &IPAPIGeoLocator{
+		BaseURL: "https://ipapi.co",
+		Client:  &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// Locate looks up the city, region, country and coordinates of ip.
+func (l *IPAPIGeoLocator) Locate(ip string) (*infra.IPInfo, error) {
+	resp, err := l.Client.Get(l.BaseURL + "/" + url.PathEscape(ip) + "/json/")
+	if err != nil {
+		return nil, fmt.Errorf("error locating ip address: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		City      string  `json:"city"`
+		Region    string  `json:"region"`
+		Country   string  `json:"country_name"`
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Error     bool    `json:"error"`
+		Reason    string  `json:"reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error decoding ip location response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || body.Error {
+		return nil, fmt.Errorf("ip address could not be located: %s", body.Reason)
+	}
+
+	return &infra.IPInfo{
+		IP:        ip,
+		City:      body.City,
+		Region:    body.Region,
+		Country:   body.Country,
+		Latitude:  strconv.FormatFloat(body.Latitude, 'f', -1, 64),
+		Longitude: strconv.FormatFloat(body.Longitude, 'f', -1, 64),
+	}, nil
+}
+
+// NewGeoLocatorFromEnv creates the GeoLocator selected by the GEO_PROVIDER environment variable.
+// An empty value or "none" records client IPs without a location.
+func NewGeoLocatorFromEnv() (GeoLocator, error) {
+	switch provider := strings.ToLower(os.Getenv("GEO_PROVIDER")); provider {
+	case "", "none":
+		return &NoopGeoLocator{}, nil
+	case "ipapi":
+		return NewIPAPIGeoLocator(), nil
+	default:
+		return nil, fmt.Errorf("unknown geo provider %q", provider)
+	}
+}
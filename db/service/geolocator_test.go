@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	infra "github.com/thebravebyte/numeris/db"
+)
+
+func TestIPAPIGeoLocator(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    *infra.IPInfo
+		wantErr bool
+	}{
+		{
+			name:   "located",
+			status: http.StatusOK,
+			body:   `{"city":"Lagos","region":"Lagos","country_name":"Nigeria","latitude":6.4541,"longitude":3.3947}`,
+			want: &infra.IPInfo{
+				IP:        "203.0.113.7",
+				City:      "Lagos",
+				Region:    "Lagos",
+				Country:   "Nigeria",
+				Latitude:  "6.4541",
+				Longitude: "3.3947",
+			},
+		},
+		{name: "provider error", status: http.StatusOK, body: `{"error":true,"reason":"Reserved IP Address"}`, wantErr: true},
+		{name: "rate limited", status: http.StatusTooManyRequests, body: `{"error":true,"reason":"RateLimited"}`, wantErr: true},
+		{name: "malformed response", status: http.StatusOK, body: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			locator := &IPAPIGeoLocator{BaseURL: srv.URL, Client: srv.Client()}
+			got, err := locator.Locate("203.0.113.7")
+			if path != "/203.0.113.7/json/" {
+				t.Errorf("requested path = %q, want %q", path, "/203.0.113.7/json/")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Locate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("Locate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPAPIGeoLocatorUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	locator := &IPAPIGeoLocator{BaseURL: srv.URL, Client: srv.Client()}
+	if _, err := locator.Locate("203.0.113.7"); err == nil {
+		t.Error("Locate() error = nil, want an error for an unreachable provider")
+	}
+}
+
+func TestNewGeoLocatorFromEnv(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     string
+		wantErr  bool
+	}{
+		{provider: "", want: "*service.NoopGeoLocator"},
+		{provider: "none", want: "*service.NoopGeoLocator"},
+		{provider: "IPAPI", want: "*service.IPAPIGeoLocator"},
+		{provider: "maxmind", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			t.Setenv("GEO_PROVIDER", tt.provider)
+
+			got, err := NewGeoLocatorFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewGeoLocatorFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if typ := fmt.Sprintf("%T", got); typ != tt.want {
+				t.Errorf("NewGeoLocatorFromEnv() = %s, want %s", typ, tt.want)
+			}
+		})
+	}
+}
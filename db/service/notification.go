@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// NotificationService delivers invoice notifications to customers.
+type NotificationService interface {
+	SendInvoiceEmail(invoice *domain.Invoice, message string, identity domain.EmailIdentity, pdf []byte) error
+	SendReminder(invoice *domain.Invoice, message string, identity domain.EmailIdentity) error
+	SendPortalLink(customerEmail, link string, identity domain.EmailIdentity) error
+	SendEmailVerification(email, link string) error
+}
+
+// EmailNotification is a NotificationService that delivers notifications by email through
+// whichever EmailSender is configured, so the flows do not depend on a provider.
+type EmailNotification struct {
+	Sender EmailSender
+	From   string
+}
+
+// NewEmailNotification creates an EmailNotification sending from the given address.
+func NewEmailNotification(sender EmailSender, from string) *EmailNotification {
+	return &EmailNotification{Sender: sender, From: from}
+}
+
+// SendInvoiceEmail emails an issued invoice to its customer on behalf of the user identity, with the invoice
+// PDF attached when pdf is not empty.
+func (n *EmailNotification) SendInvoiceEmail(invoice *domain.Invoice, message string, identity domain.EmailIdentity, pdf []byte) error {
+	email := &infra.EmailTemplate{
+		UUID:     primitive.NewObjectID().Hex(),
+		Subject:  fmt.Sprintf("Invoice %s from %s", invoice.InvoiceNumber, invoice.Sender.Name),
+		Content:  message,
+		Receiver: invoice.Customer.Email,
+		Sender:   n.From,
+		FromName: identity.FromName,
+		ReplyTo:  identity.ReplyTo,
+	}
+	if len(pdf) > 0 {
+		email.Attachments = []infra.EmailAttachment{{
+			FileName:    fmt.Sprintf("invoice_%s.pdf", invoice.InvoiceNumber),
+			ContentType: "application/pdf",
+			Content:     pdf,
+		}}
+	}
+	return n.Sender.Send(email)
+}
+
+// SendReminder emails the reminder message to the invoice customer on behalf of the user identity.
+func (n *EmailNotification) SendReminder(invoice *domain.Invoice, message string, identity domain.EmailIdentity) error {
+	return n.Sender.Send(&infra.EmailTemplate{
+		UUID:     primitive.NewObjectID().Hex(),
+		Subject:  fmt.Sprintf("Reminder: invoice %s", invoice.InvoiceNumber),
+		Content:  message,
+		Receiver: invoice.Customer.Email,
+		Sender:   n.From,
+		FromName: identity.FromName,
+		ReplyTo:  identity.ReplyTo,
+	})
+}
+
+// SendPortalLink emails a customer portal link to the customer on behalf of the user identity.
+func (n *EmailNotification) SendPortalLink(customerEmail, link string, identity domain.EmailIdentity) error {
+	return n.Sender.Send(&infra.EmailTemplate{
+		UUID:     primitive.NewObjectID().Hex(),
+		Subject:  "Your invoices",
+		Content:  fmt.Sprintf("Use the link below to view your invoices. It expires in 24 hours.\n\n%s", link),
+		Receiver: customerEmail,
+		Sender:   n.From,
+		FromName: identity.FromName,
+		ReplyTo:  identity.ReplyTo,
+	})
+}
+
+// SendEmailVerification emails the link confirming a new account's email address.
+func (n *EmailNotification) SendEmailVerification(email, link string) error {
+	return n.Sender.Send(&infra.EmailTemplate{
+		UUID:     primitive.NewObjectID().Hex(),
+		Subject:  "Verify your email address",
+		Content:  fmt.Sprintf("Use the link below to verify your email address. It expires in 24 hours.\n\n%s", link),
+		Receiver: email,
+		Sender:   n.From,
+	})
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	infra "github.com/thebravebyte/numeris/db"
+	"github.com/thebravebyte/numeris/domain"
+)
+
+// recordingSender is an EmailSender keeping the messages it is given instead of delivering them.
+type recordingSender struct {
+	sent []*infra.EmailTemplate
+}
+
+func (s *recordingSender) Send(message *infra.EmailTemplate) error {
+	s.sent = append(s.sent, message)
+	return nil
+}
+
+// testNotificationInvoice returns an issued invoice from Ada Lovelace to Grace Hopper.
+func testNotificationInvoice() *domain.Invoice {
+	return &domain.Invoice{
+		InvoiceID:     "6650a1f2c3d4e5f601234567",
+		InvoiceNumber: "INV-000042",
+		Status:        "issued",
+		Customer:      domain.CustomerDetails{Name: "Grace Hopper", Email: "grace@example.com"},
+		Sender:        domain.SenderDetails{Name: "Ada Lovelace", Email: "ada@example.com"},
+	}
+}
+
+func TestEmailNotificationSendInvoiceEmail(t *testing.T) {
+	sender := &recordingSender{}
+	notification := NewEmailNotification(sender, "invoices@numeris.example")
+	identity := domain.EmailIdentity{FromName: "Lovelace Studio", ReplyTo: "billing@lovelace.example"}
+
+	if err := notification.SendInvoiceEmail(testNotificationInvoice(), "Thank you for your business", identity, nil); err != nil {
+		t.Fatalf("SendInvoiceEmail: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("%d emails sent, want 1", len(sender.sent))
+	}
+
+	email := sender.sent[0]
+	if email.UUID == "" {
+		t.Error("email has no UUID")
+	}
+	want := infra.EmailTemplate{
+		UUID:     email.UUID,
+		Subject:  "Invoice INV-000042 from Ada Lovelace",
+		Content:  "Thank you for your business",
+		Receiver: "grace@example.com",
+		Sender:   "invoices@numeris.example",
+		FromName: "Lovelace Studio",
+		ReplyTo:  "billing@lovelace.example",
+	}
+	if email.Subject != want.Subject || email.Content != want.Content || email.Receiver != want.Receiver ||
+		email.Sender != want.Sender || email.FromName != want.FromName || email.ReplyTo != want.ReplyTo {
+		t.Errorf("email = %+v, want %+v", *email, want)
+	}
+	if len(email.Attachments) != 0 {
+		t.Errorf("email without a PDF has %d attachments", len(email.Attachments))
+	}
+}
+
+func TestEmailNotificationAttachesInvoicePDF(t *testing.T) {
+	sender := &recordingSender{}
+	notification := NewEmailNotification(sender, "invoices@numeris.example")
+	pdf := []byte("%PDF-1.3 invoice")
+
+	if err := notification.SendInvoiceEmail(testNotificationInvoice(), "Thank you for your business", domain.EmailIdentity{}, pdf); err != nil {
+		t.Fatalf("SendInvoiceEmail: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("%d emails sent, want 1", len(sender.sent))
+	}
+
+	attachments := sender.sent[0].Attachments
+	if len(attachments) != 1 {
+		t.Fatalf("%d attachments, want 1", len(attachments))
+	}
+	attachment := attachments[0]
+	if attachment.FileName != "invoice_INV-000042.pdf" || attachment.ContentType != "application/pdf" {
+		t.Errorf("attachment is %q of type %q, want %q of type %q", attachment.FileName, attachment.ContentType, "invoice_INV-000042.pdf", "application/pdf")
+	}
+	if string(attachment.Content) != string(pdf) {
+		t.Errorf("attachment content = %q, want %q", attachment.Content, pdf)
+	}
+}
+
+func TestEmailNotificationSendEmailVerification(t *testing.T) {
+	sender := &recordingSender{}
+	notification := NewEmailNotification(sender, "invoices@numeris.example")
+	link := "https://numeris.example/api/verify-email?token=abc123"
+
+	if err := notification.SendEmailVerification("ada@example.com", link); err != nil {
+		t.Fatalf("SendEmailVerification: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("%d emails sent, want 1", len(sender.sent))
+	}
+
+	email := sender.sent[0]
+	if email.Receiver != "ada@example.com" || email.Sender != "invoices@numeris.example" {
+		t.Errorf("email from %q to %q, want from %q to %q", email.Sender, email.Receiver, "invoices@numeris.example", "ada@example.com")
+	}
+	if !strings.Contains(email.Content, link) {
+		t.Errorf("email content %q does not contain the link %q", email.Content, link)
+	}
+}
@@ -34,8 +34,8 @@ func (hs *PasswordHasher) VerifyPassword(hashPassword, password string) (bool, e
 		return false, infra.ErrInvalidLoginDetails
 	}
 
-	// check if the hashPassword is empty
-	err := bcrypt.CompareHashAndPassword([]byte(password), []byte(hashPassword))
+	// compare the stored hash against the plaintext password
+	err := bcrypt.CompareHashAndPassword([]byte(hashPassword), []byte(password))
 	if err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
 			return false, infra.ErrUnMatchedPassword
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	infra "github.com/thebravebyte/numeris/db"
+)
+
+func TestPasswordHasherVerifyPassword(t *testing.T) {
+	hasher := &PasswordHasher{}
+
+	hash, err := hasher.CreateHash("correct-horse")
+	if err != nil {
+		t.Fatalf("CreateHash: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+		wantErr  error
+	}{
+		{name: "correct password", hash: hash, password: "correct-horse", want: true},
+		{name: "wrong password", hash: hash, password: "battery-staple", wantErr: infra.ErrUnMatchedPassword},
+		{name: "empty password", hash: hash, password: "", wantErr: infra.ErrInvalidLoginDetails},
+		{name: "empty hash", hash: "", password: "correct-horse", wantErr: infra.ErrInvalidLoginDetails},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hasher.VerifyPassword(tt.hash, tt.password)
+			if got != tt.want {
+				t.Errorf("VerifyPassword() = %v, want %v", got, tt.want)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("VerifyPassword() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyPassword() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherCreateHashRejectsEmptyPassword(t *testing.T) {
+	if _, err := (&PasswordHasher{}).CreateHash(""); err == nil {
+		t.Fatal("CreateHash(\"\") returned no error")
+	}
+}
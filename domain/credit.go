@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// How an exceeded customer credit limit is enforced.
+const (
+	// CreditLimitWarn lets the invoice through and reports a warning.
+	CreditLimitWarn = "warn"
+	// CreditLimitBlock rejects the invoice.
+	CreditLimitBlock = "block"
+)
+
+// CreditLimit caps the outstanding balance of a customer, identified by email.
+type CreditLimit struct {
+	CustomerEmail string    `json:"customer_email" bson:"customer_email"`
+	Limit         float64   `json:"limit" bson:"limit"`
+	Enforcement   string    `json:"enforcement" bson:"enforcement"`
+	UpdatedAt     time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// NewCreditLimit validates and creates a CreditLimit. The enforcement defaults to CreditLimitWarn.
+func NewCreditLimit(customerEmail string, limit float64, enforcement string) (*CreditLimit, error) {
+	customerEmail = strings.ToLower(strings.TrimSpace(customerEmail))
+	if _, err := mail.ParseAddress(customerEmail); err != nil {
+		return nil, errors.New("customer email is not a valid email address")
+	}
+	if limit <= 0 {
+		return nil, errors.New("credit limit must be greater than zero")
+	}
+	if enforcement == "" {
+		enforcement = CreditLimitWarn
+	}
+	if enforcement != CreditLimitWarn && enforcement != CreditLimitBlock {
+		return nil, fmt.Errorf("unknown credit limit enforcement %q, expected %s or %s", enforcement, CreditLimitWarn, CreditLimitBlock)
+	}
+
+	return &CreditLimit{
+		CustomerEmail: customerEmail,
+		Limit:         math.Round(limit*100) / 100,
+		Enforcement:   enforcement,
+		UpdatedAt:     time.Now(),
+	}, nil
+}
+
+// Check reports whether adding amount to the outstanding balance of the customer stays within the limit.
+// It returns an error wrapping ErrCreditLimitExceeded when it does not.
+func (l CreditLimit) Check(outstanding, amount float64) error {
+	if roundCents(outstanding+amount) <= l.Limit {
+		return nil
+	}
+	return fmt.Errorf("%w: %s owes %.2f, and %.2f more exceeds the limit of %.2f",
+		ErrCreditLimitExceeded, l.CustomerEmail, outstanding, amount, l.Limit)
+}
+
+// CreditLimitFor returns the credit limit the user set for a customer, or nil when there is none.
+func (u *User) CreditLimitFor(customerEmail string) *CreditLimit {
+	customerEmail = strings.ToLower(strings.TrimSpace(customerEmail))
+	for idx := range u.CreditLimits {
+		if u.CreditLimits[idx].CustomerEmail == customerEmail {
+			return &u.CreditLimits[idx]
+		}
+	}
+	return nil
+}
+
+// CreditUtilization is how much of its credit limit a customer is using.
+type CreditUtilization struct {
+	CustomerEmail string  `json:"customer_email"`
+	Limit         float64 `json:"limit"`
+	Enforcement   string  `json:"enforcement"`
+	Outstanding   float64 `json:"outstanding"`
+	Available     float64 `json:"available"`
+	// Utilization is the outstanding balance as a percentage of the limit, above 100 when exceeded
+	Utilization float64 `json:"utilization"`
+}
+
+// NewCreditUtilization computes the utilization of a credit limit from the customer's outstanding balance.
+func NewCreditUtilization(limit CreditLimit, outstanding float64) CreditUtilization {
+	return CreditUtilization{
+		CustomerEmail: limit.CustomerEmail,
+		Limit:         limit.Limit,
+		Enforcement:   limit.Enforcement,
+		Outstanding:   roundCents(outstanding),
+		Available:     max(roundCents(limit.Limit-outstanding), 0),
+		Utilization:   math.Round(outstanding/limit.Limit*10000) / 100,
+	}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SupportedCurrencies is the allow-list of ISO 4217 currency codes invoices can be billed in.
+var SupportedCurrencies = map[string]string{
+	"AUD": "Australian Dollar",
+	"CAD": "Canadian Dollar",
+	"CHF": "Swiss Franc",
+	"CNY": "Chinese Yuan",
+	"EGP": "Egyptian Pound",
+	"EUR": "Euro",
+	"GBP": "British Pound",
+	"GHS": "Ghanaian Cedi",
+	"INR": "Indian Rupee",
+	"JPY": "Japanese Yen",
+	"KES": "Kenyan Shilling",
+	"NGN": "Nigerian Naira",
+	"USD": "US Dollar",
+	"XOF": "West African CFA Franc",
+	"ZAR": "South African Rand",
+}
+
+// NormalizeCurrency upper-cases a currency code and checks it against SupportedCurrencies.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := SupportedCurrencies[code]; !ok {
+		return "", fmt.Errorf("unsupported currency %q", code)
+	}
+	return code, nil
+}
+
+// Currency is a supported currency code with its name.
+type Currency struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Currencies returns the supported currencies sorted by code.
+func Currencies() []Currency {
+	currencies := make([]Currency, 0, len(SupportedCurrencies))
+	for code, name := range SupportedCurrencies {
+		currencies = append(currencies, Currency{Code: code, Name: name})
+	}
+	sort.Slice(currencies, func(a, b int) bool {
+		return currencies[a].Code < currencies[b].Code
+	})
+	return currencies
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "testing"
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{code: "USD", want: "USD"},
+		{code: "NGN", want: "NGN"},
+		{code: "EUR", want: "EUR"},
+		{code: " eur ", want: "EUR"},
+		{code: "XYZ", wantErr: true},
+		{code: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := NormalizeCurrency(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeCurrency(%q) error = %v, wantErr %v", tt.code, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeCurrency(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvoiceCurrency(t *testing.T) {
+	for _, code := range []string{"USD", "NGN", "EUR", "XYZ", ""} {
+		t.Run(code, func(t *testing.T) {
+			args := newValidInvoiceArgs()
+			_, err := NewInvoice("user-1", args.invoiceNumber, code, 0, args.issueDate, args.dueDate, args.items, args.paymentInfo,
+				CustomerDetails{Name: "Grace Hopper", Phone: "+15550100", Email: "grace@example.com", Address: "1 Harbor Road"},
+				SenderDetails{Name: "Ada Lovelace", Phone: "+15550101", Email: "ada@example.com", Address: "2 Engine Street"},
+				"draft",
+			)
+			_, supported := SupportedCurrencies[code]
+			if (err == nil) != supported {
+				t.Errorf("NewInvoice() with currency %q: error = %v, want an error: %v", code, err, !supported)
+			}
+		})
+	}
+}
+
+func TestCurrenciesAreSorted(t *testing.T) {
+	currencies := Currencies()
+	if len(currencies) != len(SupportedCurrencies) {
+		t.Fatalf("Currencies() returned %d currencies, want %d", len(currencies), len(SupportedCurrencies))
+	}
+	for idx := 1; idx < len(currencies); idx++ {
+		if currencies[idx-1].Code >= currencies[idx].Code {
+			t.Errorf("Currencies() is not sorted: %s before %s", currencies[idx-1].Code, currencies[idx].Code)
+		}
+	}
+}
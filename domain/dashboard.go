@@ -1 +1,33 @@
-package domain
\ No newline at end of file
+package domain
+
+// Dashboard is the overview of a user's invoices shown when they open the app, gathered in one response.
+type Dashboard struct {
+	Summary InvoiceSummary `json:"summary"`
+	// StatusCounts holds every status of InvoiceStatuses, with zero when no invoice has it
+	StatusCounts     map[string]int64  `json:"status_counts"`
+	RecentActivities []Activity        `json:"recent_activities"`
+	Upcoming         []UpcomingInvoice `json:"upcoming"`
+}
+
+// UpcomingInvoice is an invoice that is ready to be issued soon, as listed on the dashboard.
+type UpcomingInvoice struct {
+	InvoiceID       string  `json:"invoice_id" bson:"invoice_id"`
+	InvoiceNumber   string  `json:"invoice_number" bson:"invoice_number"`
+	Status          string  `json:"status" bson:"status"`
+	IssueDate       string  `json:"issue_date" bson:"issue_date"`
+	DueDate         string  `json:"due_date" bson:"due_date"`
+	CustomerName    string  `json:"customer_name" bson:"customer_name"`
+	CustomerEmail   string  `json:"customer_email" bson:"customer_email"`
+	BillingCurrency string  `json:"billing_currency" bson:"billing_currency"`
+	TotalAmountDue  float64 `json:"total_amount_due" bson:"total_amount_due"`
+}
+
+// NewStatusCounts returns counts for every status of InvoiceStatuses, taking the number of invoices of each
+// status from counts and zero for the others.
+func NewStatusCounts(counts map[string]int64) map[string]int64 {
+	all := make(map[string]int64, len(InvoiceStatuses))
+	for _, status := range InvoiceStatuses {
+		all[status] = counts[status]
+	}
+	return all
+}
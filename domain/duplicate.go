@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// DuplicateSet is a group of invoices to the same customer, for the same amount, issued close together.
+type DuplicateSet struct {
+	CustomerEmail string    `json:"customer_email"`
+	Amount        float64   `json:"amount"`
+	Invoices      []Invoice `json:"invoices"`
+}
+
+// NewDuplicateSets splits invoices that share a customer and amount into sets whose issue dates are at most
+// windowDays apart from the previous invoice. Only sets of two or more invoices are returned.
+func NewDuplicateSets(customerEmail string, amount float64, invoices []Invoice, windowDays int) []DuplicateSet {
+	sorted := make([]Invoice, len(invoices))
+	copy(sorted, invoices)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].IssueDate < sorted[j].IssueDate })
+
+	window := time.Duration(windowDays) * 24 * time.Hour
+	sets := make([]DuplicateSet, 0)
+	current := make([]Invoice, 0)
+
+	flush := func() {
+		if len(current) > 1 {
+			sets = append(sets, DuplicateSet{CustomerEmail: customerEmail, Amount: amount, Invoices: current})
+		}
+		current = make([]Invoice, 0)
+	}
+
+	var previous time.Time
+	for _, invoice := range sorted {
+		issued, err := time.Parse("2006-01-02", invoice.IssueDate)
+		if err != nil {
+			continue
+		}
+		if len(current) > 0 && issued.Sub(previous) > window {
+			flush()
+		}
+		current = append(current, invoice)
+		previous = issued
+	}
+	flush()
+
+	return sets
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// EmailIdentity is how a user's outgoing emails present themselves to customers.
+// Empty fields fall back to the system defaults.
+type EmailIdentity struct {
+	FromName string `json:"from_name,omitempty" bson:"from_name,omitempty"`
+	ReplyTo  string `json:"reply_to,omitempty" bson:"reply_to,omitempty"`
+}
+
+// NewEmailIdentity validates and creates an EmailIdentity.
+func NewEmailIdentity(fromName, replyTo string) (*EmailIdentity, error) {
+	fromName = strings.TrimSpace(fromName)
+	replyTo = strings.TrimSpace(replyTo)
+
+	if len(fromName) > 64 {
+		return nil, errors.New("from name cannot be longer than 64 characters")
+	}
+	// names end up in mail headers, so line breaks would allow header injection
+	if strings.ContainsAny(fromName, "\r\n<>\"") {
+		return nil, errors.New("from name contains invalid characters")
+	}
+
+	if replyTo != "" {
+		address, err := mail.ParseAddress(replyTo)
+		if err != nil || address.Address != replyTo {
+			return nil, errors.New("reply-to must be a plain email address")
+		}
+	}
+
+	return &EmailIdentity{FromName: fromName, ReplyTo: replyTo}, nil
+}
@@ -9,6 +9,10 @@ var (
 	ErrInvalidPassword    = errors.New("invalid password")
 	ErrInvalidPhoneNumber = errors.New("invalid phone number")
 
+	ErrCreditLimitExceeded = errors.New("customer credit limit exceeded")
+	ErrSenderEmailMismatch = errors.New("sender email does not match the account")
+	ErrAmendWindowClosed   = errors.New("the grace period to amend the issued invoice has passed")
+
 	// ErrEmailToken      = errors.New("email already token")
 	// ErrUUID            = errors.New("cannot create uuid for user")
 	// ErrEmptyName       = errors.New("cannot create empty name")
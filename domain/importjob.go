@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Statuses of an import job.
+const (
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+)
+
+// Outcomes of a single imported row.
+const (
+	ImportRowCreated = "created"
+	ImportRowSkipped = "skipped"
+	ImportRowFailed  = "failed"
+)
+
+// MaxImportRows is the maximum number of rows in a single import file.
+const MaxImportRows = 5000
+
+// ImportRowResult is the outcome of one row of an import file. Row counts from 1 after the header.
+type ImportRowResult struct {
+	Row           int    `json:"row" bson:"row"`
+	InvoiceNumber string `json:"invoice_number,omitempty" bson:"invoice_number,omitempty"`
+	Status        string `json:"status" bson:"status"`
+	InvoiceID     string `json:"invoice_id,omitempty" bson:"invoice_id,omitempty"`
+	Error         string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// ImportJob tracks the import of an invoice file. Rows are processed in order and ProcessedRows is saved after
+// each one, so submitting the same file with the same idempotency key resumes after the last processed row.
+// FailedRecords keeps the failed rows, followed by their error, for the user to fix and import again.
+type ImportJob struct {
+	ID             string            `json:"id" bson:"_id"`
+	UserID         string            `json:"user_id" bson:"user_id"`
+	IdempotencyKey string            `json:"idempotency_key" bson:"idempotency_key"`
+	FileName       string            `json:"file_name" bson:"file_name"`
+	FileHash       string            `json:"-" bson:"file_hash"`
+	Header         []string          `json:"-" bson:"header"`
+	Status         string            `json:"status" bson:"status"`
+	TotalRows      int               `json:"total_rows" bson:"total_rows"`
+	ProcessedRows  int               `json:"processed_rows" bson:"processed_rows"`
+	CreatedRows    int               `json:"created_rows" bson:"created_rows"`
+	SkippedRows    int               `json:"skipped_rows" bson:"skipped_rows"`
+	FailedRows     int               `json:"failed_rows" bson:"failed_rows"`
+	Rows           []ImportRowResult `json:"rows" bson:"rows"`
+	FailedRecords  [][]string        `json:"-" bson:"failed_records"`
+	CreatedAt      time.Time         `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" bson:"updated_at"`
+}
+
+// NewImportJob validates and creates a running ImportJob for a file of totalRows rows.
+func NewImportJob(userID, idempotencyKey, fileName, fileHash string, header []string, totalRows int) (*ImportJob, error) {
+	idempotencyKey = strings.TrimSpace(idempotencyKey)
+	if idempotencyKey == "" || len(idempotencyKey) > 128 {
+		return nil, errors.New("idempotency key must be between 1 and 128 characters")
+	}
+	if totalRows == 0 {
+		return nil, errors.New("import file has no rows")
+	}
+	if totalRows > MaxImportRows {
+		return nil, errors.New("import file has too many rows")
+	}
+
+	now := time.Now()
+	return &ImportJob{
+		ID:             generateID(),
+		UserID:         userID,
+		IdempotencyKey: idempotencyKey,
+		FileName:       fileName,
+		FileHash:       fileHash,
+		Header:         header,
+		Status:         ImportJobRunning,
+		TotalRows:      totalRows,
+		Rows:           make([]ImportRowResult, 0),
+		FailedRecords:  make([][]string, 0),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// Record adds the outcome of the next row to the job. The record of a failed row is kept with its error.
+func (j *ImportJob) Record(result ImportRowResult, record []string) {
+	j.ProcessedRows++
+	j.Rows = append(j.Rows, result)
+	switch result.Status {
+	case ImportRowCreated:
+		j.CreatedRows++
+	case ImportRowSkipped:
+		j.SkippedRows++
+	case ImportRowFailed:
+		j.FailedRows++
+		j.FailedRecords = append(j.FailedRecords, append(append([]string(nil), record...), result.Error))
+	}
+	if j.ProcessedRows >= j.TotalRows {
+		j.Status = ImportJobCompleted
+	}
+	j.UpdatedAt = time.Now()
+}
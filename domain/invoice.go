@@ -3,6 +3,9 @@ package domain
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,23 +17,62 @@ type Invoice struct {
 	IssueDate       string             `json:"issue_date" bson:"issue_date"`
 	DueDate         string             `json:"due_date" bson:"due_date"`
 	BillingCurrency string             `json:"billing_currency" bson:"billing_currency"`
+	BaseCurrency    string             `json:"base_currency,omitempty" bson:"base_currency,omitempty"`
+	BaseAmountDue   float64            `json:"base_amount_due,omitempty" bson:"base_amount_due,omitempty"`
 	Discount        float64            `json:"discount" bson:"discount"`
 	TotalAmountDue  float64            `json:"total_amount_due" bson:"total_amount_due"`
 	Notes           string             `json:"notes" bson:"notes"`
 	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt       time.Time          `json:"updated_at" bson:"updated_at"`
 	PaymentInfo     PaymentInformation `json:"payment_info" bson:"payment_info"`
+	PaymentMethods  []PaymentMethod    `json:"payment_methods,omitempty" bson:"payment_methods,omitempty"`
 	Items           []Item             `json:"items" bson:"items"`
 	Customer        CustomerDetails    `json:"customer" bson:"customer"`
 	Sender          SenderDetails      `json:"sender" bson:"sender"`
 	Status          string             `json:"status" validate:"required"`
+	IssuedAt        *time.Time         `json:"issued_at,omitempty" bson:"issued_at,omitempty"`
+	PaidAt          *time.Time         `json:"paid_at,omitempty" bson:"paid_at,omitempty"`
+	Payments        []Payment          `json:"payments,omitempty" bson:"payments,omitempty"`
+	Expenses        []Expense          `json:"expenses,omitempty" bson:"expenses,omitempty"`
+	VoidedAt        *time.Time         `json:"voided_at,omitempty" bson:"voided_at,omitempty"`
+	VoidedBy        string             `json:"voided_by,omitempty" bson:"voided_by,omitempty"`
+	VoidReason      string             `json:"void_reason,omitempty" bson:"void_reason,omitempty"`
+	CancelledAt     *time.Time         `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+	RefundedAt      *time.Time         `json:"refunded_at,omitempty" bson:"refunded_at,omitempty"`
+	RefundAmount    float64            `json:"refund_amount,omitempty" bson:"refund_amount,omitempty"`
+	RefundReason    string             `json:"refund_reason,omitempty" bson:"refund_reason,omitempty"`
+	PDFFileID       string             `json:"pdf_file_id,omitempty" bson:"pdf_file_id,omitempty"`
+	Tags            []string           `json:"tags,omitempty" bson:"tags,omitempty"`
+	ItemSort        string             `json:"item_sort,omitempty" bson:"item_sort,omitempty"`
+	Reminders       []InvoiceReminder  `json:"reminders,omitempty" bson:"reminders,omitempty"`
+	ScheduledSendAt *time.Time         `json:"scheduled_send_at,omitempty" bson:"scheduled_send_at,omitempty"`
+	DeletedAt       *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Tax             *InvoiceTax        `json:"tax,omitempty" bson:"tax,omitempty"`
+	CustomStatus    *CustomStatus      `json:"custom_status,omitempty" bson:"custom_status,omitempty"`
 }
 
+// Item is a line item of an invoice. Billable is nil on items stored before the flag existed, and an item
+// is billable unless it is explicitly marked otherwise.
 type Item struct {
 	Description string  `json:"description" bson:"description"`
 	Quantity    int     `json:"quantity" bson:"quantity"`
 	UnitPrice   float64 `json:"unit_price" bson:"unit_price"`
 	TotalPrice  float64 `json:"total_price" bson:"total_price"`
+	Billable    *bool   `json:"billable,omitempty" bson:"billable,omitempty"`
+	Order       int     `json:"order" bson:"order"`
+}
+
+// IsBillable reports whether the item counts towards the invoice total.
+func (i Item) IsBillable() bool {
+	return i.Billable == nil || *i.Billable
+}
+
+// Expense is a reimbursable cost passed through to the customer at cost, billed apart from service items.
+type Expense struct {
+	Description string  `json:"description" bson:"description"`
+	Amount      float64 `json:"amount" bson:"amount"`
+	ReceiptURL  string  `json:"receipt_url,omitempty" bson:"receipt_url,omitempty"`
+	Taxable     bool    `json:"taxable" bson:"taxable"`
 }
 
 type PaymentInformation struct {
@@ -54,12 +96,34 @@ type SenderDetails struct {
 	Address string `json:"address" bson:"address"`
 }
 
+// InvoiceStatuses lists every status an invoice can have.
+var InvoiceStatuses = []string{"draft", "pending", "scheduled", "issued", "overdue", "paid", "refunded", "voided", "cancelled"}
+
+// IsInvoiceStatus reports whether status is one of InvoiceStatuses.
+func IsInvoiceStatus(status string) bool {
+	for _, known := range InvoiceStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+// InvoiceFilter narrows a list of invoices. Empty fields are not applied; From and To bound the issue
+// date and are both inclusive.
+type InvoiceFilter struct {
+	Status string
+	Tag    string
+	From   time.Time
+	To     time.Time
+}
+
 // NewInvoice creates a new Invoice object with the provided details.
 // It validates the input parameters and returns an error if any validation fails.
 //
 // Parameters:
 //   - userID: A string representing the user ID associated with the invoice.
-//   - invoiceNumber: A string representing the unique invoice number.
+//   - invoiceNumber: A string representing the unique invoice number, or empty to generate one on save.
 //   - billingCurrency: A string representing the currency used for billing.
 //   - discount: A float64 representing the discount percentage to be applied to the total amount.
 //   - issueDate: A time.Time representing the date the invoice was issued.
@@ -83,15 +147,16 @@ func NewInvoice(
 	sender SenderDetails,
 	status string,
 ) (*Invoice, error) {
-	// validate inputs
-	if invoiceNumber == "" {
-		return nil, errors.New("invoice number cannot be empty")
-	}
+	// validate inputs; an empty invoice number is generated when the invoice is saved
 	if billingCurrency == "" {
 		return nil, errors.New("billing currency cannot be empty")
 	}
-	if discount < 0 || discount > 100 {
-		return nil, errors.New("discount must be between 0 and 100")
+	billingCurrency, err := NormalizeCurrency(billingCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid billing currency: %w", err)
+	}
+	if err := validateDiscount(discount); err != nil {
+		return nil, err
 	}
 	if len(items) == 0 {
 		return nil, errors.New("invoice must have at least one item")
@@ -117,13 +182,17 @@ func NewInvoice(
 		return nil, err
 	}
 
-	// validate payment info
-	if err := validatePaymentInfo(paymentInfo); err != nil {
-		return nil, err
+	// validate payment info, which may be left empty when other payment methods are set
+	if paymentInfo != (PaymentInformation{}) {
+		if err := validatePaymentInfo(paymentInfo); err != nil {
+			return nil, err
+		}
 	}
 
 	// calculate total amount
+	priceItems(items)
 	totalAmount := calculateTotalAmount(items, discount)
+	numberItems(items)
 
 	// construct  the invoice model
 	invoice := &Invoice{
@@ -147,34 +216,425 @@ func NewInvoice(
 	return invoice, nil
 }
 
+// RestoreInvoice validates an invoice exported from Numeris so it can be imported again, keeping its number,
+// dates and status. Unlike NewInvoice the dates may be in the past, since exported invoices were usually issued
+// long ago. The invoice gets a new InvoiceID and its totals are recomputed from its items and expenses; an
+// unknown status falls back to draft. Stored documents belong to the exported invoice and are not kept.
+func RestoreInvoice(exported Invoice) (*Invoice, error) {
+	invoice := exported
+
+	invoice.InvoiceNumber = strings.TrimSpace(invoice.InvoiceNumber)
+	if invoice.InvoiceNumber == "" {
+		return nil, errors.New("invoice number cannot be empty")
+	}
+
+	billingCurrency, err := NormalizeCurrency(invoice.BillingCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid billing currency: %w", err)
+	}
+	invoice.BillingCurrency = billingCurrency
+
+	if err := validateDiscount(invoice.Discount); err != nil {
+		return nil, err
+	}
+	if len(invoice.Items) == 0 {
+		return nil, errors.New("invoice must have at least one item")
+	}
+	for _, item := range invoice.Items {
+		if err := validateItem(item); err != nil {
+			return nil, err
+		}
+	}
+	for _, expense := range invoice.Expenses {
+		if err := validateExpense(expense); err != nil {
+			return nil, err
+		}
+	}
+
+	customer, sender := invoice.Customer, invoice.Sender
+	if err := validateDetails(customer.Name, customer.Phone, customer.Email, customer.Address); err != nil {
+		return nil, errors.New("invalid customer details: " + err.Error())
+	}
+	if err := validateDetails(sender.Name, sender.Phone, sender.Email, sender.Address); err != nil {
+		return nil, errors.New("invalid sender details: " + err.Error())
+	}
+
+	issueDate, err := time.Parse("2006-01-02", invoice.IssueDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue date format: %v", err)
+	}
+	dueDate, err := time.Parse("2006-01-02", invoice.DueDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid due date format: %v", err)
+	}
+	if issueDate.After(dueDate) {
+		return nil, errors.New("issue date cannot be after due date")
+	}
+
+	if invoice.PaymentInfo != (PaymentInformation{}) {
+		if err := validatePaymentInfo(invoice.PaymentInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	tags, err := NormalizeTags(invoice.Tags)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Tags = tags
+
+	if !IsInvoiceStatus(invoice.Status) {
+		invoice.Status = "draft"
+	}
+
+	// older exports wrote unset times as the zero time, which would otherwise mark the invoice deleted
+	for _, at := range []**time.Time{
+		&invoice.IssuedAt, &invoice.PaidAt, &invoice.VoidedAt, &invoice.CancelledAt,
+		&invoice.RefundedAt, &invoice.ScheduledSendAt, &invoice.DeletedAt,
+	} {
+		if *at != nil && (*at).IsZero() {
+			*at = nil
+		}
+	}
+
+	// copy the slices so the restored invoice never shares them with the exported one
+	invoice.Items = append([]Item(nil), invoice.Items...)
+	invoice.Expenses = append([]Expense(nil), invoice.Expenses...)
+	priceItems(invoice.Items)
+	numberItems(invoice.Items)
+	invoice.Tax = nil
+	if exported.Tax != nil {
+		if err := invoice.SetTax(exported.Tax.Rate, exported.Tax.Jurisdiction); err != nil {
+			return nil, err
+		}
+	}
+	invoice.recalculateTotal()
+
+	invoice.InvoiceID = generateID()
+	invoice.PDFFileID = ""
+	if invoice.CreatedAt.IsZero() {
+		invoice.CreatedAt = invoice.UpdatedAt
+	}
+	return &invoice, nil
+}
+
 // AddItem adds an item to the invoice
 func (i *Invoice) AddItem(item Item) error {
 	if err := validateItem(item); err != nil {
 		return err
 	}
 	i.Items = append(i.Items, item)
-	i.TotalAmountDue = calculateTotalAmount(i.Items, i.Discount)
+	priceItems(i.Items)
+	numberItems(i.Items)
+	i.recalculateTotal()
+	return nil
+}
+
+// SetExpenses replaces the invoice's pass-through expenses and recalculates the total amount due
+func (i *Invoice) SetExpenses(expenses []Expense) error {
+	for _, expense := range expenses {
+		if err := validateExpense(expense); err != nil {
+			return err
+		}
+	}
+	i.Expenses = expenses
+	i.recalculateTotal()
+	return nil
+}
+
+// SetTags replaces the invoice's tags with their normalized form
+func (i *Invoice) SetTags(tags []string) error {
+	normalized, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	i.Tags = normalized
 	i.UpdatedAt = time.Now()
 	return nil
 }
 
 // UpdateDiscount updates the discount and recalculates the total amount due
 func (i *Invoice) UpdateDiscount(discount float64) error {
-	if discount < 0 || discount > 100 {
-		return errors.New("discount must be between 0 and 100")
+	if err := validateDiscount(discount); err != nil {
+		return err
 	}
 	i.Discount = discount
-	i.TotalAmountDue = calculateTotalAmount(i.Items, i.Discount)
+	i.recalculateTotal()
+	return nil
+}
+
+// recalculateTotal recomputes the total amount due from the items, discount, tax and expenses.
+// The discount and tax apply to service items only; expenses are passed through at cost.
+func (i *Invoice) recalculateTotal() {
+	services := calculateTotalAmount(i.Items, i.Discount)
+	if i.Tax != nil {
+		i.Tax.Amount = calculateTaxAmount(services+calculateTaxableExpenseAmount(i.Expenses), i.Tax.Rate)
+	}
+	i.TotalAmountDue = roundCents(services + i.TaxAmount() + calculateExpenseAmount(i.Expenses))
+	i.UpdatedAt = time.Now()
+}
+
+// Subtotal returns the sum of the billable items of the invoice, before the discount.
+func (i *Invoice) Subtotal() float64 {
+	return calculateSubtotal(i.Items)
+}
+
+// DiscountAmount returns the amount the discount takes off the subtotal of the invoice.
+func (i *Invoice) DiscountAmount() float64 {
+	return calculateDiscountAmount(i.Subtotal(), i.Discount)
+}
+
+// ExpenseAmount returns the sum of the pass-through expenses of the invoice, which are not discounted.
+func (i *Invoice) ExpenseAmount() float64 {
+	return roundCents(calculateExpenseAmount(i.Expenses))
+}
+
+// Void marks an issued invoice as voided. Unlike deleting, the invoice and its number are kept for
+// audit; unlike cancelling, it applies after issue. A voided invoice no longer counts towards
+// reports and can't be edited or paid.
+func (i *Invoice) Void(reason, voidedBy string) error {
+	if i.Status != "issued" && i.Status != "overdue" {
+		return fmt.Errorf("only issued or overdue invoices can be voided, invoice is %s", i.Status)
+	}
+	if reason == "" {
+		return errors.New("a reason is required to void an invoice")
+	}
+
+	i.Status = "voided"
+	i.VoidReason = reason
+	i.VoidedBy = voidedBy
+	now := time.Now()
+	i.VoidedAt = &now
+	i.UpdatedAt = now
+	return nil
+}
+
+// MarkPaid records that an issued or overdue invoice was paid at paidAt, which cannot be in the future
+// or before the issue date. The balance still due is recorded as a payment made with method, which may be empty.
+func (i *Invoice) MarkPaid(paidAt time.Time, method string) error {
+	if i.Status != "issued" && i.Status != "overdue" {
+		return fmt.Errorf("only issued or overdue invoices can be marked as paid, invoice is %s", i.Status)
+	}
+	if paidAt.After(time.Now()) {
+		return errors.New("payment date cannot be in the future")
+	}
+	if issueDate, err := time.Parse("2006-01-02", i.IssueDate); err == nil && paidAt.Before(issueDate) {
+		return errors.New("payment date cannot be before the issue date")
+	}
+
+	i.Payments = append(i.Payments, Payment{Amount: i.BalanceDue(), PaidAt: paidAt, Method: NormalizePaymentMethod(method)})
+	i.Status = "paid"
+	i.PaidAt = &paidAt
+	i.UpdatedAt = time.Now()
+	return nil
+}
+
+// Cancel withdraws an invoice that has not been paid. Unlike Void it applies to drafts too, and like a voided
+// invoice a cancelled one stays on record but is no longer issued, reminded or counted in totals.
+func (i *Invoice) Cancel() error {
+	if i.Status == "paid" {
+		return errors.New("paid invoices cannot be cancelled")
+	}
+	if i.IsWithdrawn() {
+		return fmt.Errorf("invoice is already %s", i.Status)
+	}
+
+	i.Status = "cancelled"
+	now := time.Now()
+	i.CancelledAt = &now
+	i.UpdatedAt = now
+	return nil
+}
+
+// Refund records that money was returned to the customer on a paid invoice. An amount of zero refunds
+// everything that was paid; a smaller amount is a partial refund.
+func (i *Invoice) Refund(amount float64, reason string) error {
+	if i.Status != "paid" {
+		return fmt.Errorf("only paid invoices can be refunded, invoice is %s", i.Status)
+	}
+
+	paid := i.AmountPaid()
+	// invoices paid before payments were recorded only have their total
+	if paid == 0 {
+		paid = i.TotalAmountDue
+	}
+	if amount == 0 {
+		amount = paid
+	}
+	if amount < 0 {
+		return errors.New("refund amount cannot be negative")
+	}
+	if roundCents(amount) > paid {
+		return fmt.Errorf("refund amount %.2f exceeds the %.2f paid", amount, paid)
+	}
+
+	i.Status = "refunded"
+	i.RefundAmount = roundCents(amount)
+	i.RefundReason = reason
+	now := time.Now()
+	i.RefundedAt = &now
+	i.UpdatedAt = now
+	return nil
+}
+
+// InvoicePatch holds the fields of an invoice to change without resubmitting the whole invoice. Nil fields
+// are left as they are.
+type InvoicePatch struct {
+	Notes    *string
+	Discount *float64
+	Items    []Item
+	DueDate  *string
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (p InvoicePatch) IsEmpty() bool {
+	return p.Notes == nil && p.Discount == nil && p.Items == nil && p.DueDate == nil
+}
+
+// ChangesTotal reports whether the patch changes the items or discount, so the total amount due is recalculated.
+func (p InvoicePatch) ChangesTotal() bool {
+	return p.Discount != nil || p.Items != nil
+}
+
+// Fields returns the names of the fields the patch changes.
+func (p InvoicePatch) Fields() []string {
+	fields := make([]string, 0, 4)
+	if p.Notes != nil {
+		fields = append(fields, "notes")
+	}
+	if p.Discount != nil {
+		fields = append(fields, "discount")
+	}
+	if p.Items != nil {
+		fields = append(fields, "items")
+	}
+	if p.DueDate != nil {
+		fields = append(fields, "due_date")
+	}
+	return fields
+}
+
+// ApplyPatch changes the fields set in patch on an invoice that has not been issued yet and recalculates the
+// total amount due when the items or discount change. Unlike NewInvoice the dates are not checked against
+// today, so older drafts stay editable; the due date only has to be on or after the issue date.
+func (i *Invoice) ApplyPatch(patch InvoicePatch) error {
+	if !i.IsDraft() {
+		return fmt.Errorf("only draft or pending invoices can be updated, invoice is %s", i.Status)
+	}
+	return i.applyPatch(patch)
+}
+
+// Amend applies patch to an issued invoice, so a mistake noticed right after issuing can be fixed in place.
+// It is only allowed until grace has passed since the invoice was issued; after that the invoice must be
+// voided and recreated.
+func (i *Invoice) Amend(patch InvoicePatch, now time.Time, grace time.Duration) error {
+	if i.Status != "issued" {
+		return fmt.Errorf("only issued invoices can be amended, invoice is %s", i.Status)
+	}
+	if i.IssuedAt == nil || now.Sub(*i.IssuedAt) > grace {
+		return ErrAmendWindowClosed
+	}
+	return i.applyPatch(patch)
+}
+
+// applyPatch validates and applies patch without checking the status of the invoice.
+func (i *Invoice) applyPatch(patch InvoicePatch) error {
+	if patch.Discount != nil {
+		if err := validateDiscount(*patch.Discount); err != nil {
+			return err
+		}
+	}
+
+	if patch.Items != nil {
+		if len(patch.Items) == 0 {
+			return errors.New("invoice must have at least one item")
+		}
+		for _, item := range patch.Items {
+			if err := validateItem(item); err != nil {
+				return err
+			}
+		}
+	}
+
+	if patch.DueDate != nil {
+		dueDate, err := time.Parse("2006-01-02", *patch.DueDate)
+		if err != nil {
+			return fmt.Errorf("invalid due date format: %v", err)
+		}
+		issueDate, err := time.Parse("2006-01-02", i.IssueDate)
+		if err != nil {
+			return fmt.Errorf("invalid issue date format: %v", err)
+		}
+		if issueDate.After(dueDate) {
+			return errors.New("issue date cannot be after due date")
+		}
+		i.DueDate = *patch.DueDate
+	}
+
+	if patch.Notes != nil {
+		i.Notes = *patch.Notes
+	}
+	if patch.Discount != nil {
+		i.Discount = *patch.Discount
+	}
+	if patch.Items != nil {
+		i.Items = append([]Item(nil), patch.Items...)
+		priceItems(i.Items)
+		numberItems(i.Items)
+	}
+
+	if patch.ChangesTotal() {
+		i.recalculateTotal()
+	}
+	i.UpdatedAt = time.Now()
+	return nil
+}
+
+// ChangeCustomer replaces the customer of an invoice that has not been issued yet. Once issued, the
+// customer has received the invoice, so it must be voided and recreated instead.
+func (i *Invoice) ChangeCustomer(customer CustomerDetails) error {
+	if !i.IsDraft() {
+		return fmt.Errorf("only draft or pending invoices can change customer, invoice is %s", i.Status)
+	}
+	if err := validateDetails(customer.Name, customer.Phone, customer.Email, customer.Address); err != nil {
+		return errors.New("invalid customer details: " + err.Error())
+	}
+
+	i.Customer = customer
 	i.UpdatedAt = time.Now()
 	return nil
 }
 
+// IsDraft reports whether the invoice has not been issued yet, including an invoice scheduled to be sent.
+// Only issued invoices keep a stored PDF; drafts are rendered again on every download.
+func (i *Invoice) IsDraft() bool {
+	return i.Status == "draft" || i.Status == "pending" || i.Status == "scheduled"
+}
+
+// IsDeleted reports whether the invoice has been soft-deleted. Deleted invoices are left out of lists and
+// stats until they are restored.
+func (i *Invoice) IsDeleted() bool {
+	return i.DeletedAt != nil
+}
+
+// IsWithdrawn reports whether the invoice was voided or cancelled, so it no longer counts towards totals.
+func (i *Invoice) IsWithdrawn() bool {
+	return i.Status == "voided" || i.Status == "cancelled"
+}
+
 // UpdatePaymentInfo updates the payment information for the invoice
 func (i *Invoice) UpdatePaymentInfo(paymentInfo PaymentInformation) error {
 	if err := validatePaymentInfo(paymentInfo); err != nil {
 		return err
 	}
 	i.PaymentInfo = paymentInfo
+	// keep the bank transfer method in step with the bank details
+	for idx, method := range i.PaymentMethods {
+		if method.Type == PaymentMethodBankTransfer {
+			i.PaymentMethods[idx] = BankTransferMethod(paymentInfo)
+			break
+		}
+	}
 	i.UpdatedAt = time.Now()
 	return nil
 }
@@ -227,44 +687,153 @@ func validateDetails(name, phone, email, address string) error {
 	return nil
 }
 
+// ValidatePaymentInfo checks the payment information on its own, before it is attached to an invoice
+func ValidatePaymentInfo(paymentInfo PaymentInformation) error {
+	return validatePaymentInfo(paymentInfo)
+}
+
 // validatePaymentInfo checks the validity of the payment information
 func validatePaymentInfo(paymentInfo PaymentInformation) error {
 	if paymentInfo.AccountName == "" {
 		return errors.New("account name cannot be empty")
 	}
-	if paymentInfo.AccountNumber == "" && len(paymentInfo.AccountNumber) < 11 {
+	if paymentInfo.AccountNumber == "" {
 		return errors.New("account number cannot be empty")
 	}
-	if paymentInfo.RoutingNumber == "" && len(paymentInfo.RoutingNumber) < 7 {
+	if len(paymentInfo.AccountNumber) < 11 {
+		return errors.New("account number must be at least 11 digits")
+	}
+	if !isDigits(paymentInfo.AccountNumber) {
+		return errors.New("account number must contain only digits")
+	}
+	if paymentInfo.RoutingNumber == "" {
 		return errors.New("routing number cannot be empty")
 	}
+	if len(paymentInfo.RoutingNumber) < 7 {
+		return errors.New("routing number must be at least 7 digits")
+	}
+	if !isDigits(paymentInfo.RoutingNumber) {
+		return errors.New("routing number must contain only digits")
+	}
 	if paymentInfo.BankName == "" {
 		return errors.New("bank name cannot be empty")
 	}
 	return nil
 }
 
-// validateItem checks the validity of an item
+// isDigits reports whether s is made of ASCII digits only.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateItem checks the validity of an item.
+// Non-billable items are informational, so they may have a zero quantity or price.
 func validateItem(item Item) error {
 	if item.Description == "" {
 		return errors.New("item description cannot be empty")
 	}
-	if item.Quantity <= 0 {
+	if item.IsBillable() && item.Quantity <= 0 {
 		return errors.New("item quantity must be greater than 0")
 	}
+	if item.Quantity < 0 {
+		return errors.New("item quantity cannot be negative")
+	}
 	if item.UnitPrice < 0 {
 		return errors.New("item unit price must be greater than or equal to 0")
 	}
 	return nil
 }
 
-// calculateTotalAmount calculates the total amount due
+// validateDiscount checks that the discount is a percentage of the billable subtotal. A discount above
+// 100 would take more than the subtotal off the invoice.
+func validateDiscount(discount float64) error {
+	if discount < 0 || discount > 100 {
+		return fmt.Errorf("discount is a percentage of the subtotal and must be between 0 and 100, got %v", discount)
+	}
+	return nil
+}
+
+// calculateTotalAmount calculates the total amount due, skipping non-billable items.
+// The discount is rounded to cents before it is taken off, and the total never drops below zero.
 func calculateTotalAmount(items []Item, discount float64) float64 {
-	total := 0.0
+	subtotal := calculateSubtotal(items)
+	return max(roundCents(subtotal-calculateDiscountAmount(subtotal, discount)), 0)
+}
+
+// priceItems sets the total price of every item from its quantity and unit price, rounded to cents, so a
+// total price sent by the client is never stored.
+func priceItems(items []Item) {
+	for idx := range items {
+		items[idx].TotalPrice = roundCents(float64(items[idx].Quantity) * items[idx].UnitPrice)
+	}
+}
+
+// calculateSubtotal sums the billable items, rounded to cents.
+func calculateSubtotal(items []Item) float64 {
+	subtotal := 0.0
 	for _, item := range items {
-		total += float64(item.Quantity) * item.UnitPrice
+		if !item.IsBillable() {
+			continue
+		}
+		subtotal += float64(item.Quantity) * item.UnitPrice
+	}
+	return roundCents(subtotal)
+}
+
+// calculateDiscountAmount returns the amount a discount percentage takes off the subtotal, rounded to cents.
+func calculateDiscountAmount(subtotal, discount float64) float64 {
+	return roundCents(subtotal * discount / 100)
+}
+
+// roundCents rounds an amount to two decimal places
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// validateExpense checks the validity of a pass-through expense
+func validateExpense(expense Expense) error {
+	if expense.Description == "" {
+		return errors.New("expense description cannot be empty")
+	}
+	if expense.Amount <= 0 {
+		return errors.New("expense amount must be greater than 0")
 	}
-	return total * (1 - discount/100)
+	if expense.ReceiptURL != "" {
+		if _, err := url.ParseRequestURI(expense.ReceiptURL); err != nil {
+			return fmt.Errorf("invalid expense receipt url: %v", err)
+		}
+	}
+	return nil
+}
+
+// calculateExpenseAmount sums the pass-through expenses
+func calculateExpenseAmount(expenses []Expense) float64 {
+	total := 0.0
+	for _, expense := range expenses {
+		total += expense.Amount
+	}
+	return total
+}
+
+// calculateTaxableExpenseAmount sums the pass-through expenses that are marked taxable
+func calculateTaxableExpenseAmount(expenses []Expense) float64 {
+	total := 0.0
+	for _, expense := range expenses {
+		if expense.Taxable {
+			total += expense.Amount
+		}
+	}
+	return total
+}
+
+// SequentialInvoiceNumber formats the invoice number generated from a user's invoice counter, e.g. "INV-000123".
+func SequentialInvoiceNumber(counter int64) string {
+	return fmt.Sprintf("INV-%06d", counter)
 }
 
 // generateID generates a unique ID for the invoice
@@ -0,0 +1,574 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// validInvoiceArgs holds arguments NewInvoice accepts, so tests only change what they are about.
+type validInvoiceArgs struct {
+	invoiceNumber string
+	discount      float64
+	issueDate     string
+	dueDate       string
+	items         []Item
+	paymentInfo   PaymentInformation
+	status        string
+}
+
+func newValidInvoiceArgs() validInvoiceArgs {
+	today := time.Now().UTC()
+	return validInvoiceArgs{
+		invoiceNumber: "INV-000001",
+		issueDate:     today.Format("2006-01-02"),
+		dueDate:       today.AddDate(0, 0, 14).Format("2006-01-02"),
+		items:         []Item{{Description: "Design work", Quantity: 2, UnitPrice: 150}},
+		paymentInfo: PaymentInformation{
+			AccountName:   "Ada Lovelace",
+			AccountNumber: "01234567890",
+			RoutingNumber: "1234567",
+			BankName:      "Numeris Bank",
+		},
+	}
+}
+
+func (a validInvoiceArgs) newInvoice() (*Invoice, error) {
+	return NewInvoice(
+		"user-1",
+		a.invoiceNumber,
+		"USD",
+		a.discount,
+		a.issueDate,
+		a.dueDate,
+		a.items,
+		a.paymentInfo,
+		CustomerDetails{Name: "Grace Hopper", Phone: "+15550100", Email: "grace@example.com", Address: "1 Harbor Road"},
+		SenderDetails{Name: "Ada Lovelace", Phone: "+15550101", Email: "ada@example.com", Address: "2 Engine Street"},
+		a.status,
+	)
+}
+
+func TestNewInvoiceStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+	}{
+		{name: "draft", status: "draft"},
+		{name: "pending", status: "pending"},
+		{name: "omitted", status: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := newValidInvoiceArgs()
+			args.status = tt.status
+
+			invoice, err := args.newInvoice()
+			if err != nil {
+				t.Fatalf("NewInvoice: %v", err)
+			}
+			if invoice.Status != tt.status {
+				t.Errorf("Status = %q, want %q", invoice.Status, tt.status)
+			}
+			if invoice.TotalAmountDue != 300 {
+				t.Errorf("TotalAmountDue = %v, want 300", invoice.TotalAmountDue)
+			}
+		})
+	}
+}
+
+func TestCalculateTotalAmount(t *testing.T) {
+	nonBillable := false
+	items := []Item{
+		{Description: "Design work", Quantity: 3, UnitPrice: 33.33},
+		{Description: "Internal review", Quantity: 1, UnitPrice: 500, Billable: &nonBillable},
+	}
+
+	tests := []struct {
+		name     string
+		discount float64
+		want     float64
+	}{
+		{name: "no discount", discount: 0, want: 99.99},
+		{name: "rounded discount", discount: 33.33, want: 66.66},
+		{name: "full discount", discount: 100, want: 0},
+		{name: "over discount is clamped", discount: 150, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateTotalAmount(items, tt.discount); got != tt.want {
+				t.Errorf("calculateTotalAmount(%v) = %v, want %v", tt.discount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItemWithoutBillableFlag(t *testing.T) {
+	// items stored or sent before the billable flag existed have no billable field
+	doc, err := bson.Marshal(bson.M{"description": "Design work", "quantity": 2, "unit_price": 150.0})
+	if err != nil {
+		t.Fatalf("bson.Marshal: %v", err)
+	}
+	var stored Item
+	if err := bson.Unmarshal(doc, &stored); err != nil {
+		t.Fatalf("bson.Unmarshal: %v", err)
+	}
+
+	var sent Item
+	if err := json.Unmarshal([]byte(`{"description":"Design work","quantity":2,"unit_price":150}`), &sent); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	for name, item := range map[string]Item{"bson": stored, "json": sent} {
+		t.Run(name, func(t *testing.T) {
+			if !item.IsBillable() {
+				t.Error("IsBillable() = false, want true")
+			}
+			if got := calculateTotalAmount([]Item{item}, 0); got != 300 {
+				t.Errorf("calculateTotalAmount() = %v, want 300", got)
+			}
+		})
+	}
+}
+
+func TestNewInvoiceDiscount(t *testing.T) {
+	tests := []struct {
+		name      string
+		discount  float64
+		wantTotal float64
+		wantErr   bool
+	}{
+		{name: "no discount", discount: 0, wantTotal: 300},
+		{name: "partial discount", discount: 12.5, wantTotal: 262.5},
+		{name: "full discount", discount: 100, wantTotal: 0},
+		{name: "over discount", discount: 100.01, wantErr: true},
+		{name: "negative discount", discount: -5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := newValidInvoiceArgs()
+			args.discount = tt.discount
+
+			invoice, err := args.newInvoice()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewInvoice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if invoice.TotalAmountDue != tt.wantTotal {
+				t.Errorf("TotalAmountDue = %v, want %v", invoice.TotalAmountDue, tt.wantTotal)
+			}
+			if invoice.TotalAmountDue < 0 {
+				t.Errorf("TotalAmountDue = %v is negative", invoice.TotalAmountDue)
+			}
+		})
+	}
+}
+
+func TestInvoiceRefund(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		payments   []Payment
+		amount     float64
+		wantAmount float64
+		wantErr    bool
+	}{
+		{name: "full refund", status: "paid", payments: []Payment{{Amount: 300}}, wantAmount: 300},
+		{name: "partial refund", status: "paid", payments: []Payment{{Amount: 300}}, amount: 120.5, wantAmount: 120.5},
+		{name: "paid without payments", status: "paid", wantAmount: 300},
+		{name: "more than paid", status: "paid", payments: []Payment{{Amount: 300}}, amount: 300.01, wantErr: true},
+		{name: "negative amount", status: "paid", payments: []Payment{{Amount: 300}}, amount: -1, wantErr: true},
+		{name: "issued invoice", status: "issued", wantErr: true},
+		{name: "already refunded", status: "refunded", payments: []Payment{{Amount: 300}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &Invoice{Status: tt.status, TotalAmountDue: 300, Payments: tt.payments}
+
+			err := invoice.Refund(tt.amount, "duplicate charge")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Refund() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if invoice.Status != tt.status {
+					t.Errorf("status = %q after a rejected refund, want %q", invoice.Status, tt.status)
+				}
+				return
+			}
+			if invoice.Status != "refunded" || invoice.RefundAmount != tt.wantAmount || invoice.RefundReason != "duplicate charge" {
+				t.Errorf("invoice is %s with %v refunded for %q, want refunded with %v for %q",
+					invoice.Status, invoice.RefundAmount, invoice.RefundReason, tt.wantAmount, "duplicate charge")
+			}
+		})
+	}
+}
+
+func TestItemTotalPriceIsComputed(t *testing.T) {
+	args := newValidInvoiceArgs()
+	args.items = []Item{
+		{Description: "Design work", Quantity: 2, UnitPrice: 150, TotalPrice: 1},
+		{Description: "Hosting", Quantity: 3, UnitPrice: 19.99, TotalPrice: 9999},
+	}
+
+	invoice, err := args.newInvoice()
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+	for idx, want := range []float64{300, 59.97} {
+		if got := invoice.Items[idx].TotalPrice; got != want {
+			t.Errorf("NewInvoice item %d TotalPrice = %v, want %v", idx, got, want)
+		}
+	}
+	if invoice.TotalAmountDue != 359.97 {
+		t.Errorf("TotalAmountDue = %v, want 359.97", invoice.TotalAmountDue)
+	}
+
+	if err := invoice.AddItem(Item{Description: "Support", Quantity: 1, UnitPrice: 40, TotalPrice: 0.01}); err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+	if got := invoice.Items[2].TotalPrice; got != 40 {
+		t.Errorf("AddItem TotalPrice = %v, want 40", got)
+	}
+	if invoice.TotalAmountDue != 399.97 {
+		t.Errorf("TotalAmountDue after AddItem = %v, want 399.97", invoice.TotalAmountDue)
+	}
+}
+
+func TestValidatePaymentInfo(t *testing.T) {
+	valid := newValidInvoiceArgs().paymentInfo
+
+	tests := []struct {
+		name    string
+		change  func(info *PaymentInformation)
+		wantErr bool
+	}{
+		{name: "valid", change: func(info *PaymentInformation) {}},
+		{name: "empty account name", change: func(info *PaymentInformation) { info.AccountName = "" }, wantErr: true},
+		{name: "empty account number", change: func(info *PaymentInformation) { info.AccountNumber = "" }, wantErr: true},
+		{name: "short account number", change: func(info *PaymentInformation) { info.AccountNumber = "0123456789" }, wantErr: true},
+		{name: "non-numeric account number", change: func(info *PaymentInformation) { info.AccountNumber = "0123456789A" }, wantErr: true},
+		{name: "empty routing number", change: func(info *PaymentInformation) { info.RoutingNumber = "" }, wantErr: true},
+		{name: "short routing number", change: func(info *PaymentInformation) { info.RoutingNumber = "123456" }, wantErr: true},
+		{name: "non-numeric routing number", change: func(info *PaymentInformation) { info.RoutingNumber = "123-4567" }, wantErr: true},
+		{name: "empty bank name", change: func(info *PaymentInformation) { info.BankName = "" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := valid
+			tt.change(&info)
+			if err := validatePaymentInfo(info); (err != nil) != tt.wantErr {
+				t.Errorf("validatePaymentInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSequentialInvoiceNumber(t *testing.T) {
+	tests := map[int64]string{1: "INV-000001", 123: "INV-000123", 999999: "INV-999999", 1234567: "INV-1234567"}
+	for counter, want := range tests {
+		if got := SequentialInvoiceNumber(counter); got != want {
+			t.Errorf("SequentialInvoiceNumber(%d) = %q, want %q", counter, got, want)
+		}
+	}
+}
+
+func TestRestoreInvoice(t *testing.T) {
+	exported, err := newValidInvoiceArgs().newInvoice()
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+	exported.IssueDate = "2021-03-01"
+	exported.DueDate = "2021-03-31"
+	exported.Status = "paid"
+	exported.PDFFileID = "stored-pdf"
+
+	tests := []struct {
+		name       string
+		modify     func(invoice *Invoice)
+		wantStatus string
+		wantErr    bool
+	}{
+		{name: "paid invoice from the past", wantStatus: "paid"},
+		{name: "unknown status", modify: func(invoice *Invoice) { invoice.Status = "archived" }, wantStatus: "draft"},
+		{name: "no number", modify: func(invoice *Invoice) { invoice.InvoiceNumber = " " }, wantErr: true},
+		{name: "no items", modify: func(invoice *Invoice) { invoice.Items = nil }, wantErr: true},
+		{name: "due before issue", modify: func(invoice *Invoice) { invoice.DueDate = "2021-02-01" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := *exported
+			invoice.Items = append([]Item(nil), exported.Items...)
+			if tt.modify != nil {
+				tt.modify(&invoice)
+			}
+
+			got, err := RestoreInvoice(invoice)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RestoreInvoice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.InvoiceID == exported.InvoiceID {
+				t.Error("restored invoice kept the exported InvoiceID")
+			}
+			if got.InvoiceNumber != exported.InvoiceNumber || got.IssueDate != exported.IssueDate || got.Status != tt.wantStatus {
+				t.Errorf("RestoreInvoice() = %s issued %s %s, want %s issued %s %s",
+					got.InvoiceNumber, got.IssueDate, got.Status, exported.InvoiceNumber, exported.IssueDate, tt.wantStatus)
+			}
+			if got.TotalAmountDue != exported.TotalAmountDue {
+				t.Errorf("TotalAmountDue = %v, want %v", got.TotalAmountDue, exported.TotalAmountDue)
+			}
+			if got.PDFFileID != "" {
+				t.Errorf("PDFFileID = %q, want the stored document dropped", got.PDFFileID)
+			}
+		})
+	}
+}
+
+func TestRestoreInvoiceWithZeroTimes(t *testing.T) {
+	exported, err := newValidInvoiceArgs().newInvoice()
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+	raw, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	// exports written before unset times were left out carry them as the zero time
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	fields["deleted_at"] = "0001-01-01T00:00:00Z"
+	fields["scheduled_send_at"] = "0001-01-01T00:00:00Z"
+	if raw, err = json.Marshal(fields); err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	var old Invoice
+	if err := json.Unmarshal(raw, &old); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	got, err := RestoreInvoice(old)
+	if err != nil {
+		t.Fatalf("RestoreInvoice: %v", err)
+	}
+	if got.IsDeleted() || got.ScheduledSendAt != nil {
+		t.Errorf("restored invoice deleted %v, scheduled at %v, want neither", got.IsDeleted(), got.ScheduledSendAt)
+	}
+}
+
+func TestInvoiceApplyPatch(t *testing.T) {
+	notes := "Thanks for your business"
+	discount := 10.0
+	badDiscount := 120.0
+	dueDate := "2021-04-30"
+	earlyDueDate := "2021-02-28"
+
+	tests := []struct {
+		name      string
+		status    string
+		patch     InvoicePatch
+		wantNotes string
+		wantDue   string
+		wantTotal float64
+		wantErr   bool
+	}{
+		{name: "notes only", status: "draft", patch: InvoicePatch{Notes: &notes}, wantNotes: notes, wantDue: "2021-03-31", wantTotal: 300},
+		{name: "discount", status: "draft", patch: InvoicePatch{Discount: &discount}, wantDue: "2021-03-31", wantTotal: 270},
+		{
+			name:      "items",
+			status:    "pending",
+			patch:     InvoicePatch{Items: []Item{{Description: "Review", Quantity: 3, UnitPrice: 40}}},
+			wantDue:   "2021-03-31",
+			wantTotal: 120,
+		},
+		// the invoice was issued in 2021, which NewInvoice would reject as a past date
+		{name: "due date of an old draft", status: "draft", patch: InvoicePatch{DueDate: &dueDate}, wantDue: dueDate, wantTotal: 300},
+		{name: "due before issue", status: "draft", patch: InvoicePatch{DueDate: &earlyDueDate}, wantErr: true},
+		{name: "invalid discount", status: "draft", patch: InvoicePatch{Discount: &badDiscount}, wantErr: true},
+		{name: "no items", status: "draft", patch: InvoicePatch{Items: []Item{}}, wantErr: true},
+		{name: "issued invoice", status: "issued", patch: InvoicePatch{Notes: &notes}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice, err := newValidInvoiceArgs().newInvoice()
+			if err != nil {
+				t.Fatalf("NewInvoice: %v", err)
+			}
+			invoice.IssueDate = "2021-03-01"
+			invoice.DueDate = "2021-03-31"
+			invoice.Status = tt.status
+			before := *invoice
+
+			err = invoice.ApplyPatch(tt.patch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyPatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if invoice.Notes != before.Notes || invoice.Discount != before.Discount || invoice.DueDate != before.DueDate ||
+					invoice.TotalAmountDue != before.TotalAmountDue {
+					t.Errorf("rejected patch changed the invoice to %+v", invoice)
+				}
+				return
+			}
+			if invoice.Notes != tt.wantNotes || invoice.DueDate != tt.wantDue || invoice.TotalAmountDue != tt.wantTotal {
+				t.Errorf("ApplyPatch() = notes %q, due %s, total %v; want notes %q, due %s, total %v",
+					invoice.Notes, invoice.DueDate, invoice.TotalAmountDue, tt.wantNotes, tt.wantDue, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestInvoicePatchFields(t *testing.T) {
+	notes := "note"
+	discount := 5.0
+
+	tests := []struct {
+		name         string
+		patch        InvoicePatch
+		want         []string
+		wantEmpty    bool
+		changesTotal bool
+	}{
+		{name: "empty", wantEmpty: true},
+		{name: "notes", patch: InvoicePatch{Notes: &notes}, want: []string{"notes"}},
+		{name: "discount", patch: InvoicePatch{Discount: &discount}, want: []string{"discount"}, changesTotal: true},
+		{
+			name:         "items and notes",
+			patch:        InvoicePatch{Notes: &notes, Items: []Item{{Description: "Review", Quantity: 1, UnitPrice: 40}}},
+			want:         []string{"notes", "items"},
+			changesTotal: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.patch.IsEmpty(); got != tt.wantEmpty {
+				t.Errorf("IsEmpty() = %v, want %v", got, tt.wantEmpty)
+			}
+			if got := tt.patch.ChangesTotal(); got != tt.changesTotal {
+				t.Errorf("ChangesTotal() = %v, want %v", got, tt.changesTotal)
+			}
+			if got := strings.Join(tt.patch.Fields(), ","); got != strings.Join(tt.want, ",") {
+				t.Errorf("Fields() = %s, want %s", got, strings.Join(tt.want, ","))
+			}
+		})
+	}
+}
+
+func TestInvoiceAmend(t *testing.T) {
+	issuedAt := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	grace := 15 * time.Minute
+	notes := "Corrected the bank account"
+
+	tests := []struct {
+		name     string
+		status   string
+		issuedAt time.Time
+		now      time.Time
+		wantErr  bool
+		// wantClosed is set when the error must be ErrAmendWindowClosed
+		wantClosed bool
+	}{
+		{name: "right after issuing", status: "issued", issuedAt: issuedAt, now: issuedAt.Add(time.Minute)},
+		{name: "at the end of the window", status: "issued", issuedAt: issuedAt, now: issuedAt.Add(grace)},
+		{name: "after the window", status: "issued", issuedAt: issuedAt, now: issuedAt.Add(grace + time.Second), wantErr: true, wantClosed: true},
+		{name: "issue time unknown", status: "issued", now: issuedAt, wantErr: true, wantClosed: true},
+		{name: "draft", status: "draft", now: issuedAt, wantErr: true},
+		{name: "paid", status: "paid", issuedAt: issuedAt, now: issuedAt.Add(time.Minute), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice, err := newValidInvoiceArgs().newInvoice()
+			if err != nil {
+				t.Fatalf("NewInvoice: %v", err)
+			}
+			invoice.Status = tt.status
+			if !tt.issuedAt.IsZero() {
+				invoice.IssuedAt = &tt.issuedAt
+			}
+
+			err = invoice.Amend(InvoicePatch{Notes: &notes}, tt.now, grace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Amend() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if closed := errors.Is(err, ErrAmendWindowClosed); closed != tt.wantClosed {
+				t.Errorf("Amend() error = %v, want ErrAmendWindowClosed %v", err, tt.wantClosed)
+			}
+			if amended := invoice.Notes == notes; amended == tt.wantErr {
+				t.Errorf("notes amended = %v, want %v", amended, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvoiceIsDeleted(t *testing.T) {
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		deletedAt *time.Time
+		want      bool
+	}{
+		{name: "never deleted", want: false},
+		{name: "deleted", deletedAt: &deletedAt, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &Invoice{DeletedAt: tt.deletedAt}
+			if got := invoice.IsDeleted(); got != tt.want {
+				t.Errorf("IsDeleted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvoiceJSONOmitsUnsetTimes(t *testing.T) {
+	invoice, err := newValidInvoiceArgs().newInvoice()
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+	raw, err := json.Marshal(invoice)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	for _, key := range []string{"issued_at", "paid_at", "voided_at", "cancelled_at", "refunded_at", "scheduled_send_at", "deleted_at"} {
+		if value, ok := fields[key]; ok {
+			t.Errorf("%s = %v, want it left out of a new invoice", key, value)
+		}
+	}
+
+	if err := invoice.Cancel(); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	raw, err = json.Marshal(invoice)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if !strings.Contains(string(raw), `"cancelled_at"`) {
+		t.Errorf("cancelled invoice JSON has no cancelled_at: %s", raw)
+	}
+}
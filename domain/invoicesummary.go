@@ -1,8 +1,39 @@
 package domain
 
+// InvoiceSummary holds the invoice totals of a user grouped by status.
 type InvoiceSummary struct {
-	TotalPaid    float64
-	TotalOverdue float64
-	TotalPending float64
-	TotalUnpaid  float64
+	TotalPaid    float64 `json:"total_paid" bson:"total_paid"`
+	TotalOverdue float64 `json:"total_overdue" bson:"total_overdue"`
+	TotalDraft   float64 `json:"total_draft" bson:"total_draft"`
+	TotalPending float64 `json:"total_pending" bson:"total_pending"`
+	TotalUnpaid  float64 `json:"total_unpaid" bson:"total_unpaid"`
+}
+
+// SummaryReconciliation reports the cached invoice summary of a user before and after it was recomputed
+// from the invoices.
+type SummaryReconciliation struct {
+	Before  InvoiceSummary `json:"before"`
+	After   InvoiceSummary `json:"after"`
+	Changed bool           `json:"changed"`
+}
+
+// InvoiceGroup is a categorised list of invoices with its size.
+type InvoiceGroup struct {
+	Count    int       `json:"count"`
+	Invoices []Invoice `json:"invoices"`
+}
+
+// NewInvoiceGroup wraps invoices into an InvoiceGroup.
+func NewInvoiceGroup(invoices []Invoice) InvoiceGroup {
+	if invoices == nil {
+		invoices = make([]Invoice, 0)
+	}
+	return InvoiceGroup{Count: len(invoices), Invoices: invoices}
+}
+
+// ActionNeeded groups the invoices that require the user's attention.
+type ActionNeeded struct {
+	ReadyToIssue InvoiceGroup `json:"ready_to_issue"`
+	Overdue      InvoiceGroup `json:"overdue"`
+	DueSoon      InvoiceGroup `json:"due_soon"`
 }
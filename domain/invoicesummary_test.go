@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestInvoiceSummaryRoundTrip(t *testing.T) {
+	summary := InvoiceSummary{
+		TotalPaid:    920.5,
+		TotalOverdue: 240,
+		TotalDraft:   10.25,
+		TotalPending: 20,
+		TotalUnpaid:  300,
+	}
+
+	t.Run("bson", func(t *testing.T) {
+		data, err := bson.Marshal(summary)
+		if err != nil {
+			t.Fatalf("bson.Marshal: %v", err)
+		}
+		var got InvoiceSummary
+		if err := bson.Unmarshal(data, &got); err != nil {
+			t.Fatalf("bson.Unmarshal: %v", err)
+		}
+		if got != summary {
+			t.Errorf("bson round trip = %+v, want %+v", got, summary)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(summary)
+		if err != nil {
+			t.Fatalf("json.Marshal: %v", err)
+		}
+		var got InvoiceSummary
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("json.Unmarshal: %v", err)
+		}
+		if got != summary {
+			t.Errorf("json round trip = %+v, want %+v", got, summary)
+		}
+	})
+
+	t.Run("same field names", func(t *testing.T) {
+		data, err := bson.Marshal(summary)
+		if err != nil {
+			t.Fatalf("bson.Marshal: %v", err)
+		}
+		var stored map[string]any
+		if err := bson.Unmarshal(data, &stored); err != nil {
+			t.Fatalf("bson.Unmarshal: %v", err)
+		}
+
+		data, err = json.Marshal(summary)
+		if err != nil {
+			t.Fatalf("json.Marshal: %v", err)
+		}
+		var returned map[string]any
+		if err := json.Unmarshal(data, &returned); err != nil {
+			t.Fatalf("json.Unmarshal: %v", err)
+		}
+
+		for field := range stored {
+			if _, ok := returned[field]; !ok {
+				t.Errorf("stored field %q is missing from the JSON response", field)
+			}
+		}
+		if len(stored) != len(returned) {
+			t.Errorf("stored fields %v, returned fields %v", stored, returned)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ItemSortKeys lists the keys invoice items can be sorted by. "order" is the manual order of the items.
+var ItemSortKeys = []string{"order", "description", "total", "quantity"}
+
+// ValidateItemSort checks an item sort option: one of ItemSortKeys, optionally prefixed with "-" for
+// descending order. An empty option keeps the manual order.
+func ValidateItemSort(option string) error {
+	key := strings.TrimPrefix(option, "-")
+	if option == "" {
+		return nil
+	}
+	for _, known := range ItemSortKeys {
+		if key == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown item sort %q, expected one of %s", option, strings.Join(ItemSortKeys, ", "))
+}
+
+// SortItems orders the invoice items by option, see ValidateItemSort. Ties keep the manual order.
+func (i *Invoice) SortItems(option string) error {
+	if err := ValidateItemSort(option); err != nil {
+		return err
+	}
+
+	descending := strings.HasPrefix(option, "-")
+	key := strings.TrimPrefix(option, "-")
+
+	less := func(a, b Item) bool {
+		switch key {
+		case "description":
+			return strings.ToLower(a.Description) < strings.ToLower(b.Description)
+		case "total":
+			return a.TotalPrice < b.TotalPrice
+		case "quantity":
+			return a.Quantity < b.Quantity
+		default:
+			return a.Order < b.Order
+		}
+	}
+
+	// sort by manual order first so equal keys stay in manual order
+	sort.SliceStable(i.Items, func(x, y int) bool { return i.Items[x].Order < i.Items[y].Order })
+	sort.SliceStable(i.Items, func(x, y int) bool {
+		if descending {
+			return less(i.Items[y], i.Items[x])
+		}
+		return less(i.Items[x], i.Items[y])
+	})
+	return nil
+}
+
+// numberItems gives every item without a manual order the position it was added at, after the
+// highest order already set, so the manual order defaults to insertion order.
+func numberItems(items []Item) {
+	next := 0
+	for _, item := range items {
+		next = max(next, item.Order)
+	}
+	for idx := range items {
+		if items[idx].Order <= 0 {
+			next++
+			items[idx].Order = next
+		}
+	}
+}
+
+// SetItemSort sets the item order used when the invoice is shown or rendered and sorts the items by it.
+func (i *Invoice) SetItemSort(option string) error {
+	if err := i.SortItems(option); err != nil {
+		return err
+	}
+	i.ItemSort = option
+	return nil
+}
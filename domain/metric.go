@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// PaymentTimeMetric describes how long it takes, in days, for issued invoices to be paid.
+type PaymentTimeMetric struct {
+	AverageDays float64 `json:"average_days"`
+	MedianDays  float64 `json:"median_days"`
+	SampleSize  int     `json:"sample_size"`
+}
+
+// NewPaymentTimeMetric computes the mean and median of the given days-to-payment samples.
+// An empty sample yields a zeroed metric.
+func NewPaymentTimeMetric(days []int64) *PaymentTimeMetric {
+	metric := &PaymentTimeMetric{SampleSize: len(days)}
+	if len(days) == 0 {
+		return metric
+	}
+
+	sorted := make([]int64, len(days))
+	copy(sorted, days)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total int64
+	for _, d := range sorted {
+		total += d
+	}
+	metric.AverageDays = float64(total) / float64(len(sorted))
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		metric.MedianDays = float64(sorted[mid-1]+sorted[mid]) / 2
+	} else {
+		metric.MedianDays = float64(sorted[mid])
+	}
+
+	return metric
+}
+
+// RevenuePeriod is the revenue collected from paid invoices between From (inclusive) and To (exclusive).
+type RevenuePeriod struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Revenue      float64   `json:"revenue"`
+	InvoiceCount int64     `json:"invoice_count"`
+}
+
+// TopCustomer is the amount billed to one customer, identified by their lowercased email address.
+type TopCustomer struct {
+	Email        string  `json:"email" bson:"_id"`
+	Name         string  `json:"name" bson:"name"`
+	Total        float64 `json:"total" bson:"total"`
+	InvoiceCount int64   `json:"invoice_count" bson:"invoice_count"`
+}
+
+// MonthlyRevenue is the revenue collected from invoices paid in one calendar month, formatted as YYYY-MM.
+type MonthlyRevenue struct {
+	Month        string  `json:"month" bson:"_id"`
+	Revenue      float64 `json:"revenue" bson:"revenue"`
+	InvoiceCount int64   `json:"invoice_count" bson:"invoice_count"`
+}
+
+// NewRevenueSeries returns one MonthlyRevenue per month for the given number of months starting with the month
+// of from, taking each month's revenue from revenue and zero for the months missing from it.
+func NewRevenueSeries(from time.Time, months int, revenue []MonthlyRevenue) []MonthlyRevenue {
+	byMonth := make(map[string]MonthlyRevenue, len(revenue))
+	for _, month := range revenue {
+		byMonth[month.Month] = month
+	}
+
+	start := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	series := make([]MonthlyRevenue, months)
+	for idx := range series {
+		month := start.AddDate(0, idx, 0).Format("2006-01")
+		entry := byMonth[month]
+		series[idx] = MonthlyRevenue{
+			Month:        month,
+			Revenue:      math.Round(entry.Revenue*100) / 100,
+			InvoiceCount: entry.InvoiceCount,
+		}
+	}
+	return series
+}
+
+// RevenueComparison compares the revenue of a period with the equivalent prior period.
+// The percentage changes are nil when the prior period has nothing to compare against.
+type RevenueComparison struct {
+	Period        string        `json:"period"`
+	Current       RevenuePeriod `json:"current"`
+	Previous      RevenuePeriod `json:"previous"`
+	RevenueChange *float64      `json:"revenue_change_percent"`
+	CountChange   *float64      `json:"invoice_count_change_percent"`
+}
+
+// NewRevenueComparison builds a RevenueComparison and computes the percentage changes.
+func NewRevenueComparison(period string, current, previous RevenuePeriod) *RevenueComparison {
+	return &RevenueComparison{
+		Period:        period,
+		Current:       current,
+		Previous:      previous,
+		RevenueChange: percentChange(previous.Revenue, current.Revenue),
+		CountChange:   percentChange(float64(previous.InvoiceCount), float64(current.InvoiceCount)),
+	}
+}
+
+// percentChange returns the change from previous to current in percent, rounded to two decimals,
+// or nil when previous is zero and the change is undefined.
+func percentChange(previous, current float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round((current-previous)/previous*10000) / 100
+	return &change
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewRevenueSeries(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    time.Time
+		months  int
+		revenue []MonthlyRevenue
+		want    []MonthlyRevenue
+	}{
+		{
+			name:   "no revenue",
+			from:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			months: 3,
+			want:   []MonthlyRevenue{{Month: "2024-03"}, {Month: "2024-04"}, {Month: "2024-05"}},
+		},
+		{
+			name:   "gaps are zero filled",
+			from:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			months: 4,
+			revenue: []MonthlyRevenue{
+				{Month: "2024-03", Revenue: 120, InvoiceCount: 2},
+				{Month: "2024-06", Revenue: 80.5, InvoiceCount: 1},
+			},
+			want: []MonthlyRevenue{
+				{Month: "2024-03", Revenue: 120, InvoiceCount: 2},
+				{Month: "2024-04"},
+				{Month: "2024-05"},
+				{Month: "2024-06", Revenue: 80.5, InvoiceCount: 1},
+			},
+		},
+		{
+			name:   "across a year and from mid month",
+			from:   time.Date(2023, 11, 17, 9, 30, 0, 0, time.UTC),
+			months: 3,
+			revenue: []MonthlyRevenue{
+				{Month: "2024-01", Revenue: 10.005, InvoiceCount: 1},
+			},
+			want: []MonthlyRevenue{
+				{Month: "2023-11"},
+				{Month: "2023-12"},
+				{Month: "2024-01", Revenue: 10.01, InvoiceCount: 1},
+			},
+		},
+		{
+			name:   "months outside the series are left out",
+			from:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			months: 1,
+			revenue: []MonthlyRevenue{
+				{Month: "2023-12", Revenue: 500, InvoiceCount: 3},
+				{Month: "2024-01", Revenue: 40, InvoiceCount: 1},
+				{Month: "2024-02", Revenue: 700, InvoiceCount: 4},
+			},
+			want: []MonthlyRevenue{{Month: "2024-01", Revenue: 40, InvoiceCount: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRevenueSeries(tt.from, tt.months, tt.revenue); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewRevenueSeries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Payment is an amount received against an invoice.
+type Payment struct {
+	Amount float64   `json:"amount" bson:"amount"`
+	PaidAt time.Time `json:"paid_at" bson:"paid_at"`
+	Method string    `json:"method,omitempty" bson:"method,omitempty"`
+}
+
+// NormalizePaymentMethod trims and lower-cases the method a payment was made with, so "Bank_Transfer " and
+// "bank_transfer" are filtered as the same method.
+func NormalizePaymentMethod(method string) string {
+	return strings.ToLower(strings.TrimSpace(method))
+}
+
+// AmountPaid returns the sum of the payments recorded on the invoice.
+func (i *Invoice) AmountPaid() float64 {
+	var paid float64
+	for _, payment := range i.Payments {
+		paid += payment.Amount
+	}
+	return roundCents(paid)
+}
+
+// BalanceDue returns what the customer still owes on the invoice: the total minus the recorded payments.
+func (i *Invoice) BalanceDue() float64 {
+	return roundCents(i.TotalAmountDue - i.AmountPaid())
+}
+
+// OutstandingInvoice is an unpaid invoice with the balance still due on it.
+type OutstandingInvoice struct {
+	InvoiceID       string          `json:"invoice_id" bson:"invoice_id"`
+	InvoiceNumber   string          `json:"invoice_number" bson:"invoice_number"`
+	Status          string          `json:"status" bson:"status"`
+	IssueDate       string          `json:"issue_date" bson:"issue_date"`
+	DueDate         string          `json:"due_date" bson:"due_date"`
+	BillingCurrency string          `json:"billing_currency" bson:"billing_currency"`
+	Customer        CustomerDetails `json:"customer" bson:"customer"`
+	TotalAmountDue  float64         `json:"total_amount_due" bson:"total_amount_due"`
+	AmountPaid      float64         `json:"amount_paid" bson:"amount_paid"`
+	BalanceDue      float64         `json:"balance_due" bson:"balance_due"`
+}
+
+// OutstandingBalances lists the unpaid invoices of a user by due date, with the total still owed on them.
+type OutstandingBalances struct {
+	Invoices         []OutstandingInvoice `json:"invoices"`
+	TotalOutstanding float64              `json:"total_outstanding"`
+}
+
+// NewOutstandingBalances totals the balance due of the invoices.
+func NewOutstandingBalances(invoices []OutstandingInvoice) *OutstandingBalances {
+	var total float64
+	for _, invoice := range invoices {
+		total += invoice.BalanceDue
+	}
+	return &OutstandingBalances{
+		Invoices:         invoices,
+		TotalOutstanding: roundCents(total),
+	}
+}
+
+// LedgerPayment is a payment recorded against one of a user's invoices, with a reference to the invoice.
+type LedgerPayment struct {
+	InvoiceID     string          `json:"invoice_id" bson:"invoice_id"`
+	InvoiceNumber string          `json:"invoice_number" bson:"invoice_number"`
+	InvoiceStatus string          `json:"invoice_status" bson:"invoice_status"`
+	Customer      CustomerDetails `json:"customer" bson:"customer"`
+	Currency      string          `json:"currency" bson:"currency"`
+	Amount        float64         `json:"amount" bson:"amount"`
+	PaidAt        time.Time       `json:"paid_at" bson:"paid_at"`
+	Method        string          `json:"method,omitempty" bson:"method,omitempty"`
+}
+
+// PaymentFilter narrows the payments ledger. Empty fields are not applied; From and To bound the payment
+// time and are both inclusive.
+type PaymentFilter struct {
+	Method string
+	From   time.Time
+	To     time.Time
+}
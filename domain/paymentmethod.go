@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// Payment method types an invoice can offer.
+const (
+	PaymentMethodBankTransfer = "bank_transfer"
+	PaymentMethodPayPal       = "paypal"
+	PaymentMethodCrypto       = "crypto"
+	PaymentMethodPayLink      = "pay_link"
+)
+
+// MaxPaymentMethods is the maximum number of payment methods on a single invoice.
+const MaxPaymentMethods = 5
+
+// PaymentMethod is one way the customer can pay an invoice. Only the fields of its Type are used:
+// the bank fields for a bank transfer, Email for PayPal, Network and Address for crypto and URL for a pay link.
+type PaymentMethod struct {
+	Type          string `json:"type" bson:"type"`
+	Label         string `json:"label,omitempty" bson:"label,omitempty"`
+	AccountName   string `json:"account_name,omitempty" bson:"account_name,omitempty"`
+	AccountNumber string `json:"account_number,omitempty" bson:"account_number,omitempty"`
+	RoutingNumber string `json:"routing_number,omitempty" bson:"routing_number,omitempty"`
+	BankName      string `json:"bank_name,omitempty" bson:"bank_name,omitempty"`
+	Email         string `json:"email,omitempty" bson:"email,omitempty"`
+	Network       string `json:"network,omitempty" bson:"network,omitempty"`
+	Address       string `json:"address,omitempty" bson:"address,omitempty"`
+	URL           string `json:"url,omitempty" bson:"url,omitempty"`
+}
+
+// BankTransferMethod maps the bank details of PaymentInformation to a bank transfer PaymentMethod.
+func BankTransferMethod(paymentInfo PaymentInformation) PaymentMethod {
+	return PaymentMethod{
+		Type:          PaymentMethodBankTransfer,
+		AccountName:   paymentInfo.AccountName,
+		AccountNumber: paymentInfo.AccountNumber,
+		RoutingNumber: paymentInfo.RoutingNumber,
+		BankName:      paymentInfo.BankName,
+	}
+}
+
+// Validate checks that the fields required by the payment method type are set and well formed.
+func (m PaymentMethod) Validate() error {
+	switch m.Type {
+	case PaymentMethodBankTransfer:
+		return validatePaymentInfo(PaymentInformation{
+			AccountName:   m.AccountName,
+			AccountNumber: m.AccountNumber,
+			RoutingNumber: m.RoutingNumber,
+			BankName:      m.BankName,
+		})
+	case PaymentMethodPayPal:
+		if _, err := mail.ParseAddress(m.Email); err != nil {
+			return errors.New("paypal payment method needs a valid email")
+		}
+	case PaymentMethodCrypto:
+		if strings.TrimSpace(m.Network) == "" {
+			return errors.New("crypto payment method needs a network")
+		}
+		if strings.TrimSpace(m.Address) == "" {
+			return errors.New("crypto payment method needs a wallet address")
+		}
+	case PaymentMethodPayLink:
+		link, err := url.Parse(m.URL)
+		if err != nil || link.Scheme != "https" || link.Host == "" {
+			return errors.New("pay link payment method needs an https URL")
+		}
+	default:
+		return fmt.Errorf("unknown payment method type %q", m.Type)
+	}
+	return nil
+}
+
+// Name returns the label of the payment method, or a name derived from its type.
+func (m PaymentMethod) Name() string {
+	if m.Label != "" {
+		return m.Label
+	}
+	switch m.Type {
+	case PaymentMethodBankTransfer:
+		return fmt.Sprintf("Bank Transfer (%s)", m.BankName)
+	case PaymentMethodPayPal:
+		return "PayPal"
+	case PaymentMethodCrypto:
+		return fmt.Sprintf("Crypto (%s)", m.Network)
+	case PaymentMethodPayLink:
+		return "Pay Online"
+	}
+	return m.Type
+}
+
+// Details returns the lines a customer needs to pay with the method, for documents and emails.
+func (m PaymentMethod) Details() string {
+	switch m.Type {
+	case PaymentMethodBankTransfer:
+		return fmt.Sprintf("Account Name: %s\nAccount Number: %s\nRouting Number: %s\nBank Name: %s",
+			m.AccountName, m.AccountNumber, m.RoutingNumber, m.BankName)
+	case PaymentMethodPayPal:
+		return fmt.Sprintf("PayPal: %s", m.Email)
+	case PaymentMethodCrypto:
+		return fmt.Sprintf("Network: %s\nAddress: %s", m.Network, m.Address)
+	case PaymentMethodPayLink:
+		return fmt.Sprintf("Pay online: %s", m.URL)
+	}
+	return ""
+}
+
+// SetPaymentMethods validates and sets the payment methods of the invoice. The bank details of PaymentInfo,
+// when set, are kept as the first method unless a bank transfer is already listed, so invoices created with
+// bank details only keep working.
+func (i *Invoice) SetPaymentMethods(methods []PaymentMethod) error {
+	hasBankTransfer := false
+	for _, method := range methods {
+		if err := method.Validate(); err != nil {
+			return err
+		}
+		if method.Type == PaymentMethodBankTransfer {
+			hasBankTransfer = true
+		}
+	}
+
+	combined := make([]PaymentMethod, 0, len(methods)+1)
+	if !hasBankTransfer && i.PaymentInfo != (PaymentInformation{}) {
+		combined = append(combined, BankTransferMethod(i.PaymentInfo))
+	}
+	combined = append(combined, methods...)
+
+	if len(combined) == 0 {
+		return errors.New("invoice must have at least one payment method")
+	}
+	if len(combined) > MaxPaymentMethods {
+		return fmt.Errorf("an invoice can have at most %d payment methods", MaxPaymentMethods)
+	}
+
+	i.PaymentMethods = combined
+	return nil
+}
+
+// PaymentMethodList returns the payment methods of the invoice. Invoices stored before payment methods
+// existed only have PaymentInfo, which is returned as a bank transfer.
+func (i *Invoice) PaymentMethodList() []PaymentMethod {
+	if len(i.PaymentMethods) > 0 {
+		return i.PaymentMethods
+	}
+	if i.PaymentInfo != (PaymentInformation{}) {
+		return []PaymentMethod{BankTransferMethod(i.PaymentInfo)}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "strings"
+
+// CustomerProfile is the full profile of a customer, built from every invoice a user has sent them.
+// The contact details are taken from the most recently updated invoice.
+type CustomerProfile struct {
+	CustomerDetails
+	InvoiceCount     int     `json:"invoice_count"`
+	TotalInvoiced    float64 `json:"total_invoiced"`
+	TotalOutstanding float64 `json:"total_outstanding"`
+}
+
+// SenderProfile is the public profile of the user sending an invoice.
+type SenderProfile struct {
+	ID          string `json:"id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+// NewSenderProfile extracts the public profile of a user.
+func NewSenderProfile(user *User) *SenderProfile {
+	return &SenderProfile{
+		ID:          user.ID,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+	}
+}
+
+// NewCustomerProfiles builds one CustomerProfile per customer email, keyed by the lowercased email.
+func NewCustomerProfiles(invoices []*Invoice) map[string]*CustomerProfile {
+	profiles := make(map[string]*CustomerProfile)
+	latest := make(map[string]*Invoice)
+
+	for _, invoice := range invoices {
+		key := strings.ToLower(invoice.Customer.Email)
+		profile, ok := profiles[key]
+		if !ok {
+			profile = &CustomerProfile{}
+			profiles[key] = profile
+		}
+
+		if last, ok := latest[key]; !ok || invoice.UpdatedAt.After(last.UpdatedAt) {
+			latest[key] = invoice
+			profile.CustomerDetails = invoice.Customer
+		}
+
+		profile.InvoiceCount++
+		if invoice.IsWithdrawn() {
+			continue
+		}
+		profile.TotalInvoiced += invoice.TotalAmountDue
+		if invoice.Status != "paid" && !invoice.IsDraft() {
+			profile.TotalOutstanding += invoice.TotalAmountDue
+		}
+	}
+
+	return profiles
+}
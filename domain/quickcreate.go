@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// defaultPaymentTermDays is the payment term of a clone when the original's dates cannot be parsed.
+const defaultPaymentTermDays = 30
+
+// CloneAsDraft copies the invoice into a new draft numbered invoiceNumber and issued on issueDate, for billing
+// a customer the same as last time. The payment term of the original is kept, so the due date moves with
+// the issue date. Payment, void, cancellation, reminder and PDF details are not copied.
+func (i *Invoice) CloneAsDraft(invoiceNumber string, issueDate time.Time) (*Invoice, error) {
+	items := make([]Item, len(i.Items))
+	copy(items, i.Items)
+
+	issue := issueDate.Format("2006-01-02")
+	due := issueDate.AddDate(0, 0, i.paymentTermDays()).Format("2006-01-02")
+
+	clone, err := NewInvoice("", invoiceNumber, i.BillingCurrency, i.Discount, issue, due, items,
+		i.PaymentInfo, i.Customer, i.Sender, "draft")
+	if err != nil {
+		return nil, err
+	}
+
+	clone.Notes = i.Notes
+	clone.ItemSort = i.ItemSort
+	clone.PaymentMethods = append([]PaymentMethod(nil), i.PaymentMethods...)
+	clone.Tags = append([]string(nil), i.Tags...)
+	if err := clone.SetExpenses(append([]Expense(nil), i.Expenses...)); err != nil {
+		return nil, err
+	}
+	if i.Tax != nil {
+		if err := clone.SetTax(i.Tax.Rate, i.Tax.Jurisdiction); err != nil {
+			return nil, err
+		}
+	}
+	return clone, nil
+}
+
+// paymentTermDays returns the number of days between the issue and due dates of the invoice.
+func (i *Invoice) paymentTermDays() int {
+	issueDate, err := time.Parse("2006-01-02", i.IssueDate)
+	if err != nil {
+		return defaultPaymentTermDays
+	}
+	dueDate, err := time.Parse("2006-01-02", i.DueDate)
+	if err != nil || dueDate.Before(issueDate) {
+		return defaultPaymentTermDays
+	}
+	return int(dueDate.Sub(issueDate).Hours() / 24)
+}
+
+// NextInvoiceNumber returns the number following previous by incrementing its trailing digits and keeping
+// their zero padding, so "INV-0042" becomes "INV-0043". A number without trailing digits gets "-2" appended.
+func NextInvoiceNumber(previous string) string {
+	start := len(previous)
+	for start > 0 && previous[start-1] >= '0' && previous[start-1] <= '9' {
+		start--
+	}
+	if start == len(previous) {
+		return previous + "-2"
+	}
+
+	digits := previous[start:]
+	next, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return previous + "-2"
+	}
+	return fmt.Sprintf("%s%0*d", previous[:start], len(digits), next+1)
+}
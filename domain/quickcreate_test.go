@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCloneAsDraft(t *testing.T) {
+	original, err := newValidInvoiceArgs().newInvoice()
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+	original.IssueDate = "2024-01-10"
+	original.DueDate = "2024-01-24"
+	original.Notes = "Thank you"
+	original.Tags = []string{"design"}
+	paidAt := time.Date(2024, 1, 20, 10, 0, 0, 0, time.UTC)
+	original.Status = "paid"
+	issuedAt := paidAt.AddDate(0, 0, -10)
+	original.IssuedAt = &issuedAt
+	original.PaidAt = &paidAt
+	original.Payments = []Payment{{Amount: original.TotalAmountDue, PaidAt: paidAt}}
+	original.PDFFileID = "stored-pdf"
+
+	// a clone is validated like a new invoice, so it cannot be issued in the past
+	issueDate := time.Now().UTC()
+	clone, err := original.CloneAsDraft("INV-000002", issueDate)
+	if err != nil {
+		t.Fatalf("CloneAsDraft: %v", err)
+	}
+
+	if clone.Status != "draft" || clone.InvoiceNumber != "INV-000002" || clone.InvoiceID == original.InvoiceID {
+		t.Errorf("clone = %s %s %s, want a draft INV-000002 with a new id", clone.InvoiceID, clone.InvoiceNumber, clone.Status)
+	}
+	// the 14 day payment term moves with the issue date
+	wantIssue, wantDue := issueDate.Format("2006-01-02"), issueDate.AddDate(0, 0, 14).Format("2006-01-02")
+	if clone.IssueDate != wantIssue || clone.DueDate != wantDue {
+		t.Errorf("clone dates = %s to %s, want %s to %s", clone.IssueDate, clone.DueDate, wantIssue, wantDue)
+	}
+	if clone.IssuedAt != nil || clone.PaidAt != nil || len(clone.Payments) != 0 || clone.PDFFileID != "" {
+		t.Errorf("clone kept the issue, payment or PDF details of the original: %+v", clone)
+	}
+	if clone.TotalAmountDue != original.TotalAmountDue || clone.Notes != original.Notes || clone.Customer != original.Customer {
+		t.Errorf("clone = total %v, notes %q, customer %+v; want the original's", clone.TotalAmountDue, clone.Notes, clone.Customer)
+	}
+
+	// editing the clone leaves the original as it was
+	clone.Items[0].Description = "Edited"
+	clone.Tags[0] = "edited"
+	if original.Items[0].Description != "Design work" || original.Tags[0] != "design" {
+		t.Errorf("editing the clone changed the original to items %+v, tags %v", original.Items, original.Tags)
+	}
+}
+
+func TestNextInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		previous string
+		want     string
+	}{
+		{previous: "INV-0042", want: "INV-0043"},
+		{previous: "INV-0099", want: "INV-0100"},
+		{previous: "INV-9", want: "INV-10"},
+		{previous: "2024", want: "2025"},
+		{previous: "INV", want: "INV-2"},
+		{previous: "", want: "-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.previous, func(t *testing.T) {
+			if got := NextInvoiceNumber(tt.previous); got != tt.want {
+				t.Errorf("NextInvoiceNumber(%q) = %q, want %q", tt.previous, got, tt.want)
+			}
+		})
+	}
+}
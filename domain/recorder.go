@@ -2,9 +2,11 @@ package domain
 
 import "time"
 
+// Activity is an action recorded for a user, such as a login or an invoice being issued. It is the single
+// schema activities are saved, read back and returned to clients with.
 type Activity struct {
-	UserID    string
-	Action    string
-	Timestamp time.Time
-	Metadata  map[string]interface{}
+	UserID    string                 `json:"user_id" bson:"user_id"`
+	Action    string                 `json:"action" bson:"action"`
+	Timestamp time.Time              `json:"timestamp" bson:"timestamp"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty" bson:"metadata,omitempty"`
 }
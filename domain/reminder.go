@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxInvoiceReminders is the maximum number of reminders on a single invoice.
+	MaxInvoiceReminders = 5
+	// MaxReminderDays is how many days before the due date a reminder can be sent at the earliest.
+	MaxReminderDays = 365
+)
+
+// InvoiceReminder is a message emailed to the customer a number of days before the invoice is due.
+// LastSentOn is the date it was last sent, so the scheduler never sends it twice on the same day.
+type InvoiceReminder struct {
+	DaysBeforeDueDate int    `json:"days_before_due_date" bson:"days_before_due_date"`
+	Message           string `json:"message" bson:"message"`
+	LastSentOn        string `json:"last_sent_on,omitempty" bson:"last_sent_on,omitempty"`
+}
+
+// ScheduledReminder is a reminder that is due to be sent today, with what is needed to send it.
+type ScheduledReminder struct {
+	UserID   string          `json:"user_id"`
+	Identity EmailIdentity   `json:"email_identity"`
+	Invoice  Invoice         `json:"invoice"`
+	Reminder InvoiceReminder `json:"reminder"`
+}
+
+// AddReminder adds a reminder sent daysBeforeDueDate days before the due date. Only one reminder can be
+// set per day.
+func (i *Invoice) AddReminder(daysBeforeDueDate int, message string) error {
+	message = strings.TrimSpace(message)
+	if daysBeforeDueDate < 1 || daysBeforeDueDate > MaxReminderDays {
+		return fmt.Errorf("reminders can be sent between 1 and %d days before the due date", MaxReminderDays)
+	}
+	if message == "" {
+		return errors.New("reminder message cannot be empty")
+	}
+	if i.Status == "paid" || i.IsWithdrawn() {
+		return fmt.Errorf("cannot add a reminder to a %s invoice", i.Status)
+	}
+	for _, reminder := range i.Reminders {
+		if reminder.DaysBeforeDueDate == daysBeforeDueDate {
+			return fmt.Errorf("a reminder %d days before the due date already exists", daysBeforeDueDate)
+		}
+	}
+	if len(i.Reminders) >= MaxInvoiceReminders {
+		return fmt.Errorf("an invoice can have at most %d reminders", MaxInvoiceReminders)
+	}
+
+	i.Reminders = append(i.Reminders, InvoiceReminder{
+		DaysBeforeDueDate: daysBeforeDueDate,
+		Message:           message,
+	})
+	i.UpdatedAt = time.Now()
+	return nil
+}
+
+// DueReminders returns the reminders of the invoice to send on today: those whose due date minus
+// DaysBeforeDueDate is today and that have not been sent today. Only issued invoices get reminders.
+func (i *Invoice) DueReminders(today time.Time) []InvoiceReminder {
+	if i.Status != "issued" {
+		return nil
+	}
+
+	dueDate, err := time.Parse("2006-01-02", i.DueDate)
+	if err != nil {
+		return nil
+	}
+
+	day := today.Format("2006-01-02")
+	due := make([]InvoiceReminder, 0)
+	for _, reminder := range i.Reminders {
+		if reminder.LastSentOn == day {
+			continue
+		}
+		if dueDate.AddDate(0, 0, -reminder.DaysBeforeDueDate).Format("2006-01-02") == day {
+			due = append(due, reminder)
+		}
+	}
+	return due
+}
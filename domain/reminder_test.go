@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvoiceAddReminder(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		reminders []InvoiceReminder
+		days      int
+		message   string
+		wantErr   bool
+	}{
+		{name: "added", status: "draft", days: 3, message: "Due in three days"},
+		{name: "issued invoice", status: "issued", days: 3, message: "Due in three days"},
+		{name: "zero days", status: "draft", days: 0, message: "Due today", wantErr: true},
+		{name: "too many days", status: "draft", days: MaxReminderDays + 1, message: "Due next year", wantErr: true},
+		{name: "blank message", status: "draft", days: 3, message: "   ", wantErr: true},
+		{name: "paid invoice", status: "paid", days: 3, message: "Due in three days", wantErr: true},
+		{name: "cancelled invoice", status: "cancelled", days: 3, message: "Due in three days", wantErr: true},
+		{
+			name:      "same day twice",
+			status:    "draft",
+			reminders: []InvoiceReminder{{DaysBeforeDueDate: 3, Message: "Due in three days"}},
+			days:      3,
+			message:   "Still due in three days",
+			wantErr:   true,
+		},
+		{
+			name:   "too many reminders",
+			status: "draft",
+			reminders: []InvoiceReminder{
+				{DaysBeforeDueDate: 1}, {DaysBeforeDueDate: 2}, {DaysBeforeDueDate: 4}, {DaysBeforeDueDate: 5}, {DaysBeforeDueDate: 6},
+			},
+			days:    3,
+			message: "Due in three days",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &Invoice{Status: tt.status, Reminders: append([]InvoiceReminder(nil), tt.reminders...)}
+
+			err := invoice.AddReminder(tt.days, tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddReminder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(invoice.Reminders) != len(tt.reminders) {
+					t.Errorf("rejected reminder was added: %+v", invoice.Reminders)
+				}
+				return
+			}
+
+			want := InvoiceReminder{DaysBeforeDueDate: tt.days, Message: tt.message}
+			if len(invoice.Reminders) != 1 || invoice.Reminders[0] != want {
+				t.Errorf("Reminders = %+v, want [%+v]", invoice.Reminders, want)
+			}
+		})
+	}
+}
+
+func TestInvoiceDueReminders(t *testing.T) {
+	today := time.Date(2024, 6, 10, 9, 30, 0, 0, time.UTC)
+	reminders := []InvoiceReminder{
+		{DaysBeforeDueDate: 3, Message: "Due in three days"},
+		{DaysBeforeDueDate: 7, Message: "Due in a week"},
+	}
+
+	tests := []struct {
+		name      string
+		status    string
+		dueDate   string
+		reminders []InvoiceReminder
+		wantDays  []int
+	}{
+		{name: "due in three days", status: "issued", dueDate: "2024-06-13", reminders: reminders, wantDays: []int{3}},
+		{name: "due in a week", status: "issued", dueDate: "2024-06-17", reminders: reminders, wantDays: []int{7}},
+		{name: "no reminder today", status: "issued", dueDate: "2024-06-14", reminders: reminders},
+		{
+			name:      "already sent today",
+			status:    "issued",
+			dueDate:   "2024-06-13",
+			reminders: []InvoiceReminder{{DaysBeforeDueDate: 3, Message: "Due in three days", LastSentOn: "2024-06-10"}},
+		},
+		{
+			name:      "sent on an earlier day",
+			status:    "issued",
+			dueDate:   "2024-06-13",
+			reminders: []InvoiceReminder{{DaysBeforeDueDate: 3, Message: "Due in three days", LastSentOn: "2024-06-09"}},
+			wantDays:  []int{3},
+		},
+		{name: "draft", status: "draft", dueDate: "2024-06-13", reminders: reminders},
+		{name: "paid", status: "paid", dueDate: "2024-06-13", reminders: reminders},
+		{name: "malformed due date", status: "issued", dueDate: "13/06/2024", reminders: reminders},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &Invoice{Status: tt.status, DueDate: tt.dueDate, Reminders: tt.reminders}
+
+			due := invoice.DueReminders(today)
+			if len(due) != len(tt.wantDays) {
+				t.Fatalf("DueReminders() = %+v, want reminders %v days before the due date", due, tt.wantDays)
+			}
+			for i, reminder := range due {
+				if reminder.DaysBeforeDueDate != tt.wantDays[i] {
+					t.Errorf("DueReminders()[%d] is %d days before the due date, want %d", i, reminder.DaysBeforeDueDate, tt.wantDays[i])
+				}
+			}
+		})
+	}
+}
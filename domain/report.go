@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// ReportTotal is the number and amount of invoices sharing a status and currency in a report. Status is empty
+// for the grand total of a currency.
+type ReportTotal struct {
+	Status   string  `json:"status,omitempty"`
+	Currency string  `json:"currency"`
+	Count    int     `json:"count"`
+	Amount   float64 `json:"amount"`
+}
+
+// MonthlyReport summarizes the invoices a user issued in a month, with totals by status.
+type MonthlyReport struct {
+	Month       string        `json:"month"`
+	Currency    string        `json:"currency,omitempty"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Invoices    []Invoice     `json:"invoices"`
+	ByStatus    []ReportTotal `json:"by_status"`
+	Totals      []ReportTotal `json:"totals"`
+}
+
+// ReportAmount returns the amount and currency an invoice counts for in a report in currency: its base amount
+// when it was recorded in that currency, otherwise its total in the billing currency.
+func (i *Invoice) ReportAmount(currency string) (float64, string) {
+	if currency != "" && i.BaseCurrency == currency {
+		return i.BaseAmountDue, i.BaseCurrency
+	}
+	return i.TotalAmountDue, i.BillingCurrency
+}
+
+// NewMonthlyReport builds the report of month from the invoices issued in it. Amounts are reported in the
+// user's currency where the invoice has a base amount in it, so invoices left in another currency are totalled
+// apart. Status totals follow the order of InvoiceStatuses.
+func NewMonthlyReport(month time.Time, currency string, invoices []Invoice, generatedAt time.Time) *MonthlyReport {
+	type key struct{ status, currency string }
+	byStatus := make(map[key]*ReportTotal)
+	totals := make(map[string]*ReportTotal)
+
+	for idx := range invoices {
+		amount, invoiceCurrency := invoices[idx].ReportAmount(currency)
+
+		k := key{invoices[idx].Status, invoiceCurrency}
+		if byStatus[k] == nil {
+			byStatus[k] = &ReportTotal{Status: k.status, Currency: k.currency}
+		}
+		byStatus[k].Count++
+		byStatus[k].Amount += amount
+
+		// voided and cancelled invoices are listed but do not add to the totals
+		if invoices[idx].IsWithdrawn() {
+			continue
+		}
+		if totals[invoiceCurrency] == nil {
+			totals[invoiceCurrency] = &ReportTotal{Currency: invoiceCurrency}
+		}
+		totals[invoiceCurrency].Count++
+		totals[invoiceCurrency].Amount += amount
+	}
+
+	report := &MonthlyReport{
+		Month:       month.Format("2006-01"),
+		Currency:    currency,
+		GeneratedAt: generatedAt,
+		Invoices:    invoices,
+		ByStatus:    make([]ReportTotal, 0, len(byStatus)),
+		Totals:      make([]ReportTotal, 0, len(totals)),
+	}
+
+	statusOrder := make(map[string]int, len(InvoiceStatuses))
+	for idx, status := range InvoiceStatuses {
+		statusOrder[status] = idx
+	}
+	for _, total := range byStatus {
+		total.Amount = roundCents(total.Amount)
+		report.ByStatus = append(report.ByStatus, *total)
+	}
+	sort.Slice(report.ByStatus, func(a, b int) bool {
+		if report.ByStatus[a].Status != report.ByStatus[b].Status {
+			return statusOrder[report.ByStatus[a].Status] < statusOrder[report.ByStatus[b].Status]
+		}
+		return report.ByStatus[a].Currency < report.ByStatus[b].Currency
+	})
+
+	for _, total := range totals {
+		total.Amount = roundCents(total.Amount)
+		report.Totals = append(report.Totals, *total)
+	}
+	// the user's currency comes first, then the others alphabetically
+	sort.Slice(report.Totals, func(a, b int) bool {
+		if (report.Totals[a].Currency == currency) != (report.Totals[b].Currency == currency) {
+			return report.Totals[a].Currency == currency
+		}
+		return report.Totals[a].Currency < report.Totals[b].Currency
+	})
+
+	return report
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ScheduledSend is an invoice whose scheduled send time has come.
+type ScheduledSend struct {
+	UserID    string    `json:"user_id" bson:"user_id"`
+	InvoiceID string    `json:"invoice_id" bson:"invoice_id"`
+	SendAt    time.Time `json:"scheduled_send_at" bson:"scheduled_send_at"`
+}
+
+// ScheduleSend schedules an invoice that has not been issued yet to be issued and emailed to its customer at
+// sendAt, which must be after now. A scheduled invoice can be rescheduled until it is sent.
+func (i *Invoice) ScheduleSend(sendAt, now time.Time) error {
+	if !i.IsDraft() {
+		return fmt.Errorf("only draft, pending or scheduled invoices can be scheduled, invoice is %s", i.Status)
+	}
+	if !sendAt.After(now) {
+		return errors.New("scheduled send time must be in the future")
+	}
+
+	i.Status = "scheduled"
+	i.ScheduledSendAt = &sendAt
+	i.UpdatedAt = now
+	return nil
+}
+
+// UnscheduleSend returns a scheduled invoice to draft, so it is not sent.
+func (i *Invoice) UnscheduleSend() error {
+	if i.Status != "scheduled" {
+		return fmt.Errorf("invoice is not scheduled to be sent, invoice is %s", i.Status)
+	}
+
+	i.Status = "draft"
+	i.ScheduledSendAt = nil
+	i.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SenderPolicy decides which sender emails a user may put on an invoice. When enforced, the sender email must
+// be the user's account email, or an address on one of TeamDomains when the account email is on that same
+// domain, so a team can bill from a shared mailbox such as billing@team.com.
+type SenderPolicy struct {
+	Enforce     bool
+	TeamDomains []string
+}
+
+// NewSenderPolicy creates a SenderPolicy, lower-casing the team domains and dropping empty ones.
+func NewSenderPolicy(enforce bool, teamDomains []string) SenderPolicy {
+	domains := make([]string, 0, len(teamDomains))
+	for _, domain := range teamDomains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return SenderPolicy{Enforce: enforce, TeamDomains: domains}
+}
+
+// Check returns an error wrapping ErrSenderEmailMismatch when the policy is enforced and the user may not
+// send invoices as senderEmail.
+func (p SenderPolicy) Check(user *User, senderEmail string) error {
+	if !p.Enforce {
+		return nil
+	}
+
+	sender := strings.ToLower(strings.TrimSpace(senderEmail))
+	account := strings.ToLower(strings.TrimSpace(user.Email))
+	if sender == account {
+		return nil
+	}
+
+	senderDomain := emailDomain(sender)
+	if senderDomain != "" && senderDomain == emailDomain(account) {
+		for _, domain := range p.TeamDomains {
+			if senderDomain == domain {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: invoices must be sent from %s", ErrSenderEmailMismatch, user.Email)
+}
+
+// emailDomain returns the part of an email address after the @, or an empty string when there is none.
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return email[at+1:]
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "strings"
+
+// CustomerStatement summarises the invoices a user has sent to one customer.
+type CustomerStatement struct {
+	CustomerEmail    string    `json:"customer_email"`
+	InvoiceCount     int       `json:"invoice_count"`
+	TotalInvoiced    float64   `json:"total_invoiced"`
+	TotalPaid        float64   `json:"total_paid"`
+	TotalOutstanding float64   `json:"total_outstanding"`
+	Invoices         []Invoice `json:"invoices"`
+}
+
+// NewCustomerStatement builds a statement from the invoices sent to a customer.
+func NewCustomerStatement(customerEmail string, invoices []Invoice) *CustomerStatement {
+	statement := &CustomerStatement{
+		CustomerEmail: customerEmail,
+		InvoiceCount:  len(invoices),
+		Invoices:      invoices,
+	}
+
+	for _, invoice := range invoices {
+		// voided and cancelled invoices stay listed for audit but no longer count towards the totals
+		if invoice.IsWithdrawn() {
+			continue
+		}
+		statement.TotalInvoiced += invoice.TotalAmountDue
+		if invoice.Status == "paid" {
+			statement.TotalPaid += invoice.TotalAmountDue
+			continue
+		}
+		statement.TotalOutstanding += invoice.TotalAmountDue
+	}
+
+	return statement
+}
+
+// DistinctCustomers returns the customers found on the given invoices, one entry per email address.
+func DistinctCustomers(invoices []*Invoice) []CustomerDetails {
+	seen := make(map[string]bool)
+	customers := make([]CustomerDetails, 0)
+
+	for _, invoice := range invoices {
+		key := strings.ToLower(invoice.Customer.Email)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		customers = append(customers, invoice.Customer)
+	}
+
+	return customers
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// customStatusPattern matches the name of a custom invoice status, e.g. "partially_paid".
+var customStatusPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// maxCustomStatuses is the most custom statuses an account can define.
+const maxCustomStatuses = 20
+
+// CustomStatus is an invoice status defined by an account on top of the core InvoiceStatuses. It labels
+// invoices of one core status, its bucket, so an invoice labelled "partially_paid" still counts as "issued"
+// in every report and aggregation.
+type CustomStatus struct {
+	Name   string `json:"name" bson:"name"`
+	Bucket string `json:"bucket" bson:"bucket"`
+}
+
+// StatusConfig is the set of custom invoice statuses of an account and the transitions allowed into them. The
+// account is the organization its invoices belong to. The core statuses and their transitions always apply.
+type StatusConfig struct {
+	Statuses []CustomStatus `json:"statuses" bson:"statuses"`
+	// Transitions maps a status to the custom statuses an invoice in it may be labelled with
+	Transitions map[string][]string `json:"transitions" bson:"transitions"`
+}
+
+// CoreStatusTransitions lists the core statuses an invoice can move to from each core status.
+var CoreStatusTransitions = map[string][]string{
+	"draft":     {"pending", "scheduled", "issued", "cancelled"},
+	"pending":   {"draft", "scheduled", "issued", "cancelled"},
+	"scheduled": {"draft", "pending", "issued", "cancelled"},
+	"issued":    {"overdue", "paid", "voided", "cancelled"},
+	"overdue":   {"paid", "voided", "cancelled"},
+	"paid":      {"refunded"},
+	"refunded":  {},
+	"voided":    {},
+	"cancelled": {},
+}
+
+// Custom returns the custom status with the given name, or nil when the configuration has none.
+func (c StatusConfig) Custom(name string) *CustomStatus {
+	for idx := range c.Statuses {
+		if c.Statuses[idx].Name == name {
+			return &c.Statuses[idx]
+		}
+	}
+	return nil
+}
+
+// IsStatus reports whether status is a core status or one of the custom statuses.
+func (c StatusConfig) IsStatus(status string) bool {
+	return IsInvoiceStatus(status) || c.Custom(status) != nil
+}
+
+// Bucket returns the core status a status counts as: the status itself for a core status, the bucket of a
+// custom status, or an empty string for an unknown status.
+func (c StatusConfig) Bucket(status string) string {
+	if IsInvoiceStatus(status) {
+		return status
+	}
+	if custom := c.Custom(status); custom != nil {
+		return custom.Bucket
+	}
+	return ""
+}
+
+// CanTransition reports whether an invoice in status from may move to status to. Core statuses follow
+// CoreStatusTransitions. An invoice may be labelled with a custom status when the configuration allows it from
+// its current status, and a custom status may always be dropped for its own bucket.
+func (c StatusConfig) CanTransition(from, to string) bool {
+	if from == to || !c.IsStatus(from) || !c.IsStatus(to) {
+		return false
+	}
+	if c.Custom(to) != nil {
+		return slices.Contains(c.Transitions[from], to)
+	}
+	if c.Custom(from) != nil && c.Bucket(from) == to {
+		return true
+	}
+	return slices.Contains(CoreStatusTransitions[c.Bucket(from)], to)
+}
+
+// Validate checks the custom statuses and transitions: names must be unique lower-case identifiers that do not
+// shadow a core status, buckets must be core statuses, and a transition into a custom status must come from a
+// status of the same bucket, since a label never changes the core status of an invoice.
+func (c StatusConfig) Validate() error {
+	if len(c.Statuses) > maxCustomStatuses {
+		return fmt.Errorf("at most %d custom statuses can be defined", maxCustomStatuses)
+	}
+
+	seen := make(map[string]bool, len(c.Statuses))
+	for _, status := range c.Statuses {
+		if !customStatusPattern.MatchString(status.Name) {
+			return fmt.Errorf("invalid custom status %q, expected lower-case letters, digits and underscores", status.Name)
+		}
+		if IsInvoiceStatus(status.Name) {
+			return fmt.Errorf("custom status %q cannot replace a core status", status.Name)
+		}
+		if seen[status.Name] {
+			return fmt.Errorf("custom status %q is defined more than once", status.Name)
+		}
+		seen[status.Name] = true
+		if !IsInvoiceStatus(status.Bucket) {
+			return fmt.Errorf("custom status %q must count as one of %v, got %q", status.Name, InvoiceStatuses, status.Bucket)
+		}
+	}
+
+	for from, targets := range c.Transitions {
+		if !c.IsStatus(from) {
+			return fmt.Errorf("transition from unknown status %q", from)
+		}
+		for _, to := range targets {
+			custom := c.Custom(to)
+			if custom == nil {
+				return fmt.Errorf("transition from %q to %q: only custom statuses can be configured as targets", from, to)
+			}
+			if c.Bucket(from) != custom.Bucket {
+				return fmt.Errorf("transition from %q to %q: both statuses must count as the same core status", from, to)
+			}
+		}
+	}
+	return nil
+}
+
+// EffectiveStatus returns the custom status the invoice is labelled with, or its core status when it has no
+// label or the label belongs to a core status the invoice has since left.
+func (i *Invoice) EffectiveStatus() string {
+	if i.CustomStatus != nil && i.CustomStatus.Bucket == i.Status {
+		return i.CustomStatus.Name
+	}
+	return i.Status
+}
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+// testStatusConfig labels issued invoices as partially paid and in dispute, and paid ones as reconciled.
+func testStatusConfig() StatusConfig {
+	return StatusConfig{
+		Statuses: []CustomStatus{
+			{Name: "partially_paid", Bucket: "issued"},
+			{Name: "in_dispute", Bucket: "issued"},
+			{Name: "reconciled", Bucket: "paid"},
+		},
+		Transitions: map[string][]string{
+			"issued":         {"partially_paid", "in_dispute"},
+			"partially_paid": {"in_dispute"},
+			"paid":           {"reconciled"},
+		},
+	}
+}
+
+func TestStatusConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  StatusConfig
+		wantErr bool
+	}{
+		{name: "empty", config: StatusConfig{}},
+		{name: "valid", config: testStatusConfig()},
+		{
+			name:    "invalid name",
+			config:  StatusConfig{Statuses: []CustomStatus{{Name: "Partially Paid", Bucket: "issued"}}},
+			wantErr: true,
+		},
+		{
+			name:    "shadows a core status",
+			config:  StatusConfig{Statuses: []CustomStatus{{Name: "paid", Bucket: "issued"}}},
+			wantErr: true,
+		},
+		{
+			name:    "defined twice",
+			config:  StatusConfig{Statuses: []CustomStatus{{Name: "in_dispute", Bucket: "issued"}, {Name: "in_dispute", Bucket: "overdue"}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown bucket",
+			config:  StatusConfig{Statuses: []CustomStatus{{Name: "in_dispute", Bucket: "disputed"}}},
+			wantErr: true,
+		},
+		{
+			name: "transition from unknown status",
+			config: StatusConfig{
+				Statuses:    []CustomStatus{{Name: "in_dispute", Bucket: "issued"}},
+				Transitions: map[string][]string{"archived": {"in_dispute"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "transition into a core status",
+			config: StatusConfig{
+				Statuses:    []CustomStatus{{Name: "in_dispute", Bucket: "issued"}},
+				Transitions: map[string][]string{"in_dispute": {"paid"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "transition across buckets",
+			config: StatusConfig{
+				Statuses:    []CustomStatus{{Name: "in_dispute", Bucket: "issued"}},
+				Transitions: map[string][]string{"draft": {"in_dispute"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusConfigCanTransition(t *testing.T) {
+	config := testStatusConfig()
+
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{from: "issued", to: "partially_paid", want: true},
+		{from: "partially_paid", to: "in_dispute", want: true},
+		{from: "in_dispute", to: "partially_paid", want: false},
+		{from: "partially_paid", to: "issued", want: true},
+		{from: "partially_paid", to: "paid", want: true},
+		{from: "draft", to: "partially_paid", want: false},
+		{from: "paid", to: "reconciled", want: true},
+		{from: "paid", to: "draft", want: false},
+		{from: "issued", to: "issued", want: false},
+		{from: "issued", to: "archived", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			if got := config.CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusConfigBucket(t *testing.T) {
+	config := testStatusConfig()
+
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{status: "draft", want: "draft"},
+		{status: "partially_paid", want: "issued"},
+		{status: "reconciled", want: "paid"},
+		{status: "archived", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := config.Bucket(tt.status); got != tt.want {
+				t.Errorf("Bucket(%q) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvoiceEffectiveStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		label  *CustomStatus
+		want   string
+	}{
+		{name: "no label", status: "issued", want: "issued"},
+		{name: "labelled", status: "issued", label: &CustomStatus{Name: "partially_paid", Bucket: "issued"}, want: "partially_paid"},
+		{name: "label of a status the invoice left", status: "paid", label: &CustomStatus{Name: "partially_paid", Bucket: "issued"}, want: "paid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &Invoice{Status: tt.status, CustomStatus: tt.label}
+			if got := invoice.EffectiveStatus(); got != tt.want {
+				t.Errorf("EffectiveStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonthlyReportCountsCustomStatusUnderItsBucket(t *testing.T) {
+	invoices := []Invoice{
+		{Status: "issued", BillingCurrency: "USD", TotalAmountDue: 100},
+		{Status: "issued", BillingCurrency: "USD", TotalAmountDue: 50, CustomStatus: &CustomStatus{Name: "partially_paid", Bucket: "issued"}},
+	}
+
+	report := NewMonthlyReport(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "USD", invoices, time.Now())
+
+	want := ReportTotal{Status: "issued", Currency: "USD", Count: 2, Amount: 150}
+	if len(report.ByStatus) != 1 || report.ByStatus[0] != want {
+		t.Errorf("ByStatus = %+v, want [%+v]", report.ByStatus, want)
+	}
+}
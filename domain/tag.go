@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxInvoiceTags is the maximum number of tags on a single invoice.
+	MaxInvoiceTags = 10
+	// MaxTagLength is the maximum length of a single tag.
+	MaxTagLength = 32
+)
+
+// NormalizeTags trims and lower-cases tags and drops duplicates, keeping the first occurrence order.
+// Tags may only contain letters, digits, spaces, dashes and underscores.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, fmt.Errorf("tags cannot be empty")
+		}
+		if len(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+		for _, r := range tag {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == ' ') {
+				return nil, fmt.Errorf("tag %q contains invalid characters", tag)
+			}
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > MaxInvoiceTags {
+		return nil, fmt.Errorf("an invoice can have at most %d tags", MaxInvoiceTags)
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// UnassignedJurisdiction is the jurisdiction tax is reported under when the invoice does not name one.
+const UnassignedJurisdiction = "unassigned"
+
+// jurisdictionPattern matches an ISO 3166-1 alpha-2 country code, optionally followed by an ISO 3166-2
+// subdivision, e.g. "NG" or "US-CA".
+var jurisdictionPattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// InvoiceTax is the sales tax charged on an invoice and the jurisdiction it is owed to. The tax applies to the
+// discounted billable items and the expenses marked taxable.
+type InvoiceTax struct {
+	Jurisdiction string  `json:"jurisdiction,omitempty" bson:"jurisdiction,omitempty"`
+	Rate         float64 `json:"rate" bson:"rate"`
+	Amount       float64 `json:"amount" bson:"amount"`
+}
+
+// NormalizeJurisdiction upper-cases a jurisdiction code and checks it is a country or country subdivision code.
+// An empty code is allowed, for tax that is not owed to a particular jurisdiction.
+func NormalizeJurisdiction(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code != "" && !jurisdictionPattern.MatchString(code) {
+		return "", fmt.Errorf("invalid tax jurisdiction %q, expected a country code such as \"NG\" or a subdivision such as \"US-CA\"", code)
+	}
+	return code, nil
+}
+
+// SetTax sets the tax rate, as a percentage, and the jurisdiction of the invoice and recalculates the total
+// amount due. A zero rate without a jurisdiction removes the tax.
+func (i *Invoice) SetTax(rate float64, jurisdiction string) error {
+	if rate < 0 || rate > 100 {
+		return fmt.Errorf("tax rate is a percentage and must be between 0 and 100, got %v", rate)
+	}
+	jurisdiction, err := NormalizeJurisdiction(jurisdiction)
+	if err != nil {
+		return err
+	}
+
+	if rate == 0 && jurisdiction == "" {
+		i.Tax = nil
+	} else {
+		i.Tax = &InvoiceTax{Jurisdiction: jurisdiction, Rate: rate}
+	}
+	i.recalculateTotal()
+	return nil
+}
+
+// TaxAmount returns the tax charged on the invoice.
+func (i *Invoice) TaxAmount() float64 {
+	if i.Tax == nil {
+		return 0
+	}
+	return i.Tax.Amount
+}
+
+// calculateTaxAmount returns the tax a percentage rate adds to the taxable amount, rounded to cents.
+func calculateTaxAmount(taxable, rate float64) float64 {
+	return roundCents(taxable * rate / 100)
+}
+
+// JurisdictionTax is the tax collected for a jurisdiction at one rate and in one currency.
+type JurisdictionTax struct {
+	Jurisdiction  string  `json:"jurisdiction" bson:"jurisdiction"`
+	Rate          float64 `json:"rate" bson:"rate"`
+	Currency      string  `json:"currency" bson:"currency"`
+	TaxableAmount float64 `json:"taxable_amount" bson:"taxable_amount"`
+	TaxCollected  float64 `json:"tax_collected" bson:"tax_collected"`
+	InvoiceCount  int     `json:"invoice_count" bson:"invoice_count"`
+}
@@ -0,0 +1,123 @@
+package domain
+
+import "testing"
+
+func TestNormalizeJurisdiction(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{code: "", want: ""},
+		{code: "ng", want: "NG"},
+		{code: " us-ca ", want: "US-CA"},
+		{code: "GB-ENG", want: "GB-ENG"},
+		{code: "USA", wantErr: true},
+		{code: "US-", wantErr: true},
+		{code: "US-CALI", wantErr: true},
+		{code: "1A", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := NormalizeJurisdiction(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeJurisdiction(%q) error = %v, wantErr %v", tt.code, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeJurisdiction(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvoiceSetTax(t *testing.T) {
+	tests := []struct {
+		name         string
+		rate         float64
+		jurisdiction string
+		wantTax      *InvoiceTax
+		wantTotal    float64
+		wantErr      bool
+	}{
+		// 300 of items less 10% is 270, plus 25 of expenses
+		{name: "no tax", wantTotal: 295},
+		{name: "taxed", rate: 7.5, jurisdiction: "us-ca", wantTax: &InvoiceTax{Jurisdiction: "US-CA", Rate: 7.5, Amount: 20.25}, wantTotal: 315.25},
+		{name: "without jurisdiction", rate: 5, wantTax: &InvoiceTax{Rate: 5, Amount: 13.5}, wantTotal: 308.5},
+		{name: "negative rate", rate: -1, wantErr: true},
+		{name: "rate above 100", rate: 101, wantErr: true},
+		{name: "invalid jurisdiction", rate: 5, jurisdiction: "California", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := newValidInvoiceArgs()
+			args.discount = 10
+			invoice, err := args.newInvoice()
+			if err != nil {
+				t.Fatalf("NewInvoice: %v", err)
+			}
+			if err := invoice.SetExpenses([]Expense{{Description: "Courier", Amount: 25}}); err != nil {
+				t.Fatalf("SetExpenses: %v", err)
+			}
+
+			err = invoice.SetTax(tt.rate, tt.jurisdiction)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetTax() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			switch {
+			case tt.wantTax == nil && invoice.Tax != nil:
+				t.Errorf("Tax = %+v, want none", *invoice.Tax)
+			case tt.wantTax != nil && (invoice.Tax == nil || *invoice.Tax != *tt.wantTax):
+				t.Errorf("Tax = %+v, want %+v", invoice.Tax, *tt.wantTax)
+			}
+			if invoice.TotalAmountDue != tt.wantTotal {
+				t.Errorf("TotalAmountDue = %v, want %v", invoice.TotalAmountDue, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestInvoiceTaxFollowsDiscount(t *testing.T) {
+	invoice, err := newValidInvoiceArgs().newInvoice()
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+	if err := invoice.SetTax(10, "NG"); err != nil {
+		t.Fatalf("SetTax: %v", err)
+	}
+	if err := invoice.UpdateDiscount(50); err != nil {
+		t.Fatalf("UpdateDiscount: %v", err)
+	}
+
+	// 300 less 50% is 150, plus 10% tax
+	if invoice.TaxAmount() != 15 || invoice.TotalAmountDue != 165 {
+		t.Errorf("TaxAmount() = %v, TotalAmountDue = %v, want 15 and 165", invoice.TaxAmount(), invoice.TotalAmountDue)
+	}
+}
+
+func TestInvoiceTaxOnTaxableExpenses(t *testing.T) {
+	invoice, err := newValidInvoiceArgs().newInvoice()
+	if err != nil {
+		t.Fatalf("NewInvoice: %v", err)
+	}
+	if err := invoice.SetTax(10, "NG"); err != nil {
+		t.Fatalf("SetTax: %v", err)
+	}
+	expenses := []Expense{
+		{Description: "Courier", Amount: 25},
+		{Description: "Materials", Amount: 50, Taxable: true},
+	}
+	if err := invoice.SetExpenses(expenses); err != nil {
+		t.Fatalf("SetExpenses: %v", err)
+	}
+
+	// 10% tax on the 300 of items and the 50 of taxable materials, not on the courier
+	if invoice.TaxAmount() != 35 || invoice.TotalAmountDue != 410 {
+		t.Errorf("TaxAmount() = %v, TotalAmountDue = %v, want 35 and 410", invoice.TaxAmount(), invoice.TotalAmountDue)
+	}
+}
@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"regexp"
@@ -15,17 +18,27 @@ const emailRegex = `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
 
 // User represents a user.
 type User struct {
-	ID          string    `json:"id" bson:"_id,omitempty" validate:"required"`
-	FirstName   string    `json:"first_name" bson:"first_name" validate:"required"`
-	LastName    string    `json:"last_name" bson:"last_name" validate:"required"`
-	Email       string    `json:"email" bson:"email" validate:"required,email"`
-	Password    string    `json:"password" bson:"password" validate:"required"`
-	PhoneNumber string    `json:"phone_number" bson:"phone_number" validate:"required"`
-	CreatedAt   time.Time `json:"created_at" bson:"created_at" validate:"required"`
-	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at" validate:"required"`
-	// InvoiceSummary InvoiceSummary `json:"invoice_summary" bson:"invoice_summary"`
+	ID              string         `json:"id" bson:"_id,omitempty" validate:"required"`
+	FirstName       string         `json:"first_name" bson:"first_name" validate:"required"`
+	LastName        string         `json:"last_name" bson:"last_name" validate:"required"`
+	Email           string         `json:"email" bson:"email" validate:"required,email"`
+	Password        string         `json:"-" bson:"password" validate:"required"`
+	PhoneNumber     string         `json:"phone_number" bson:"phone_number" validate:"required"`
+	CreatedAt       time.Time      `json:"created_at" bson:"created_at" validate:"required"`
+	UpdatedAt       time.Time      `json:"updated_at" bson:"updated_at" validate:"required"`
+	InvoiceSummary  InvoiceSummary `json:"invoice_summary" bson:"invoice_summary"`
+	DefaultCurrency string         `json:"default_currency,omitempty" bson:"default_currency,omitempty"`
+	EmailIdentity   EmailIdentity  `json:"email_identity" bson:"email_identity,omitempty"`
+	CreditLimits    []CreditLimit  `json:"credit_limits,omitempty" bson:"credit_limits,omitempty"`
+	InvoiceCounter  int64          `json:"-" bson:"invoice_counter,omitempty"`
+	EmailVerified   bool           `json:"email_verified" bson:"email_verified"`
+	// VerificationToken is the SHA-256 hash of the pending email verification token
+	VerificationToken     string    `json:"-" bson:"verification_token,omitempty"`
+	VerificationExpiresAt time.Time `json:"-" bson:"verification_expires_at,omitempty"`
 	// ActivityLog    []Activity     `json:"activity_log" bson:"activity_log"`
-	Token string `json:"token,omitempty" bson:"token,omitempty"`
+	Token string `json:"-" bson:"token,omitempty"`
+	// StatusConfig holds the custom invoice statuses of the account
+	StatusConfig StatusConfig `json:"status_config" bson:"status_config,omitempty"`
 }
 
 // NewUser creates a new User
@@ -52,6 +65,48 @@ func NewUser(
 	}, nil
 }
 
+// EmailVerificationTTL is how long an email verification token can be used.
+const EmailVerificationTTL = 24 * time.Hour
+
+// StartEmailVerification creates a new email verification token for the user and returns it.
+// Only the hash of the token is kept on the user, so a leaked user document cannot verify the email.
+func (u *User) StartEmailVerification() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("cannot generate verification token: %w", err)
+	}
+
+	token := hex.EncodeToString(raw)
+	u.EmailVerified = false
+	u.VerificationToken = HashVerificationToken(token)
+	u.VerificationExpiresAt = time.Now().Add(EmailVerificationTTL)
+	return token, nil
+}
+
+// HashVerificationToken returns the hash stored for an email verification token.
+func HashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// UpdateProfile changes the user's name and phone number. The email and password are changed through
+// their own flows and are never touched here.
+func (u *User) UpdateProfile(firstName, lastName, phoneNumber string) error {
+	firstName = strings.TrimSpace(firstName)
+	lastName = strings.TrimSpace(lastName)
+	phoneNumber = strings.TrimSpace(phoneNumber)
+
+	if err := validateFields(firstName, lastName, u.Email, gonull.NewNullable(phoneNumber)); err != nil {
+		return err
+	}
+
+	u.FirstName = firstName
+	u.LastName = lastName
+	u.PhoneNumber = phoneNumber
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
 // validateEmail checks if the provided email address is in a valid format.
 func validateEmail(email string) error {
 	// using regular expression for validating an email address.
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartEmailVerification(t *testing.T) {
+	user := &User{EmailVerified: true}
+
+	before := time.Now()
+	token, err := user.StartEmailVerification()
+	if err != nil {
+		t.Fatalf("StartEmailVerification: %v", err)
+	}
+
+	if len(token) != 64 {
+		t.Errorf("token length = %d, want 64", len(token))
+	}
+	if user.EmailVerified {
+		t.Error("EmailVerified = true, want false until the token is used")
+	}
+	if user.VerificationToken == token {
+		t.Error("VerificationToken holds the raw token, want its hash")
+	}
+	if user.VerificationToken != HashVerificationToken(token) {
+		t.Errorf("VerificationToken = %q, want %q", user.VerificationToken, HashVerificationToken(token))
+	}
+	if expires := user.VerificationExpiresAt.Sub(before); expires < EmailVerificationTTL || expires > EmailVerificationTTL+time.Minute {
+		t.Errorf("VerificationExpiresAt is %v after the start, want %v", expires, EmailVerificationTTL)
+	}
+
+	next, err := user.StartEmailVerification()
+	if err != nil {
+		t.Fatalf("StartEmailVerification: %v", err)
+	}
+	if next == token {
+		t.Error("StartEmailVerification() returned the same token twice")
+	}
+	if user.VerificationToken != HashVerificationToken(next) {
+		t.Error("StartEmailVerification() kept the previous token")
+	}
+}